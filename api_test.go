@@ -0,0 +1,2517 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"simple-cashier-api/database"
+	"simple-cashier-api/handlers"
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+	"simple-cashier-api/repositories/memory"
+	"simple-cashier-api/services"
+)
+
+// testAPI runs the full router in-process. It uses the throwaway PostgreSQL
+// database in TEST_DB_CONN when set (migrated and emptied first), and the
+// in-memory backend otherwise, so the suite also runs offline.
+type testAPI struct {
+	t        *testing.T
+	router   http.Handler
+	repos    Repositories
+	token    string
+	terminal models.Terminal
+}
+
+// newTestAPI runs without tax or service charge, so totals are plain sums of
+// the discounted lines.
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+
+	return newTestAPIWithConfig(t, Config{IdempotencyKeyTTL: time.Hour, LowStockThreshold: 10})
+}
+
+// newTestAPIWithConfig registers a till, signs in at it as an admin, whose
+// token request sends unless the caller passes its own Authorization header,
+// and opens a shift there so checkouts go through.
+func newTestAPIWithConfig(t *testing.T, config Config) *testAPI {
+	t.Helper()
+
+	config.AuthTokenSecret = "test-secret"
+	config.AccessTokenTTL = 15 * time.Minute
+	config.RefreshTokenTTL = time.Hour
+
+	repos := newTestRepositories(t)
+	api := &testAPI{t: t, router: newRouter(config, repos), repos: repos}
+	api.addUser("admin", models.RoleAdmin)
+	rec := api.request(http.MethodPost, "/api/auth/login", models.LoginRequest{Username: "admin", Password: testPassword})
+	api.mustStatus(rec, http.StatusOK)
+	api.token = decode[models.TokenResponse](t, rec).AccessToken
+
+	rec = api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "TEST-1", Name: "Kasir Test", Active: true})
+	api.mustStatus(rec, http.StatusCreated)
+	api.terminal = decode[models.Terminal](t, rec)
+
+	api.token = api.pinLogin("admin").AccessToken
+	api.mustStatus(api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{}), http.StatusCreated)
+	return api
+}
+
+const (
+	testPassword = "rahasia123"
+	testPIN      = "2468"
+)
+
+// createUser adds an active user with testPassword and testPIN and logs them
+// in with the PIN at the test till, where a shift is open.
+func (a *testAPI) createUser(username, role string) models.TokenResponse {
+	a.t.Helper()
+
+	a.addUser(username, role)
+	return a.pinLogin(username)
+}
+
+func (a *testAPI) addUser(username, role string) {
+	a.t.Helper()
+
+	user := models.User{Username: username, Name: strings.ToUpper(username), Role: role, Active: true, Password: testPassword, PIN: testPIN}
+	if err := services.NewUserService(a.repos.Users).Create(&user); err != nil {
+		a.t.Fatalf("create user %s: %v", username, err)
+	}
+}
+
+func (a *testAPI) pinLogin(username string) models.TokenResponse {
+	a.t.Helper()
+
+	rec := a.request(http.MethodPost, "/api/auth/pin-login", models.PINLoginRequest{TerminalKey: a.terminal.Key, Username: username, PIN: testPIN})
+	a.mustStatus(rec, http.StatusOK)
+	return decode[models.TokenResponse](a.t, rec)
+}
+
+func newTestRepositories(t *testing.T) Repositories {
+	t.Helper()
+
+	conn := os.Getenv("TEST_DB_CONN")
+	if conn == "" {
+		return newMemoryRepositories(memory.NewStore())
+	}
+
+	db, err := database.InitDB(conn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := db.Exec("TRUNCATE categories, products, transactions, inventory_movements, product_price_history, suppliers, purchase_orders, promotions, vouchers, tax_classes, users, terminals, shifts, idempotency_keys RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	return newPostgresRepositories(db)
+}
+
+// request sends body (a string is sent verbatim, anything else as JSON) and
+// returns the recorded response.
+func (a *testAPI) request(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			a.t.Fatalf("encode body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// mustStatus fails the test unless the response has the wanted status.
+func (a *testAPI) mustStatus(rec *httptest.ResponseRecorder, want int) {
+	a.t.Helper()
+
+	if rec.Code != want {
+		a.t.Fatalf("status = %d, want %d, body: %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %T from %q: %v", v, rec.Body.String(), err)
+	}
+	return v
+}
+
+func (a *testAPI) createCategory(name string) models.Category {
+	a.t.Helper()
+
+	rec := a.request(http.MethodPost, "/api/categories", models.Category{Name: name, Description: "Kategori " + name})
+	a.mustStatus(rec, http.StatusCreated)
+	return decode[models.Category](a.t, rec)
+}
+
+func (a *testAPI) createProduct(name string, price, stock int, categoryID *int) models.Product {
+	a.t.Helper()
+
+	rec := a.request(http.MethodPost, "/api/products", models.Product{Name: name, Price: price, Stock: stock, CategoryID: categoryID})
+	a.mustStatus(rec, http.StatusCreated)
+	return decode[models.Product](a.t, rec)
+}
+
+func (a *testAPI) checkout(items []models.CheckoutItem, paid int) models.Transaction {
+	a.t.Helper()
+
+	rec := a.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{Items: items, Payments: cash(paid)})
+	a.mustStatus(rec, http.StatusOK)
+	return decode[models.Transaction](a.t, rec)
+}
+
+func intPtr(v int) *int { return &v }
+
+func cash(amount int) []models.CheckoutPayment {
+	return []models.CheckoutPayment{{Method: models.PaymentMethodCash, Amount: amount}}
+}
+
+func TestHealth(t *testing.T) {
+	api := newTestAPI(t)
+
+	rec := api.request(http.MethodGet, "/health", nil)
+	api.mustStatus(rec, http.StatusOK)
+
+	body := decode[map[string]string](t, rec)
+	if body["status"] != "OK" {
+		t.Errorf("status = %q, want OK", body["status"])
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	api := newTestAPI(t)
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+		code   string
+	}{
+		{"bad request", http.MethodGet, "/api/products/abc", nil, http.StatusBadRequest, "bad_request"},
+		{"not found", http.MethodDelete, "/api/products/9999", nil, http.StatusNotFound, "not_found"},
+		{"unknown route", http.MethodGet, "/api/nope", nil, http.StatusNotFound, "not_found"},
+		{"method not allowed", http.MethodGet, "/api/checkout", nil, http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"validation", http.MethodPost, "/api/checkout", models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: 1, Quantity: 0}}}, http.StatusUnprocessableEntity, "validation_error"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := api.request(tc.method, tc.path, tc.body)
+			api.mustStatus(rec, tc.status)
+
+			body := decode[models.ErrorResponse](t, rec)
+			if body.Code != tc.code || body.Message == "" {
+				t.Errorf("unexpected envelope %+v, want code %q", body, tc.code)
+			}
+			if body.RequestID == "" || body.RequestID != rec.Header().Get("X-Request-ID") {
+				t.Errorf("request_id = %q, header = %q", body.RequestID, rec.Header().Get("X-Request-ID"))
+			}
+		})
+	}
+
+	t.Run("validation errors list every field", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/products", models.Product{Price: -1, Stock: -1})
+		api.mustStatus(rec, http.StatusUnprocessableEntity)
+
+		body := decode[struct {
+			Details []models.FieldError `json:"details"`
+		}](t, rec)
+		if len(body.Details) != 3 || body.Details[0].Field != "name" || body.Details[0].Rule != "required" {
+			t.Errorf("unexpected field errors %+v", body.Details)
+		}
+	})
+
+	t.Run("caller request id is kept", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/products/9999", nil, "X-Request-ID", "till-3-0042")
+		if body := decode[models.ErrorResponse](t, rec); body.RequestID != "till-3-0042" {
+			t.Errorf("request_id = %q, want till-3-0042", body.RequestID)
+		}
+	})
+}
+
+func TestAuthEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+	noToken := []string{"Authorization", ""}
+
+	t.Run("api needs a valid access token", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodGet, "/health", nil, noToken...), http.StatusOK)
+
+		for _, auth := range []string{"", "Bearer", "Bearer not-a-token", "Basic " + api.token} {
+			rec := api.request(http.MethodGet, "/api/products", nil, "Authorization", auth)
+			api.mustStatus(rec, http.StatusUnauthorized)
+			if body := decode[models.ErrorResponse](t, rec); body.Code != "unauthorized" || rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("Authorization %q: unexpected response %+v", auth, body)
+			}
+		}
+	})
+
+	t.Run("login", func(t *testing.T) {
+		for _, req := range []models.LoginRequest{
+			{Username: "admin", Password: "salah-password"},
+			{Username: "nobody", Password: testPassword},
+		} {
+			rec := api.request(http.MethodPost, "/api/auth/login", req, noToken...)
+			api.mustStatus(rec, http.StatusUnauthorized)
+			if body := decode[models.ErrorResponse](t, rec); body.Message != "invalid username or password" {
+				t.Errorf("login %s: message = %q", req.Username, body.Message)
+			}
+		}
+
+		rec := api.request(http.MethodPost, "/api/auth/login", models.LoginRequest{Username: " ADMIN ", Password: testPassword}, noToken...)
+		api.mustStatus(rec, http.StatusOK)
+		tokens := decode[models.TokenResponse](t, rec)
+		if tokens.TokenType != "Bearer" || tokens.ExpiresIn != 900 || tokens.User.Role != models.RoleAdmin || tokens.User.Password != "" {
+			t.Errorf("unexpected login response %+v", tokens)
+		}
+		if strings.Contains(rec.Body.String(), "password") {
+			t.Errorf("login response leaks the password: %s", rec.Body.String())
+		}
+
+		rec = api.request(http.MethodGet, "/api/auth/me", nil, "Authorization", "Bearer "+tokens.AccessToken)
+		api.mustStatus(rec, http.StatusOK)
+		if me := decode[models.User](t, rec); me.Username != "admin" {
+			t.Errorf("me = %+v, want admin", me)
+		}
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		tokens := api.createUser("refresher", models.RoleCashier)
+
+		api.mustStatus(api.request(http.MethodGet, "/api/products", nil, "Authorization", "Bearer "+tokens.RefreshToken), http.StatusUnauthorized)
+		api.mustStatus(api.request(http.MethodPost, "/api/auth/refresh", models.RefreshRequest{RefreshToken: tokens.AccessToken}, noToken...), http.StatusUnauthorized)
+
+		rec := api.request(http.MethodPost, "/api/auth/refresh", models.RefreshRequest{RefreshToken: tokens.RefreshToken}, noToken...)
+		api.mustStatus(rec, http.StatusOK)
+		refreshed := decode[models.TokenResponse](t, rec)
+		api.mustStatus(api.request(http.MethodGet, "/api/products", nil, "Authorization", "Bearer "+refreshed.AccessToken), http.StatusOK)
+	})
+
+	t.Run("only admins manage users", func(t *testing.T) {
+		cashier := api.createUser("kasir1", models.RoleCashier)
+		asCashier := []string{"Authorization", "Bearer " + cashier.AccessToken}
+
+		rec := api.request(http.MethodGet, "/api/users", nil, asCashier...)
+		api.mustStatus(rec, http.StatusForbidden)
+		if body := decode[models.ErrorResponse](t, rec); body.Code != "forbidden" {
+			t.Errorf("code = %q, want forbidden", body.Code)
+		}
+
+		rec = api.request(http.MethodPost, "/api/users", map[string]string{"username": "Spv.Budi", "name": "Budi", "role": "supervisor", "password": testPassword})
+		api.mustStatus(rec, http.StatusCreated)
+		created := decode[models.User](t, rec)
+		if created.Username != "spv.budi" || !created.Active || strings.Contains(rec.Body.String(), "password") {
+			t.Errorf("unexpected created user %s", rec.Body.String())
+		}
+
+		rec = api.request(http.MethodPost, "/api/users", models.User{Username: "spv.budi", Name: "Budi", Role: "owner", Password: "pendek"})
+		api.mustStatus(rec, http.StatusUnprocessableEntity)
+		body := decode[struct {
+			Details []models.FieldError `json:"details"`
+		}](t, rec)
+		if len(body.Details) != 2 || body.Details[0].Field != "role" || body.Details[1].Field != "password" {
+			t.Errorf("unexpected field errors %+v", body.Details)
+		}
+
+		rec = api.request(http.MethodGet, "/api/users?role=cashier&active=true", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if page := decode[models.Page[models.User]](t, rec); page.Pagination.Total != 2 {
+			t.Errorf("active cashiers = %d, want 2", page.Pagination.Total)
+		}
+	})
+
+	t.Run("transactions record the cashier", func(t *testing.T) {
+		cashier := api.createUser("kasir2", models.RoleCashier)
+		asCashier := []string{"Authorization", "Bearer " + cashier.AccessToken}
+		product := api.createProduct("Aqua 600ml", 3000, 10, nil)
+
+		rec := api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+			Items:    []models.CheckoutItem{{ProductID: product.ID, Quantity: 1}},
+			Payments: cash(3000),
+		}, asCashier...)
+		api.mustStatus(rec, http.StatusOK)
+		trx := decode[models.Transaction](t, rec)
+		if trx.UserID == nil || *trx.UserID != cashier.User.ID || trx.Cashier != "kasir2" {
+			t.Errorf("transaction by %v %q, want %d kasir2", trx.UserID, trx.Cashier, cashier.User.ID)
+		}
+
+		page := decode[models.Page[models.Transaction]](t, api.request(http.MethodGet, fmt.Sprintf("/api/transactions?user_id=%d", cashier.User.ID), nil))
+		if page.Pagination.Total != 1 || page.Data[0].ID != trx.ID {
+			t.Errorf("transactions by kasir2 = %+v", page.Data)
+		}
+
+		history := decode[models.Page[models.InventoryMovement]](t, api.request(http.MethodGet, fmt.Sprintf("/api/products/%d/stock-history", product.ID), nil))
+		if len(history.Data) == 0 || history.Data[0].Actor != "kasir2" {
+			t.Errorf("sale movement = %+v, want actor kasir2", history.Data)
+		}
+	})
+
+	t.Run("deactivated users are locked out", func(t *testing.T) {
+		cashier := api.createUser("kasir3", models.RoleCashier)
+
+		update := cashier.User
+		update.Active = false
+		rec := api.request(http.MethodPut, fmt.Sprintf("/api/users/%d", cashier.User.ID), update)
+		api.mustStatus(rec, http.StatusOK)
+
+		api.mustStatus(api.request(http.MethodGet, "/api/products", nil, "Authorization", "Bearer "+cashier.AccessToken), http.StatusUnauthorized)
+		api.mustStatus(api.request(http.MethodPost, "/api/auth/refresh", models.RefreshRequest{RefreshToken: cashier.RefreshToken}, noToken...), http.StatusUnauthorized)
+		api.mustStatus(api.request(http.MethodPost, "/api/auth/login", models.LoginRequest{Username: "kasir3", Password: testPassword}, noToken...), http.StatusUnauthorized)
+	})
+
+	t.Run("the last admin stays an admin", func(t *testing.T) {
+		me := decode[models.User](t, api.request(http.MethodGet, "/api/auth/me", nil))
+		me.Role = models.RoleSupervisor
+		api.mustStatus(api.request(http.MethodPut, fmt.Sprintf("/api/users/%d", me.ID), me), http.StatusConflict)
+	})
+}
+
+func TestAuthorization(t *testing.T) {
+	api := newTestAPI(t)
+	product := api.createProduct("Indomie Goreng", 3500, 20, nil)
+	trx := api.checkout([]models.CheckoutItem{{ProductID: product.ID, Quantity: 1}}, 3500)
+
+	cashier := api.createUser("kasir", models.RoleCashier)
+	supervisor := api.createUser("spv", models.RoleSupervisor)
+	productPath := fmt.Sprintf("/api/products/%d", product.ID)
+
+	cases := []struct {
+		name       string
+		token      string
+		method     string
+		path       string
+		body       any
+		status     int
+		permission string
+	}{
+		{"cashier lists products", cashier.AccessToken, http.MethodGet, "/api/products", nil, http.StatusOK, ""},
+		{"cashier checks out", cashier.AccessToken, http.MethodPost, "/api/checkout", models.CheckoutRequest{
+			Items: []models.CheckoutItem{{ProductID: product.ID, Quantity: 1}}, Payments: cash(3500),
+		}, http.StatusOK, ""},
+		{"cashier prints a receipt", cashier.AccessToken, http.MethodGet, fmt.Sprintf("/api/transactions/%d/receipt", trx.ID), nil, http.StatusOK, ""},
+		{"cashier edits a product", cashier.AccessToken, http.MethodPut, productPath, product, http.StatusForbidden, "product:write"},
+		{"cashier deletes a category", cashier.AccessToken, http.MethodDelete, "/api/categories/1", nil, http.StatusForbidden, "category:delete"},
+		{"cashier reads the report", cashier.AccessToken, http.MethodGet, "/api/report", nil, http.StatusForbidden, "report:read"},
+		{"cashier voids", cashier.AccessToken, http.MethodPost, fmt.Sprintf("/api/transactions/%d/void", trx.ID), nil, http.StatusForbidden, "transaction:void"},
+		{"cashier adjusts stock", cashier.AccessToken, http.MethodPost, productPath + "/stock-adjustments", nil, http.StatusForbidden, "inventory:adjust"},
+		{"cashier lists users", cashier.AccessToken, http.MethodGet, "/api/users", nil, http.StatusForbidden, "user:read"},
+		{"supervisor reads the report", supervisor.AccessToken, http.MethodGet, "/api/report/hari-ini", nil, http.StatusOK, ""},
+		{"supervisor edits a product", supervisor.AccessToken, http.MethodPut, productPath, models.ProductUpdate{Product: product}, http.StatusOK, ""},
+		{"supervisor deletes a product", supervisor.AccessToken, http.MethodDelete, productPath, nil, http.StatusForbidden, "product:delete"},
+		{"supervisor creates a voucher", supervisor.AccessToken, http.MethodPost, "/api/vouchers", nil, http.StatusForbidden, "voucher:write"},
+		{"supervisor voids", supervisor.AccessToken, http.MethodPost, fmt.Sprintf("/api/transactions/%d/void", trx.ID), nil, http.StatusCreated, ""},
+		{"unsupported methods are still 405", cashier.AccessToken, http.MethodPatch, productPath, nil, http.StatusMethodNotAllowed, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := api.request(tc.method, tc.path, tc.body, "Authorization", "Bearer "+tc.token)
+			api.mustStatus(rec, tc.status)
+			if tc.status != http.StatusForbidden {
+				return
+			}
+
+			body := decode[struct {
+				Code    string                  `json:"code"`
+				Message string                  `json:"message"`
+				Details models.PermissionDenied `json:"details"`
+			}](t, rec)
+			if body.Code != "forbidden" || body.Message != "missing permission "+tc.permission || body.Details.Permission != tc.permission {
+				t.Errorf("unexpected 403 %s", rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("me lists the role's permissions", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/auth/me", nil, "Authorization", "Bearer "+cashier.AccessToken)
+		api.mustStatus(rec, http.StatusOK)
+		me := decode[models.CurrentUser](t, rec)
+		if me.Username != "kasir" || !slices.Contains(me.Permissions, models.PermTransactionCreate) || slices.Contains(me.Permissions, models.PermReportRead) {
+			t.Errorf("unexpected me %+v", me)
+		}
+	})
+
+	t.Run("roles can be redefined", func(t *testing.T) {
+		api := newTestAPIWithConfig(t, Config{RolePermissions: map[string][]string{
+			models.RoleCashier: {models.PermReportRead},
+		}})
+		cashier := api.createUser("kasir", models.RoleCashier)
+		asCashier := []string{"Authorization", "Bearer " + cashier.AccessToken}
+
+		api.mustStatus(api.request(http.MethodGet, "/api/report/hari-ini", nil, asCashier...), http.StatusOK)
+		api.mustStatus(api.request(http.MethodGet, "/api/products", nil, asCashier...), http.StatusForbidden)
+	})
+}
+
+func TestTerminalEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+	api.createUser("kasir", models.RoleCashier)
+
+	rec := api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "kasir-1", Name: "Kasir Depan", Active: true})
+	api.mustStatus(rec, http.StatusCreated)
+	terminal := decode[models.Terminal](t, rec)
+	if terminal.Code != "KASIR-1" || !terminal.Active || len(terminal.Key) != 64 {
+		t.Fatalf("unexpected terminal %+v", terminal)
+	}
+	path := fmt.Sprintf("/api/terminals/%d", terminal.ID)
+
+	t.Run("key is only shown once", func(t *testing.T) {
+		rec := api.request(http.MethodGet, path, nil)
+		api.mustStatus(rec, http.StatusOK)
+		if got := decode[models.Terminal](t, rec); got.Key != "" || got.Code != "KASIR-1" {
+			t.Errorf("unexpected terminal %+v", got)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "KASIR-1", Name: "Lagi"}), http.StatusUnprocessableEntity)
+		api.mustStatus(api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "kasir 2", Name: "Spasi"}), http.StatusUnprocessableEntity)
+		api.mustStatus(api.request(http.MethodGet, "/api/terminals/999", nil), http.StatusNotFound)
+	})
+
+	login := func(key, username, pin string) *httptest.ResponseRecorder {
+		return api.request(http.MethodPost, "/api/auth/pin-login", models.PINLoginRequest{TerminalKey: key, Username: username, PIN: pin})
+	}
+
+	t.Run("PIN login", func(t *testing.T) {
+		api.mustStatus(login("wrong-key", "kasir", testPIN), http.StatusUnauthorized)
+		api.mustStatus(login(terminal.Key, "kasir", "0000"), http.StatusUnauthorized)
+
+		rec := login(terminal.Key, "kasir", testPIN)
+		api.mustStatus(rec, http.StatusOK)
+		tokens := decode[models.TokenResponse](t, rec)
+		if tokens.Terminal == nil || tokens.Terminal.ID != terminal.ID || tokens.Terminal.Key != "" {
+			t.Fatalf("unexpected terminal in %+v", tokens)
+		}
+		asCashier := []string{"Authorization", "Bearer " + tokens.AccessToken}
+		api.mustStatus(api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{}, asCashier...), http.StatusCreated)
+
+		rec = api.request(http.MethodGet, "/api/auth/me", nil, asCashier...)
+		api.mustStatus(rec, http.StatusOK)
+		if me := decode[models.CurrentUser](t, rec); me.TerminalID == nil || *me.TerminalID != terminal.ID {
+			t.Errorf("me has no terminal: %s", rec.Body.String())
+		}
+
+		product := api.createProduct("Indomie Goreng", 3500, 10, nil)
+		rec = api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+			Items: []models.CheckoutItem{{ProductID: product.ID, Quantity: 1}}, Payments: cash(3500),
+		}, asCashier...)
+		api.mustStatus(rec, http.StatusOK)
+		if trx := decode[models.Transaction](t, rec); trx.TerminalID == nil || *trx.TerminalID != terminal.ID {
+			t.Errorf("transaction not recorded at the terminal: %s", rec.Body.String())
+		}
+
+		api.mustStatus(api.request(http.MethodPut, path, models.Terminal{Code: "KASIR-1", Name: "Kasir Depan", Active: false}), http.StatusOK)
+		api.mustStatus(api.request(http.MethodGet, "/api/auth/me", nil, asCashier...), http.StatusUnauthorized)
+		api.mustStatus(login(terminal.Key, "kasir", testPIN), http.StatusUnauthorized)
+	})
+
+	t.Run("PIN locks after repeated failures", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "KASIR-2", Name: "Kasir Belakang", Active: true})
+		api.mustStatus(rec, http.StatusCreated)
+		key := decode[models.Terminal](t, rec).Key
+
+		api.createUser("budi", models.RoleCashier)
+		for range services.MaxPINFailures {
+			api.mustStatus(login(key, "budi", "0000"), http.StatusUnauthorized)
+		}
+		rec = login(key, "budi", testPIN)
+		api.mustStatus(rec, http.StatusUnauthorized)
+		if !strings.Contains(rec.Body.String(), "locked") {
+			t.Errorf("expected a locked PIN, got %s", rec.Body.String())
+		}
+	})
+
+	t.Run("cashiers cannot manage terminals", func(t *testing.T) {
+		cashier := api.createUser("siti", models.RoleCashier)
+		api.mustStatus(api.request(http.MethodGet, "/api/terminals", nil, "Authorization", "Bearer "+cashier.AccessToken), http.StatusForbidden)
+	})
+}
+
+func TestShiftEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+	product := api.createProduct("Indomie Goreng", 3500, 20, nil)
+
+	rec := api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "KASIR-2", Name: "Kasir Belakang", Active: true})
+	api.mustStatus(rec, http.StatusCreated)
+	terminal := decode[models.Terminal](t, rec)
+
+	api.addUser("kasir", models.RoleCashier)
+	rec = api.request(http.MethodPost, "/api/auth/pin-login", models.PINLoginRequest{TerminalKey: terminal.Key, Username: "kasir", PIN: testPIN})
+	api.mustStatus(rec, http.StatusOK)
+	asCashier := []string{"Authorization", "Bearer " + decode[models.TokenResponse](t, rec).AccessToken}
+	sale := models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: product.ID, Quantity: 1}}, Payments: cash(5000)}
+
+	t.Run("checkout needs an open shift", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/checkout", sale, asCashier...)
+		api.mustStatus(rec, http.StatusConflict)
+		if !strings.Contains(rec.Body.String(), "no shift is open") {
+			t.Errorf("unexpected 409 %s", rec.Body.String())
+		}
+		api.mustStatus(api.request(http.MethodGet, "/api/shifts/current", nil, asCashier...), http.StatusNotFound)
+
+		rec = api.request(http.MethodPost, "/api/auth/login", models.LoginRequest{Username: "kasir", Password: testPassword})
+		api.mustStatus(rec, http.StatusOK)
+		noTerminal := []string{"Authorization", "Bearer " + decode[models.TokenResponse](t, rec).AccessToken}
+		api.mustStatus(api.request(http.MethodPost, "/api/checkout", sale, noTerminal...), http.StatusConflict)
+		api.mustStatus(api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{}, noTerminal...), http.StatusConflict)
+	})
+
+	rec = api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{OpeningFloat: 500000}, asCashier...)
+	api.mustStatus(rec, http.StatusCreated)
+	shift := decode[models.Shift](t, rec)
+	if shift.TerminalID != terminal.ID || shift.Cashier != "kasir" || shift.Status != models.ShiftStatusOpen || shift.ExpectedCash != 500000 {
+		t.Fatalf("unexpected shift %s", rec.Body.String())
+	}
+	path := fmt.Sprintf("/api/shifts/%d", shift.ID)
+
+	t.Run("one open shift per terminal and cashier", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{}, asCashier...), http.StatusConflict)
+
+		api.mustStatus(api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{OpeningFloat: -1}, asCashier...), http.StatusUnprocessableEntity)
+
+		// The admin already runs the shift at the test till.
+		rec := api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "KASIR-3", Name: "Kasir Samping", Active: true})
+		api.mustStatus(rec, http.StatusCreated)
+		rec = api.request(http.MethodPost, "/api/auth/pin-login", models.PINLoginRequest{TerminalKey: decode[models.Terminal](t, rec).Key, Username: "admin", PIN: testPIN})
+		api.mustStatus(rec, http.StatusOK)
+		rec = api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{}, "Authorization", "Bearer "+decode[models.TokenResponse](t, rec).AccessToken)
+		api.mustStatus(rec, http.StatusConflict)
+		if !strings.Contains(rec.Body.String(), "already has a shift open") {
+			t.Errorf("unexpected 409 %s", rec.Body.String())
+		}
+	})
+
+	t.Run("cash movements", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/checkout", sale, asCashier...)
+		api.mustStatus(rec, http.StatusOK)
+		if trx := decode[models.Transaction](t, rec); trx.ShiftID == nil || *trx.ShiftID != shift.ID {
+			t.Errorf("transaction not recorded in the shift: %s", rec.Body.String())
+		}
+
+		api.mustStatus(api.request(http.MethodPost, path+"/cash-movements", models.CashMovementRequest{Type: models.CashMovementIn, Amount: 100000, Reason: "Kas kecil"}, asCashier...), http.StatusCreated)
+		api.mustStatus(api.request(http.MethodPost, path+"/cash-movements", models.CashMovementRequest{Type: models.CashMovementOut, Amount: 50000, Reason: "Setor bank"}, asCashier...), http.StatusCreated)
+		api.mustStatus(api.request(http.MethodPost, path+"/cash-movements", models.CashMovementRequest{Type: "tip", Amount: 1000}, asCashier...), http.StatusUnprocessableEntity)
+
+		rec = api.request(http.MethodGet, "/api/shifts/current", nil, asCashier...)
+		api.mustStatus(rec, http.StatusOK)
+		current := decode[models.Shift](t, rec)
+		if current.ID != shift.ID || current.CashSales != 3500 || current.CashIn != 100000 || current.CashOut != 50000 ||
+			current.ExpectedCash != 553500 || current.TransactionCount != 1 || len(current.Movements) != 2 {
+			t.Errorf("unexpected current shift %s", rec.Body.String())
+		}
+	})
+
+	t.Run("only supervisors review shifts", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodGet, path, nil, asCashier...), http.StatusForbidden)
+
+		rec := api.request(http.MethodGet, fmt.Sprintf("/api/shifts?terminal_id=%d&status=open", terminal.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		if page := decode[models.Page[models.Shift]](t, rec); page.Pagination.Total != 1 || page.Data[0].ID != shift.ID {
+			t.Errorf("unexpected shifts %s", rec.Body.String())
+		}
+		api.mustStatus(api.request(http.MethodGet, "/api/shifts?status=paused", nil), http.StatusBadRequest)
+		api.mustStatus(api.request(http.MethodGet, "/api/shifts/abc", nil), http.StatusBadRequest)
+		api.mustStatus(api.request(http.MethodGet, "/api/shifts/999", nil), http.StatusNotFound)
+	})
+
+	t.Run("another terminal's shift is off limits", func(t *testing.T) {
+		movement := models.CashMovementRequest{Type: models.CashMovementOut, Amount: 50000, Reason: "Ambil"}
+		api.mustStatus(api.request(http.MethodPost, path+"/cash-movements", movement), http.StatusForbidden)
+		api.mustStatus(api.request(http.MethodPost, path+"/close", models.CloseShiftRequest{}), http.StatusForbidden)
+
+		own := decode[models.Shift](t, api.request(http.MethodGet, "/api/shifts/current", nil))
+		other := fmt.Sprintf("/api/shifts/%d", own.ID)
+		api.mustStatus(api.request(http.MethodPost, other+"/cash-movements", movement, asCashier...), http.StatusForbidden)
+		api.mustStatus(api.request(http.MethodPost, other+"/close", models.CloseShiftRequest{}, asCashier...), http.StatusForbidden)
+
+		rec := api.request(http.MethodGet, path, nil)
+		if s := decode[models.Shift](t, rec); s.Status != models.ShiftStatusOpen || s.CashOut != 50000 {
+			t.Errorf("shift changed from another terminal %s", rec.Body.String())
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		bad := models.CloseShiftRequest{CashCounts: []models.CashCount{{Denomination: 25000, Count: 1}}}
+		api.mustStatus(api.request(http.MethodPost, path+"/close", bad, asCashier...), http.StatusUnprocessableEntity)
+
+		rec := api.request(http.MethodPost, path+"/close", models.CloseShiftRequest{
+			CashCounts: []models.CashCount{
+				{Denomination: 100000, Count: 5},
+				{Denomination: 1000, Count: 1},
+				{Denomination: 50000, Count: 1},
+				{Denomination: 2000, Count: 1},
+			},
+			Note: "Kurang 500",
+		}, asCashier...)
+		api.mustStatus(rec, http.StatusOK)
+		closed := decode[models.Shift](t, rec)
+		if closed.Status != models.ShiftStatusClosed || closed.ClosedAt == nil || closed.ExpectedCash != 553500 ||
+			closed.CountedCash == nil || *closed.CountedCash != 553000 || closed.Variance == nil || *closed.Variance != -500 {
+			t.Errorf("unexpected closed shift %s", rec.Body.String())
+		}
+		if len(closed.CashCounts) != 4 || closed.CashCounts[0].Denomination != 100000 || closed.CashCounts[0].Amount != 500000 {
+			t.Errorf("unexpected counts %+v", closed.CashCounts)
+		}
+
+		api.mustStatus(api.request(http.MethodPost, path+"/close", models.CloseShiftRequest{}, asCashier...), http.StatusConflict)
+		api.mustStatus(api.request(http.MethodPost, path+"/cash-movements", models.CashMovementRequest{Type: models.CashMovementIn, Amount: 1000, Reason: "Telat"}, asCashier...), http.StatusConflict)
+		api.mustStatus(api.request(http.MethodPost, "/api/checkout", sale, asCashier...), http.StatusConflict)
+
+		rec = api.request(http.MethodGet, path, nil)
+		api.mustStatus(rec, http.StatusOK)
+		if stored := decode[models.Shift](t, rec); stored.Note != "Kurang 500" || stored.ClosedBy != "kasir" || *stored.Variance != -500 {
+			t.Errorf("unexpected stored shift %s", rec.Body.String())
+		}
+	})
+
+	t.Run("voids and refunds are paid out by the shift they are made on", func(t *testing.T) {
+		earlier := decode[models.Shift](t, api.request(http.MethodGet, "/api/shifts/current", nil))
+		sold := api.checkout([]models.CheckoutItem{{ProductID: product.ID, Quantity: 2}}, 10000)
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/shifts/%d/close", earlier.ID), models.CloseShiftRequest{}), http.StatusOK)
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/void", sold.ID), nil), http.StatusConflict)
+
+		rec := api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{OpeningFloat: 50000})
+		api.mustStatus(rec, http.StatusCreated)
+		later := decode[models.Shift](t, rec)
+
+		rec = api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/void", sold.ID), nil)
+		api.mustStatus(rec, http.StatusCreated)
+		if void := decode[models.Refund](t, rec); void.ShiftID == nil || *void.ShiftID != later.ID || void.CashAmount != 7000 {
+			t.Errorf("unexpected void %s", rec.Body.String())
+		}
+
+		// Cash goes back first, up to the cash the sale took.
+		rec = api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+			Items: []models.CheckoutItem{{ProductID: product.ID, Quantity: 2}},
+			Payments: []models.CheckoutPayment{
+				{Method: models.PaymentMethodCash, Amount: 2000},
+				{Method: models.PaymentMethodQRIS, Amount: 5000, Reference: "QR-2"},
+			},
+		})
+		api.mustStatus(rec, http.StatusOK)
+		split := decode[models.Transaction](t, rec)
+		rec = api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", split.ID), models.RefundRequest{
+			Items: []models.RefundItem{{DetailID: split.Details[0].ID, Quantity: 1}},
+		})
+		api.mustStatus(rec, http.StatusCreated)
+		if refund := decode[models.Refund](t, rec); refund.TotalAmount != 3500 || refund.CashAmount != 2000 {
+			t.Errorf("unexpected refund %s", rec.Body.String())
+		}
+
+		rec = api.request(http.MethodGet, fmt.Sprintf("/api/shifts/%d", later.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		if s := decode[models.Shift](t, rec); s.CashSales != 2000 || s.CashRefunds != 9000 || s.ExpectedCash != 43000 {
+			t.Errorf("unexpected shift %s", rec.Body.String())
+		}
+		rec = api.request(http.MethodGet, fmt.Sprintf("/api/shifts/%d", earlier.ID), nil)
+		if s := decode[models.Shift](t, rec); s.CashRefunds != 0 || s.CashSales != earlier.CashSales+7000 {
+			t.Errorf("closed shift changed %s", rec.Body.String())
+		}
+	})
+}
+
+func TestShiftReports(t *testing.T) {
+	api := newTestAPI(t)
+	indomie := api.createProduct("Indomie Goreng", 3500, 20, nil)
+	kopi := api.createProduct("Kopi Susu", 20000, 20, nil)
+
+	shift := decode[models.Shift](t, api.request(http.MethodGet, "/api/shifts/current", nil))
+	path := fmt.Sprintf("/api/shifts/%d", shift.ID)
+
+	first := api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 2}}, 10000)
+	rec := api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+		Items:    []models.CheckoutItem{{ProductID: kopi.ID, Quantity: 2}},
+		Payments: []models.CheckoutPayment{{Method: models.PaymentMethodQRIS, Amount: 40000, Reference: "QR-1"}},
+	})
+	api.mustStatus(rec, http.StatusOK)
+	second := decode[models.Transaction](t, rec)
+	voided := api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}}, 3500)
+	api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/void", voided.ID), nil), http.StatusCreated)
+	refund := models.RefundRequest{Items: []models.RefundItem{{DetailID: second.Details[0].ID, Quantity: 1}}}
+	api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", second.ID), refund), http.StatusCreated)
+
+	// Without a terminal there is no shift to report them in, so sales, voids
+	// and refunds after a password login are refused.
+	api.addUser("spv", models.RoleSupervisor)
+	rec = api.request(http.MethodPost, "/api/auth/login", models.LoginRequest{Username: "spv", Password: testPassword})
+	api.mustStatus(rec, http.StatusOK)
+	noTerminal := []string{"Authorization", "Bearer " + decode[models.TokenResponse](t, rec).AccessToken}
+	api.mustStatus(api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+		Items: []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}}, Payments: cash(3500),
+	}, noTerminal...), http.StatusConflict)
+	api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/void", first.ID), nil, noTerminal...), http.StatusConflict)
+	api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", second.ID), refund, noTerminal...), http.StatusConflict)
+
+	t.Run("X report", func(t *testing.T) {
+		rec := api.request(http.MethodGet, path+"/report", nil)
+		api.mustStatus(rec, http.StatusOK)
+		x := decode[models.ShiftReport](t, rec)
+		if x.Type != models.ShiftReportX || x.Number != 0 || x.ShiftID != shift.ID || x.ClosedAt != nil || x.CountedCash != nil || x.Variance != nil {
+			t.Errorf("unexpected X report %s", rec.Body.String())
+		}
+		// 7000 + 40000 + 3500 rung up, the 3500 voided and 20000 of it refunded.
+		if x.GrossSales != 50500 || x.Discounts != 0 || x.ServiceCharge != 0 || x.Tax != 0 || x.NetSales != 27000 ||
+			x.Voids != 3500 || x.VoidCount != 1 || x.Refunds != 20000 || x.RefundCount != 1 {
+			t.Errorf("unexpected sales %s", rec.Body.String())
+		}
+		// The voided sale is left out of the count and the basket, like the
+		// shift's own transaction_count.
+		current := decode[models.Shift](t, api.request(http.MethodGet, path, nil))
+		if x.TransactionCount != 2 || x.AverageBasket != 23500 || current.TransactionCount != x.TransactionCount {
+			t.Errorf("count %d, average %d; shift count %d", x.TransactionCount, x.AverageBasket, current.TransactionCount)
+		}
+		if x.FirstReceipt != services.ReceiptNumber(first.ID) || x.LastReceipt != services.ReceiptNumber(voided.ID) {
+			t.Errorf("receipts %s to %s", x.FirstReceipt, x.LastReceipt)
+		}
+		// Cash is the 10500 taken less the 3500 voided, and adds up to the
+		// drawer; QRIS is the 40000 taken less the 20000 refunded. Together
+		// they are the net sales.
+		want := []models.PaymentTotal{
+			{Method: models.PaymentMethodCash, Transactions: 1, Amount: 7000},
+			{Method: models.PaymentMethodQRIS, Transactions: 1, Amount: 20000},
+		}
+		if !slices.Equal(x.Payments, want) {
+			t.Errorf("payments = %+v, want %+v", x.Payments, want)
+		}
+		if x.OpeningFloat != 0 || x.ExpectedCash != x.OpeningFloat+x.Payments[0].Amount {
+			t.Errorf("float %d, expected cash %d", x.OpeningFloat, x.ExpectedCash)
+		}
+
+		rec = api.request(http.MethodGet, path+"/report?format=text", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if !strings.Contains(rec.Body.String(), "X REPORT") || !strings.Contains(rec.Body.String(), "27.000") {
+			t.Errorf("unexpected printout:\n%s", rec.Body.String())
+		}
+		api.mustStatus(api.request(http.MethodGet, path+"/report?format=pdf", nil), http.StatusBadRequest)
+		api.mustStatus(api.request(http.MethodGet, "/api/z-reports", nil), http.StatusOK)
+	})
+
+	api.mustStatus(api.request(http.MethodPost, path+"/close", models.CloseShiftRequest{
+		CashCounts: []models.CashCount{{Denomination: 5000, Count: 1}, {Denomination: 2000, Count: 1}},
+	}), http.StatusOK)
+
+	t.Run("Z report", func(t *testing.T) {
+		rec := api.request(http.MethodGet, path+"/report", nil)
+		api.mustStatus(rec, http.StatusOK)
+		z := decode[models.ShiftReport](t, rec)
+		if z.Type != models.ShiftReportZ || z.Number != 1 || z.NetSales != 27000 || z.ClosedAt == nil ||
+			z.ExpectedCash != 7000 || z.CountedCash == nil || *z.CountedCash != 7000 || len(z.Payments) != 2 {
+			t.Errorf("unexpected Z report %s", rec.Body.String())
+		}
+
+		// Refunds need an open shift to pay them out.
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", second.ID), refund), http.StatusConflict)
+
+		rec = api.request(http.MethodGet, "/api/z-reports/1?format=escpos&width=80mm", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="z-report-000001.bin"` {
+			t.Errorf("Content-Disposition = %q", got)
+		}
+		if !strings.Contains(rec.Body.String(), "Z REPORT") {
+			t.Errorf("unexpected printout %q", rec.Body.String())
+		}
+
+		api.mustStatus(api.request(http.MethodGet, "/api/z-reports/2", nil), http.StatusNotFound)
+		api.mustStatus(api.request(http.MethodGet, "/api/z-reports/abc", nil), http.StatusBadRequest)
+	})
+
+	t.Run("numbers follow each other", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/shifts", models.OpenShiftRequest{OpeningFloat: 100000})
+		api.mustStatus(rec, http.StatusCreated)
+		next := decode[models.Shift](t, rec)
+
+		// A refund after the close goes into the shift it is made in and
+		// does not change the stored report.
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", second.ID), refund), http.StatusCreated)
+		rec = api.request(http.MethodGet, "/api/z-reports/1", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if reprint := decode[models.ShiftReport](t, rec); reprint.Refunds != 20000 || reprint.NetSales != 27000 || reprint.ShiftID != shift.ID {
+			t.Errorf("Z report changed: %s", rec.Body.String())
+		}
+
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/shifts/%d/close", next.ID), models.CloseShiftRequest{}), http.StatusOK)
+
+		rec = api.request(http.MethodGet, fmt.Sprintf("/api/z-reports?terminal_id=%d", api.terminal.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		page := decode[models.Page[models.ShiftReport]](t, rec)
+		if page.Pagination.Total != 2 || page.Data[0].Number != 2 || page.Data[0].ShiftID != next.ID || page.Data[0].TransactionCount != 0 ||
+			page.Data[0].Refunds != 20000 || page.Data[0].NetSales != -20000 || page.Data[0].Variance == nil || *page.Data[0].Variance != -100000 {
+			t.Errorf("unexpected Z reports %s", rec.Body.String())
+		}
+		// The refund went back by QRIS, which the sale was paid with.
+		if want := []models.PaymentTotal{{Method: models.PaymentMethodQRIS, Amount: -20000}}; !slices.Equal(page.Data[0].Payments, want) {
+			t.Errorf("payments = %+v, want %+v", page.Data[0].Payments, want)
+		}
+		api.mustStatus(api.request(http.MethodGet, "/api/z-reports?start_date=kemarin", nil), http.StatusBadRequest)
+	})
+
+	t.Run("only supervisors reprint Z reports, and other terminals' reports", func(t *testing.T) {
+		cashier := api.createUser("kasir", models.RoleCashier)
+		api.mustStatus(api.request(http.MethodGet, "/api/z-reports/1", nil, "Authorization", "Bearer "+cashier.AccessToken), http.StatusForbidden)
+		api.mustStatus(api.request(http.MethodGet, path+"/report", nil, "Authorization", "Bearer "+cashier.AccessToken), http.StatusOK)
+
+		rec := api.request(http.MethodPost, "/api/terminals", models.Terminal{Code: "KASIR-2", Name: "Kasir Belakang", Active: true})
+		api.mustStatus(rec, http.StatusCreated)
+		rec = api.request(http.MethodPost, "/api/auth/pin-login", models.PINLoginRequest{TerminalKey: decode[models.Terminal](t, rec).Key, Username: "kasir", PIN: testPIN})
+		api.mustStatus(rec, http.StatusOK)
+		elsewhere := decode[models.TokenResponse](t, rec)
+		api.mustStatus(api.request(http.MethodGet, path+"/report", nil, "Authorization", "Bearer "+elsewhere.AccessToken), http.StatusForbidden)
+	})
+}
+
+func TestManagerApproval(t *testing.T) {
+	api := newTestAPIWithConfig(t, Config{IdempotencyKeyTTL: time.Hour, DiscountApprovalPercent: 10})
+	product := api.createProduct("Kopi Susu", 20000, 20, nil)
+	cashier := api.createUser("kasir", models.RoleCashier)
+	api.createUser("spv", models.RoleSupervisor)
+	api.createUser("rina", models.RoleCashier)
+
+	asCashier := func(headers ...string) []string {
+		return append([]string{"Authorization", "Bearer " + cashier.AccessToken}, headers...)
+	}
+	approvedBy := func(username, pin string) []string {
+		return asCashier(handlers.HeaderApprover, username, handlers.HeaderApproverPIN, pin)
+	}
+
+	t.Run("void", func(t *testing.T) {
+		trx := api.checkout([]models.CheckoutItem{{ProductID: product.ID, Quantity: 1}}, 20000)
+		path := fmt.Sprintf("/api/transactions/%d/void", trx.ID)
+
+		rec := api.request(http.MethodPost, path, nil, asCashier()...)
+		api.mustStatus(rec, http.StatusForbidden)
+		if denied := decode[struct {
+			Details models.PermissionDenied `json:"details"`
+		}](t, rec); !denied.Details.Approvable {
+			t.Errorf("void should be approvable: %s", rec.Body.String())
+		}
+
+		api.mustStatus(api.request(http.MethodPost, path, nil, approvedBy("spv", "0000")...), http.StatusForbidden)
+		api.mustStatus(api.request(http.MethodPost, path, nil, approvedBy("rina", testPIN)...), http.StatusForbidden)
+
+		rec = api.request(http.MethodPost, path, nil, approvedBy("SPV", testPIN)...)
+		api.mustStatus(rec, http.StatusCreated)
+		refund := decode[models.Refund](t, rec)
+		if refund.Cashier != "kasir" || refund.ApprovedBy != "spv" || refund.ApproverID == nil {
+			t.Errorf("approval not recorded: %+v", refund)
+		}
+	})
+
+	t.Run("price override", func(t *testing.T) {
+		req := models.CheckoutRequest{
+			Items:    []models.CheckoutItem{{ProductID: product.ID, Quantity: 1, UnitPrice: intPtr(18000)}},
+			Payments: cash(18000),
+		}
+		rec := api.request(http.MethodPost, "/api/checkout", req, asCashier()...)
+		api.mustStatus(rec, http.StatusForbidden)
+		if !strings.Contains(rec.Body.String(), models.PermTransactionPriceOverride) {
+			t.Errorf("unexpected 403 %s", rec.Body.String())
+		}
+
+		rec = api.request(http.MethodPost, "/api/checkout", req, approvedBy("spv", testPIN)...)
+		api.mustStatus(rec, http.StatusOK)
+		trx := decode[models.Transaction](t, rec)
+		if trx.TotalAmount != 18000 || trx.Details[0].OriginalPrice == nil || *trx.Details[0].OriginalPrice != 20000 || trx.ApprovedBy != "spv" {
+			t.Errorf("unexpected transaction %s", rec.Body.String())
+		}
+	})
+
+	t.Run("manual discount", func(t *testing.T) {
+		small := models.CheckoutRequest{
+			Items:           []models.CheckoutItem{{ProductID: product.ID, Quantity: 1}},
+			DiscountPercent: 10,
+			Payments:        cash(18000),
+		}
+		rec := api.request(http.MethodPost, "/api/checkout", small, asCashier()...)
+		api.mustStatus(rec, http.StatusOK)
+		if trx := decode[models.Transaction](t, rec); trx.TotalAmount != 18000 || trx.ApprovedBy != "" {
+			t.Errorf("unexpected transaction %s", rec.Body.String())
+		}
+
+		large := small
+		large.DiscountPercent = 50
+		large.Payments = cash(10000)
+		api.mustStatus(api.request(http.MethodPost, "/api/checkout", large, asCashier()...), http.StatusForbidden)
+		rec = api.request(http.MethodPost, "/api/checkout", large, approvedBy("spv", testPIN)...)
+		api.mustStatus(rec, http.StatusOK)
+		if trx := decode[models.Transaction](t, rec); trx.TotalAmount != 10000 || trx.Promotions[0].Name != services.ManualDiscountName {
+			t.Errorf("unexpected transaction %s", rec.Body.String())
+		}
+
+		large.DiscountPercent = 101
+		api.mustStatus(api.request(http.MethodPost, "/api/checkout", large, approvedBy("spv", testPIN)...), http.StatusUnprocessableEntity)
+	})
+
+	t.Run("stock adjustment", func(t *testing.T) {
+		path := fmt.Sprintf("/api/products/%d/stock-adjustments", product.ID)
+		adj := models.StockAdjustment{Type: models.MovementTypeDamage, Quantity: -1, Reason: "pecah"}
+		api.mustStatus(api.request(http.MethodPost, path, adj, asCashier()...), http.StatusForbidden)
+
+		rec := api.request(http.MethodPost, path, adj, approvedBy("spv", testPIN)...)
+		api.mustStatus(rec, http.StatusCreated)
+		if m := decode[models.InventoryMovement](t, rec); m.Actor != "kasir" || m.ApprovedBy != "spv" {
+			t.Errorf("unexpected movement %s", rec.Body.String())
+		}
+	})
+}
+
+func TestProductEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+	makanan := api.createCategory("Makanan")
+
+	indomie := api.createProduct("Indomie Goreng", 3500, 100, &makanan.ID)
+	api.createProduct("Aqua 600ml", 2000, 50, nil)
+
+	t.Run("list", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/products", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if page := decode[models.Page[models.ProductDetail]](t, rec); len(page.Data) != 2 || page.Pagination.Total != 2 {
+			t.Errorf("got %+v, want 2 products", page)
+		}
+	})
+
+	t.Run("list filtered by name", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/products?name=indomie", nil)
+		api.mustStatus(rec, http.StatusOK)
+		products := decode[models.Page[models.ProductDetail]](t, rec).Data
+		if len(products) != 1 || products[0].ID != indomie.ID {
+			t.Errorf("got %+v, want only %q", products, indomie.Name)
+		}
+	})
+
+	t.Run("get includes category", func(t *testing.T) {
+		rec := api.request(http.MethodGet, fmt.Sprintf("/api/products/%d", indomie.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		p := decode[models.ProductDetail](t, rec)
+		if p.Category == nil || p.Category.Name != "Makanan" {
+			t.Errorf("category = %+v, want Makanan", p.Category)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		path := fmt.Sprintf("/api/products/%d", indomie.ID)
+		rec := api.request(http.MethodPut, path, models.ProductUpdate{Product: models.Product{Name: "Indomie Goreng Special", Price: 4000, CategoryID: &makanan.ID}})
+		api.mustStatus(rec, http.StatusOK)
+		if p := decode[models.Product](t, rec); p.Stock != 100 {
+			t.Errorf("stock = %d, want it left at 100", p.Stock)
+		}
+
+		p := decode[models.ProductDetail](t, api.request(http.MethodGet, path, nil))
+		if p.Name != "Indomie Goreng Special" || p.Price != 4000 || p.Stock != 100 {
+			t.Errorf("product not updated: %+v", p)
+		}
+	})
+
+	t.Run("update books a stock change in the ledger", func(t *testing.T) {
+		path := fmt.Sprintf("/api/products/%d", indomie.ID)
+		api.mustStatus(api.request(http.MethodPut, path, models.Product{Name: "Indomie Goreng Special", Price: 4000, Stock: 100, CategoryID: &makanan.ID}), http.StatusOK)
+
+		rec := api.request(http.MethodPut, path, models.Product{Name: "Indomie Goreng Special", Price: 4000, Stock: 90, CategoryID: &makanan.ID})
+		api.mustStatus(rec, http.StatusOK)
+		if p := decode[models.Product](t, rec); p.Stock != 90 {
+			t.Errorf("stock = %d, want 90", p.Stock)
+		}
+
+		history := decode[models.Page[models.InventoryMovement]](t, api.request(http.MethodGet, path+"/stock-history", nil))
+		if len(history.Data) != 2 {
+			t.Fatalf("got %d movements, want the initial stock and one adjustment: %+v", len(history.Data), history.Data)
+		}
+		if m := history.Data[0]; m.Type != models.MovementTypeAdjustment || m.Quantity != -10 || m.Balance != 90 ||
+			m.Actor != "admin" || m.Reason != repositories.StockEditReason {
+			t.Errorf("adjustment = %+v, want -10 to 90 by admin", m)
+		}
+	})
+
+	t.Run("price history", func(t *testing.T) {
+		path := fmt.Sprintf("/api/products/%d", indomie.ID)
+		rec := api.request(http.MethodPut, path, models.ProductUpdate{Product: models.Product{Name: "Indomie Goreng Spesial", Price: 4000, CategoryID: &makanan.ID}})
+		api.mustStatus(rec, http.StatusOK)
+
+		rec = api.request(http.MethodGet, path+"/price-history", nil)
+		api.mustStatus(rec, http.StatusOK)
+		changes := decode[models.Page[models.PriceChange]](t, rec).Data
+		if len(changes) != 2 {
+			t.Fatalf("got %d price changes, want 2 (a rename is not a change): %+v", len(changes), changes)
+		}
+		if c := changes[0]; c.OldPrice == nil || *c.OldPrice != 3500 || c.NewPrice != 4000 {
+			t.Errorf("latest change = %+v, want 3500 -> 4000", c)
+		}
+		if c := changes[1]; c.OldPrice != nil || c.NewPrice != 3500 {
+			t.Errorf("first change = %+v, want initial price 3500", c)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		doomed := api.createProduct("Teh Botol", 4000, 10, nil)
+		path := fmt.Sprintf("/api/products/%d", doomed.ID)
+
+		api.mustStatus(api.request(http.MethodDelete, path, nil), http.StatusOK)
+		api.mustStatus(api.request(http.MethodGet, path, nil), http.StatusNotFound)
+		api.mustStatus(api.request(http.MethodDelete, path, nil), http.StatusNotFound)
+	})
+
+	t.Run("delete archives a product with a stock ledger", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/products", models.Product{Name: "Teh Kotak", SKU: "TK-250", Price: 4000, Stock: 10})
+		api.mustStatus(rec, http.StatusCreated)
+		path := fmt.Sprintf("/api/products/%d", decode[models.Product](t, rec).ID)
+
+		api.mustStatus(api.request(http.MethodDelete, path, nil), http.StatusOK)
+		api.mustStatus(api.request(http.MethodGet, path, nil), http.StatusNotFound)
+		api.mustStatus(api.request(http.MethodPost, path+"/stock-adjustments", models.StockAdjustment{Type: models.MovementTypeAdjustment, Quantity: 1, Reason: "Found"}), http.StatusNotFound)
+		if page := decode[models.Page[models.ProductDetail]](t, api.request(http.MethodGet, "/api/products?name=Teh+Kotak", nil)); len(page.Data) != 0 {
+			t.Errorf("archived product listed: %+v", page.Data)
+		}
+		if history := decode[models.Page[models.InventoryMovement]](t, api.request(http.MethodGet, path+"/stock-history", nil)); len(history.Data) != 1 {
+			t.Errorf("got %d movements, want the opening one kept", len(history.Data))
+		}
+
+		// Its SKU is free again.
+		api.mustStatus(api.request(http.MethodPost, "/api/products", models.Product{Name: "Teh Kotak 250ml", SKU: "TK-250", Price: 4000}), http.StatusCreated)
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"create invalid json", http.MethodPost, "/api/products", "{", http.StatusBadRequest},
+		{"create blank name", http.MethodPost, "/api/products", models.Product{Name: " ", Price: 1000}, http.StatusUnprocessableEntity},
+		{"create negative price", http.MethodPost, "/api/products", models.Product{Name: "x", Price: -1}, http.StatusUnprocessableEntity},
+		{"create unknown category", http.MethodPost, "/api/products", models.Product{Name: "x", CategoryID: intPtr(9999)}, http.StatusUnprocessableEntity},
+		{"list invalid in_stock", http.MethodGet, "/api/products?in_stock=maybe", nil, http.StatusBadRequest},
+		{"list invalid limit", http.MethodGet, "/api/products?limit=0", nil, http.StatusBadRequest},
+		{"list unknown sort field", http.MethodGet, "/api/products?sort=-colour", nil, http.StatusUnprocessableEntity},
+		{"list inverted price range", http.MethodGet, "/api/products?min_price=5000&max_price=1000", nil, http.StatusUnprocessableEntity},
+		{"update negative stock", http.MethodPut, fmt.Sprintf("/api/products/%d", indomie.ID), models.Product{Name: "x", Stock: -5}, http.StatusUnprocessableEntity},
+		{"get invalid id", http.MethodGet, "/api/products/abc", nil, http.StatusBadRequest},
+		{"get unknown id", http.MethodGet, "/api/products/9999", nil, http.StatusNotFound},
+		{"update invalid id", http.MethodPut, "/api/products/abc", models.Product{Name: "x"}, http.StatusBadRequest},
+		{"update invalid json", http.MethodPut, fmt.Sprintf("/api/products/%d", indomie.ID), "{", http.StatusBadRequest},
+		{"update unknown id", http.MethodPut, "/api/products/9999", models.Product{Name: "x"}, http.StatusNotFound},
+		{"delete invalid id", http.MethodDelete, "/api/products/abc", nil, http.StatusBadRequest},
+		{"delete unknown id", http.MethodDelete, "/api/products/9999", nil, http.StatusNotFound},
+		{"price history of unknown product", http.MethodGet, "/api/products/9999/price-history", nil, http.StatusNotFound},
+		{"price history method not allowed", http.MethodPost, fmt.Sprintf("/api/products/%d/price-history", indomie.ID), nil, http.StatusMethodNotAllowed},
+		{"collection method not allowed", http.MethodDelete, "/api/products", nil, http.StatusMethodNotAllowed},
+		{"item method not allowed", http.MethodPost, fmt.Sprintf("/api/products/%d", indomie.ID), nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestProductUpdateStockNeedsInventoryAdjust(t *testing.T) {
+	api := newTestAPIWithConfig(t, Config{IdempotencyKeyTTL: time.Hour, RolePermissions: map[string][]string{
+		models.RoleCashier: {models.PermProductRead, models.PermProductWrite},
+	}})
+	product := api.createProduct("Indomie Goreng", 3500, 20, nil)
+	cashier := api.createUser("kasir", models.RoleCashier)
+	api.createUser("spv", models.RoleSupervisor)
+	path := fmt.Sprintf("/api/products/%d", product.ID)
+	asCashier := func(headers ...string) []string {
+		return append([]string{"Authorization", "Bearer " + cashier.AccessToken}, headers...)
+	}
+
+	// Sending the stock back unchanged is not an adjustment.
+	api.mustStatus(api.request(http.MethodPut, path, models.Product{Name: "Indomie Goreng Special", Price: 3500, Stock: 20}, asCashier()...), http.StatusOK)
+
+	rec := api.request(http.MethodPut, path, models.Product{Name: "Indomie Goreng Special", Price: 3500, Stock: 15}, asCashier()...)
+	api.mustStatus(rec, http.StatusForbidden)
+	if !strings.Contains(rec.Body.String(), models.PermInventoryAdjust) {
+		t.Errorf("unexpected 403 %s", rec.Body.String())
+	}
+
+	rec = api.request(http.MethodPut, path, models.Product{Name: "Indomie Goreng Special", Price: 3500, Stock: 15},
+		asCashier(handlers.HeaderApprover, "spv", handlers.HeaderApproverPIN, testPIN)...)
+	api.mustStatus(rec, http.StatusOK)
+	history := decode[models.Page[models.InventoryMovement]](t, api.request(http.MethodGet, path+"/stock-history", nil))
+	if m := history.Data[0]; m.Quantity != -5 || m.Balance != 15 || m.Actor != "kasir" || m.ApprovedBy != "spv" {
+		t.Errorf("adjustment = %+v, want -5 to 15 by kasir, approved by spv", m)
+	}
+}
+
+func TestProductUpdateKeepsFieldsLeftOut(t *testing.T) {
+	api := newTestAPI(t)
+	makanan := api.createCategory("Makanan")
+	exempt := decode[models.TaxClass](t, api.request(http.MethodPost, "/api/tax-classes", models.TaxClass{Name: "Bebas PPN"}))
+	rec := api.request(http.MethodPost, "/api/products", models.Product{
+		Name: "Indomie Goreng", SKU: "IDM-GRG", Barcodes: []string{"8992761111113"}, Price: 3500, CostPrice: 2800, Stock: 20,
+		CategoryID: &makanan.ID, TaxClassID: &exempt.ID,
+	})
+	api.mustStatus(rec, http.StatusCreated)
+	path := fmt.Sprintf("/api/products/%d", decode[models.Product](t, rec).ID)
+
+	fields := []struct {
+		name  string
+		clear any
+		isSet func(models.ProductDetail) bool
+	}{
+		{"sku", "", func(p models.ProductDetail) bool { return p.SKU == "IDM-GRG" }},
+		{"barcodes", []string{}, func(p models.ProductDetail) bool { return len(p.Barcodes) == 1 }},
+		{"cost_price", 0, func(p models.ProductDetail) bool { return p.CostPrice == 2800 }},
+		{"category_id", nil, func(p models.ProductDetail) bool { return p.CategoryID != nil && *p.CategoryID == makanan.ID }},
+		{"tax_class_id", nil, func(p models.ProductDetail) bool { return p.TaxClassID != nil && *p.TaxClassID == exempt.ID }},
+	}
+	for _, field := range fields {
+		t.Run(field.name, func(t *testing.T) {
+			api.mustStatus(api.request(http.MethodPut, path, map[string]any{"name": "Indomie Goreng", "price": 3500}), http.StatusOK)
+			if p := decode[models.ProductDetail](t, api.request(http.MethodGet, path, nil)); !field.isSet(p) {
+				t.Errorf("left out: got %+v, want %s kept", p, field.name)
+			}
+
+			body := map[string]any{"name": "Indomie Goreng", "price": 3500, field.name: field.clear}
+			api.mustStatus(api.request(http.MethodPut, path, body), http.StatusOK)
+			if p := decode[models.ProductDetail](t, api.request(http.MethodGet, path, nil)); field.isSet(p) {
+				t.Errorf("cleared: got %+v, want %s cleared", p, field.name)
+			}
+		})
+	}
+}
+
+func TestProductListing(t *testing.T) {
+	api := newTestAPI(t)
+	makanan := api.createCategory("Makanan")
+	minuman := api.createCategory("Minuman")
+
+	api.createProduct("Indomie Goreng", 3500, 100, &makanan.ID)
+	api.createProduct("Mie Sedaap", 3000, 5, &makanan.ID)
+	api.createProduct("Aqua 600ml", 2000, 0, &minuman.ID)
+	api.createProduct("Teh Botol", 4000, 8, &minuman.ID)
+	api.createProduct("Kopi Kapal Api", 1500, 40, nil)
+
+	list := func(query string) models.Page[models.ProductDetail] {
+		t.Helper()
+		rec := api.request(http.MethodGet, "/api/products"+query, nil)
+		api.mustStatus(rec, http.StatusOK)
+		return decode[models.Page[models.ProductDetail]](t, rec)
+	}
+	names := func(products []models.ProductDetail) string {
+		names := make([]string, len(products))
+		for i, p := range products {
+			names[i] = p.Name
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"?sort=price", "Kopi Kapal Api,Aqua 600ml,Mie Sedaap,Indomie Goreng,Teh Botol"},
+		{"?sort=-stock,name", "Indomie Goreng,Kopi Kapal Api,Teh Botol,Mie Sedaap,Aqua 600ml"},
+		{fmt.Sprintf("?category_id=%d", minuman.ID), "Aqua 600ml,Teh Botol"},
+		{"?min_price=2000&max_price=3500&sort=-price", "Indomie Goreng,Mie Sedaap,Aqua 600ml"},
+		{"?in_stock=false", "Aqua 600ml"},
+		{"?low_stock=true&in_stock=true&sort=stock", "Mie Sedaap,Teh Botol"},
+		{"?low_stock=false", "Indomie Goreng,Kopi Kapal Api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := names(list(tt.query).Data); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("pages and links", func(t *testing.T) {
+		page := list("?sort=name&limit=2&page=2")
+		if names(page.Data) != "Kopi Kapal Api,Mie Sedaap" || page.Pagination.Total != 5 || page.Pagination.TotalPages != 3 {
+			t.Fatalf("unexpected page %+v", page)
+		}
+		if page.Links == nil || page.Links.Next != "/api/products?limit=2&page=3&sort=name" || page.Links.Prev != "/api/products?limit=2&page=1&sort=name" {
+			t.Errorf("unexpected links %+v", page.Links)
+		}
+
+		last := list("?sort=name&limit=2&page=3")
+		if len(last.Data) != 1 || last.Links.Next != "" {
+			t.Errorf("last page %+v", last)
+		}
+	})
+}
+
+func TestProductLookup(t *testing.T) {
+	api := newTestAPI(t)
+
+	rec := api.request(http.MethodPost, "/api/products", models.Product{
+		Name:     "Indomie Goreng",
+		SKU:      "idm-grg",
+		Barcodes: []string{"036000291452", "8992761111113"},
+		Price:    3500,
+		Stock:    10,
+	})
+	api.mustStatus(rec, http.StatusCreated)
+	indomie := decode[models.Product](t, rec)
+	if indomie.SKU != "IDM-GRG" || indomie.Barcodes[0] != "0036000291452" {
+		t.Errorf("codes not normalised: %+v", indomie)
+	}
+
+	for _, query := range []string{"barcode=036000291452", "barcode=0036000291452", "barcode=8992761111113", "sku=Idm-Grg"} {
+		t.Run(query, func(t *testing.T) {
+			rec := api.request(http.MethodGet, "/api/products/lookup?"+query, nil)
+			api.mustStatus(rec, http.StatusOK)
+			if p := decode[models.ProductDetail](t, rec); p.ID != indomie.ID || len(p.Barcodes) != 2 {
+				t.Errorf("got %+v, want %q", p, indomie.Name)
+			}
+		})
+	}
+
+	t.Run("checkout by barcode and sku", func(t *testing.T) {
+		trx := api.checkout([]models.CheckoutItem{{Barcode: "036000291452", Quantity: 1}, {SKU: "IDM-GRG", Quantity: 2}}, 10500)
+		if len(trx.Details) != 1 || trx.Details[0].ProductID != indomie.ID || trx.Details[0].Quantity != 3 {
+			t.Errorf("unexpected details %+v", trx.Details)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"lookup unknown barcode", http.MethodGet, "/api/products/lookup?barcode=4006381333931", nil, http.StatusNotFound},
+		{"lookup unknown sku", http.MethodGet, "/api/products/lookup?sku=NOPE", nil, http.StatusNotFound},
+		{"lookup bad checksum", http.MethodGet, "/api/products/lookup?barcode=4006381333932", nil, http.StatusUnprocessableEntity},
+		{"lookup without code", http.MethodGet, "/api/products/lookup", nil, http.StatusUnprocessableEntity},
+		{"lookup method not allowed", http.MethodPost, "/api/products/lookup", nil, http.StatusMethodNotAllowed},
+		{"create duplicate sku", http.MethodPost, "/api/products", models.Product{Name: "x", SKU: "IDM-GRG"}, http.StatusUnprocessableEntity},
+		{"create taken barcode", http.MethodPost, "/api/products", models.Product{Name: "x", Barcodes: []string{"0036000291452"}}, http.StatusUnprocessableEntity},
+		{"checkout unknown barcode", http.MethodPost, "/api/checkout", models.CheckoutRequest{Items: []models.CheckoutItem{{Barcode: "4006381333931", Quantity: 1}}, Payments: cash(3500)}, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestInventoryEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+
+	indomie := api.createProduct("Indomie Goreng", 3500, 20, nil)
+	stockPath := fmt.Sprintf("/api/products/%d/stock-adjustments", indomie.ID)
+	historyPath := fmt.Sprintf("/api/products/%d/stock-history", indomie.ID)
+
+	t.Run("adjustments move stock", func(t *testing.T) {
+		rec := api.request(http.MethodPost, stockPath, map[string]any{
+			"type": models.MovementTypeRestock, "quantity": 12, "actor": "gudang",
+		})
+		api.mustStatus(rec, http.StatusCreated)
+		if m := decode[models.InventoryMovement](t, rec); m.Balance != 32 || m.Quantity != 12 || m.Actor != "admin" {
+			t.Errorf("restock = %+v, want +12 to 32 by admin", m)
+		}
+
+		rec = api.request(http.MethodPost, stockPath, models.StockAdjustment{
+			Type: models.MovementTypeDamage, Quantity: -2, Reason: "Bungkus sobek",
+		})
+		api.mustStatus(rec, http.StatusCreated)
+
+		product := decode[models.ProductDetail](t, api.request(http.MethodGet, fmt.Sprintf("/api/products/%d", indomie.ID), nil))
+		if product.Stock != 30 {
+			t.Errorf("stock = %d, want 30", product.Stock)
+		}
+	})
+
+	t.Run("history records sales, refunds and edits", func(t *testing.T) {
+		trx := api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 5}}, 17500)
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", trx.ID), models.RefundRequest{
+			Reason: "Salah beli",
+			Items:  []models.RefundItem{{DetailID: trx.Details[0].ID, Quantity: 2}},
+		}), http.StatusCreated)
+
+		api.mustStatus(api.request(http.MethodPost, stockPath, models.StockAdjustment{
+			Type: models.MovementTypeAdjustment, Quantity: 3, Reason: "Stock opname",
+		}), http.StatusCreated)
+
+		rec := api.request(http.MethodGet, historyPath, nil)
+		api.mustStatus(rec, http.StatusOK)
+		page := decode[models.Page[models.InventoryMovement]](t, rec)
+
+		want := []struct {
+			typ      string
+			quantity int
+			balance  int
+		}{
+			{models.MovementTypeAdjustment, 3, 30},
+			{models.MovementTypeRefund, 2, 27},
+			{models.MovementTypeSale, -5, 25},
+			{models.MovementTypeDamage, -2, 30},
+			{models.MovementTypeRestock, 12, 32},
+			{models.MovementTypeOpening, 20, 20},
+		}
+		if page.Pagination.Total != len(want) || len(page.Data) != len(want) {
+			t.Fatalf("got %d movements, want %d: %+v", len(page.Data), len(want), page.Data)
+		}
+		for i, w := range want {
+			m := page.Data[i]
+			if m.Type != w.typ || m.Quantity != w.quantity || m.Balance != w.balance {
+				t.Errorf("movement %d = %s %+d -> %d, want %s %+d -> %d", i, m.Type, m.Quantity, m.Balance, w.typ, w.quantity, w.balance)
+			}
+		}
+		if sale := page.Data[2]; sale.ReferenceType != models.ReferenceTypeTransaction || sale.ReferenceID == nil || *sale.ReferenceID != trx.ID {
+			t.Errorf("sale reference = %s %v, want transaction %d", sale.ReferenceType, sale.ReferenceID, trx.ID)
+		}
+		if refund := page.Data[1]; refund.ReferenceType != models.ReferenceTypeRefund || refund.Reason != "Salah beli" {
+			t.Errorf("refund movement = %+v", refund)
+		}
+	})
+
+	t.Run("history is paged", func(t *testing.T) {
+		page := decode[models.Page[models.InventoryMovement]](t, api.request(http.MethodGet, historyPath+"?limit=2&page=3", nil))
+		if len(page.Data) != 2 || page.Data[1].Type != models.MovementTypeOpening {
+			t.Errorf("unexpected last page %+v", page.Data)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"below zero", http.MethodPost, stockPath, models.StockAdjustment{Type: models.MovementTypeLoss, Quantity: -100, Reason: "Hilang"}, http.StatusUnprocessableEntity},
+		{"unknown type", http.MethodPost, stockPath, models.StockAdjustment{Type: models.MovementTypeSale, Quantity: -1}, http.StatusUnprocessableEntity},
+		{"restock removing stock", http.MethodPost, stockPath, models.StockAdjustment{Type: models.MovementTypeRestock, Quantity: -1}, http.StatusUnprocessableEntity},
+		{"adjustment without reason", http.MethodPost, stockPath, models.StockAdjustment{Type: models.MovementTypeAdjustment, Quantity: 1}, http.StatusUnprocessableEntity},
+		{"invalid json", http.MethodPost, stockPath, "{", http.StatusBadRequest},
+		{"unknown product", http.MethodPost, "/api/products/9999/stock-adjustments", models.StockAdjustment{Type: models.MovementTypeRestock, Quantity: 1}, http.StatusNotFound},
+		{"history of unknown product", http.MethodGet, "/api/products/9999/stock-history", nil, http.StatusNotFound},
+		{"invalid product id", http.MethodGet, "/api/products/abc/stock-history", nil, http.StatusBadRequest},
+		{"unknown action", http.MethodGet, fmt.Sprintf("/api/products/%d/explode", indomie.ID), nil, http.StatusNotFound},
+		{"history method not allowed", http.MethodPost, historyPath, nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestCategoryEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+
+	makanan := api.createCategory("Makanan")
+	api.createCategory("Minuman")
+
+	t.Run("list", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/categories", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if page := decode[models.Page[models.Category]](t, rec); len(page.Data) != 2 || page.Pagination.Total != 2 {
+			t.Errorf("got %+v, want 2 categories", page)
+		}
+	})
+
+	t.Run("list sorted and paged", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/categories?sort=-name&limit=1", nil)
+		api.mustStatus(rec, http.StatusOK)
+		page := decode[models.Page[models.Category]](t, rec)
+		if len(page.Data) != 1 || page.Data[0].Name != "Minuman" || page.Links.Next == "" {
+			t.Errorf("unexpected page %+v", page)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		rec := api.request(http.MethodGet, fmt.Sprintf("/api/categories/%d", makanan.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		if c := decode[models.Category](t, rec); c.Name != "Makanan" {
+			t.Errorf("name = %q, want Makanan", c.Name)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		path := fmt.Sprintf("/api/categories/%d", makanan.ID)
+		api.mustStatus(api.request(http.MethodPut, path, models.Category{Name: "Makanan Berat"}), http.StatusOK)
+
+		if c := decode[models.Category](t, api.request(http.MethodGet, path, nil)); c.Name != "Makanan Berat" {
+			t.Errorf("name = %q, want Makanan Berat", c.Name)
+		}
+	})
+
+	t.Run("delete detaches products", func(t *testing.T) {
+		snack := api.createCategory("Snack")
+		chitato := api.createProduct("Chitato", 10000, 5, &snack.ID)
+
+		api.mustStatus(api.request(http.MethodDelete, fmt.Sprintf("/api/categories/%d", snack.ID), nil), http.StatusOK)
+
+		p := decode[models.ProductDetail](t, api.request(http.MethodGet, fmt.Sprintf("/api/products/%d", chitato.ID), nil))
+		if p.CategoryID != nil || p.Category != nil {
+			t.Errorf("product still linked to deleted category: %+v", p)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"create invalid json", http.MethodPost, "/api/categories", "{", http.StatusBadRequest},
+		{"list invalid page", http.MethodGet, "/api/categories?page=abc", nil, http.StatusBadRequest},
+		{"list unknown sort field", http.MethodGet, "/api/categories?sort=price", nil, http.StatusUnprocessableEntity},
+		{"create blank name", http.MethodPost, "/api/categories", models.Category{}, http.StatusUnprocessableEntity},
+		{"create duplicate name", http.MethodPost, "/api/categories", models.Category{Name: "MINUMAN"}, http.StatusUnprocessableEntity},
+		{"rename to existing name", http.MethodPut, fmt.Sprintf("/api/categories/%d", makanan.ID), models.Category{Name: "Minuman"}, http.StatusUnprocessableEntity},
+		{"get invalid id", http.MethodGet, "/api/categories/abc", nil, http.StatusBadRequest},
+		{"get unknown id", http.MethodGet, "/api/categories/9999", nil, http.StatusNotFound},
+		{"update invalid id", http.MethodPut, "/api/categories/abc", models.Category{Name: "x"}, http.StatusBadRequest},
+		{"update invalid json", http.MethodPut, fmt.Sprintf("/api/categories/%d", makanan.ID), "{", http.StatusBadRequest},
+		{"update unknown id", http.MethodPut, "/api/categories/9999", models.Category{Name: "x"}, http.StatusNotFound},
+		{"delete invalid id", http.MethodDelete, "/api/categories/abc", nil, http.StatusBadRequest},
+		{"delete unknown id", http.MethodDelete, "/api/categories/9999", nil, http.StatusNotFound},
+		{"collection method not allowed", http.MethodPut, "/api/categories", nil, http.StatusMethodNotAllowed},
+		{"item method not allowed", http.MethodPost, fmt.Sprintf("/api/categories/%d", makanan.ID), nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestSupplierEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+
+	rec := api.request(http.MethodPost, "/api/suppliers", models.Supplier{Name: " PT Sumber Makmur ", Email: "sales@sumbermakmur.co.id"})
+	api.mustStatus(rec, http.StatusCreated)
+	supplier := decode[models.Supplier](t, rec)
+	if supplier.ID == 0 || supplier.Name != "PT Sumber Makmur" {
+		t.Fatalf("unexpected supplier %+v", supplier)
+	}
+	path := fmt.Sprintf("/api/suppliers/%d", supplier.ID)
+
+	t.Run("list and get", func(t *testing.T) {
+		page := decode[models.Page[models.Supplier]](t, api.request(http.MethodGet, "/api/suppliers?name=makmur", nil))
+		if len(page.Data) != 1 || page.Data[0].ID != supplier.ID {
+			t.Errorf("unexpected list %+v", page.Data)
+		}
+		api.mustStatus(api.request(http.MethodGet, path, nil), http.StatusOK)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		supplier.Phone = "021-555-0101"
+		rec := api.request(http.MethodPut, path, supplier)
+		api.mustStatus(rec, http.StatusOK)
+		if got := decode[models.Supplier](t, rec); got.Phone != "021-555-0101" {
+			t.Errorf("phone = %q", got.Phone)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"create without name", http.MethodPost, "/api/suppliers", models.Supplier{}, http.StatusUnprocessableEntity},
+		{"create with bad email", http.MethodPost, "/api/suppliers", models.Supplier{Name: "CV Baru", Email: "sales"}, http.StatusUnprocessableEntity},
+		{"create duplicate name", http.MethodPost, "/api/suppliers", models.Supplier{Name: "pt sumber makmur"}, http.StatusUnprocessableEntity},
+		{"list unknown sort", http.MethodGet, "/api/suppliers?sort=phone", nil, http.StatusUnprocessableEntity},
+		{"get invalid id", http.MethodGet, "/api/suppliers/abc", nil, http.StatusBadRequest},
+		{"get unknown id", http.MethodGet, "/api/suppliers/9999", nil, http.StatusNotFound},
+		{"delete unknown id", http.MethodDelete, "/api/suppliers/9999", nil, http.StatusNotFound},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestPurchaseOrderEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+
+	rec := api.request(http.MethodPost, "/api/suppliers", models.Supplier{Name: "PT Indofood"})
+	api.mustStatus(rec, http.StatusCreated)
+	supplier := decode[models.Supplier](t, rec)
+
+	indomie := api.createProduct("Indomie Goreng", 3500, 5, nil)
+	aqua := api.createProduct("Aqua 600ml", 2000, 0, nil)
+
+	createOrder := func() models.PurchaseOrder {
+		t.Helper()
+		rec := api.request(http.MethodPost, "/api/purchase-orders", models.PurchaseOrderRequest{
+			SupplierID: supplier.ID,
+			Items: []models.PurchaseOrderItemLine{
+				{ProductID: indomie.ID, Quantity: 40, UnitCost: 2800},
+				{ProductID: aqua.ID, Quantity: 24, UnitCost: 1500},
+			},
+		})
+		api.mustStatus(rec, http.StatusCreated)
+		return decode[models.PurchaseOrder](t, rec)
+	}
+	stockOf := func(id int) int {
+		t.Helper()
+		return decode[models.ProductDetail](t, api.request(http.MethodGet, fmt.Sprintf("/api/products/%d", id), nil)).Stock
+	}
+
+	po := createOrder()
+	path := fmt.Sprintf("/api/purchase-orders/%d", po.ID)
+
+	t.Run("created as draft", func(t *testing.T) {
+		if po.Status != models.PurchaseOrderStatusDraft || po.TotalCost != 40*2800+24*1500 || po.SupplierName != "PT Indofood" {
+			t.Errorf("unexpected purchase order %+v", po)
+		}
+		if len(po.Items) != 2 || po.Items[0].ProductName != "Indomie Goreng" || po.Items[0].Subtotal != 112000 {
+			t.Errorf("unexpected items %+v", po.Items)
+		}
+	})
+
+	t.Run("draft can be edited", func(t *testing.T) {
+		rec := api.request(http.MethodPut, path, models.PurchaseOrderRequest{
+			SupplierID: supplier.ID,
+			Notes:      "Kirim Senin",
+			Items: []models.PurchaseOrderItemLine{
+				{ProductID: indomie.ID, Quantity: 40, UnitCost: 2800},
+				{ProductID: aqua.ID, Quantity: 48, UnitCost: 1500},
+			},
+		})
+		api.mustStatus(rec, http.StatusOK)
+		po = decode[models.PurchaseOrder](t, rec)
+		if po.Notes != "Kirim Senin" || po.Items[1].Quantity != 48 {
+			t.Errorf("unexpected purchase order %+v", po)
+		}
+	})
+
+	t.Run("receiving a draft is rejected", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodPost, path+"/receive", models.ReceiveRequest{
+			Items: []models.ReceiveItem{{ItemID: po.Items[0].ID, Quantity: 1}},
+		}), http.StatusConflict)
+	})
+
+	t.Run("order then receive in parts", func(t *testing.T) {
+		rec := api.request(http.MethodPost, path+"/order", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if ordered := decode[models.PurchaseOrder](t, rec); ordered.Status != models.PurchaseOrderStatusOrdered || ordered.OrderedAt == nil {
+			t.Errorf("unexpected ordered purchase order %+v", ordered)
+		}
+		api.mustStatus(api.request(http.MethodPut, path, models.PurchaseOrderRequest{
+			SupplierID: supplier.ID,
+			Items:      []models.PurchaseOrderItemLine{{ProductID: indomie.ID, Quantity: 1}},
+		}), http.StatusConflict)
+
+		rec = api.request(http.MethodPost, path+"/receive", map[string]any{
+			"items": []models.ReceiveItem{{ItemID: po.Items[0].ID, Quantity: 40}, {ItemID: po.Items[1].ID, Quantity: 20}},
+			"actor": "gudang",
+		})
+		api.mustStatus(rec, http.StatusOK)
+		partial := decode[models.PurchaseOrder](t, rec)
+		if partial.Status != models.PurchaseOrderStatusPartiallyReceived || partial.Items[1].ReceivedQuantity != 20 {
+			t.Errorf("unexpected partial receipt %+v", partial)
+		}
+		if stockOf(indomie.ID) != 45 || stockOf(aqua.ID) != 20 {
+			t.Errorf("stock = %d and %d, want 45 and 20", stockOf(indomie.ID), stockOf(aqua.ID))
+		}
+
+		api.mustStatus(api.request(http.MethodPost, path+"/receive", models.ReceiveRequest{
+			Items: []models.ReceiveItem{{ItemID: po.Items[1].ID, Quantity: 29}},
+		}), http.StatusUnprocessableEntity)
+		api.mustStatus(api.request(http.MethodPost, path+"/receive", models.ReceiveRequest{
+			Items: []models.ReceiveItem{{ItemID: 9999, Quantity: 1}},
+		}), http.StatusUnprocessableEntity)
+
+		rec = api.request(http.MethodPost, path+"/receive", models.ReceiveRequest{
+			Items: []models.ReceiveItem{{ItemID: po.Items[1].ID, Quantity: 28}},
+		})
+		api.mustStatus(rec, http.StatusOK)
+		if received := decode[models.PurchaseOrder](t, rec); received.Status != models.PurchaseOrderStatusReceived || received.ReceivedAt == nil {
+			t.Errorf("unexpected received purchase order %+v", received)
+		}
+		if stockOf(aqua.ID) != 48 {
+			t.Errorf("stock = %d, want 48", stockOf(aqua.ID))
+		}
+
+		// 5 units at cost 0 averaged with 40 received at 2800.
+		product := decode[models.ProductDetail](t, api.request(http.MethodGet, fmt.Sprintf("/api/products/%d", indomie.ID), nil))
+		if product.CostPrice != 2489 {
+			t.Errorf("cost_price = %d, want 2489", product.CostPrice)
+		}
+
+		rec = api.request(http.MethodPut, fmt.Sprintf("/api/products/%d", indomie.ID), map[string]any{"name": product.Name, "price": 4000})
+		api.mustStatus(rec, http.StatusOK)
+		if edited := decode[models.Product](t, rec); edited.CostPrice != 2489 {
+			t.Errorf("cost_price after an edit without it = %d, want 2489 kept", edited.CostPrice)
+		}
+
+		history := decode[models.Page[models.InventoryMovement]](t, api.request(http.MethodGet, fmt.Sprintf("/api/products/%d/stock-history", aqua.ID), nil))
+		latest := history.Data[0]
+		if latest.Type != models.MovementTypeRestock || latest.ReferenceType != models.ReferenceTypePurchaseOrder || *latest.ReferenceID != po.ID || latest.Actor != "admin" {
+			t.Errorf("unexpected movement %+v", latest)
+		}
+
+		api.mustStatus(api.request(http.MethodPost, path+"/cancel", nil), http.StatusConflict)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		other := createOrder()
+		rec := api.request(http.MethodPost, fmt.Sprintf("/api/purchase-orders/%d/cancel", other.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		if cancelled := decode[models.PurchaseOrder](t, rec); cancelled.Status != models.PurchaseOrderStatusCancelled {
+			t.Errorf("status = %q, want cancelled", cancelled.Status)
+		}
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/purchase-orders/%d/order", other.ID), nil), http.StatusConflict)
+	})
+
+	t.Run("list filtered by status", func(t *testing.T) {
+		page := decode[models.Page[models.PurchaseOrder]](t, api.request(http.MethodGet, "/api/purchase-orders?status=received", nil))
+		if len(page.Data) != 1 || page.Data[0].ID != po.ID {
+			t.Errorf("unexpected list %+v", page.Data)
+		}
+		page = decode[models.Page[models.PurchaseOrder]](t, api.request(http.MethodGet, fmt.Sprintf("/api/purchase-orders?supplier_id=%d", supplier.ID), nil))
+		if page.Pagination.Total != 2 {
+			t.Errorf("total = %d, want 2", page.Pagination.Total)
+		}
+	})
+
+	t.Run("referenced supplier and product stay", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodDelete, fmt.Sprintf("/api/suppliers/%d", supplier.ID), nil), http.StatusConflict)
+		api.mustStatus(api.request(http.MethodDelete, fmt.Sprintf("/api/products/%d", aqua.ID), nil), http.StatusConflict)
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"create without items", http.MethodPost, "/api/purchase-orders", models.PurchaseOrderRequest{SupplierID: supplier.ID}, http.StatusUnprocessableEntity},
+		{"create for unknown supplier", http.MethodPost, "/api/purchase-orders", models.PurchaseOrderRequest{
+			SupplierID: 9999, Items: []models.PurchaseOrderItemLine{{ProductID: indomie.ID, Quantity: 1}},
+		}, http.StatusUnprocessableEntity},
+		{"create for unknown product", http.MethodPost, "/api/purchase-orders", models.PurchaseOrderRequest{
+			SupplierID: supplier.ID, Items: []models.PurchaseOrderItemLine{{ProductID: 9999, Quantity: 1}},
+		}, http.StatusUnprocessableEntity},
+		{"create with duplicate product", http.MethodPost, "/api/purchase-orders", models.PurchaseOrderRequest{
+			SupplierID: supplier.ID, Items: []models.PurchaseOrderItemLine{{ProductID: indomie.ID, Quantity: 1}, {ProductID: indomie.ID, Quantity: 2}},
+		}, http.StatusUnprocessableEntity},
+		{"receive a received order", http.MethodPost, path + "/receive", models.ReceiveRequest{Items: []models.ReceiveItem{{ItemID: po.Items[0].ID, Quantity: 1}}}, http.StatusConflict},
+		{"receive without items", http.MethodPost, path + "/receive", models.ReceiveRequest{}, http.StatusUnprocessableEntity},
+		{"list unknown status", http.MethodGet, "/api/purchase-orders?status=lost", nil, http.StatusUnprocessableEntity},
+		{"list invalid supplier_id", http.MethodGet, "/api/purchase-orders?supplier_id=x", nil, http.StatusBadRequest},
+		{"get invalid id", http.MethodGet, "/api/purchase-orders/abc", nil, http.StatusBadRequest},
+		{"get unknown id", http.MethodGet, "/api/purchase-orders/9999", nil, http.StatusNotFound},
+		{"order unknown id", http.MethodPost, "/api/purchase-orders/9999/order", nil, http.StatusNotFound},
+		{"unknown action", http.MethodPost, path + "/explode", nil, http.StatusNotFound},
+		{"receive method not allowed", http.MethodGet, path + "/receive", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestPromotionEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+	makanan := api.createCategory("Makanan")
+	indomie := api.createProduct("Indomie Goreng", 3500, 20, &makanan.ID)
+	aqua := api.createProduct("Aqua 600ml", 2000, 20, nil)
+
+	createPromotion := func(body any) models.Promotion {
+		t.Helper()
+		rec := api.request(http.MethodPost, "/api/promotions", body)
+		api.mustStatus(rec, http.StatusCreated)
+		return decode[models.Promotion](t, rec)
+	}
+
+	// Promotions are active unless the body says otherwise.
+	food := createPromotion(fmt.Sprintf(`{"name": "Diskon Makanan", "type": "percent_off", "value": 10, "category_ids": [%d]}`, makanan.ID))
+	if !food.Active || len(food.ProductIDs) != 0 {
+		t.Errorf("created promotion = %+v, want active with no products", food)
+	}
+	createPromotion(models.Promotion{
+		Name: "Hemat 1000", Type: models.PromotionTypeCartAmountOff, Value: 1000, MinSpend: 20000, Active: true,
+	})
+	createPromotion(models.Promotion{
+		Name: "Nonaktif", Type: models.PromotionTypeCartPercentOff, Value: 50, Active: false,
+	})
+	tomorrow := time.Now().Add(24 * time.Hour)
+	createPromotion(models.Promotion{
+		Name: "Besok", Type: models.PromotionTypeCartPercentOff, Value: 50, StartsAt: &tomorrow, Active: true,
+	})
+
+	var trx models.Transaction
+
+	t.Run("checkout applies active promotions", func(t *testing.T) {
+		trx = api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 4}, {ProductID: aqua.ID, Quantity: 4}}, 20000)
+
+		// 10% off 14000 of food, then 1000 off the cart spread over 12600 and 8000.
+		if trx.Subtotal != 22000 || trx.DiscountAmount != 2400 || trx.TotalAmount != 19600 || trx.ChangeAmount != 400 {
+			t.Errorf("totals = %d - %d = %d, change %d", trx.Subtotal, trx.DiscountAmount, trx.TotalAmount, trx.ChangeAmount)
+		}
+		if d := trx.Details; d[0].Discount != 2012 || d[0].Subtotal != 11988 || d[1].Discount != 388 {
+			t.Errorf("line discounts = %+v", d)
+		}
+		if len(trx.Promotions) != 2 || trx.Promotions[0].Name != "Diskon Makanan" || trx.Promotions[0].Amount != 1400 ||
+			trx.Promotions[1].Amount != 1000 {
+			t.Errorf("applied promotions = %+v", trx.Promotions)
+		}
+
+		small := api.checkout([]models.CheckoutItem{{ProductID: aqua.ID, Quantity: 1}}, 2000)
+		if small.DiscountAmount != 0 || len(small.Promotions) != 0 {
+			t.Errorf("small cart got promotions %+v", small.Promotions)
+		}
+	})
+
+	t.Run("refunds give back the discounted price", func(t *testing.T) {
+		rec := api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", trx.ID), models.RefundRequest{
+			Items: []models.RefundItem{{DetailID: trx.Details[0].ID, Quantity: 2}},
+		})
+		api.mustStatus(rec, http.StatusCreated)
+		if refund := decode[models.Refund](t, rec); refund.TotalAmount != 5994 {
+			t.Errorf("refund = %d, want 5994", refund.TotalAmount)
+		}
+	})
+
+	t.Run("report totals discounts by promotion", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/report/hari-ini", nil)
+		api.mustStatus(rec, http.StatusOK)
+		report := decode[models.TransactionReport](t, rec)
+		if report.TotalDiscount != 2400 || len(report.Promotions) != 2 {
+			t.Fatalf("discounts = %d over %+v, want 2400 over 2 promotions", report.TotalDiscount, report.Promotions)
+		}
+		if u := report.Promotions[0]; u.Name != "Diskon Makanan" || u.DiscountAmount != 1400 || u.Transactions != 1 {
+			t.Errorf("first promotion = %+v", u)
+		}
+	})
+
+	t.Run("fully refunded sales give their use back", func(t *testing.T) {
+		refunded := api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}}, 3500)
+		if len(refunded.Promotions) != 1 {
+			t.Fatalf("applied promotions = %+v", refunded.Promotions)
+		}
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", refunded.ID), models.RefundRequest{
+			Items: []models.RefundItem{{DetailID: refunded.Details[0].ID, Quantity: 1}},
+		}), http.StatusCreated)
+
+		report := decode[models.TransactionReport](t, api.request(http.MethodGet, "/api/report/hari-ini", nil))
+		if u := report.Promotions[0]; u.Name != "Diskon Makanan" || u.DiscountAmount != 1400 || u.Transactions != 1 {
+			t.Errorf("first promotion = %+v, want only the kept sale", u)
+		}
+	})
+
+	t.Run("list by active", func(t *testing.T) {
+		page := decode[models.Page[models.Promotion]](t, api.request(http.MethodGet, "/api/promotions?active=false", nil))
+		if len(page.Data) != 1 || page.Data[0].Name != "Nonaktif" {
+			t.Errorf("inactive promotions = %+v", page.Data)
+		}
+	})
+
+	t.Run("update and delete keep past discounts", func(t *testing.T) {
+		path := fmt.Sprintf("/api/promotions/%d", food.ID)
+		food.Value = 20
+		api.mustStatus(api.request(http.MethodPut, path, food), http.StatusOK)
+		if p := decode[models.Promotion](t, api.request(http.MethodGet, path, nil)); p.Value != 20 || !slices.Equal(p.CategoryIDs, []int{makanan.ID}) {
+			t.Errorf("promotion not updated: %+v", p)
+		}
+
+		api.mustStatus(api.request(http.MethodDelete, path, nil), http.StatusOK)
+		api.mustStatus(api.request(http.MethodGet, path, nil), http.StatusNotFound)
+
+		got := decode[models.Transaction](t, api.request(http.MethodGet, fmt.Sprintf("/api/transactions/%d", trx.ID), nil))
+		if p := got.Promotions[0]; p.PromotionID != nil || p.Name != "Diskon Makanan" || p.Amount != 1400 {
+			t.Errorf("applied promotion after delete = %+v", p)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"create invalid json", http.MethodPost, "/api/promotions", "{", http.StatusBadRequest},
+		{"create unknown type", http.MethodPost, "/api/promotions", models.Promotion{Name: "x", Type: "free_lunch"}, http.StatusUnprocessableEntity},
+		{"create percent over 100", http.MethodPost, "/api/promotions", models.Promotion{Name: "x", Type: models.PromotionTypePercentOff, Value: 101}, http.StatusUnprocessableEntity},
+		{"create unknown product", http.MethodPost, "/api/promotions", models.Promotion{Name: "x", Type: models.PromotionTypePercentOff, Value: 5, ProductIDs: []int{9999}}, http.StatusUnprocessableEntity},
+		{"create unknown category", http.MethodPost, "/api/promotions", models.Promotion{Name: "x", Type: models.PromotionTypePercentOff, Value: 5, CategoryIDs: []int{9999}}, http.StatusUnprocessableEntity},
+		{"create unknown bundle product", http.MethodPost, "/api/promotions", models.Promotion{Name: "x", Type: models.PromotionTypeBundle, BundleItems: []models.BundleItem{{ProductID: 9999, Quantity: 2}}}, http.StatusUnprocessableEntity},
+		{"list invalid active", http.MethodGet, "/api/promotions?active=maybe", nil, http.StatusBadRequest},
+		{"get invalid id", http.MethodGet, "/api/promotions/abc", nil, http.StatusBadRequest},
+		{"get unknown id", http.MethodGet, "/api/promotions/9999", nil, http.StatusNotFound},
+		{"update unknown id", http.MethodPut, "/api/promotions/9999", models.Promotion{Name: "x", Type: models.PromotionTypeCartAmountOff, Value: 1}, http.StatusNotFound},
+		{"delete unknown id", http.MethodDelete, "/api/promotions/9999", nil, http.StatusNotFound},
+		{"collection method not allowed", http.MethodDelete, "/api/promotions", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestVoucherEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+	makanan := api.createCategory("Makanan")
+	indomie := api.createProduct("Indomie Goreng", 3500, 50, &makanan.ID)
+	aqua := api.createProduct("Aqua 600ml", 2000, 50, nil)
+
+	createVoucher := func(body any) models.Voucher {
+		t.Helper()
+		rec := api.request(http.MethodPost, "/api/vouchers", body)
+		api.mustStatus(rec, http.StatusCreated)
+		return decode[models.Voucher](t, rec)
+	}
+	generate := func(id, count int, prefix string) []models.VoucherCode {
+		t.Helper()
+		rec := api.request(http.MethodPost, fmt.Sprintf("/api/vouchers/%d/codes", id), models.GenerateCodesRequest{Count: count, Prefix: prefix})
+		api.mustStatus(rec, http.StatusCreated)
+		return decode[[]models.VoucherCode](t, rec)
+	}
+	fieldError := func(rec *httptest.ResponseRecorder) models.FieldError {
+		t.Helper()
+		api.mustStatus(rec, http.StatusUnprocessableEntity)
+		body := decode[struct {
+			Details []models.FieldError `json:"details"`
+		}](t, rec)
+		if len(body.Details) != 1 {
+			t.Fatalf("field errors = %+v, want one", body.Details)
+		}
+		return body.Details[0]
+	}
+
+	// Vouchers are active and single use per code unless the body says otherwise.
+	food := createVoucher(fmt.Sprintf(`{"name": "Diskon Makanan", "type": "percent_off", "value": 10, "max_discount": 1000, "min_purchase": 10000, "category_ids": [%d]}`, makanan.ID))
+	if !food.Active || food.UsesPerCode != 1 || food.MaxUses != 0 {
+		t.Errorf("created voucher = %+v, want active and single use", food)
+	}
+
+	codes := generate(food.ID, 3, "hemat")
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if !strings.HasPrefix(c.Code, "HEMAT") || len(c.Code) != len("HEMAT")+10 || seen[c.Code] {
+			t.Errorf("unexpected code %q in %+v", c.Code, codes)
+		}
+		seen[c.Code] = true
+	}
+	if len(codes) != 3 {
+		t.Fatalf("generated %d codes, want 3", len(codes))
+	}
+	code := codes[0].Code
+	cart := []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 4}, {ProductID: aqua.ID, Quantity: 1}}
+
+	t.Run("validate previews the discount", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/vouchers/validate", models.VoucherValidationRequest{
+			VoucherCode: strings.ToLower(code), Items: cart,
+		})
+		api.mustStatus(rec, http.StatusOK)
+
+		// 10% of the 14000 of food is capped at 1000.
+		preview := decode[models.VoucherPreview](t, rec)
+		if preview.VoucherCode != code || preview.VoucherDiscount != 1000 || preview.TotalAmount != 15000 || preview.RemainingUses != 1 {
+			t.Errorf("preview = %+v", preview)
+		}
+	})
+
+	t.Run("validate reports why a code can't be used", func(t *testing.T) {
+		f := fieldError(api.request(http.MethodPost, "/api/vouchers/validate", models.VoucherValidationRequest{
+			VoucherCode: code, Items: []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}},
+		}))
+		if f.Field != "voucher_code" || f.Rule != "redeemable" || !strings.Contains(f.Message, "minimum purchase") {
+			t.Errorf("below minimum purchase: %+v", f)
+		}
+
+		f = fieldError(api.request(http.MethodPost, "/api/vouchers/validate", models.VoucherValidationRequest{VoucherCode: "NOPE"}))
+		if f.Field != "voucher_code" || f.Rule != "exists" {
+			t.Errorf("unknown code: %+v", f)
+		}
+	})
+
+	var trx models.Transaction
+
+	t.Run("checkout redeems the code once", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{Items: cart, Payments: cash(15000), VoucherCode: code})
+		api.mustStatus(rec, http.StatusOK)
+		trx = decode[models.Transaction](t, rec)
+
+		if trx.DiscountAmount != 1000 || trx.TotalAmount != 15000 || trx.Details[0].Discount != 1000 || trx.Details[1].Discount != 0 {
+			t.Errorf("totals = %d - %d = %d, details %+v", trx.Subtotal, trx.DiscountAmount, trx.TotalAmount, trx.Details)
+		}
+		if v := trx.Voucher; v == nil || v.Code != code || v.VoucherID != food.ID || v.Amount != 1000 {
+			t.Errorf("voucher = %+v", trx.Voucher)
+		}
+
+		got := decode[models.Transaction](t, api.request(http.MethodGet, fmt.Sprintf("/api/transactions/%d", trx.ID), nil))
+		if got.Voucher == nil || got.Voucher.Code != code {
+			t.Errorf("stored voucher = %+v", got.Voucher)
+		}
+
+		f := fieldError(api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{Items: cart, Payments: cash(15000), VoucherCode: code}))
+		if f.Rule != "redeemable" {
+			t.Errorf("second redemption: %+v", f)
+		}
+	})
+
+	t.Run("redemptions are counted", func(t *testing.T) {
+		v := decode[models.Voucher](t, api.request(http.MethodGet, fmt.Sprintf("/api/vouchers/%d", food.ID), nil))
+		if v.Redemptions != 1 || v.CodeCount != 3 {
+			t.Errorf("voucher = %+v, want 1 redemption of 3 codes", v)
+		}
+
+		page := decode[models.Page[models.VoucherCode]](t, api.request(http.MethodGet, fmt.Sprintf("/api/vouchers/%d/codes?limit=2", food.ID), nil))
+		if page.Pagination.Total != 3 || len(page.Data) != 2 || page.Data[0].Redemptions != 1 || page.Data[1].Redemptions != 0 {
+			t.Errorf("codes = %+v", page)
+		}
+
+		api.mustStatus(api.request(http.MethodDelete, fmt.Sprintf("/api/vouchers/%d", food.ID), nil), http.StatusConflict)
+	})
+
+	t.Run("concurrent checkouts can't over-redeem", func(t *testing.T) {
+		limited := createVoucher(models.Voucher{
+			Name: "Hemat 500", Type: models.VoucherTypeAmountOff, Value: 500, UsesPerCode: 10, MaxUses: 3, Active: true,
+		})
+		shared := generate(limited.ID, 1, "")[0].Code
+
+		var wg sync.WaitGroup
+		statuses := make([]int, 8)
+		for i := range statuses {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				statuses[i] = api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+					Items: []models.CheckoutItem{{ProductID: aqua.ID, Quantity: 1}}, Payments: cash(2000), VoucherCode: shared,
+				}).Code
+			}()
+		}
+		wg.Wait()
+
+		ok := 0
+		for _, status := range statuses {
+			if status == http.StatusOK {
+				ok++
+			} else if status != http.StatusUnprocessableEntity {
+				t.Errorf("unexpected status %d", status)
+			}
+		}
+		if ok != 3 {
+			t.Errorf("%d checkouts redeemed the code, want 3", ok)
+		}
+		if v := decode[models.Voucher](t, api.request(http.MethodGet, fmt.Sprintf("/api/vouchers/%d", limited.ID), nil)); v.Redemptions != 3 {
+			t.Errorf("redemptions = %d, want 3", v.Redemptions)
+		}
+	})
+
+	t.Run("unused vouchers can be deleted", func(t *testing.T) {
+		unused := createVoucher(models.Voucher{Name: "Coba", Type: models.VoucherTypeAmountOff, Value: 100, UsesPerCode: 1})
+		generate(unused.ID, 2, "X")
+		api.mustStatus(api.request(http.MethodDelete, fmt.Sprintf("/api/vouchers/%d", unused.ID), nil), http.StatusOK)
+		api.mustStatus(api.request(http.MethodGet, fmt.Sprintf("/api/vouchers/%d/codes", unused.ID), nil), http.StatusNotFound)
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"create invalid json", http.MethodPost, "/api/vouchers", "{", http.StatusBadRequest},
+		{"create unknown type", http.MethodPost, "/api/vouchers", models.Voucher{Name: "x", Type: "free_lunch", Value: 1, UsesPerCode: 1}, http.StatusUnprocessableEntity},
+		{"create cap on amount off", http.MethodPost, "/api/vouchers", models.Voucher{Name: "x", Type: models.VoucherTypeAmountOff, Value: 1, MaxDiscount: 1, UsesPerCode: 1}, http.StatusUnprocessableEntity},
+		{"create unknown product", http.MethodPost, "/api/vouchers", models.Voucher{Name: "x", Type: models.VoucherTypeAmountOff, Value: 1, UsesPerCode: 1, ProductIDs: []int{9999}}, http.StatusUnprocessableEntity},
+		{"generate too many", http.MethodPost, fmt.Sprintf("/api/vouchers/%d/codes", food.ID), models.GenerateCodesRequest{Count: 1001}, http.StatusUnprocessableEntity},
+		{"generate bad prefix", http.MethodPost, fmt.Sprintf("/api/vouchers/%d/codes", food.ID), models.GenerateCodesRequest{Count: 1, Prefix: "A B"}, http.StatusUnprocessableEntity},
+		{"generate unknown voucher", http.MethodPost, "/api/vouchers/9999/codes", models.GenerateCodesRequest{Count: 1}, http.StatusNotFound},
+		{"validate without code", http.MethodPost, "/api/vouchers/validate", models.VoucherValidationRequest{}, http.StatusUnprocessableEntity},
+		{"validate method not allowed", http.MethodGet, "/api/vouchers/validate", nil, http.StatusMethodNotAllowed},
+		{"get invalid id", http.MethodGet, "/api/vouchers/abc", nil, http.StatusBadRequest},
+		{"get unknown id", http.MethodGet, "/api/vouchers/9999", nil, http.StatusNotFound},
+		{"unknown action", http.MethodGet, fmt.Sprintf("/api/vouchers/%d/nope", food.ID), nil, http.StatusNotFound},
+		{"checkout unknown code", http.MethodPost, "/api/checkout", models.CheckoutRequest{Items: cart, Payments: cash(20000), VoucherCode: "NOPE"}, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestTaxEndpoints(t *testing.T) {
+	api := newTestAPIWithConfig(t, Config{IdempotencyKeyTTL: time.Hour, TaxRateBP: 1100, ServiceChargeBP: 500})
+
+	rec := api.request(http.MethodPost, "/api/tax-classes", models.TaxClass{Name: "Bebas PPN", RateBP: 0})
+	api.mustStatus(rec, http.StatusCreated)
+	exempt := decode[models.TaxClass](t, rec)
+
+	t.Run("tax classes are validated", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodPost, "/api/tax-classes", models.TaxClass{Name: "bebas ppn"}), http.StatusUnprocessableEntity)
+		api.mustStatus(api.request(http.MethodPost, "/api/tax-classes", models.TaxClass{Name: "Aneh", RateBP: 10001}), http.StatusUnprocessableEntity)
+		api.mustStatus(api.request(http.MethodGet, "/api/tax-classes/99", nil), http.StatusNotFound)
+
+		rec := api.request(http.MethodPost, "/api/products", models.Product{Name: "Gula", Price: 1, TaxClassID: intPtr(99)})
+		api.mustStatus(rec, http.StatusUnprocessableEntity)
+		if !strings.Contains(rec.Body.String(), `"tax_class_id"`) {
+			t.Errorf("unknown tax class: %s", rec.Body.String())
+		}
+	})
+
+	nasi := api.createProduct("Nasi Goreng", 25000, 10, nil)
+	rec = api.request(http.MethodPost, "/api/products", models.Product{Name: "Beras 1kg", Price: 10000, Stock: 10, TaxClassID: &exempt.ID})
+	api.mustStatus(rec, http.StatusCreated)
+	beras := decode[models.Product](t, rec)
+
+	var trx models.Transaction
+
+	t.Run("checkout adds service charge and tax", func(t *testing.T) {
+		// 5% service on 60000 is 3000, spread 2500 / 500. Only the nasi is
+		// taxed: 11% of 52500 is 5775.
+		trx = api.checkout([]models.CheckoutItem{{ProductID: nasi.ID, Quantity: 2}, {ProductID: beras.ID, Quantity: 1}}, 70000)
+		if trx.Subtotal != 60000 || trx.ServiceCharge != 3000 || trx.TaxAmount != 5775 || trx.TotalAmount != 68775 || trx.ChangeAmount != 1225 {
+			t.Errorf("totals = %d + %d + %d = %d, change %d", trx.Subtotal, trx.ServiceCharge, trx.TaxAmount, trx.TotalAmount, trx.ChangeAmount)
+		}
+		if d := trx.Details[0]; d.TaxRateBP != 1100 || d.ServiceCharge != 2500 || d.TaxAmount != 5775 || d.Total != 58275 {
+			t.Errorf("taxed line = %+v", d)
+		}
+		if d := trx.Details[1]; d.TaxRateBP != 0 || d.ServiceCharge != 500 || d.TaxAmount != 0 || d.Total != 10500 {
+			t.Errorf("exempt line = %+v", d)
+		}
+
+		rec := api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+			Items: []models.CheckoutItem{{ProductID: nasi.ID, Quantity: 1}}, Payments: cash(25000),
+		})
+		api.mustStatus(rec, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("refunds give back their share of tax and service", func(t *testing.T) {
+		rec := api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", trx.ID), models.RefundRequest{
+			Items: []models.RefundItem{{DetailID: trx.Details[0].ID, Quantity: 1}},
+		})
+		api.mustStatus(rec, http.StatusCreated)
+		if d := decode[models.Refund](t, rec).Details[0]; d.Amount != 29137 || d.ServiceCharge != 1250 || d.TaxAmount != 2887 {
+			t.Errorf("refund detail = %+v", d)
+		}
+	})
+
+	t.Run("report separates tax and service from revenue", func(t *testing.T) {
+		report := decode[models.TransactionReport](t, api.request(http.MethodGet, "/api/report/hari-ini", nil))
+		if report.TotalRevenue != 35000 || report.TotalTax != 2888 || report.TotalService != 1750 {
+			t.Errorf("revenue / tax / service = %d / %d / %d, want 35000 / 2888 / 1750", report.TotalRevenue, report.TotalTax, report.TotalService)
+		}
+		if len(report.Taxes) != 1 || report.Taxes[0] != (models.TaxCollected{TaxRateBP: 1100, TaxAmount: 2888}) {
+			t.Errorf("taxes = %+v", report.Taxes)
+		}
+	})
+
+	t.Run("classes in use can't be deleted", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodDelete, fmt.Sprintf("/api/tax-classes/%d", exempt.ID), nil), http.StatusConflict)
+	})
+
+	t.Run("inclusive prices", func(t *testing.T) {
+		api := newTestAPIWithConfig(t, Config{IdempotencyKeyTTL: time.Hour, TaxRateBP: 1100, PricesIncludeTax: true})
+		kopi := api.createProduct("Kopi Susu", 22200, 10, nil)
+
+		trx := api.checkout([]models.CheckoutItem{{ProductID: kopi.ID, Quantity: 1}}, 22200)
+		if !trx.PricesIncludeTax || trx.TaxAmount != 2200 || trx.TotalAmount != 22200 {
+			t.Errorf("tax %d of total %d, want 2200 of 22200", trx.TaxAmount, trx.TotalAmount)
+		}
+	})
+}
+
+func TestCheckoutEndpoint(t *testing.T) {
+	api := newTestAPI(t)
+
+	indomie := api.createProduct("Indomie Goreng", 3500, 10, nil)
+	aqua := api.createProduct("Aqua 600ml", 2000, 2, nil)
+
+	t.Run("success deducts stock and returns change", func(t *testing.T) {
+		trx := api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 2}, {ProductID: aqua.ID, Quantity: 1}}, 10000)
+
+		if trx.TotalAmount != 9000 || trx.ChangeAmount != 1000 || trx.Status != models.TransactionStatusCompleted {
+			t.Errorf("unexpected transaction %+v", trx)
+		}
+		if len(trx.Details) != 2 || trx.Details[0].ProductName != "Indomie Goreng" || trx.Details[0].UnitPrice != 3500 {
+			t.Errorf("unexpected details %+v", trx.Details)
+		}
+
+		p := decode[models.ProductDetail](t, api.request(http.MethodGet, fmt.Sprintf("/api/products/%d", indomie.ID), nil))
+		if p.Stock != 8 {
+			t.Errorf("stock = %d, want 8", p.Stock)
+		}
+	})
+
+	t.Run("insufficient stock lists offending products", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/checkout", models.CheckoutRequest{
+			Items:    []models.CheckoutItem{{ProductID: aqua.ID, Quantity: 5}},
+			Payments: cash(100000),
+		})
+		api.mustStatus(rec, http.StatusConflict)
+
+		body := decode[struct {
+			Code    string                 `json:"code"`
+			Details []models.StockShortage `json:"details"`
+		}](t, rec)
+		if body.Code != "insufficient_stock" {
+			t.Errorf("code = %q, want insufficient_stock", body.Code)
+		}
+		if len(body.Details) != 1 || body.Details[0].ProductID != aqua.ID || body.Details[0].Available != 1 {
+			t.Errorf("unexpected shortages %+v", body.Details)
+		}
+	})
+
+	t.Run("idempotent replay", func(t *testing.T) {
+		req := models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}}, Payments: cash(3500)}
+
+		first := api.request(http.MethodPost, "/api/checkout", req, "Idempotency-Key", "retry-1")
+		api.mustStatus(first, http.StatusOK)
+		second := api.request(http.MethodPost, "/api/checkout", req, "Idempotency-Key", "retry-1")
+		api.mustStatus(second, http.StatusOK)
+
+		if first.Body.String() != second.Body.String() || second.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("replay differs:\n%s\n%s", first.Body.String(), second.Body.String())
+		}
+
+		req.Items[0].Quantity = 2
+		req.Payments = cash(7000)
+		api.mustStatus(api.request(http.MethodPost, "/api/checkout", req, "Idempotency-Key", "retry-1"), http.StatusUnprocessableEntity)
+	})
+
+	t.Run("idempotency keys are per user", func(t *testing.T) {
+		req := models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}}, Payments: cash(3500)}
+		first := api.request(http.MethodPost, "/api/checkout", req, "Idempotency-Key", "retry-2")
+		api.mustStatus(first, http.StatusOK)
+
+		other := api.createUser("kasir-retry", models.RoleCashier)
+		second := api.request(http.MethodPost, "/api/checkout", req, "Idempotency-Key", "retry-2", "Authorization", "Bearer "+other.AccessToken)
+		api.mustStatus(second, http.StatusOK)
+		if second.Header().Get("Idempotent-Replayed") != "" || decode[models.Transaction](t, second).ID == decode[models.Transaction](t, first).ID {
+			t.Errorf("another user's key replayed the first user's transaction:\n%s", second.Body.String())
+		}
+	})
+
+	errorCases := []struct {
+		name string
+		body any
+		want int
+	}{
+		{"invalid json", "{", http.StatusBadRequest},
+		{"no items", models.CheckoutRequest{Payments: cash(1000)}, http.StatusUnprocessableEntity},
+		{"unknown product", models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: 9999, Quantity: 1}}, Payments: cash(1000)}, http.StatusUnprocessableEntity},
+		{"zero quantity", models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 0}}, Payments: cash(1000)}, http.StatusUnprocessableEntity},
+		{"missing payment", models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}}}, http.StatusUnprocessableEntity},
+		{"underpaid", models.CheckoutRequest{Items: []models.CheckoutItem{{ProductID: indomie.ID, Quantity: 1}}, Payments: cash(1000)}, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(http.MethodPost, "/api/checkout", tc.body), tc.want)
+		})
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodGet, "/api/checkout", nil), http.StatusMethodNotAllowed)
+	})
+}
+
+func TestTransactionEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+
+	indomie := api.createProduct("Indomie Goreng", 3500, 20, nil)
+	aqua := api.createProduct("Aqua 600ml", 2000, 20, nil)
+
+	small := api.checkout([]models.CheckoutItem{{ProductID: aqua.ID, Quantity: 1}}, 2000)
+	large := api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 4}, {ProductID: aqua.ID, Quantity: 1}}, 20000)
+
+	t.Run("list and filter", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/transactions", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if page := decode[models.Page[models.Transaction]](t, rec); page.Pagination.Total != 2 {
+			t.Errorf("total = %d, want 2", page.Pagination.Total)
+		}
+
+		rec = api.request(http.MethodGet, fmt.Sprintf("/api/transactions?product_id=%d", indomie.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		page := decode[models.Page[models.Transaction]](t, rec)
+		if len(page.Data) != 1 || page.Data[0].ID != large.ID {
+			t.Errorf("product filter returned %+v", page.Data)
+		}
+
+		rec = api.request(http.MethodGet, "/api/transactions?max_total=5000&limit=1", nil)
+		api.mustStatus(rec, http.StatusOK)
+		page = decode[models.Page[models.Transaction]](t, rec)
+		if len(page.Data) != 1 || page.Data[0].ID != small.ID || page.Pagination.Limit != 1 {
+			t.Errorf("max_total filter returned %+v", page)
+		}
+	})
+
+	t.Run("get by id", func(t *testing.T) {
+		rec := api.request(http.MethodGet, fmt.Sprintf("/api/transactions/%d", large.ID), nil)
+		api.mustStatus(rec, http.StatusOK)
+		trx := decode[models.Transaction](t, rec)
+		if trx.TotalAmount != 16000 || len(trx.Details) != 2 || len(trx.Payments) != 1 {
+			t.Errorf("unexpected transaction %+v", trx)
+		}
+	})
+
+	t.Run("details keep the name and price charged", func(t *testing.T) {
+		edit := models.ProductUpdate{Product: models.Product{Name: "Indomie Goreng Jumbo", Price: 5000}}
+		api.mustStatus(api.request(http.MethodPut, fmt.Sprintf("/api/products/%d", indomie.ID), edit), http.StatusOK)
+
+		trx := decode[models.Transaction](t, api.request(http.MethodGet, fmt.Sprintf("/api/transactions/%d", large.ID), nil))
+		if d := trx.Details[0]; d.ProductName != "Indomie Goreng" || d.UnitPrice != 3500 || d.Subtotal != 14000 {
+			t.Errorf("detail = %+v, want Indomie Goreng at 3500", d)
+		}
+	})
+
+	t.Run("refund then void is rejected", func(t *testing.T) {
+		path := fmt.Sprintf("/api/transactions/%d", large.ID)
+		rec := api.request(http.MethodPost, path+"/refunds", models.RefundRequest{
+			Reason: "Damaged",
+			Items:  []models.RefundItem{{DetailID: large.Details[0].ID, Quantity: 1}},
+		})
+		api.mustStatus(rec, http.StatusCreated)
+		if refund := decode[models.Refund](t, rec); refund.TotalAmount != 3500 || refund.Details[0].ProductName != "Indomie Goreng" {
+			t.Errorf("refund = %+v, want 3500 for Indomie Goreng", refund)
+		}
+
+		api.mustStatus(api.request(http.MethodPost, path+"/void", nil), http.StatusConflict)
+		api.mustStatus(api.request(http.MethodPost, path+"/refunds", models.RefundRequest{
+			Items: []models.RefundItem{{DetailID: large.Details[0].ID, Quantity: 4}},
+		}), http.StatusUnprocessableEntity)
+	})
+
+	t.Run("voids and refunds need a shift's drawer, for admins too", func(t *testing.T) {
+		rec := api.request(http.MethodPost, "/api/auth/login", models.LoginRequest{Username: "admin", Password: testPassword})
+		api.mustStatus(rec, http.StatusOK)
+		backOffice := []string{"Authorization", "Bearer " + decode[models.TokenResponse](t, rec).AccessToken}
+
+		path := fmt.Sprintf("/api/transactions/%d", small.ID)
+		for _, rec := range []*httptest.ResponseRecorder{
+			api.request(http.MethodPost, path+"/void", nil, backOffice...),
+			api.request(http.MethodPost, path+"/refunds", models.RefundRequest{
+				Items: []models.RefundItem{{DetailID: small.Details[0].ID, Quantity: 1}},
+			}, backOffice...),
+		} {
+			api.mustStatus(rec, http.StatusConflict)
+			if !strings.Contains(rec.Body.String(), "paid out of a shift's drawer") {
+				t.Errorf("unexpected 409 %s", rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("void", func(t *testing.T) {
+		path := fmt.Sprintf("/api/transactions/%d", small.ID)
+		api.mustStatus(api.request(http.MethodPost, path+"/void", models.VoidRequest{Reason: "Mistake"}), http.StatusCreated)
+
+		trx := decode[models.Transaction](t, api.request(http.MethodGet, path, nil))
+		if trx.Status != models.TransactionStatusVoided {
+			t.Errorf("status = %q, want voided", trx.Status)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"list invalid start_date", http.MethodGet, "/api/transactions?start_date=yesterday", nil, http.StatusBadRequest},
+		{"list invalid min_total", http.MethodGet, "/api/transactions?min_total=lots", nil, http.StatusBadRequest},
+		{"list invalid page", http.MethodGet, "/api/transactions?page=0", nil, http.StatusBadRequest},
+		{"get invalid id", http.MethodGet, "/api/transactions/abc", nil, http.StatusBadRequest},
+		{"get unknown id", http.MethodGet, "/api/transactions/9999", nil, http.StatusNotFound},
+		{"void unknown id", http.MethodPost, "/api/transactions/9999/void", nil, http.StatusNotFound},
+		{"refund invalid json", http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", large.ID), "{", http.StatusBadRequest},
+		{"refund without items", http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", large.ID), models.RefundRequest{}, http.StatusUnprocessableEntity},
+		{"unknown action", http.MethodPost, fmt.Sprintf("/api/transactions/%d/explode", large.ID), nil, http.StatusNotFound},
+		{"list method not allowed", http.MethodPost, "/api/transactions", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestReceiptEndpoint(t *testing.T) {
+	api := newTestAPIWithConfig(t, Config{
+		IdempotencyKeyTTL:   time.Hour,
+		TaxRateBP:           1100,
+		ReceiptStoreName:    "Toko Maju",
+		ReceiptStoreAddress: "Jl. Merdeka No. 1\nBandung",
+		ReceiptFooter:       "Terima kasih",
+		ReceiptWidth:        "58mm",
+	})
+	indomie := api.createProduct("Indomie Goreng <Jumbo>", 3500, 10, nil)
+	trx := api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 2}}, 10000)
+	path := fmt.Sprintf("/api/transactions/%d/receipt", trx.ID)
+
+	t.Run("text", func(t *testing.T) {
+		rec := api.request(http.MethodGet, path, nil)
+		api.mustStatus(rec, http.StatusOK)
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Errorf("content type = %q", ct)
+		}
+
+		body := rec.Body.String()
+		for _, want := range []string{"Toko Maju", "Bandung", fmt.Sprintf("%06d", trx.ID), "2 x 3.500", "PPN 11%", "7.770", "Change", "2.230", "Terima kasih"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("receipt lacks %q:\n%s", want, body)
+			}
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+			if n := len([]rune(line)); n > 32 {
+				t.Errorf("line %q is %d characters wide", line, n)
+			}
+		}
+
+		wide := api.request(http.MethodGet, path+"?width=80mm", nil).Body.String()
+		if !strings.Contains(wide, strings.Repeat("-", 48)+"\n") {
+			t.Errorf("80mm receipt not 48 wide:\n%s", wide)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		rec := api.request(http.MethodGet, path+"?format=html", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if body := rec.Body.String(); !strings.Contains(body, "Indomie Goreng &lt;Jumbo&gt;") || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+			t.Errorf("html receipt:\n%s", body)
+		}
+	})
+
+	t.Run("escpos", func(t *testing.T) {
+		rec := api.request(http.MethodGet, path+"?format=escpos&width=32", nil)
+		api.mustStatus(rec, http.StatusOK)
+		body := rec.Body.Bytes()
+		if !bytes.HasPrefix(body, []byte("\x1b@")) || !bytes.HasSuffix(body, []byte("\x1dV\x41\x00")) || !bytes.Contains(body, []byte("\x1bE\x01TOTAL")) {
+			t.Errorf("escpos receipt = %q", body)
+		}
+	})
+
+	t.Run("bad requests", func(t *testing.T) {
+		api.mustStatus(api.request(http.MethodGet, path+"?format=pdf", nil), http.StatusBadRequest)
+		api.mustStatus(api.request(http.MethodGet, path+"?width=76mm", nil), http.StatusBadRequest)
+		api.mustStatus(api.request(http.MethodGet, "/api/transactions/999/receipt", nil), http.StatusNotFound)
+		api.mustStatus(api.request(http.MethodPost, path, nil), http.StatusMethodNotAllowed)
+	})
+}
+
+func TestReportEndpoints(t *testing.T) {
+	api := newTestAPI(t)
+
+	drinks := api.createCategory("Minuman")
+	indomie := api.createProduct("Indomie Goreng", 3500, 20, nil)
+	aqua := api.createProduct("Aqua 600ml", 2000, 20, &drinks.ID)
+	for _, p := range []*models.Product{&indomie, &aqua} {
+		p.CostPrice = p.Price * 3 / 4
+		api.mustStatus(api.request(http.MethodPut, fmt.Sprintf("/api/products/%d", p.ID), p), http.StatusOK)
+	}
+
+	api.checkout([]models.CheckoutItem{{ProductID: indomie.ID, Quantity: 3}}, 10500)
+	api.checkout([]models.CheckoutItem{{ProductID: aqua.ID, Quantity: 1}, {ProductID: indomie.ID, Quantity: 1}}, 5500)
+
+	t.Run("today", func(t *testing.T) {
+		rec := api.request(http.MethodGet, "/api/report/hari-ini", nil)
+		api.mustStatus(rec, http.StatusOK)
+
+		report := decode[struct {
+			TotalRevenue   int                       `json:"total_revenue"`
+			TotalTransaksi int                       `json:"total_transaksi"`
+			ProdukTerlaris models.BestSellingProduct `json:"produk_terlaris"`
+		}](t, rec)
+		if report.TotalRevenue != 16000 || report.TotalTransaksi != 2 {
+			t.Errorf("unexpected report %+v", report)
+		}
+		if report.ProdukTerlaris.Nama != "Indomie Goreng" || report.ProdukTerlaris.QuantitySold != 4 {
+			t.Errorf("best seller = %+v, want Indomie Goreng x4", report.ProdukTerlaris)
+		}
+	})
+
+	t.Run("date range", func(t *testing.T) {
+		today := time.Now().Format("2006-01-02")
+		rec := api.request(http.MethodGet, "/api/report?start_date="+today+"&end_date="+today, nil)
+		api.mustStatus(rec, http.StatusOK)
+		if report := decode[models.TransactionReport](t, rec); report.TotalTransaksi != 2 {
+			t.Errorf("total_transaksi = %d, want 2", report.TotalTransaksi)
+		}
+
+		rec = api.request(http.MethodGet, "/api/report?end_date=2000-01-01", nil)
+		api.mustStatus(rec, http.StatusOK)
+		if report := decode[models.TransactionReport](t, rec); report.TotalTransaksi != 0 || report.TotalRevenue != 0 {
+			t.Errorf("expected empty report, got %+v", report)
+		}
+	})
+
+	t.Run("gross profit", func(t *testing.T) {
+		trx := api.checkout([]models.CheckoutItem{{ProductID: aqua.ID, Quantity: 2}}, 4000)
+		if d := trx.Details[0]; d.UnitPrice != 2000 || d.UnitCost != 1500 {
+			t.Errorf("detail snapshot = %d / %d, want 2000 / 1500", d.UnitPrice, d.UnitCost)
+		}
+		api.mustStatus(api.request(http.MethodPost, fmt.Sprintf("/api/transactions/%d/refunds", trx.ID), models.RefundRequest{
+			Items: []models.RefundItem{{DetailID: trx.Details[0].ID, Quantity: 1}},
+		}), http.StatusCreated)
+
+		report := decode[models.TransactionReport](t, api.request(http.MethodGet, "/api/report/hari-ini", nil))
+		// Indomie 4 x 3500 at cost 2625; Aqua 3 sold and 1 refunded, 2000 at cost 1500.
+		if report.TotalRevenue != 18000 || report.CostOfGoodsSold != 13500 || report.GrossProfit != 4500 || report.MarginPercent != 25 {
+			t.Errorf("totals = %d / %d / %d / %v, want 18000 / 13500 / 4500 / 25",
+				report.TotalRevenue, report.CostOfGoodsSold, report.GrossProfit, report.MarginPercent)
+		}
+		if len(report.Products) != 2 || report.Products[0].ProductID != indomie.ID || report.Products[1].QuantitySold != 2 {
+			t.Errorf("unexpected products %+v", report.Products)
+		}
+		if len(report.Categories) != 2 || report.Categories[1].Name != "Minuman" || report.Categories[1].GrossProfit != 1000 {
+			t.Errorf("unexpected categories %+v", report.Categories)
+		}
+	})
+
+	errorCases := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"invalid start_date", http.MethodGet, "/api/report?start_date=01-02-2026", http.StatusBadRequest},
+		{"invalid end_date", http.MethodGet, "/api/report?end_date=" + strings.Repeat("9", 10), http.StatusBadRequest},
+		{"range method not allowed", http.MethodPost, "/api/report", http.StatusMethodNotAllowed},
+		{"today method not allowed", http.MethodPost, "/api/report/hari-ini", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api.mustStatus(api.request(tc.method, tc.path, nil), tc.want)
+		})
+	}
+}
@@ -0,0 +1,4 @@
+-- Last line of defence against overselling; checkout validates stock under a
+-- row lock before it ever gets here.
+ALTER TABLE products
+    ADD CONSTRAINT products_stock_non_negative CHECK (stock >= 0) NOT VALID;
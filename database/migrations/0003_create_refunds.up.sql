@@ -0,0 +1,26 @@
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'completed';
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id             SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    type           TEXT NOT NULL,
+    reason         TEXT NOT NULL DEFAULT '',
+    total_amount   INTEGER NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_transaction_id ON refunds (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_created_at ON refunds (created_at);
+
+CREATE TABLE IF NOT EXISTS refund_details (
+    id                    SERIAL PRIMARY KEY,
+    refund_id             INTEGER NOT NULL REFERENCES refunds (id) ON DELETE CASCADE,
+    transaction_detail_id INTEGER NOT NULL REFERENCES transaction_details (id) ON DELETE CASCADE,
+    product_id            INTEGER NOT NULL REFERENCES products (id),
+    quantity              INTEGER NOT NULL CHECK (quantity > 0),
+    amount                INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refund_details_refund_id ON refund_details (refund_id);
+CREATE INDEX IF NOT EXISTS idx_refund_details_transaction_detail_id ON refund_details (transaction_detail_id);
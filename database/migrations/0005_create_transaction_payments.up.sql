@@ -0,0 +1,15 @@
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS paid_amount INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS change_amount INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS transaction_payments (
+    id             SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    method         TEXT NOT NULL,
+    amount         INTEGER NOT NULL CHECK (amount > 0),
+    reference      TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_payments_transaction_id ON transaction_payments (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_payments_method ON transaction_payments (method);
@@ -0,0 +1,11 @@
+-- Category names used to be free-form, so older databases can hold
+-- duplicates. The oldest keeps its name and the others get their id appended
+-- so the unique index can be built.
+UPDATE categories c
+SET name = c.name || ' (' || c.id || ')'
+WHERE EXISTS (
+    SELECT 1 FROM categories o
+    WHERE lower(o.name) = lower(c.name) AND o.id < c.id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (lower(name));
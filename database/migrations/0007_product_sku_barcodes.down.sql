@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS product_barcodes;
+
+DROP INDEX IF EXISTS products_sku_key;
+
+ALTER TABLE products DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,12 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS products_sku_key ON products (sku);
+
+-- A product can carry several codes (e.g. a multipack and its single unit
+-- from different suppliers), but a code belongs to one product only.
+CREATE TABLE IF NOT EXISTS product_barcodes (
+    barcode    TEXT PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_barcodes_product_id ON product_barcodes (product_id);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS inventory_movements (
+    id             SERIAL PRIMARY KEY,
+    product_id     INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    type           TEXT NOT NULL CHECK (type IN ('opening', 'sale', 'refund', 'void', 'restock', 'adjustment', 'damage', 'loss')),
+    quantity       INTEGER NOT NULL CHECK (quantity <> 0),
+    balance        INTEGER NOT NULL,
+    reason         TEXT NOT NULL DEFAULT '',
+    actor          TEXT NOT NULL DEFAULT '',
+    reference_type TEXT,
+    reference_id   INTEGER,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_movements_product_id ON inventory_movements (product_id, id);
+
+-- Start the ledger from the stock as it is, so balances add up from here on.
+INSERT INTO inventory_movements (product_id, type, quantity, balance, reason)
+SELECT id, 'opening', stock, stock, 'Stock before the ledger was introduced'
+FROM products
+WHERE stock <> 0;
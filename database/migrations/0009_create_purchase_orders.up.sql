@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS suppliers (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(100) NOT NULL,
+    contact_name VARCHAR(100) NOT NULL DEFAULT '',
+    phone        VARCHAR(30) NOT NULL DEFAULT '',
+    email        VARCHAR(100) NOT NULL DEFAULT '',
+    address      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS suppliers_name_key ON suppliers (lower(name));
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    id          SERIAL PRIMARY KEY,
+    supplier_id INTEGER NOT NULL CONSTRAINT purchase_orders_supplier_id_fkey REFERENCES suppliers (id),
+    status      TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'ordered', 'partially_received', 'received', 'cancelled')),
+    notes       TEXT NOT NULL DEFAULT '',
+    total_cost  INTEGER NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ordered_at  TIMESTAMPTZ,
+    received_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_supplier_id ON purchase_orders (supplier_id);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_status ON purchase_orders (status);
+
+CREATE TABLE IF NOT EXISTS purchase_order_items (
+    id                SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+    product_id        INTEGER NOT NULL CONSTRAINT purchase_order_items_product_id_fkey REFERENCES products (id),
+    quantity          INTEGER NOT NULL CHECK (quantity > 0),
+    received_quantity INTEGER NOT NULL DEFAULT 0 CHECK (received_quantity BETWEEN 0 AND quantity),
+    unit_cost         INTEGER NOT NULL CHECK (unit_cost >= 0),
+    UNIQUE (purchase_order_id, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_order_items_product_id ON purchase_order_items (product_id);
@@ -0,0 +1,5 @@
+ALTER TABLE transaction_details
+    DROP COLUMN IF EXISTS unit_cost,
+    DROP COLUMN IF EXISTS unit_price;
+
+ALTER TABLE products DROP COLUMN IF EXISTS cost_price;
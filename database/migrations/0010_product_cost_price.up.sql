@@ -0,0 +1,12 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS cost_price INTEGER NOT NULL DEFAULT 0 CONSTRAINT products_cost_price_non_negative CHECK (cost_price >= 0);
+
+-- Unit price and cost as they were at checkout. Older lines get their price
+-- back from the subtotal; their cost is unknown and stays 0.
+ALTER TABLE transaction_details
+    ADD COLUMN IF NOT EXISTS unit_price INTEGER,
+    ADD COLUMN IF NOT EXISTS unit_cost INTEGER NOT NULL DEFAULT 0;
+
+UPDATE transaction_details SET unit_price = coalesce(subtotal / NULLIF(quantity, 0), 0) WHERE unit_price IS NULL;
+
+ALTER TABLE transaction_details ALTER COLUMN unit_price SET NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_price_history;
+
+ALTER TABLE transaction_details DROP COLUMN IF EXISTS product_name;
@@ -0,0 +1,24 @@
+-- The product name as it was at checkout, so receipts and audits do not
+-- change when a product is renamed. Older lines take the current name.
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS product_name TEXT;
+
+UPDATE transaction_details td
+SET product_name = p.name
+FROM products p
+WHERE p.id = td.product_id AND td.product_name IS NULL;
+
+ALTER TABLE transaction_details ALTER COLUMN product_name SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS product_price_history (
+    id         SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    old_price  INTEGER,
+    new_price  INTEGER NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_history_product_id ON product_price_history (product_id, id);
+
+-- Start every product's history from the price it has now.
+INSERT INTO product_price_history (product_id, new_price)
+SELECT id, price FROM products;
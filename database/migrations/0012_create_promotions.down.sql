@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS transaction_promotions;
+
+ALTER TABLE transaction_details DROP COLUMN IF EXISTS discount;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS discount_amount,
+    DROP COLUMN IF EXISTS subtotal;
+
+DROP TABLE IF EXISTS promotion_bundle_items;
+DROP TABLE IF EXISTS promotion_categories;
+DROP TABLE IF EXISTS promotion_products;
+DROP TABLE IF EXISTS promotions;
@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS promotions (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(100) NOT NULL,
+    type         TEXT NOT NULL CHECK (type IN ('percent_off', 'amount_off', 'buy_x_get_y', 'bundle', 'cart_percent_off', 'cart_amount_off')),
+    value        INTEGER NOT NULL DEFAULT 0 CHECK (value >= 0),
+    buy_quantity INTEGER NOT NULL DEFAULT 0 CHECK (buy_quantity >= 0),
+    get_quantity INTEGER NOT NULL DEFAULT 0 CHECK (get_quantity >= 0),
+    bundle_price INTEGER NOT NULL DEFAULT 0 CHECK (bundle_price >= 0),
+    min_spend    INTEGER NOT NULL DEFAULT 0 CHECK (min_spend >= 0),
+    starts_at    TIMESTAMPTZ,
+    ends_at      TIMESTAMPTZ,
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR starts_at < ends_at)
+);
+
+-- A line promotion applies to the products listed here and to the products
+-- of the categories listed in promotion_categories; with neither, to every
+-- product.
+CREATE TABLE IF NOT EXISTS promotion_products (
+    promotion_id INTEGER NOT NULL REFERENCES promotions (id) ON DELETE CASCADE,
+    product_id   INTEGER NOT NULL CONSTRAINT promotion_products_product_id_fkey REFERENCES products (id) ON DELETE CASCADE,
+    PRIMARY KEY (promotion_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS promotion_categories (
+    promotion_id INTEGER NOT NULL REFERENCES promotions (id) ON DELETE CASCADE,
+    category_id  INTEGER NOT NULL CONSTRAINT promotion_categories_category_id_fkey REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (promotion_id, category_id)
+);
+
+-- Deleting a product that is part of a bundle would silently change what the
+-- bundle price buys, so it is refused instead.
+CREATE TABLE IF NOT EXISTS promotion_bundle_items (
+    promotion_id INTEGER NOT NULL REFERENCES promotions (id) ON DELETE CASCADE,
+    product_id   INTEGER NOT NULL CONSTRAINT promotion_bundle_items_product_id_fkey REFERENCES products (id),
+    quantity     INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (promotion_id, product_id)
+);
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS subtotal INTEGER,
+    ADD COLUMN IF NOT EXISTS discount_amount INTEGER NOT NULL DEFAULT 0;
+
+UPDATE transactions SET subtotal = total_amount WHERE subtotal IS NULL;
+
+ALTER TABLE transactions ALTER COLUMN subtotal SET NOT NULL;
+
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS discount INTEGER NOT NULL DEFAULT 0;
+
+-- The promotions applied at checkout. Name and type are copied so the record
+-- survives the promotion being edited or deleted.
+CREATE TABLE IF NOT EXISTS transaction_promotions (
+    id             SERIAL PRIMARY KEY,
+    transaction_id INTEGER NOT NULL REFERENCES transactions (id) ON DELETE CASCADE,
+    promotion_id   INTEGER REFERENCES promotions (id) ON DELETE SET NULL,
+    name           VARCHAR(100) NOT NULL,
+    type           TEXT NOT NULL,
+    amount         INTEGER NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_promotions_transaction_id ON transaction_promotions (transaction_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS voucher_redemptions;
+DROP TABLE IF EXISTS voucher_codes;
+DROP TABLE IF EXISTS voucher_categories;
+DROP TABLE IF EXISTS voucher_products;
+DROP TABLE IF EXISTS vouchers;
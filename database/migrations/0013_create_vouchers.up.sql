@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS vouchers (
+    id            SERIAL PRIMARY KEY,
+    name          VARCHAR(100) NOT NULL,
+    type          TEXT NOT NULL CHECK (type IN ('percent_off', 'amount_off')),
+    value         INTEGER NOT NULL CHECK (value > 0),
+    max_discount  INTEGER NOT NULL DEFAULT 0 CHECK (max_discount >= 0),
+    min_purchase  INTEGER NOT NULL DEFAULT 0 CHECK (min_purchase >= 0),
+    starts_at     TIMESTAMPTZ,
+    ends_at       TIMESTAMPTZ,
+    uses_per_code INTEGER NOT NULL DEFAULT 1 CHECK (uses_per_code > 0),
+    max_uses      INTEGER NOT NULL DEFAULT 0 CHECK (max_uses >= 0),
+    active        BOOLEAN NOT NULL DEFAULT TRUE,
+    redemptions   INTEGER NOT NULL DEFAULT 0 CHECK (redemptions >= 0),
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR starts_at < ends_at)
+);
+
+CREATE TABLE IF NOT EXISTS voucher_products (
+    voucher_id INTEGER NOT NULL REFERENCES vouchers (id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL CONSTRAINT voucher_products_product_id_fkey REFERENCES products (id) ON DELETE CASCADE,
+    PRIMARY KEY (voucher_id, product_id)
+);
+
+CREATE TABLE IF NOT EXISTS voucher_categories (
+    voucher_id  INTEGER NOT NULL REFERENCES vouchers (id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL CONSTRAINT voucher_categories_category_id_fkey REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (voucher_id, category_id)
+);
+
+-- Codes are stored upper case. Checkout locks the code row and then its
+-- voucher row, so the redemption counts can't be raised past their limits by
+-- concurrent checkouts.
+CREATE TABLE IF NOT EXISTS voucher_codes (
+    id          SERIAL PRIMARY KEY,
+    voucher_id  INTEGER NOT NULL REFERENCES vouchers (id) ON DELETE CASCADE,
+    code        VARCHAR(32) NOT NULL CONSTRAINT voucher_codes_code_key UNIQUE,
+    redemptions INTEGER NOT NULL DEFAULT 0 CHECK (redemptions >= 0),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_voucher_codes_voucher_id ON voucher_codes (voucher_id);
+
+-- A redeemed code can't be deleted, which keeps its voucher from being
+-- deleted too.
+CREATE TABLE IF NOT EXISTS voucher_redemptions (
+    id              SERIAL PRIMARY KEY,
+    transaction_id  INTEGER NOT NULL UNIQUE REFERENCES transactions (id) ON DELETE CASCADE,
+    voucher_id      INTEGER NOT NULL REFERENCES vouchers (id),
+    voucher_code_id INTEGER NOT NULL REFERENCES voucher_codes (id),
+    code            VARCHAR(32) NOT NULL,
+    name            VARCHAR(100) NOT NULL,
+    amount          INTEGER NOT NULL CHECK (amount > 0)
+);
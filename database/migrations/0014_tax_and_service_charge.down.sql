@@ -0,0 +1,18 @@
+ALTER TABLE refund_details
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS service_charge;
+
+ALTER TABLE transaction_details
+    DROP COLUMN IF EXISTS total,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS tax_rate_bp,
+    DROP COLUMN IF EXISTS service_charge;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS prices_include_tax,
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS service_charge;
+
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class_id;
+
+DROP TABLE IF EXISTS tax_classes;
@@ -0,0 +1,35 @@
+-- Rates are in basis points: 1100 is 11%. Products without a tax class are
+-- taxed at the configured default rate.
+CREATE TABLE IF NOT EXISTS tax_classes (
+    id      SERIAL PRIMARY KEY,
+    name    VARCHAR(50) NOT NULL,
+    rate_bp INTEGER NOT NULL CHECK (rate_bp >= 0 AND rate_bp <= 10000)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tax_classes_name_key ON tax_classes (lower(name));
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS tax_class_id INTEGER CONSTRAINT products_tax_class_id_fkey REFERENCES tax_classes (id);
+
+CREATE INDEX IF NOT EXISTS idx_products_tax_class_id ON products (tax_class_id);
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS service_charge INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_amount INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- total is what was charged for the line. Older lines carried neither tax
+-- nor service charge, so theirs is the subtotal.
+ALTER TABLE transaction_details
+    ADD COLUMN IF NOT EXISTS service_charge INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_rate_bp INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_amount INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS total INTEGER;
+
+UPDATE transaction_details SET total = subtotal WHERE total IS NULL;
+
+ALTER TABLE transaction_details ALTER COLUMN total SET NOT NULL;
+
+ALTER TABLE refund_details
+    ADD COLUMN IF NOT EXISTS service_charge INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_amount INTEGER NOT NULL DEFAULT 0;
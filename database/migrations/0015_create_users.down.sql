@@ -0,0 +1,5 @@
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS cashier,
+    DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS users;
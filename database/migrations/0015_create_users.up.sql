@@ -0,0 +1,20 @@
+-- Users are deactivated rather than deleted, so transactions keep pointing at
+-- the cashier who rang them up. Usernames are stored lower case.
+CREATE TABLE IF NOT EXISTS users (
+    id            SERIAL PRIMARY KEY,
+    username      VARCHAR(50) NOT NULL,
+    name          VARCHAR(100) NOT NULL,
+    role          TEXT NOT NULL CHECK (role IN ('cashier', 'supervisor', 'admin')),
+    active        BOOLEAN NOT NULL DEFAULT TRUE,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (username);
+
+-- cashier is the username at checkout. Older transactions have neither.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users (id),
+    ADD COLUMN IF NOT EXISTS cashier VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions (user_id);
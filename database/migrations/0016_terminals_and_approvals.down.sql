@@ -0,0 +1,20 @@
+ALTER TABLE inventory_movements DROP COLUMN IF EXISTS approved_by;
+
+ALTER TABLE refunds
+    DROP COLUMN IF EXISTS approved_by,
+    DROP COLUMN IF EXISTS approver_id,
+    DROP COLUMN IF EXISTS cashier,
+    DROP COLUMN IF EXISTS user_id;
+
+ALTER TABLE transaction_details DROP COLUMN IF EXISTS original_price;
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS approved_by,
+    DROP COLUMN IF EXISTS approver_id,
+    DROP COLUMN IF EXISTS terminal_id;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS pin_failures,
+    DROP COLUMN IF EXISTS pin_hash;
+
+DROP TABLE IF EXISTS terminals;
@@ -0,0 +1,36 @@
+-- Terminals are the registered tills. Only a hash of each terminal's key is
+-- kept; the key itself is shown once, when the terminal is registered.
+CREATE TABLE IF NOT EXISTS terminals (
+    id         SERIAL PRIMARY KEY,
+    code       VARCHAR(20) NOT NULL,
+    name       VARCHAR(100) NOT NULL,
+    active     BOOLEAN NOT NULL DEFAULT TRUE,
+    key_hash   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS terminals_code_key ON terminals (code);
+CREATE UNIQUE INDEX IF NOT EXISTS terminals_key_hash_key ON terminals (key_hash);
+
+-- pin_hash is empty for users without a PIN. pin_failures counts failed PIN
+-- attempts in a row; the PIN is locked once it reaches the limit.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS pin_hash TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS pin_failures INTEGER NOT NULL DEFAULT 0;
+
+-- approved_by is the username of the manager who approved a price override
+-- or a large discount, empty when none was needed.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS terminal_id INTEGER REFERENCES terminals (id),
+    ADD COLUMN IF NOT EXISTS approver_id INTEGER REFERENCES users (id),
+    ADD COLUMN IF NOT EXISTS approved_by VARCHAR(50) NOT NULL DEFAULT '';
+
+ALTER TABLE transaction_details ADD COLUMN IF NOT EXISTS original_price INTEGER;
+
+ALTER TABLE refunds
+    ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users (id),
+    ADD COLUMN IF NOT EXISTS cashier VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS approver_id INTEGER REFERENCES users (id),
+    ADD COLUMN IF NOT EXISTS approved_by VARCHAR(50) NOT NULL DEFAULT '';
+
+ALTER TABLE inventory_movements ADD COLUMN IF NOT EXISTS approved_by TEXT NOT NULL DEFAULT '';
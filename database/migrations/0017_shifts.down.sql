@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_refunds_shift_id;
+
+ALTER TABLE refunds DROP COLUMN IF EXISTS cash_amount;
+ALTER TABLE refunds DROP COLUMN IF EXISTS shift_id;
+
+DROP INDEX IF EXISTS idx_transactions_shift_id;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS shift_id;
+
+DROP TABLE IF EXISTS shift_cash_counts;
+DROP TABLE IF EXISTS shift_cash_movements;
+DROP TABLE IF EXISTS shifts;
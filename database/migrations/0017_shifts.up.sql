@@ -0,0 +1,56 @@
+-- A shift is a cashier's session at a terminal. The partial unique indexes
+-- allow one open shift per terminal and per cashier. The cash figures are
+-- computed while the shift is open and frozen when it is closed.
+CREATE TABLE IF NOT EXISTS shifts (
+    id                SERIAL PRIMARY KEY,
+    terminal_id       INTEGER NOT NULL REFERENCES terminals (id),
+    user_id           INTEGER REFERENCES users (id),
+    cashier           VARCHAR(50) NOT NULL DEFAULT '',
+    status            VARCHAR(10) NOT NULL DEFAULT 'open',
+    opening_float     INTEGER NOT NULL DEFAULT 0,
+    cash_sales        INTEGER,
+    cash_refunds      INTEGER,
+    transaction_count INTEGER,
+    expected_cash     INTEGER,
+    counted_cash      INTEGER,
+    variance          INTEGER,
+    note              TEXT NOT NULL DEFAULT '',
+    closed_by         VARCHAR(50) NOT NULL DEFAULT '',
+    opened_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at         TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shifts_open_terminal_key ON shifts (terminal_id) WHERE status = 'open';
+CREATE UNIQUE INDEX IF NOT EXISTS shifts_open_user_key ON shifts (user_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS shift_cash_movements (
+    id         SERIAL PRIMARY KEY,
+    shift_id   INTEGER NOT NULL REFERENCES shifts (id),
+    type       VARCHAR(10) NOT NULL,
+    amount     INTEGER NOT NULL,
+    reason     VARCHAR(255) NOT NULL DEFAULT '',
+    user_id    INTEGER REFERENCES users (id),
+    actor      VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_shift_cash_movements_shift_id ON shift_cash_movements (shift_id);
+
+CREATE TABLE IF NOT EXISTS shift_cash_counts (
+    shift_id     INTEGER NOT NULL REFERENCES shifts (id),
+    denomination INTEGER NOT NULL,
+    count        INTEGER NOT NULL,
+    PRIMARY KEY (shift_id, denomination)
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS shift_id INTEGER REFERENCES shifts (id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_shift_id ON transactions (shift_id);
+
+-- A void or refund is paid out of the drawer of the shift it is made on,
+-- which need not be the shift of the sale. cash_amount is the part of it
+-- given back in cash.
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS shift_id INTEGER REFERENCES shifts (id);
+ALTER TABLE refunds ADD COLUMN IF NOT EXISTS cash_amount INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_refunds_shift_id ON refunds (shift_id);
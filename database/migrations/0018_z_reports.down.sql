@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS z_report_payments;
+DROP TABLE IF EXISTS z_reports;
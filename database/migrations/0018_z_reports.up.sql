@@ -0,0 +1,41 @@
+-- A Z report is stored once per shift when it closes. number is assigned
+-- under a table lock as the next in sequence, so there are no gaps.
+CREATE TABLE IF NOT EXISTS z_reports (
+    number            INTEGER PRIMARY KEY,
+    shift_id          INTEGER NOT NULL REFERENCES shifts (id),
+    terminal_id       INTEGER NOT NULL REFERENCES terminals (id),
+    cashier           VARCHAR(50) NOT NULL DEFAULT '',
+    opened_at         TIMESTAMPTZ NOT NULL,
+    closed_at         TIMESTAMPTZ NOT NULL,
+    gross_sales       INTEGER NOT NULL,
+    discounts         INTEGER NOT NULL,
+    refunds           INTEGER NOT NULL,
+    refund_count      INTEGER NOT NULL,
+    voids             INTEGER NOT NULL,
+    void_count        INTEGER NOT NULL,
+    service_charge    INTEGER NOT NULL,
+    tax               INTEGER NOT NULL,
+    net_sales         INTEGER NOT NULL,
+    transaction_count INTEGER NOT NULL,
+    average_basket    INTEGER NOT NULL,
+    first_receipt     VARCHAR(20) NOT NULL DEFAULT '',
+    last_receipt      VARCHAR(20) NOT NULL DEFAULT '',
+    opening_float     INTEGER NOT NULL,
+    expected_cash     INTEGER NOT NULL,
+    counted_cash      INTEGER,
+    variance          INTEGER,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS z_reports_shift_id_key ON z_reports (shift_id);
+CREATE INDEX IF NOT EXISTS idx_z_reports_terminal_id ON z_reports (terminal_id);
+
+-- position keeps the payment methods in the order the report lists them.
+CREATE TABLE IF NOT EXISTS z_report_payments (
+    z_report_number INTEGER NOT NULL REFERENCES z_reports (number),
+    position        INTEGER NOT NULL,
+    method          VARCHAR(20) NOT NULL,
+    transactions    INTEGER NOT NULL,
+    amount          INTEGER NOT NULL,
+    PRIMARY KEY (z_report_number, method)
+);
@@ -0,0 +1,4 @@
+-- pin_locked_until ends the lockout of a PIN that reached the failure limit,
+-- so wrong PINs typed by someone else lock a user out for a while, not for
+-- good. It is NULL while the PIN is not locked.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pin_locked_until TIMESTAMPTZ;
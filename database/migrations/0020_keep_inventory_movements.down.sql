@@ -0,0 +1,3 @@
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_product_id_fkey;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+-- The stock ledger is the audit trail of a product's stock, so a product with
+-- movements can no longer be deleted, rather than taking its ledger with it.
+ALTER TABLE inventory_movements DROP CONSTRAINT IF EXISTS inventory_movements_product_id_fkey;
+ALTER TABLE inventory_movements ADD CONSTRAINT inventory_movements_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE RESTRICT;
@@ -0,0 +1,3 @@
+-- Deleting a product with stock movements archives it instead, keeping its
+-- stock ledger. Archived products are left out of the catalogue.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
@@ -3,8 +3,10 @@ module simple-cashier-api
 go 1.23.2
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/lib/pq v1.10.9
 	github.com/spf13/viper v1.21.0
+	golang.org/x/crypto v0.32.0
 )
 
 require (
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type actorKey struct{}
+
+// Headers a supervisor's approval is given in, see Authorize.
+const (
+	HeaderApprover    = "X-Approver"
+	HeaderApproverPIN = "X-Approver-PIN"
+)
+
+// Authenticate lets through only requests carrying a valid access token as
+// "Authorization: Bearer <token>", and puts the token's user and terminal in
+// the request context for the handlers behind it.
+func Authenticate(auth *services.AuthService, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			writeError(w, r, services.ErrInvalidToken)
+			return
+		}
+
+		actor, err := auth.Authenticate(token)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorKey{}, &actor)))
+	})
+}
+
+// MethodPermissions maps a request method to the permission it needs.
+type MethodPermissions map[string]string
+
+// ByMethod picks the permission by the request method alone.
+func ByMethod(permissions MethodPermissions) func(*http.Request) string {
+	return func(r *http.Request) string {
+		return permissions[r.Method]
+	}
+}
+
+// ByAction picks the permission for routes of the form prefix{id}/{action}
+// by the action ("" for the resource itself) and then the method.
+func ByAction(prefix string, permissions map[string]MethodPermissions) func(*http.Request) string {
+	return func(r *http.Request) string {
+		_, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		return permissions[action][r.Method]
+	}
+}
+
+// Authorize answers 403, naming the missing permission, unless the
+// authenticated user's role holds the one permissionFor picks or, for an
+// approvable permission, a supervisor approves the request with their
+// username and PIN in the X-Approver and X-Approver-PIN headers. The
+// approver is recorded on the actor in the context. An empty permission is
+// a request the route does not serve, such as an unsupported method, which
+// is left to next to reject.
+func Authorize(approvals *services.ApprovalService, permissionFor func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		permission := permissionFor(r)
+		if permission != "" {
+			actor := ActorFromContext(r.Context())
+			approver, err := approvals.Require(actor.User, permission, approvalFromRequest(r))
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+			if approver != nil {
+				actor.Approver = approver
+			}
+		}
+		next(w, r)
+	}
+}
+
+// approvalFromRequest returns the approval given in the request headers, or
+// nil when there is none.
+func approvalFromRequest(r *http.Request) *models.Approval {
+	username := r.Header.Get(HeaderApprover)
+	if username == "" {
+		return nil
+	}
+	return &models.Approval{Username: username, PIN: r.Header.Get(HeaderApproverPIN)}
+}
+
+// ActorFromContext returns who is making the request, as set by Authenticate
+// and Authorize. Handlers behind them can rely on it being set; elsewhere it
+// has an empty user.
+func ActorFromContext(ctx context.Context) *models.Actor {
+	if actor, ok := ctx.Value(actorKey{}).(*models.Actor); ok {
+		return actor
+	}
+	return &models.Actor{User: &models.User{}}
+}
+
+// UserFromContext returns the user set by Authenticate. Handlers behind it
+// can rely on it being set; elsewhere it is an empty user.
+func UserFromContext(ctx context.Context) *models.User {
+	return ActorFromContext(ctx).User
+}
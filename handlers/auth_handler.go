@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type AuthHandler struct {
+	service    *services.AuthService
+	authorizer *services.Authorizer
+}
+
+func NewAuthHandler(service *services.AuthService, authorizer *services.Authorizer) *AuthHandler {
+	return &AuthHandler{service: service, authorizer: authorizer}
+}
+
+func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.Login(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	tokens, err := h.service.Login(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) HandlePINLogin(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.PINLogin(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+// PINLogin signs a cashier in at a registered terminal with their PIN.
+func (h *AuthHandler) PINLogin(w http.ResponseWriter, r *http.Request) {
+	var req models.PINLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	tokens, err := h.service.PINLogin(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.Refresh(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	tokens, err := h.service.Refresh(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Me(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+// Me returns the user the request's access token belongs to, with what their
+// role may do and the terminal they signed in at.
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	actor := ActorFromContext(r.Context())
+	writeJSON(w, http.StatusOK, models.CurrentUser{
+		User:        *actor.User,
+		Permissions: h.authorizer.Permissions(actor.User.Role),
+		TerminalID:  actor.TerminalID,
+	})
+}
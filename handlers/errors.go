@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+// Error codes used in models.ErrorResponse.
+const (
+	CodeBadRequest        = "bad_request"
+	CodeNotFound          = "not_found"
+	CodeMethodNotAllowed  = "method_not_allowed"
+	CodeConflict          = "conflict"
+	CodeInsufficientStock = "insufficient_stock"
+	CodeValidation        = "validation_error"
+	CodeUnauthorized      = "unauthorized"
+	CodeForbidden         = "forbidden"
+	CodeInternal          = "internal_error"
+)
+
+// writeError maps a service error to a status code by its kind. Errors
+// without a kind are unexpected, so their text is logged but not returned.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var status int
+	var code string
+	switch {
+	case errors.Is(err, repositories.ErrInsufficientStock):
+		status, code = http.StatusConflict, CodeInsufficientStock
+	case errors.Is(err, repositories.ErrNotFound):
+		status, code = http.StatusNotFound, CodeNotFound
+	case errors.Is(err, repositories.ErrConflict):
+		status, code = http.StatusConflict, CodeConflict
+	case errors.Is(err, repositories.ErrValidation):
+		status, code = http.StatusUnprocessableEntity, CodeValidation
+	case errors.Is(err, repositories.ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		status, code = http.StatusUnauthorized, CodeUnauthorized
+	case errors.Is(err, repositories.ErrForbidden):
+		status, code = http.StatusForbidden, CodeForbidden
+	default:
+		log.Printf("request %s: %s %s: %v", RequestIDFromContext(r.Context()), r.Method, r.URL.Path, err)
+		writeErrorResponse(w, r, http.StatusInternalServerError, models.ErrorResponse{
+			Code:    CodeInternal,
+			Message: "internal server error",
+		})
+		return
+	}
+
+	resp := models.ErrorResponse{Code: code, Message: err.Error()}
+	var detailed interface{ Details() any }
+	if errors.As(err, &detailed) {
+		resp.Details = detailed.Details()
+	}
+	writeErrorResponse(w, r, status, resp)
+}
+
+// writeBadRequest is for requests that cannot be parsed at all, such as a
+// malformed body or a non-numeric ID.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, message string) {
+	writeErrorResponse(w, r, http.StatusBadRequest, models.ErrorResponse{Code: CodeBadRequest, Message: message})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeErrorResponse(w, r, http.StatusMethodNotAllowed, models.ErrorResponse{Code: CodeMethodNotAllowed, Message: "method not allowed"})
+}
+
+// NotFound answers requests for unknown routes.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeErrorResponse(w, r, http.StatusNotFound, models.ErrorResponse{Code: CodeNotFound, Message: "resource not found"})
+}
+
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, status int, resp models.ErrorResponse) {
+	resp.RequestID = RequestIDFromContext(r.Context())
+	writeJSON(w, status, resp)
+}
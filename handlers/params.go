@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+// parseDateParam parses an optional YYYY-MM-DD query parameter, returning nil
+// when it is empty.
+func parseDateParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// parseIntParam parses an optional integer query parameter, returning nil when
+// it is empty.
+func parseIntParam(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// parsePositiveIntParam parses an optional positive integer query parameter,
+// returning 0 when it is empty so the service default applies.
+func parsePositiveIntParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if parsed < 1 {
+		return 0, errors.New("must be greater than zero")
+	}
+	return parsed, nil
+}
+
+// parseBoolParam parses an optional true/false query parameter, returning nil
+// when it is empty.
+func parseBoolParam(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// parseSortParam splits a sort parameter such as "price,-name" into fields,
+// a leading "-" meaning descending. Field names are checked by the services.
+func parseSortParam(value string) []models.SortField {
+	if value == "" {
+		return nil
+	}
+
+	fields := make([]models.SortField, 0)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		fields = append(fields, models.SortField{Field: strings.TrimPrefix(part, "-"), Desc: desc})
+	}
+	return fields
+}
+
+// parsePrintParams reads the format and width query parameters of a printout,
+// writing a 400 response and returning false when one is invalid. Both are
+// empty when not given.
+func parsePrintParams(w http.ResponseWriter, r *http.Request) (format string, width int, ok bool) {
+	query := r.URL.Query()
+
+	format = query.Get("format")
+	if format != "" && !slices.Contains(models.ReceiptFormats, format) {
+		writeBadRequest(w, r, "Invalid format, expected one of "+strings.Join(models.ReceiptFormats, ", "))
+		return "", 0, false
+	}
+
+	if value := query.Get("width"); value != "" {
+		if width, ok = services.ReceiptWidths[value]; !ok {
+			writeBadRequest(w, r, "Invalid width, expected 32, 48, 58mm or 80mm")
+			return "", 0, false
+		}
+	}
+	return format, width, true
+}
+
+// parsePageParams reads the page and limit query parameters shared by every
+// listing, writing a 400 response and returning false when one is invalid.
+func parsePageParams(w http.ResponseWriter, r *http.Request) (page, limit int, ok bool) {
+	var err error
+	if page, err = parsePositiveIntParam(r.URL.Query().Get("page")); err != nil {
+		writeBadRequest(w, r, "Invalid page")
+		return 0, 0, false
+	}
+	if limit, err = parsePositiveIntParam(r.URL.Query().Get("limit")); err != nil {
+		writeBadRequest(w, r, "Invalid limit")
+		return 0, 0, false
+	}
+	return page, limit, true
+}
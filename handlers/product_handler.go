@@ -11,11 +11,13 @@ import (
 )
 
 type ProductHandler struct {
-	service *services.ProductService
+	service   *services.ProductService
+	inventory *services.InventoryService
+	approvals *services.ApprovalService
 }
 
-func NewProductHandler(service *services.ProductService) *ProductHandler {
-	return &ProductHandler{service: service}
+func NewProductHandler(service *services.ProductService, inventory *services.InventoryService, approvals *services.ApprovalService) *ProductHandler {
+	return &ProductHandler{service: service, inventory: inventory, approvals: approvals}
 }
 
 func (h *ProductHandler) HandleProducts(w http.ResponseWriter, r *http.Request) {
@@ -25,34 +27,72 @@ func (h *ProductHandler) HandleProducts(w http.ResponseWriter, r *http.Request)
 	case http.MethodPost:
 		h.Create(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 	}
 }
 
 func (h *ProductHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Query().Get("name")
+	query := r.URL.Query()
+	filter := models.ProductFilter{
+		Name: query.Get("name"),
+		Sort: parseSortParam(query.Get("sort")),
+	}
+
+	var err error
+	intParams := []struct {
+		name string
+		dest **int
+	}{
+		{"category_id", &filter.CategoryID},
+		{"min_price", &filter.MinPrice},
+		{"max_price", &filter.MaxPrice},
+	}
+	for _, p := range intParams {
+		if *p.dest, err = parseIntParam(query.Get(p.name)); err != nil {
+			writeBadRequest(w, r, "Invalid "+p.name)
+			return
+		}
+	}
+
+	boolParams := []struct {
+		name string
+		dest **bool
+	}{
+		{"in_stock", &filter.InStock},
+		{"low_stock", &filter.LowStock},
+	}
+	for _, p := range boolParams {
+		if *p.dest, err = parseBoolParam(query.Get(p.name)); err != nil {
+			writeBadRequest(w, r, "Invalid "+p.name)
+			return
+		}
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
 
-	products, err := h.service.GetAll(name)
+	products, err := h.service.GetAll(filter)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(products)
+	writePage(w, r, products)
 }
 
 func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var product models.Product
 	err := json.NewDecoder(r.Body).Decode(&product)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeBadRequest(w, r, "Invalid request body")
 		return
 	}
 
 	err = h.service.Create(&product)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
@@ -61,7 +101,34 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
+func (h *ProductHandler) HandleLookup(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Lookup(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+// Lookup serves barcode scanners: one indexed query by barcode or SKU.
+func (h *ProductHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	product, err := h.service.Lookup(query.Get("barcode"), query.Get("sku"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, product)
+}
+
 func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Request) {
+	if idStr, action, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/products/"), "/"); ok {
+		h.handleSubresource(w, r, idStr, action)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		h.GetByID(w, r)
@@ -70,7 +137,7 @@ func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Reques
 	case http.MethodDelete:
 		h.Delete(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 	}
 }
 
@@ -78,13 +145,13 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/products/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "Invalid product ID")
 		return
 	}
 
 	product, err := h.service.GetByID(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -92,43 +159,65 @@ func (h *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
+// Update saves a product. Changing its stock is a stock adjustment, so it
+// also needs inventory:adjust, which a supervisor can approve in the same
+// headers Authorize reads.
 func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/products/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "Invalid product ID")
 		return
 	}
 
-	var product models.Product
-	err = json.NewDecoder(r.Body).Decode(&product)
+	var update models.ProductUpdate
+	err = json.NewDecoder(r.Body).Decode(&update)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeBadRequest(w, r, "Invalid request body")
 		return
 	}
 
-	product.ID = id
-	err = h.service.Update(&product)
+	update.ID = id
+	changes, err := h.service.ChangesStock(&update)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
+		return
+	}
+	actor := ActorFromContext(r.Context())
+	if changes {
+		approver, err := h.approvals.Require(actor.User, models.PermInventoryAdjust, approvalFromRequest(r))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if approver != nil {
+			actor.Approver = approver
+		}
+	}
+	update.Actor = actor.Username()
+	update.ApprovedBy = actor.ApprovedBy()
+
+	err = h.service.Update(&update)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(product)
+	json.NewEncoder(w).Encode(update.Product)
 }
 
 func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/products/")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		writeBadRequest(w, r, "Invalid product ID")
 		return
 	}
 
 	err = h.service.Delete(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -137,3 +226,79 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		"message": "Product deleted successfully",
 	})
 }
+
+// handleSubresource serves /api/products/{id}/stock-adjustments,
+// /api/products/{id}/stock-history and /api/products/{id}/price-history.
+func (h *ProductHandler) handleSubresource(w http.ResponseWriter, r *http.Request, idStr, action string) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid product ID")
+		return
+	}
+
+	switch {
+	case action == "stock-adjustments" && r.Method == http.MethodPost:
+		h.AdjustStock(w, r, id)
+	case action == "stock-history" && r.Method == http.MethodGet:
+		h.GetStockHistory(w, r, id)
+	case action == "price-history" && r.Method == http.MethodGet:
+		h.GetPriceHistory(w, r, id)
+	case action != "stock-adjustments" && action != "stock-history" && action != "price-history":
+		NotFound(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *ProductHandler) AdjustStock(w http.ResponseWriter, r *http.Request, id int) {
+	var adj models.StockAdjustment
+	if err := json.NewDecoder(r.Body).Decode(&adj); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+	actor := ActorFromContext(r.Context())
+	adj.Actor = actor.Username()
+	adj.ApprovedBy = actor.ApprovedBy()
+
+	movement, err := h.inventory.AdjustStock(id, adj)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, movement)
+}
+
+func (h *ProductHandler) GetStockHistory(w http.ResponseWriter, r *http.Request, id int) {
+	filter := models.StockHistoryFilter{ProductID: id}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	history, err := h.inventory.GetStockHistory(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, history)
+}
+
+func (h *ProductHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request, id int) {
+	filter := models.PriceHistoryFilter{ProductID: id}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	history, err := h.service.GetPriceHistory(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, history)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type PromotionHandler struct {
+	service *services.PromotionService
+}
+
+func NewPromotionHandler(service *services.PromotionService) *PromotionHandler {
+	return &PromotionHandler{service: service}
+}
+
+func (h *PromotionHandler) HandlePromotions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *PromotionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	var filter models.PromotionFilter
+
+	var err error
+	if filter.Active, err = parseBoolParam(r.URL.Query().Get("active")); err != nil {
+		writeBadRequest(w, r, "Invalid active")
+		return
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	promotions, err := h.service.GetAll(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, promotions)
+}
+
+// Create stores a promotion. It is active unless the body says otherwise.
+func (h *PromotionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	promotion := models.Promotion{Active: true}
+	err := json.NewDecoder(r.Body).Decode(&promotion)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	err = h.service.Create(&promotion)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promotion)
+}
+
+func (h *PromotionHandler) HandlePromotionByID(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetByID(w, r)
+	case http.MethodPut:
+		h.Update(w, r)
+	case http.MethodDelete:
+		h.Delete(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *PromotionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/promotions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid promotion ID")
+		return
+	}
+
+	promotion, err := h.service.GetByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+func (h *PromotionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/promotions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid promotion ID")
+		return
+	}
+
+	promotion := models.Promotion{Active: true}
+	err = json.NewDecoder(r.Body).Decode(&promotion)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	promotion.ID = id
+	err = h.service.Update(&promotion)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(promotion)
+}
+
+func (h *PromotionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/promotions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid promotion ID")
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Promotion deleted successfully",
+	})
+}
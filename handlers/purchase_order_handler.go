@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type PurchaseOrderHandler struct {
+	service *services.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(service *services.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{service: service}
+}
+
+func (h *PurchaseOrderHandler) HandlePurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *PurchaseOrderHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.PurchaseOrderFilter{Status: query.Get("status")}
+
+	var err error
+	if filter.SupplierID, err = parseIntParam(query.Get("supplier_id")); err != nil {
+		writeBadRequest(w, r, "Invalid supplier_id")
+		return
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	orders, err := h.service.GetAll(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, orders)
+}
+
+func (h *PurchaseOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	po, err := h.service.Create(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, po)
+}
+
+func (h *PurchaseOrderHandler) HandlePurchaseOrderByID(w http.ResponseWriter, r *http.Request) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/purchase-orders/"), "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid purchase order ID")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "" && r.Method == http.MethodPut:
+		h.Update(w, r, id)
+	case action == "order" && r.Method == http.MethodPost:
+		h.transition(w, r, id, h.service.Order)
+	case action == "cancel" && r.Method == http.MethodPost:
+		h.transition(w, r, id, h.service.Cancel)
+	case action == "receive" && r.Method == http.MethodPost:
+		h.Receive(w, r, id)
+	case action != "" && action != "order" && action != "cancel" && action != "receive":
+		NotFound(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *PurchaseOrderHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	po, err := h.service.GetByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, po)
+}
+
+func (h *PurchaseOrderHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	po, err := h.service.Update(id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, po)
+}
+
+// transition serves the bodiless status changes, order and cancel.
+func (h *PurchaseOrderHandler) transition(w http.ResponseWriter, r *http.Request, id int, change func(int) (*models.PurchaseOrder, error)) {
+	po, err := change(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, po)
+}
+
+func (h *PurchaseOrderHandler) Receive(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.ReceiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+	req.Actor = UserFromContext(r.Context()).Username
+
+	po, err := h.service.Receive(id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, po)
+}
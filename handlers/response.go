@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"simple-cashier-api/models"
+)
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// Links carry query strings, which should not come out as \u0026.
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(body)
+}
+
+// writePage writes a listing with links to the current, next and previous
+// pages. The links keep every other query parameter of the request.
+func writePage[T any](w http.ResponseWriter, r *http.Request, page *models.Page[T]) {
+	link := func(n int) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(n))
+		query.Set("limit", strconv.Itoa(page.Pagination.Limit))
+		return r.URL.Path + "?" + query.Encode()
+	}
+
+	p := page.Pagination
+	page.Links = &models.PageLinks{Self: link(p.Page)}
+	if p.Page < p.TotalPages {
+		page.Links.Next = link(p.Page + 1)
+	}
+	if p.Page > 1 {
+		page.Links.Prev = link(min(p.Page-1, max(p.TotalPages, 1)))
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type ShiftHandler struct {
+	service *services.ShiftService
+	reports *services.ShiftReportService
+}
+
+func NewShiftHandler(service *services.ShiftService, reports *services.ShiftReportService) *ShiftHandler {
+	return &ShiftHandler{service: service, reports: reports}
+}
+
+func (h *ShiftHandler) HandleShifts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Open(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *ShiftHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.ShiftFilter{Status: query.Get("status")}
+	if filter.Status != "" && !slices.Contains(models.ShiftStatuses, filter.Status) {
+		writeBadRequest(w, r, "Invalid status, expected one of "+strings.Join(models.ShiftStatuses, ", "))
+		return
+	}
+
+	var err error
+	if filter.TerminalID, err = parseIntParam(query.Get("terminal_id")); err != nil {
+		writeBadRequest(w, r, "Invalid terminal_id")
+		return
+	}
+	if filter.UserID, err = parseIntParam(query.Get("user_id")); err != nil {
+		writeBadRequest(w, r, "Invalid user_id")
+		return
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	shifts, err := h.service.GetAll(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, shifts)
+}
+
+// Open starts a shift at the terminal the user signed in at.
+func (h *ShiftHandler) Open(w http.ResponseWriter, r *http.Request) {
+	var req models.OpenShiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	shift, err := h.service.Open(req, *ActorFromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, shift)
+}
+
+func (h *ShiftHandler) HandleCurrent(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Current(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+// Current returns the open shift of the user's terminal.
+func (h *ShiftHandler) Current(w http.ResponseWriter, r *http.Request) {
+	shift, err := h.service.Current(*ActorFromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, shift)
+}
+
+func (h *ShiftHandler) HandleShiftByID(w http.ResponseWriter, r *http.Request) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/shifts/"), "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid shift ID")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "cash-movements" && r.Method == http.MethodPost:
+		h.AddCashMovement(w, r, id)
+	case action == "close" && r.Method == http.MethodPost:
+		h.Close(w, r, id)
+	case action == "report" && r.Method == http.MethodGet:
+		h.Report(w, r, id)
+	case action != "" && action != "cash-movements" && action != "close" && action != "report":
+		NotFound(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *ShiftHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	shift, err := h.service.GetByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, shift)
+}
+
+func (h *ShiftHandler) AddCashMovement(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.CashMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	movement, err := h.service.AddCashMovement(id, req, *ActorFromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, movement)
+}
+
+// Close counts the drawer and ends the shift.
+func (h *ShiftHandler) Close(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.CloseShiftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	shift, err := h.service.Close(id, req, *ActorFromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, shift)
+}
+
+// Report returns the X report of an open shift or the Z report of a closed
+// one.
+func (h *ShiftHandler) Report(w http.ResponseWriter, r *http.Request, id int) {
+	format, width, ok := parsePrintParams(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.reports.ForShift(id, *ActorFromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeReport(w, r, report, format, width)
+}
+
+func (h *ShiftHandler) HandleZReports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetZReports(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *ShiftHandler) GetZReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var filter models.ZReportFilter
+	var err error
+	if filter.TerminalID, err = parseIntParam(query.Get("terminal_id")); err != nil {
+		writeBadRequest(w, r, "Invalid terminal_id")
+		return
+	}
+	if filter.StartDate, err = parseDateParam(query.Get("start_date")); err != nil {
+		writeBadRequest(w, r, "Invalid start_date")
+		return
+	}
+	if filter.EndDate, err = parseDateParam(query.Get("end_date")); err != nil {
+		writeBadRequest(w, r, "Invalid end_date")
+		return
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	reports, err := h.reports.GetZReports(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, reports)
+}
+
+func (h *ShiftHandler) HandleZReportByNumber(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/z-reports/"))
+	if err != nil {
+		writeBadRequest(w, r, "Invalid Z report number")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetZReport(w, r, number)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+// GetZReport reprints a stored Z report.
+func (h *ShiftHandler) GetZReport(w http.ResponseWriter, r *http.Request, number int) {
+	format, width, ok := parsePrintParams(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.reports.GetZReport(number)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeReport(w, r, report, format, width)
+}
+
+// writeReport writes report as JSON, or printed in format when one is given.
+func (h *ShiftHandler) writeReport(w http.ResponseWriter, r *http.Request, report *models.ShiftReport, format string, width int) {
+	if format == "" {
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+
+	printout, err := h.reports.Render(report, format, width)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", printout.ContentType)
+	if format == models.ReceiptFormatESCPOS {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+services.ShiftReportFilename(report)+`.bin"`)
+	}
+	w.Write(printout.Body)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type TaxClassHandler struct {
+	service *services.TaxClassService
+}
+
+func NewTaxClassHandler(service *services.TaxClassService) *TaxClassHandler {
+	return &TaxClassHandler{service: service}
+}
+
+func (h *TaxClassHandler) HandleTaxClasses(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *TaxClassHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	var filter models.TaxClassFilter
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	classes, err := h.service.GetAll(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, classes)
+}
+
+func (h *TaxClassHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var class models.TaxClass
+	if err := json.NewDecoder(r.Body).Decode(&class); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	if err := h.service.Create(&class); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, class)
+}
+
+func (h *TaxClassHandler) HandleTaxClassByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/tax-classes/"))
+	if err != nil {
+		writeBadRequest(w, r, "Invalid tax class ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetByID(w, r, id)
+	case http.MethodPut:
+		h.Update(w, r, id)
+	case http.MethodDelete:
+		h.Delete(w, r, id)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *TaxClassHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	class, err := h.service.GetByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, class)
+}
+
+func (h *TaxClassHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	var class models.TaxClass
+	if err := json.NewDecoder(r.Body).Decode(&class); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	class.ID = id
+	if err := h.service.Update(&class); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, class)
+}
+
+func (h *TaxClassHandler) Delete(w http.ResponseWriter, r *http.Request, id int) {
+	if err := h.service.Delete(id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message": "Tax class deleted successfully",
+	})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type TerminalHandler struct {
+	service *services.TerminalService
+}
+
+func NewTerminalHandler(service *services.TerminalService) *TerminalHandler {
+	return &TerminalHandler{service: service}
+}
+
+func (h *TerminalHandler) HandleTerminals(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *TerminalHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	filter := models.TerminalFilter{}
+
+	var err error
+	if filter.Active, err = parseBoolParam(r.URL.Query().Get("active")); err != nil {
+		writeBadRequest(w, r, "Invalid active")
+		return
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	terminals, err := h.service.GetAll(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, terminals)
+}
+
+// Create registers a terminal, active unless the body says otherwise. The
+// response carries the terminal's key, which is not shown again.
+func (h *TerminalHandler) Create(w http.ResponseWriter, r *http.Request) {
+	terminal := models.Terminal{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&terminal); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	if err := h.service.Create(&terminal); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, terminal)
+}
+
+func (h *TerminalHandler) HandleTerminalByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/terminals/"))
+	if err != nil {
+		writeBadRequest(w, r, "Invalid terminal ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetByID(w, r, id)
+	case http.MethodPut:
+		h.Update(w, r, id)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *TerminalHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	terminal, err := h.service.GetByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, terminal)
+}
+
+// Update replaces the terminal's code, name and active flag. Terminals are
+// retired with "active": false, which also signs out everyone using them.
+func (h *TerminalHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	terminal := models.Terminal{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&terminal); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	terminal.ID = id
+	if err := h.service.Update(&terminal); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, terminal)
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"simple-cashier-api/models"
@@ -10,11 +14,15 @@ import (
 )
 
 type TransactionHandler struct {
-	service *services.TransactionService
+	service     *services.TransactionService
+	idempotency *services.IdempotencyService
+	receipts    *services.ReceiptService
+	approvals   *services.ApprovalService
 }
 
-func NewTransactionHandler(service *services.TransactionService) *TransactionHandler {
-	return &TransactionHandler{service: service}
+func NewTransactionHandler(service *services.TransactionService, idempotency *services.IdempotencyService, receipts *services.ReceiptService,
+	approvals *services.ApprovalService) *TransactionHandler {
+	return &TransactionHandler{service: service, idempotency: idempotency, receipts: receipts, approvals: approvals}
 }
 
 func (h *TransactionHandler) HandleCheckout(w http.ResponseWriter, r *http.Request) {
@@ -22,21 +30,200 @@ func (h *TransactionHandler) HandleCheckout(w http.ResponseWriter, r *http.Reque
 	case http.MethodPost:
 		h.Checkout(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 	}
 }
 
+// Checkout rings up the cart. Price overrides and large manual discounts
+// need their own permissions, which a supervisor can approve in the same
+// headers Authorize reads.
 func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	var req models.CheckoutRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeBadRequest(w, r, "Invalid request body")
 		return
 	}
 
-	transaction, err := h.service.Checkout(req.Items)
+	actor := *ActorFromContext(r.Context())
+	key := r.Header.Get("Idempotency-Key")
+	var claim *models.IdempotencyClaim
+	if key != "" {
+		fingerprint, err := services.Fingerprint(req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		stored, err := h.idempotency.Begin(actor.User.ID, key, fingerprint)
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyInvalid):
+			writeBadRequest(w, r, err.Error())
+			return
+		case err != nil:
+			writeError(w, r, err)
+			return
+		}
+
+		if stored != nil {
+			h.replay(w, r, actor.User.ID, key, stored)
+			return
+		}
+		claim = h.idempotency.Claim(actor.User.ID, key)
+	}
+
+	// The approval is checked after a replay is ruled out, so a retried
+	// checkout does not count a wrong PIN against the approver again.
+	approver, err := h.approvals.RequireAll(actor.User, h.service.CheckoutPermissions(req), approvalFromRequest(r))
+	if err != nil {
+		if key != "" {
+			h.idempotency.Release(actor.User.ID, key)
+		}
+		writeError(w, r, err)
+		return
+	}
+	if approver != nil {
+		actor.Approver = approver
+	}
+
+	transaction, err := h.service.Checkout(req, actor, claim)
+	if err != nil {
+		if key != "" {
+			// Failed checkouts change nothing, so the key is freed for a retry.
+			h.idempotency.Release(actor.User.ID, key)
+		}
+		writeError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(transaction)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	body = append(body, '\n')
+
+	if key != "" {
+		// The key already holds the committed checkout, so a retry replays
+		// it even when the response cannot be stored.
+		if err := h.idempotency.Complete(actor.User.ID, key, transaction.ID, http.StatusOK, body); err != nil {
+			log.Printf("Failed to store idempotency key %q: %v", key, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// replay answers a retried checkout with the stored response, or rebuilds it
+// from the transaction the key holds when it was never stored.
+func (h *TransactionHandler) replay(w http.ResponseWriter, r *http.Request, userID int, key string, stored *models.IdempotencyKey) {
+	status, body := stored.StatusCode, stored.ResponseBody
+	if !stored.Completed() {
+		transaction, err := h.service.GetTransactionByID(*stored.TransactionID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		body, err = json.Marshal(transaction)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		status, body = http.StatusOK, append(body, '\n')
+		if err := h.idempotency.Complete(userID, key, transaction.ID, status, body); err != nil {
+			log.Printf("Failed to store idempotency key %q: %v", key, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func (h *TransactionHandler) HandleTransactions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *TransactionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.TransactionFilter{}
+
+	var err error
+	if filter.StartDate, err = parseDateParam(query.Get("start_date")); err != nil {
+		writeBadRequest(w, r, "Invalid start_date")
+		return
+	}
+	if filter.EndDate, err = parseDateParam(query.Get("end_date")); err != nil {
+		writeBadRequest(w, r, "Invalid end_date")
+		return
+	}
+
+	intParams := []struct {
+		name string
+		dest **int
+	}{
+		{"min_total", &filter.MinTotal},
+		{"max_total", &filter.MaxTotal},
+		{"product_id", &filter.ProductID},
+		{"user_id", &filter.UserID},
+		{"shift_id", &filter.ShiftID},
+	}
+	for _, p := range intParams {
+		if *p.dest, err = parseIntParam(query.Get(p.name)); err != nil {
+			writeBadRequest(w, r, "Invalid "+p.name)
+			return
+		}
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	transactions, err := h.service.GetTransactions(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, transactions)
+}
+
+func (h *TransactionHandler) HandleTransactionByID(w http.ResponseWriter, r *http.Request) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/transactions/"), "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid transaction ID")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "void" && r.Method == http.MethodPost:
+		h.Void(w, r, id)
+	case action == "refunds" && r.Method == http.MethodPost:
+		h.Refund(w, r, id)
+	case action == "receipt" && r.Method == http.MethodGet:
+		h.Receipt(w, r, id)
+	case action != "" && action != "void" && action != "refunds" && action != "receipt":
+		NotFound(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *TransactionHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	transaction, err := h.service.GetTransactionByID(id)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -44,12 +231,46 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(transaction)
 }
 
+func (h *TransactionHandler) Void(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.VoidRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeBadRequest(w, r, "Invalid request body")
+			return
+		}
+	}
+
+	refund, err := h.service.VoidTransaction(id, req.Reason, *ActorFromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, refund)
+}
+
+func (h *TransactionHandler) Refund(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	refund, err := h.service.RefundTransaction(id, req, *ActorFromContext(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, refund)
+}
+
 func (h *TransactionHandler) HandleGetTodaysReport(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		h.GetTodaysReport(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 	}
 }
 
@@ -58,7 +279,7 @@ func (h *TransactionHandler) GetTodaysReport(w http.ResponseWriter, r *http.Requ
 
 	report, err := h.service.GetTransactionReport(&timeNow, &timeNow)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -71,7 +292,7 @@ func (h *TransactionHandler) HandleGetRangeDateTransactionReport(w http.Response
 	case http.MethodGet:
 		h.GetRangeDateTransactionReport(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r)
 	}
 }
 
@@ -79,30 +300,47 @@ func (h *TransactionHandler) GetRangeDateTransactionReport(w http.ResponseWriter
 	startDateParam := r.URL.Query().Get("start_date")
 	endDateParam := r.URL.Query().Get("end_date")
 
-	var startDate, endDate *time.Time
-	if startDateParam != "" {
-		parsed, err := time.Parse("2006-01-02", startDateParam)
-		if err != nil {
-			http.Error(w, "Invalid start_date", http.StatusBadRequest)
-			return
-		}
-		startDate = &parsed
+	startDate, err := parseDateParam(startDateParam)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid start_date")
+		return
 	}
-	if endDateParam != "" {
-		parsed, err := time.Parse("2006-01-02", endDateParam)
-		if err != nil {
-			http.Error(w, "Invalid end_date", http.StatusBadRequest)
-			return
-		}
-		endDate = &parsed
+	endDate, err := parseDateParam(endDateParam)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid end_date")
+		return
 	}
 
 	report, err := h.service.GetTransactionReport(startDate, endDate)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(report)
 }
+
+// Receipt renders the stored transaction as text (the default), HTML or
+// ESC/POS bytes that a thermal printer can print as they are.
+func (h *TransactionHandler) Receipt(w http.ResponseWriter, r *http.Request, id int) {
+	format, width, ok := parsePrintParams(w, r)
+	if !ok {
+		return
+	}
+	if format == "" {
+		format = models.ReceiptFormatText
+	}
+
+	receipt, err := h.receipts.Render(id, format, width)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", receipt.ContentType)
+	if format == models.ReceiptFormatESCPOS {
+		w.Header().Set("Content-Disposition", `attachment; filename="receipt-`+services.ReceiptNumber(id)+`.bin"`)
+	}
+	w.Write(receipt.Body)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type UserHandler struct {
+	service *services.UserService
+}
+
+func NewUserHandler(service *services.UserService) *UserHandler {
+	return &UserHandler{service: service}
+}
+
+func (h *UserHandler) HandleUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *UserHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.UserFilter{Role: query.Get("role")}
+	if filter.Role != "" && !slices.Contains(models.Roles, filter.Role) {
+		writeBadRequest(w, r, "Invalid role, expected one of "+strings.Join(models.Roles, ", "))
+		return
+	}
+
+	var err error
+	if filter.Active, err = parseBoolParam(query.Get("active")); err != nil {
+		writeBadRequest(w, r, "Invalid active")
+		return
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	users, err := h.service.GetAll(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, users)
+}
+
+// Create adds a user, active unless the body says otherwise.
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user := models.User{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	if err := h.service.Create(&user); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func (h *UserHandler) HandleUserByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/users/"))
+	if err != nil {
+		writeBadRequest(w, r, "Invalid user ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.GetByID(w, r, id)
+	case http.MethodPut:
+		h.Update(w, r, id)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	user, err := h.service.GetByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// Update replaces the user's details. Leaving out password keeps the current
+// one, and users are deactivated with "active": false rather than deleted.
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	user := models.User{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	user.ID = id
+	if err := h.service.Update(&user); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, user)
+}
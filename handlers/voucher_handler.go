@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+type VoucherHandler struct {
+	service *services.VoucherService
+}
+
+func NewVoucherHandler(service *services.VoucherService) *VoucherHandler {
+	return &VoucherHandler{service: service}
+}
+
+func (h *VoucherHandler) HandleVouchers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.GetAll(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *VoucherHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	var filter models.VoucherFilter
+
+	var err error
+	if filter.Active, err = parseBoolParam(r.URL.Query().Get("active")); err != nil {
+		writeBadRequest(w, r, "Invalid active")
+		return
+	}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	vouchers, err := h.service.GetAll(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, vouchers)
+}
+
+// Create stores a voucher without codes. It is active and each code can be
+// used once unless the body says otherwise.
+func (h *VoucherHandler) Create(w http.ResponseWriter, r *http.Request) {
+	voucher := models.Voucher{Active: true, UsesPerCode: 1}
+	if err := json.NewDecoder(r.Body).Decode(&voucher); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	if err := h.service.Create(&voucher); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, voucher)
+}
+
+func (h *VoucherHandler) HandleVoucherByID(w http.ResponseWriter, r *http.Request) {
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/vouchers/"), "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeBadRequest(w, r, "Invalid voucher ID")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetByID(w, r, id)
+	case action == "" && r.Method == http.MethodPut:
+		h.Update(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		h.Delete(w, r, id)
+	case action == "codes" && r.Method == http.MethodGet:
+		h.GetCodes(w, r, id)
+	case action == "codes" && r.Method == http.MethodPost:
+		h.GenerateCodes(w, r, id)
+	case action != "" && action != "codes":
+		NotFound(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+func (h *VoucherHandler) GetByID(w http.ResponseWriter, r *http.Request, id int) {
+	voucher, err := h.service.GetByID(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, voucher)
+}
+
+func (h *VoucherHandler) Update(w http.ResponseWriter, r *http.Request, id int) {
+	voucher := models.Voucher{Active: true, UsesPerCode: 1}
+	if err := json.NewDecoder(r.Body).Decode(&voucher); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	voucher.ID = id
+	if err := h.service.Update(&voucher); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, voucher)
+}
+
+func (h *VoucherHandler) Delete(w http.ResponseWriter, r *http.Request, id int) {
+	if err := h.service.Delete(id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message": "Voucher deleted successfully",
+	})
+}
+
+func (h *VoucherHandler) GetCodes(w http.ResponseWriter, r *http.Request, id int) {
+	filter := models.VoucherCodeFilter{VoucherID: id}
+
+	var ok bool
+	if filter.Page, filter.Limit, ok = parsePageParams(w, r); !ok {
+		return
+	}
+
+	codes, err := h.service.GetCodes(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writePage(w, r, codes)
+}
+
+// GenerateCodes responds with the new codes only, ready to be printed.
+func (h *VoucherHandler) GenerateCodes(w http.ResponseWriter, r *http.Request, id int) {
+	var req models.GenerateCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	codes, err := h.service.GenerateCodes(id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, codes)
+}
+
+func (h *VoucherHandler) HandleValidate(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.Validate(w, r)
+	default:
+		writeMethodNotAllowed(w, r)
+	}
+}
+
+// Validate previews a voucher code on a cart without redeeming it. A code
+// that can't be used is reported like a checkout would report it.
+func (h *VoucherHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	var req models.VoucherValidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "Invalid request body")
+		return
+	}
+
+	preview, err := h.service.Validate(req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, preview)
+}
@@ -1,81 +1,209 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 
 	"simple-cashier-api/database"
-	"simple-cashier-api/handlers"
-	"simple-cashier-api/repositories"
+	"simple-cashier-api/repositories/memory"
 	"simple-cashier-api/services"
 )
 
 type Config struct {
-	Port   string `mapstructure:"PORT"`
-	DBConn string `mapstructure:"DB_CONN"`
+	Port                string        `mapstructure:"PORT"`
+	DBDriver            string        `mapstructure:"DB_DRIVER"`
+	DBConn              string        `mapstructure:"DB_CONN"`
+	DBRequireMigrations bool          `mapstructure:"DB_REQUIRE_MIGRATIONS"`
+	IdempotencyKeyTTL   time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	LowStockThreshold   int           `mapstructure:"LOW_STOCK_THRESHOLD"`
+	TaxRateBP           int           `mapstructure:"TAX_RATE_BP"`
+	ServiceChargeBP     int           `mapstructure:"SERVICE_CHARGE_BP"`
+	PricesIncludeTax    bool          `mapstructure:"PRICES_INCLUDE_TAX"`
+	ReceiptStoreName    string        `mapstructure:"RECEIPT_STORE_NAME"`
+	ReceiptStoreAddress string        `mapstructure:"RECEIPT_STORE_ADDRESS"`
+	ReceiptFooter       string        `mapstructure:"RECEIPT_FOOTER"`
+	ReceiptWidth        string        `mapstructure:"RECEIPT_WIDTH"`
+	AuthTokenSecret     string        `mapstructure:"AUTH_TOKEN_SECRET"`
+	AccessTokenTTL      time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL     time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	AdminUsername       string        `mapstructure:"ADMIN_USERNAME"`
+	AdminPassword       string        `mapstructure:"ADMIN_PASSWORD"`
+	RolePermissionsFile string        `mapstructure:"ROLE_PERMISSIONS_FILE"`
+	// DiscountApprovalPercent is the largest manual discount a cashier may
+	// give without a supervisor's approval.
+	DiscountApprovalPercent int `mapstructure:"DISCOUNT_APPROVAL_PERCENT"`
+	// RolePermissions is read from RolePermissionsFile at startup.
+	RolePermissions map[string][]string `mapstructure:"-"`
 }
 
-func main() {
+func loadConfig() Config {
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
+	viper.SetDefault("DB_DRIVER", "postgres")
+	viper.SetDefault("IDEMPOTENCY_KEY_TTL", "24h")
+	viper.SetDefault("LOW_STOCK_THRESHOLD", 10)
+	viper.SetDefault("TAX_RATE_BP", 1100)
+	viper.SetDefault("SERVICE_CHARGE_BP", 0)
+	viper.SetDefault("PRICES_INCLUDE_TAX", false)
+	viper.SetDefault("RECEIPT_STORE_NAME", "Simple Cashier")
+	viper.SetDefault("RECEIPT_FOOTER", "Terima kasih")
+	viper.SetDefault("RECEIPT_WIDTH", "58mm")
+	viper.SetDefault("ACCESS_TOKEN_TTL", "15m")
+	viper.SetDefault("REFRESH_TOKEN_TTL", "168h")
+	viper.SetDefault("ADMIN_USERNAME", "admin")
+	viper.SetDefault("DISCOUNT_APPROVAL_PERCENT", 10)
+
 	if _, err := os.Stat(".env"); err == nil {
 		viper.SetConfigFile(".env")
 		_ = viper.ReadInConfig()
 	}
 
-	config := Config{
-		Port:   viper.GetString("PORT"),
-		DBConn: viper.GetString("DB_CONN"),
+	return Config{
+		Port:                viper.GetString("PORT"),
+		DBDriver:            viper.GetString("DB_DRIVER"),
+		DBConn:              viper.GetString("DB_CONN"),
+		DBRequireMigrations: viper.GetBool("DB_REQUIRE_MIGRATIONS"),
+		IdempotencyKeyTTL:   viper.GetDuration("IDEMPOTENCY_KEY_TTL"),
+		LowStockThreshold:   viper.GetInt("LOW_STOCK_THRESHOLD"),
+		TaxRateBP:           viper.GetInt("TAX_RATE_BP"),
+		ServiceChargeBP:     viper.GetInt("SERVICE_CHARGE_BP"),
+		PricesIncludeTax:    viper.GetBool("PRICES_INCLUDE_TAX"),
+		ReceiptStoreName:    viper.GetString("RECEIPT_STORE_NAME"),
+		ReceiptStoreAddress: strings.ReplaceAll(viper.GetString("RECEIPT_STORE_ADDRESS"), `\n`, "\n"),
+		ReceiptFooter:       strings.ReplaceAll(viper.GetString("RECEIPT_FOOTER"), `\n`, "\n"),
+		ReceiptWidth:        viper.GetString("RECEIPT_WIDTH"),
+		AuthTokenSecret:     viper.GetString("AUTH_TOKEN_SECRET"),
+		AccessTokenTTL:      viper.GetDuration("ACCESS_TOKEN_TTL"),
+		RefreshTokenTTL:     viper.GetDuration("REFRESH_TOKEN_TTL"),
+		AdminUsername:       viper.GetString("ADMIN_USERNAME"),
+		AdminPassword:       viper.GetString("ADMIN_PASSWORD"),
+		RolePermissionsFile: viper.GetString("ROLE_PERMISSIONS_FILE"),
+
+		DiscountApprovalPercent: viper.GetInt("DISCOUNT_APPROVAL_PERCENT"),
 	}
+}
 
-	db, err := database.InitDB(config.DBConn)
+// loadRolePermissions reads a JSON object mapping roles to the permissions
+// they hold, such as {"cashier": ["product:read", "transaction:create"]}.
+func loadRolePermissions(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return nil, err
 	}
-	defer db.Close()
-
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{
-			"status":  "OK",
-			"message": "API running",
-		})
-	})
-
-	productRepo := repositories.NewProductRepository(db)
-	productService := services.NewProductService(productRepo)
-	productHandler := handlers.NewProductHandler(productService)
 
-	http.HandleFunc("/api/products", productHandler.HandleProducts)
-	http.HandleFunc("/api/products/", productHandler.HandleProductByID)
+	var roles map[string][]string
+	if err := json.Unmarshal(data, &roles); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := services.ValidateRolePermissions(roles); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return roles, nil
+}
 
-	categoryRepo := repositories.NewCategoryRepository(db)
-	categoryService := services.NewCategoryService(categoryRepo)
-	categoryHandler := handlers.NewCategoryHandler(categoryService)
+func validRateBP(bp int) bool {
+	return bp >= 0 && bp <= services.MaxRateBP
+}
 
-	http.HandleFunc("/api/categories", categoryHandler.HandleCategories)
-	http.HandleFunc("/api/categories/", categoryHandler.HandleCategoryByID)
+func main() {
+	config := loadConfig()
+	if !validRateBP(config.TaxRateBP) || !validRateBP(config.ServiceChargeBP) {
+		log.Fatalf("TAX_RATE_BP and SERVICE_CHARGE_BP must be between 0 and %d basis points", services.MaxRateBP)
+	}
+	if _, ok := services.ReceiptWidths[config.ReceiptWidth]; !ok {
+		log.Fatalf("Unknown RECEIPT_WIDTH %q, expected 32, 48, 58mm or 80mm", config.ReceiptWidth)
+	}
+	if config.AccessTokenTTL <= 0 || config.RefreshTokenTTL <= 0 {
+		log.Fatal("ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL must be positive durations")
+	}
+	if config.DiscountApprovalPercent < 0 || config.DiscountApprovalPercent > 100 {
+		log.Fatal("DISCOUNT_APPROVAL_PERCENT must be between 0 and 100")
+	}
+	if config.RolePermissionsFile != "" {
+		roles, err := loadRolePermissions(config.RolePermissionsFile)
+		if err != nil {
+			log.Fatal("Failed to load ROLE_PERMISSIONS_FILE: ", err)
+		}
+		config.RolePermissions = roles
+	}
+	if config.AuthTokenSecret == "" {
+		log.Println("AUTH_TOKEN_SECRET is not set, using a random secret: tokens will not survive a restart")
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		config.AuthTokenSecret = hex.EncodeToString(secret)
+	}
 
-	transactionRepo := repositories.NewTransactionRepository(db)
-	transactionService := services.NewTransactionService(transactionRepo)
-	transactionHandler := handlers.NewTransactionHandler(transactionService)
+	var repos Repositories
+	switch config.DBDriver {
+	case "memory":
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			log.Fatal("The migrate command needs DB_DRIVER=postgres")
+		}
+
+		log.Println("Using in-memory storage, all data is lost on restart")
+		repos = newMemoryRepositories(memory.NewStore())
+	case "postgres":
+		db, err := database.InitDB(config.DBConn)
+		if err != nil {
+			log.Fatal("Failed to initialize database:", err)
+		}
+		defer db.Close()
+
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			if err := runMigrateCommand(db, os.Args[2:]); err != nil {
+				log.Fatal("Migration failed: ", err)
+			}
+			return
+		}
+
+		if config.DBRequireMigrations {
+			pending, err := database.PendingMigrations(db)
+			if err != nil {
+				log.Fatal("Failed to check migrations:", err)
+			}
+			if len(pending) > 0 {
+				log.Fatalf("Database schema is behind by %d migration(s), run `simple-cashier-api migrate up` first", len(pending))
+			}
+		}
+
+		repos = newPostgresRepositories(db)
+	default:
+		log.Fatalf("Unknown DB_DRIVER %q, expected postgres or memory", config.DBDriver)
+	}
 
-	http.HandleFunc("/api/checkout", transactionHandler.HandleCheckout)
+	if config.AdminPassword != "" {
+		created, err := services.NewUserService(repos.Users).EnsureAdmin(config.AdminUsername, config.AdminPassword)
+		if err != nil {
+			log.Fatal("Failed to create the admin user:", err)
+		}
+		if created {
+			log.Printf("Created admin user %q", config.AdminUsername)
+		}
+	}
 
-	http.HandleFunc("/api/report/hari-ini", transactionHandler.HandleGetTodaysReport)
-	http.HandleFunc("/api/report", transactionHandler.HandleGetRangeDateTransactionReport)
+	idempotencyService := services.NewIdempotencyService(repos.Idempotency, config.IdempotencyKeyTTL)
+	go func() {
+		for range time.Tick(time.Hour) {
+			if _, err := idempotencyService.PurgeExpired(); err != nil {
+				log.Println("Failed to purge expired idempotency keys:", err)
+			}
+		}
+	}()
 
 	addr := "0.0.0.0:" + config.Port
 	fmt.Println("Server running on", addr)
 
-	err = http.ListenAndServe(addr, nil)
+	err := http.ListenAndServe(addr, newRouter(config, repos))
 	if err != nil {
 		log.Fatal("Gagal running server:", err)
 	}
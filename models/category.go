@@ -5,3 +5,10 @@ type Category struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 }
+
+type CategoryFilter struct {
+	Name  string
+	Sort  []SortField
+	Page  int
+	Limit int
+}
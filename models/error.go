@@ -0,0 +1,17 @@
+package models
+
+// ErrorResponse is the body of every non-2xx response.
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldError describes one invalid field of a request. Field uses the JSON
+// path, e.g. "items[1].quantity".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
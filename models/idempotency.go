@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type IdempotencyKey struct {
+	Key           string
+	Fingerprint   string
+	TransactionID *int
+	StatusCode    int
+	ResponseBody  []byte
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+// IdempotencyClaim ties a checkout to the Idempotency-Key reserved for it.
+// The checkout records its transaction on the key, and keeps the key until
+// ExpiresAt, in its own database transaction, so a retry replays it even
+// when the response was never stored.
+type IdempotencyClaim struct {
+	Key       string
+	ExpiresAt time.Time
+}
+
+// Completed reports whether the original request finished and its response
+// can be replayed.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
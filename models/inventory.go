@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Inventory movement types. Every change to products.stock is recorded as
+// one movement, so the ledger always adds up to the current stock. Refund and
+// void movements use the type of the refund that caused them.
+const (
+	MovementTypeOpening    = "opening"
+	MovementTypeSale       = "sale"
+	MovementTypeRefund     = "refund"
+	MovementTypeVoid       = "void"
+	MovementTypeRestock    = "restock"
+	MovementTypeAdjustment = "adjustment"
+	MovementTypeDamage     = "damage"
+	MovementTypeLoss       = "loss"
+)
+
+// StockAdjustmentTypes are the movement types that can be posted by hand.
+var StockAdjustmentTypes = []string{
+	MovementTypeRestock,
+	MovementTypeAdjustment,
+	MovementTypeDamage,
+	MovementTypeLoss,
+}
+
+// What InventoryMovement.ReferenceID points at.
+const (
+	ReferenceTypeTransaction = "transaction"
+	ReferenceTypeRefund      = "refund"
+)
+
+// InventoryMovement is one line of the stock ledger. Quantity is the signed
+// change and Balance the stock right after it. ApprovedBy is the manager who
+// approved a manual adjustment the actor's role could not post alone.
+type InventoryMovement struct {
+	ID            int       `json:"id"`
+	ProductID     int       `json:"product_id"`
+	Type          string    `json:"type"`
+	Quantity      int       `json:"quantity"`
+	Balance       int       `json:"balance"`
+	Reason        string    `json:"reason,omitempty"`
+	Actor         string    `json:"actor,omitempty"`
+	ApprovedBy    string    `json:"approved_by,omitempty"`
+	ReferenceType string    `json:"reference_type,omitempty"`
+	ReferenceID   *int      `json:"reference_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// StockAdjustment is the body of POST /api/products/{id}/stock-adjustments.
+// Actor and ApprovedBy are filled in from the signed-in user and the
+// request's approval, never from the body.
+type StockAdjustment struct {
+	Type       string `json:"type"`
+	Quantity   int    `json:"quantity"`
+	Reason     string `json:"reason"`
+	Actor      string `json:"-"`
+	ApprovedBy string `json:"-"`
+}
+
+type StockHistoryFilter struct {
+	ProductID int
+	Page      int
+	Limit     int
+}
@@ -0,0 +1,45 @@
+package models
+
+type Pagination struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// PageLinks holds relative URLs to the neighbouring pages, keeping every
+// other query parameter of the request. Next and Prev are omitted at the ends.
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+type Page[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+	Links      *PageLinks `json:"links,omitempty"`
+}
+
+// SortField is one key of a sort query parameter such as sort=price,-name.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+func NewPage[T any](data []T, page, limit, total int) Page[T] {
+	totalPages := 0
+	if limit > 0 {
+		totalPages = (total + limit - 1) / limit
+	}
+
+	return Page[T]{
+		Data: data,
+		Pagination: Pagination{
+			Page:       page,
+			Limit:      limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	PaymentMethodCash      = "cash"
+	PaymentMethodDebitCard = "debit_card"
+	PaymentMethodQRIS      = "qris"
+	PaymentMethodEWallet   = "e_wallet"
+)
+
+var PaymentMethods = []string{
+	PaymentMethodCash,
+	PaymentMethodDebitCard,
+	PaymentMethodQRIS,
+	PaymentMethodEWallet,
+}
+
+type Payment struct {
+	ID            int       `json:"id"`
+	TransactionID int       `json:"transaction_id"`
+	Method        string    `json:"method"`
+	Amount        int       `json:"amount"`
+	Reference     string    `json:"reference,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type CheckoutPayment struct {
+	Method    string `json:"method"`
+	Amount    int    `json:"amount"`
+	Reference string `json:"reference,omitempty"`
+}
@@ -0,0 +1,96 @@
+package models
+
+// Permissions name what a role may do, as resource:action. Every route under
+// /api/ except /api/auth/* needs one of them.
+const (
+	PermProductRead   = "product:read"
+	PermProductWrite  = "product:write"
+	PermProductDelete = "product:delete"
+
+	PermCategoryRead   = "category:read"
+	PermCategoryWrite  = "category:write"
+	PermCategoryDelete = "category:delete"
+
+	PermInventoryRead   = "inventory:read"
+	PermInventoryAdjust = "inventory:adjust"
+
+	PermSupplierRead   = "supplier:read"
+	PermSupplierWrite  = "supplier:write"
+	PermSupplierDelete = "supplier:delete"
+
+	PermPurchaseOrderRead    = "purchase_order:read"
+	PermPurchaseOrderWrite   = "purchase_order:write"
+	PermPurchaseOrderReceive = "purchase_order:receive"
+
+	PermPromotionRead   = "promotion:read"
+	PermPromotionWrite  = "promotion:write"
+	PermPromotionDelete = "promotion:delete"
+
+	PermVoucherRead     = "voucher:read"
+	PermVoucherWrite    = "voucher:write"
+	PermVoucherDelete   = "voucher:delete"
+	PermVoucherValidate = "voucher:validate"
+
+	PermTaxClassRead   = "tax_class:read"
+	PermTaxClassWrite  = "tax_class:write"
+	PermTaxClassDelete = "tax_class:delete"
+
+	PermTransactionCreate = "transaction:create"
+	PermTransactionRead   = "transaction:read"
+	PermTransactionVoid   = "transaction:void"
+	PermTransactionRefund = "transaction:refund"
+	// PermTransactionPriceOverride lets a checkout line be sold at a price
+	// other than the product's.
+	PermTransactionPriceOverride = "transaction:price_override"
+	// PermTransactionDiscount allows manual discounts above
+	// DISCOUNT_APPROVAL_PERCENT at checkout.
+	PermTransactionDiscount = "transaction:discount"
+
+	// PermShiftOperate opens a shift at the user's terminal, moves cash in
+	// and out of its drawer and closes it. PermShiftRead lists every shift
+	// with its variance.
+	PermShiftOperate = "shift:operate"
+	PermShiftRead    = "shift:read"
+
+	PermReportRead = "report:read"
+
+	PermUserRead  = "user:read"
+	PermUserWrite = "user:write"
+
+	PermTerminalRead  = "terminal:read"
+	PermTerminalWrite = "terminal:write"
+)
+
+var Permissions = []string{
+	PermProductRead, PermProductWrite, PermProductDelete,
+	PermCategoryRead, PermCategoryWrite, PermCategoryDelete,
+	PermInventoryRead, PermInventoryAdjust,
+	PermSupplierRead, PermSupplierWrite, PermSupplierDelete,
+	PermPurchaseOrderRead, PermPurchaseOrderWrite, PermPurchaseOrderReceive,
+	PermPromotionRead, PermPromotionWrite, PermPromotionDelete,
+	PermVoucherRead, PermVoucherWrite, PermVoucherDelete, PermVoucherValidate,
+	PermTaxClassRead, PermTaxClassWrite, PermTaxClassDelete,
+	PermTransactionCreate, PermTransactionRead, PermTransactionVoid, PermTransactionRefund,
+	PermTransactionPriceOverride, PermTransactionDiscount,
+	PermShiftOperate, PermShiftRead,
+	PermReportRead,
+	PermUserRead, PermUserWrite,
+	PermTerminalRead, PermTerminalWrite,
+}
+
+// ApprovablePermissions guard the sensitive actions a user whose role lacks
+// the permission can still take, with the PIN of a manager whose role has it.
+var ApprovablePermissions = []string{
+	PermTransactionVoid,
+	PermTransactionPriceOverride,
+	PermTransactionDiscount,
+	PermInventoryAdjust,
+}
+
+// PermissionDenied is the details of a 403 response. Approvable tells the
+// client it may retry with a manager's approval.
+type PermissionDenied struct {
+	Permission string `json:"permission"`
+	Role       string `json:"role"`
+	Approvable bool   `json:"approvable,omitempty"`
+}
@@ -1,23 +1,102 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// Product.Barcodes are EAN-13 codes; UPC-A codes are stored with a leading
+// zero, which is how EAN-13 scanners read them. CostPrice is what one unit
+// cost to buy; receiving goods keeps it at the moving average.
 type Product struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	Price      int    `json:"price"`
-	Stock      int    `json:"stock"`
-	CategoryID *int   `json:"category_id"`
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	SKU        string   `json:"sku"`
+	Barcodes   []string `json:"barcodes"`
+	Price      int      `json:"price"`
+	CostPrice  int      `json:"cost_price"`
+	Stock      int      `json:"stock"`
+	CategoryID *int     `json:"category_id"`
+	TaxClassID *int     `json:"tax_class_id"`
+}
+
+// ProductUpdate is the body of PUT /api/products/{id}. Optional fields left
+// out of the body keep their stored value, while null or empty clears them;
+// Sent records which were given. A nil Stock keeps the stored stock; a
+// different one is booked as an adjustment by Actor, which needs
+// inventory:adjust or its approval. A nil CostPrice keeps the stored cost,
+// which receiving goods maintains. Actor and ApprovedBy are filled in from
+// the signed-in user and the approval, never from the body.
+type ProductUpdate struct {
+	Product
+	Stock      *int            `json:"stock"`
+	CostPrice  *int            `json:"cost_price"`
+	Sent       map[string]bool `json:"-"`
+	Actor      string          `json:"-"`
+	ApprovedBy string          `json:"-"`
+}
+
+func (u *ProductUpdate) UnmarshalJSON(data []byte) error {
+	type plain ProductUpdate
+	if err := json.Unmarshal(data, (*plain)(u)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	u.Sent = make(map[string]bool, len(fields))
+	for name := range fields {
+		u.Sent[name] = true
+	}
+	return nil
 }
 
 type ProductDetail struct {
 	ID         int       `json:"id"`
 	Name       string    `json:"name"`
+	SKU        string    `json:"sku"`
+	Barcodes   []string  `json:"barcodes"`
 	Price      int       `json:"price"`
+	CostPrice  int       `json:"cost_price"`
 	Stock      int       `json:"stock"`
 	CategoryID *int      `json:"category_id"`
 	Category   *Category `json:"category,omitempty"`
+	TaxClassID *int      `json:"tax_class_id"`
 }
 
 type BestSellingProduct struct {
 	Nama         string `json:"nama"`
 	QuantitySold int    `json:"qty_terjual"`
 }
+
+// ProductFilter narrows GET /api/products. Nil fields are not applied.
+// LowStock compares against LowStockThreshold, which the service fills in.
+type ProductFilter struct {
+	Name              string
+	CategoryID        *int
+	MinPrice          *int
+	MaxPrice          *int
+	InStock           *bool
+	LowStock          *bool
+	LowStockThreshold int
+	Sort              []SortField
+	Page              int
+	Limit             int
+}
+
+// PriceChange is one entry of a product's price history. OldPrice is nil for
+// the price the product was created with.
+type PriceChange struct {
+	ID        int       `json:"id"`
+	ProductID int       `json:"product_id"`
+	OldPrice  *int      `json:"old_price"`
+	NewPrice  int       `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type PriceHistoryFilter struct {
+	ProductID int
+	Page      int
+	Limit     int
+}
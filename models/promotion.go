@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Promotion types. Line promotions discount the lines of the products in
+// their scope, cart promotions the cart as a whole.
+const (
+	// PromotionTypePercentOff takes Value percent off each line in scope.
+	PromotionTypePercentOff = "percent_off"
+	// PromotionTypeAmountOff takes Value off each unit in scope.
+	PromotionTypeAmountOff = "amount_off"
+	// PromotionTypeBuyXGetY gives GetQuantity units of a line free for every
+	// BuyQuantity units bought.
+	PromotionTypeBuyXGetY = "buy_x_get_y"
+	// PromotionTypeBundle sells every full set of BundleItems for BundlePrice.
+	PromotionTypeBundle = "bundle"
+	// PromotionTypeCartPercentOff takes Value percent off the cart.
+	PromotionTypeCartPercentOff = "cart_percent_off"
+	// PromotionTypeCartAmountOff takes Value off the cart.
+	PromotionTypeCartAmountOff = "cart_amount_off"
+)
+
+var PromotionTypes = []string{
+	PromotionTypePercentOff,
+	PromotionTypeAmountOff,
+	PromotionTypeBuyXGetY,
+	PromotionTypeBundle,
+	PromotionTypeCartPercentOff,
+	PromotionTypeCartAmountOff,
+}
+
+// Promotion applies at checkout while Active and within [StartsAt, EndsAt);
+// a nil bound is open. ProductIDs and CategoryIDs scope line and cart
+// promotions alike, and with both empty every product is in scope; a cart
+// promotion takes its discount off the lines in scope only. Line promotions
+// apply before cart promotions, and MinSpend is compared with what is left of
+// the cart subtotal after the promotions applied before this one.
+type Promotion struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Value       int          `json:"value"`
+	BuyQuantity int          `json:"buy_quantity"`
+	GetQuantity int          `json:"get_quantity"`
+	BundlePrice int          `json:"bundle_price"`
+	BundleItems []BundleItem `json:"bundle_items"`
+	ProductIDs  []int        `json:"product_ids"`
+	CategoryIDs []int        `json:"category_ids"`
+	MinSpend    int          `json:"min_spend"`
+	StartsAt    *time.Time   `json:"starts_at"`
+	EndsAt      *time.Time   `json:"ends_at"`
+	Active      bool         `json:"active"`
+}
+
+type BundleItem struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+type PromotionFilter struct {
+	Active *bool
+	Page   int
+	Limit  int
+}
+
+// AppliedPromotion records a promotion given on a transaction. PromotionID
+// is nil once the promotion has been deleted; Name and Type are as they were
+// at checkout.
+type AppliedPromotion struct {
+	ID            int    `json:"id"`
+	TransactionID int    `json:"transaction_id"`
+	PromotionID   *int   `json:"promotion_id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Amount        int    `json:"amount"`
+}
+
+// PromotionUsage totals one promotion's discounts in a report period.
+type PromotionUsage struct {
+	PromotionID *int   `json:"promotion_id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	// Transactions counts the sales the promotion was used on that were kept:
+	// voided and fully refunded sales give their use back, partially
+	// refunded ones count in full, like their DiscountAmount.
+	Transactions   int `json:"transactions"`
+	DiscountAmount int `json:"discount_amount"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Purchase order statuses. A draft can be edited; once ordered it can only be
+// received or cancelled. Cancelling a partially received order closes it
+// without the outstanding quantities.
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusOrdered           = "ordered"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusReceived          = "received"
+	PurchaseOrderStatusCancelled         = "cancelled"
+)
+
+var PurchaseOrderStatuses = []string{
+	PurchaseOrderStatusDraft,
+	PurchaseOrderStatusOrdered,
+	PurchaseOrderStatusPartiallyReceived,
+	PurchaseOrderStatusReceived,
+	PurchaseOrderStatusCancelled,
+}
+
+// ReferenceTypePurchaseOrder marks the restock movements booked by receiving.
+const ReferenceTypePurchaseOrder = "purchase_order"
+
+// PurchaseOrder amounts are in the same unit as product prices. TotalCost is
+// the sum of the ordered quantities at their unit cost.
+type PurchaseOrder struct {
+	ID           int                 `json:"id"`
+	SupplierID   int                 `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name,omitempty"`
+	Status       string              `json:"status"`
+	Notes        string              `json:"notes"`
+	TotalCost    int                 `json:"total_cost"`
+	CreatedAt    time.Time           `json:"created_at"`
+	OrderedAt    *time.Time          `json:"ordered_at,omitempty"`
+	ReceivedAt   *time.Time          `json:"received_at,omitempty"`
+	Items        []PurchaseOrderItem `json:"items"`
+}
+
+type PurchaseOrderItem struct {
+	ID               int    `json:"id"`
+	PurchaseOrderID  int    `json:"purchase_order_id"`
+	ProductID        int    `json:"product_id"`
+	ProductName      string `json:"product_name,omitempty"`
+	Quantity         int    `json:"quantity"`
+	ReceivedQuantity int    `json:"received_quantity"`
+	UnitCost         int    `json:"unit_cost"`
+	Subtotal         int    `json:"subtotal"`
+}
+
+// PurchaseOrderRequest is the body for creating a purchase order and for
+// replacing a draft.
+type PurchaseOrderRequest struct {
+	SupplierID int                     `json:"supplier_id"`
+	Notes      string                  `json:"notes"`
+	Items      []PurchaseOrderItemLine `json:"items"`
+}
+
+type PurchaseOrderItemLine struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+	UnitCost  int `json:"unit_cost"`
+}
+
+// ReceiveRequest books delivered quantities against purchase order items.
+// Actor is the signed-in user, never taken from the body.
+type ReceiveRequest struct {
+	Items []ReceiveItem `json:"items"`
+	Actor string        `json:"-"`
+}
+
+type ReceiveItem struct {
+	ItemID   int `json:"item_id"`
+	Quantity int `json:"quantity"`
+}
+
+type PurchaseOrderFilter struct {
+	Status     string
+	SupplierID *int
+	Page       int
+	Limit      int
+}
@@ -0,0 +1,24 @@
+package models
+
+const (
+	ReceiptFormatText   = "text"
+	ReceiptFormatHTML   = "html"
+	ReceiptFormatESCPOS = "escpos"
+)
+
+var ReceiptFormats = []string{ReceiptFormatText, ReceiptFormatHTML, ReceiptFormatESCPOS}
+
+// ReceiptSettings are printed on every receipt. Width is the default number
+// of characters per line.
+type ReceiptSettings struct {
+	StoreName    string
+	StoreAddress string
+	Footer       string
+	Width        int
+}
+
+// Receipt is a rendered receipt, ready to be sent as is.
+type Receipt struct {
+	ContentType string
+	Body        []byte
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	RefundTypeVoid   = "void"
+	RefundTypeRefund = "refund"
+)
+
+// Refund.UserID and Cashier identify who gave the refund, and ApproverID and
+// ApprovedBy the manager who approved it when their role could not. ShiftID
+// is the shift whose drawer paid it out. Refunds are given back in cash up
+// to the cash the transaction took, less change and earlier cash refunds;
+// CashAmount is that part, and the rest goes back to the other payments.
+type Refund struct {
+	ID            int            `json:"id"`
+	TransactionID int            `json:"transaction_id"`
+	Type          string         `json:"type"`
+	Reason        string         `json:"reason"`
+	UserID        *int           `json:"user_id"`
+	Cashier       string         `json:"cashier,omitempty"`
+	ApproverID    *int           `json:"approver_id,omitempty"`
+	ApprovedBy    string         `json:"approved_by,omitempty"`
+	ShiftID       *int           `json:"shift_id"`
+	TotalAmount   int            `json:"total_amount"`
+	CashAmount    int            `json:"cash_amount"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Details       []RefundDetail `json:"details"`
+}
+
+type RefundDetail struct {
+	ID                  int    `json:"id"`
+	RefundID            int    `json:"refund_id"`
+	TransactionDetailID int    `json:"transaction_detail_id"`
+	ProductID           int    `json:"product_id"`
+	ProductName         string `json:"product_name,omitempty"`
+	Quantity            int    `json:"quantity"`
+	Amount              int    `json:"amount"`
+	ServiceCharge       int    `json:"service_charge"`
+	TaxAmount           int    `json:"tax_amount"`
+}
+
+type RefundItem struct {
+	DetailID int `json:"detail_id"`
+	Quantity int `json:"quantity"`
+}
+
+type RefundRequest struct {
+	Reason string       `json:"reason"`
+	Items  []RefundItem `json:"items"`
+}
+
+type VoidRequest struct {
+	Reason string `json:"reason"`
+}
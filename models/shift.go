@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Shift statuses. A terminal has at most one open shift, and checkout needs
+// one.
+const (
+	ShiftStatusOpen   = "open"
+	ShiftStatusClosed = "closed"
+)
+
+var ShiftStatuses = []string{ShiftStatusOpen, ShiftStatusClosed}
+
+// Cash movement types: cash put into the drawer during a shift, such as
+// change from the safe, and cash taken out, such as petty cash or a bank
+// drop.
+const (
+	CashMovementIn  = "cash_in"
+	CashMovementOut = "cash_out"
+)
+
+var CashMovementTypes = []string{CashMovementIn, CashMovementOut}
+
+// Denominations are the rupiah notes and coins a drawer is counted in.
+var Denominations = []int{100000, 50000, 20000, 10000, 5000, 2000, 1000, 500, 200, 100}
+
+// Shift is a cashier's session at a terminal, from opening the cash drawer
+// with OpeningFloat to counting it at close. CashSales is the cash taken on
+// the shift's transactions less the change given, CashRefunds the cash paid
+// out for voids and refunds made on the shift, whichever shift the sale was
+// on, and ExpectedCash is what the drawer should hold:
+//
+//	OpeningFloat + CashSales - CashRefunds + CashIn - CashOut
+//
+// A closed shift keeps the ExpectedCash it closed with, the CountedCash from
+// the denomination count and their Variance (counted minus expected).
+type Shift struct {
+	ID               int            `json:"id"`
+	TerminalID       int            `json:"terminal_id"`
+	UserID           *int           `json:"user_id"`
+	Cashier          string         `json:"cashier,omitempty"`
+	Status           string         `json:"status"`
+	OpeningFloat     int            `json:"opening_float"`
+	CashSales        int            `json:"cash_sales"`
+	CashRefunds      int            `json:"cash_refunds"`
+	CashIn           int            `json:"cash_in"`
+	CashOut          int            `json:"cash_out"`
+	ExpectedCash     int            `json:"expected_cash"`
+	CountedCash      *int           `json:"counted_cash"`
+	Variance         *int           `json:"variance"`
+	TransactionCount int            `json:"transaction_count"`
+	Note             string         `json:"note,omitempty"`
+	ClosedBy         string         `json:"closed_by,omitempty"`
+	OpenedAt         time.Time      `json:"opened_at"`
+	ClosedAt         *time.Time     `json:"closed_at,omitempty"`
+	CashCounts       []CashCount    `json:"cash_counts"`
+	Movements        []CashMovement `json:"movements"`
+}
+
+// CashCount is how many notes or coins of a denomination were counted.
+type CashCount struct {
+	Denomination int `json:"denomination"`
+	Count        int `json:"count"`
+	Amount       int `json:"amount"`
+}
+
+type CashMovement struct {
+	ID        int       `json:"id"`
+	ShiftID   int       `json:"shift_id"`
+	Type      string    `json:"type"`
+	Amount    int       `json:"amount"`
+	Reason    string    `json:"reason"`
+	UserID    *int      `json:"user_id"`
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OpenShiftRequest struct {
+	OpeningFloat int `json:"opening_float"`
+}
+
+type CashMovementRequest struct {
+	Type   string `json:"type"`
+	Amount int    `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// CloseShiftRequest counts the drawer by denomination. Denominations left
+// out were not found in the drawer.
+type CloseShiftRequest struct {
+	CashCounts []CashCount `json:"cash_counts"`
+	Note       string      `json:"note"`
+}
+
+type ShiftFilter struct {
+	TerminalID *int
+	UserID     *int
+	Status     string
+	Page       int
+	Limit      int
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Shift report types. An X report is a snapshot of an open shift, made
+// whenever it is asked for; a Z report is made once, when the shift closes.
+const (
+	ShiftReportX = "x"
+	ShiftReportZ = "z"
+)
+
+// ShiftReport sums up the sales rung up in a shift, including ones voided
+// since, less the voids and refunds made in it, whichever shift their sale
+// was on. Tax and ServiceCharge are net of what those gave back, and
+// NetSales is what was kept excluding them:
+//
+//	GrossSales - Discounts + ServiceCharge + Tax (unless prices include it)
+//	  - Voids - Refunds - ServiceCharge - Tax
+//
+// Z reports are numbered in sequence without gaps and never change, so they
+// can be reprinted.
+type ShiftReport struct {
+	Type             string         `json:"type"`
+	Number           int            `json:"number,omitempty"`
+	ShiftID          int            `json:"shift_id"`
+	TerminalID       int            `json:"terminal_id"`
+	Cashier          string         `json:"cashier,omitempty"`
+	OpenedAt         time.Time      `json:"opened_at"`
+	ClosedAt         *time.Time     `json:"closed_at"`
+	GrossSales       int            `json:"gross_sales"`
+	Discounts        int            `json:"discounts"`
+	Refunds          int            `json:"refunds"`
+	RefundCount      int            `json:"refund_count"`
+	Voids            int            `json:"voids"`
+	VoidCount        int            `json:"void_count"`
+	ServiceCharge    int            `json:"service_charge"`
+	Tax              int            `json:"tax"`
+	NetSales         int            `json:"net_sales"`
+	TransactionCount int            `json:"transaction_count"`
+	AverageBasket    int            `json:"average_basket"`
+	FirstReceipt     string         `json:"first_receipt"`
+	LastReceipt      string         `json:"last_receipt"`
+	OpeningFloat     int            `json:"opening_float"`
+	ExpectedCash     int            `json:"expected_cash"`
+	CountedCash      *int           `json:"counted_cash"`
+	Variance         *int           `json:"variance"`
+	Payments         []PaymentTotal `json:"payments"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+// ShiftSales are the totals a ShiftReport is made from: those of the
+// transactions rung up in a shift, and of the voids and refunds made in it.
+// Transactions and KeptTotal count the transactions that were not voided,
+// the other sales totals all of them. GivenBackServiceCharge and
+// GivenBackTax are the service charge and tax the voids and refunds gave
+// back. Payments are net of what the voids and refunds gave back by each
+// method, and in no particular order.
+type ShiftSales struct {
+	Transactions           int
+	KeptTotal              int
+	FirstTransactionID     int
+	LastTransactionID      int
+	Subtotal               int
+	Discounts              int
+	ServiceCharge          int
+	Tax                    int
+	Total                  int
+	Payments               []PaymentTotal
+	Refunds                int
+	RefundCount            int
+	Voids                  int
+	VoidCount              int
+	GivenBackServiceCharge int
+	GivenBackTax           int
+}
+
+// PaymentTotal is what one payment method took in a shift, cash net of the
+// change given, less what the voids and refunds made in the shift gave back
+// by it. Transactions counts the shift's transactions paid with it that were
+// not voided.
+type PaymentTotal struct {
+	Method       string `json:"method"`
+	Transactions int    `json:"transactions"`
+	Amount       int    `json:"amount"`
+}
+
+// ZReportFilter dates match the day the shift closed.
+type ZReportFilter struct {
+	TerminalID *int
+	StartDate  *time.Time
+	EndDate    *time.Time
+	Page       int
+	Limit      int
+}
@@ -0,0 +1,8 @@
+package models
+
+type StockShortage struct {
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Requested   int    `json:"requested"`
+	Available   int    `json:"available"`
+}
@@ -0,0 +1,17 @@
+package models
+
+type Supplier struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	ContactName string `json:"contact_name"`
+	Phone       string `json:"phone"`
+	Email       string `json:"email"`
+	Address     string `json:"address"`
+}
+
+type SupplierFilter struct {
+	Name  string
+	Sort  []SortField
+	Page  int
+	Limit int
+}
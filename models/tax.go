@@ -0,0 +1,30 @@
+package models
+
+// TaxClass overrides the default tax rate for the products assigned to it. A
+// class with a zero rate makes its products tax exempt. Rates are in basis
+// points: 1100 is 11%.
+type TaxClass struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	RateBP int    `json:"rate_bp"`
+}
+
+type TaxClassFilter struct {
+	Page  int
+	Limit int
+}
+
+// TaxSettings are the branch's tax rules, in basis points. TaxRateBP applies
+// to products without a tax class. With PricesIncludeTax product prices
+// already include their tax; otherwise it is added on top.
+type TaxSettings struct {
+	TaxRateBP        int
+	ServiceChargeBP  int
+	PricesIncludeTax bool
+}
+
+// TaxCollected totals one tax rate's tax in a report period.
+type TaxCollected struct {
+	TaxRateBP int `json:"tax_rate_bp"`
+	TaxAmount int `json:"tax_amount"`
+}
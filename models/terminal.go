@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Terminal is a registered till. Key is the secret the till presents to log
+// cashiers in with a PIN; it is only returned when the terminal is
+// registered, and only its hash is stored.
+type Terminal struct {
+	ID        int       `json:"id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Active    bool      `json:"active"`
+	Key       string    `json:"key,omitempty"`
+	KeyHash   string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TerminalFilter struct {
+	Active *bool
+	Page   int
+	Limit  int
+}
@@ -2,33 +2,147 @@ package models
 
 import "time"
 
+const (
+	TransactionStatusCompleted         = "completed"
+	TransactionStatusPartiallyRefunded = "partially_refunded"
+	TransactionStatusRefunded          = "refunded"
+	TransactionStatusVoided            = "voided"
+)
+
+// Transaction.Subtotal is the cart at list prices, or at the overridden
+// price of a line, and TotalAmount is what is charged: Subtotal less
+// DiscountAmount plus ServiceCharge, plus TaxAmount unless PricesIncludeTax.
+// UserID and Cashier identify who rang it up; both are empty on transactions
+// made before users existed. TerminalID and ShiftID are the terminal and
+// shift it was rung up in, and are empty on transactions made before shifts
+// existed. ApproverID and ApprovedBy are set when a manager approved a price
+// override or a large discount.
 type Transaction struct {
-	ID          int                 `json:"id"`
-	TotalAmount int                 `json:"total_amount"`
-	CreatedAt   time.Time           `json:"created_at"`
-	Details     []TransactionDetail `json:"details"`
+	ID               int                 `json:"id"`
+	UserID           *int                `json:"user_id"`
+	Cashier          string              `json:"cashier,omitempty"`
+	TerminalID       *int                `json:"terminal_id"`
+	ShiftID          *int                `json:"shift_id"`
+	ApproverID       *int                `json:"approver_id,omitempty"`
+	ApprovedBy       string              `json:"approved_by,omitempty"`
+	Subtotal         int                 `json:"subtotal"`
+	DiscountAmount   int                 `json:"discount_amount"`
+	ServiceCharge    int                 `json:"service_charge"`
+	TaxAmount        int                 `json:"tax_amount"`
+	PricesIncludeTax bool                `json:"prices_include_tax"`
+	TotalAmount      int                 `json:"total_amount"`
+	PaidAmount       int                 `json:"paid_amount"`
+	ChangeAmount     int                 `json:"change_amount"`
+	Status           string              `json:"status"`
+	CreatedAt        time.Time           `json:"created_at"`
+	Details          []TransactionDetail `json:"details"`
+	Promotions       []AppliedPromotion  `json:"promotions"`
+	Voucher          *AppliedVoucher     `json:"voucher,omitempty"`
+	Payments         []Payment           `json:"payments"`
+	Refunds          []Refund            `json:"refunds,omitempty"`
 }
 
+// TransactionDetail.Discount is the line's share of every promotion and
+// voucher applied, cart promotions included, and Subtotal is UnitPrice *
+// Quantity less Discount. ServiceCharge and TaxAmount are the line's shares
+// of the transaction's, and Total is what was charged for the line. Refunds
+// give back a share of Total. CategoryID and TaxClassID are only set on the
+// checkout draft, to scope promotions and vouchers and to pick the tax rate,
+// and are not stored. OriginalPrice is the product's price when UnitPrice was
+// overridden at checkout.
 type TransactionDetail struct {
 	ID            int    `json:"id"`
 	TransactionID int    `json:"transaction_id"`
 	ProductID     int    `json:"product_id"`
 	ProductName   string `json:"product_name,omitempty"`
+	CategoryID    *int   `json:"-"`
+	TaxClassID    *int   `json:"-"`
 	Quantity      int    `json:"quantity"`
+	UnitPrice     int    `json:"unit_price"`
+	OriginalPrice *int   `json:"original_price,omitempty"`
+	UnitCost      int    `json:"unit_cost"`
+	Discount      int    `json:"discount"`
 	Subtotal      int    `json:"subtotal"`
+	ServiceCharge int    `json:"service_charge"`
+	TaxRateBP     int    `json:"tax_rate_bp"`
+	TaxAmount     int    `json:"tax_amount"`
+	Total         int    `json:"total"`
+	RefundedQty   int    `json:"refunded_quantity"`
 }
 
+// CheckoutItem references its product by exactly one of ProductID, Barcode
+// (EAN-13 or UPC-A) or SKU. UnitPrice overrides the product's price.
 type CheckoutItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID int    `json:"product_id"`
+	Barcode   string `json:"barcode,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice *int   `json:"unit_price,omitempty"`
 }
 
+// DiscountTypeManual is the AppliedPromotion type of a manual discount.
+const DiscountTypeManual = "manual"
+
+// CheckoutRequest.DiscountPercent is a manual discount the cashier gives on
+// what is left to pay after promotions and the voucher.
 type CheckoutRequest struct {
-	Items []CheckoutItem `json:"items"`
+	Items           []CheckoutItem    `json:"items"`
+	Payments        []CheckoutPayment `json:"payments"`
+	VoucherCode     string            `json:"voucher_code,omitempty"`
+	DiscountPercent int               `json:"discount_percent,omitempty"`
+}
+
+type TransactionFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	MinTotal  *int
+	MaxTotal  *int
+	ProductID *int
+	UserID    *int
+	ShiftID   *int
+	Page      int
+	Limit     int
 }
 
+// TransactionReport figures are net of the refunds made in the period.
+// Revenue excludes tax and service charge, which are totalled separately.
+// Cost uses the unit cost recorded on each detail line at checkout. Discounts
+// are those given on the period's transactions that were not voided.
 type TransactionReport struct {
-	TotalRevenue   int `json:"total_revenue"`
-	TotalTransaksi int `json:"total_transaksi"`
-	ProdukTerlaris any `json:"produk_terlaris"`
+	TotalRevenue    int              `json:"total_revenue"`
+	TotalTransaksi  int              `json:"total_transaksi"`
+	ProdukTerlaris  any              `json:"produk_terlaris"`
+	CostOfGoodsSold int              `json:"cost_of_goods_sold"`
+	GrossProfit     int              `json:"gross_profit"`
+	MarginPercent   float64          `json:"margin_percent"`
+	Products        []ProductProfit  `json:"products"`
+	Categories      []CategoryProfit `json:"categories"`
+	TotalDiscount   int              `json:"total_discount"`
+	Promotions      []PromotionUsage `json:"promotions"`
+	TotalTax        int              `json:"total_tax"`
+	TotalService    int              `json:"total_service_charge"`
+	Taxes           []TaxCollected   `json:"taxes"`
+}
+
+type ProductProfit struct {
+	ProductID     int     `json:"product_id"`
+	Name          string  `json:"name"`
+	CategoryID    *int    `json:"category_id"`
+	CategoryName  string  `json:"category_name,omitempty"`
+	QuantitySold  int     `json:"quantity_sold"`
+	Revenue       int     `json:"revenue"`
+	Cost          int     `json:"cost"`
+	GrossProfit   int     `json:"gross_profit"`
+	MarginPercent float64 `json:"margin_percent"`
+}
+
+// CategoryProfit groups ProductProfit lines by the product's current
+// category. Products without one are grouped under a nil CategoryID.
+type CategoryProfit struct {
+	CategoryID    *int    `json:"category_id"`
+	Name          string  `json:"name"`
+	Revenue       int     `json:"revenue"`
+	Cost          int     `json:"cost"`
+	GrossProfit   int     `json:"gross_profit"`
+	MarginPercent float64 `json:"margin_percent"`
 }
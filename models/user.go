@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+// User roles.
+const (
+	RoleCashier    = "cashier"
+	RoleSupervisor = "supervisor"
+	RoleAdmin      = "admin"
+)
+
+var Roles = []string{RoleCashier, RoleSupervisor, RoleAdmin}
+
+// User is a staff account. Password and PIN are write only: they are hashed
+// on create, kept on an update that leaves them empty, and never returned.
+// PINFailures counts failed PIN attempts in a row, and PINLockedUntil is
+// when a PIN locked by too many of them opens again; setting a new PIN
+// clears both. Inactive users cannot log in and their tokens stop working.
+type User struct {
+	ID             int       `json:"id"`
+	Username       string    `json:"username"`
+	Name           string    `json:"name"`
+	Role           string    `json:"role"`
+	Active         bool      `json:"active"`
+	Password       string    `json:"password,omitempty"`
+	PasswordHash   string    `json:"-"`
+	PIN            string    `json:"pin,omitempty"`
+	PINHash        string    `json:"-"`
+	PINFailures    int       `json:"-"`
+	PINLockedUntil time.Time `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type UserFilter struct {
+	Role   string
+	Active *bool
+	Page   int
+	Limit  int
+}
+
+// CurrentUser is the signed-in user with the permissions of their role, and
+// the terminal they signed in at with a PIN.
+type CurrentUser struct {
+	User
+	Permissions []string `json:"permissions"`
+	TerminalID  *int     `json:"terminal_id,omitempty"`
+}
+
+// Actor is who makes a change: the signed-in user, the terminal they signed
+// in at with a PIN, and the manager who approved the change when the user's
+// role could not make it alone. TerminalID and Approver are nil otherwise.
+type Actor struct {
+	User       *User
+	TerminalID *int
+	Approver   *User
+}
+
+// UserID and Username identify who made the change, and are empty when
+// nobody is signed in.
+func (a Actor) UserID() *int {
+	if a.User == nil {
+		return nil
+	}
+	id := a.User.ID
+	return &id
+}
+
+func (a Actor) Username() string {
+	if a.User == nil {
+		return ""
+	}
+	return a.User.Username
+}
+
+// ApproverID and ApprovedBy identify the approving manager, and are empty
+// when no approval was needed.
+func (a Actor) ApproverID() *int {
+	if a.Approver == nil {
+		return nil
+	}
+	id := a.Approver.ID
+	return &id
+}
+
+func (a Actor) ApprovedBy() string {
+	if a.Approver == nil {
+		return ""
+	}
+	return a.Approver.Username
+}
+
+// Approval is a manager's username and PIN, given to approve an action the
+// signed-in user's role may not take alone.
+type Approval struct {
+	Username string
+	PIN      string
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// PINLoginRequest logs a cashier in at a registered terminal, which
+// identifies itself by its key.
+type PINLoginRequest struct {
+	TerminalKey string `json:"terminal_key"`
+	Username    string `json:"username"`
+	PIN         string `json:"pin"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenResponse is returned by login and refresh. ExpiresIn is the lifetime
+// of AccessToken in seconds; the refresh token lives longer and is traded for
+// a new pair at /api/auth/refresh. Terminal is set when the tokens were
+// issued by a PIN login, and both tokens only work while it stays active.
+type TokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int       `json:"expires_in"`
+	User         User      `json:"user"`
+	Terminal     *Terminal `json:"terminal,omitempty"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+// Voucher types.
+const (
+	// VoucherTypePercentOff takes Value percent off the items in scope, up to
+	// MaxDiscount when it is set.
+	VoucherTypePercentOff = "percent_off"
+	// VoucherTypeAmountOff takes Value off the items in scope.
+	VoucherTypeAmountOff = "amount_off"
+)
+
+var VoucherTypes = []string{VoucherTypePercentOff, VoucherTypeAmountOff}
+
+// Voucher is a campaign of printed codes sharing one discount. A code can be
+// redeemed UsesPerCode times, and all codes together MaxUses times (0 is
+// unlimited). It is redeemable while Active and within [StartsAt, EndsAt); a
+// nil bound is open. ProductIDs and CategoryIDs scope the discount, and with
+// both empty every product is in scope. MinPurchase is compared with the cart
+// total after promotions. Redemptions and CodeCount are read only.
+type Voucher struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Value       int        `json:"value"`
+	MaxDiscount int        `json:"max_discount"`
+	MinPurchase int        `json:"min_purchase"`
+	ProductIDs  []int      `json:"product_ids"`
+	CategoryIDs []int      `json:"category_ids"`
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	UsesPerCode int        `json:"uses_per_code"`
+	MaxUses     int        `json:"max_uses"`
+	Active      bool       `json:"active"`
+	Redemptions int        `json:"redemptions"`
+	CodeCount   int        `json:"code_count"`
+}
+
+type VoucherFilter struct {
+	Active *bool
+	Page   int
+	Limit  int
+}
+
+type VoucherCode struct {
+	ID          int       `json:"id"`
+	VoucherID   int       `json:"voucher_id"`
+	Code        string    `json:"code"`
+	Redemptions int       `json:"redemptions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type VoucherCodeFilter struct {
+	VoucherID int
+	Page      int
+	Limit     int
+}
+
+// GenerateCodesRequest asks for Count new random codes, each starting with
+// Prefix.
+type GenerateCodesRequest struct {
+	Count  int    `json:"count"`
+	Prefix string `json:"prefix"`
+}
+
+// RedeemableVoucher is a code together with its voucher, as looked up for a
+// checkout or a preview.
+type RedeemableVoucher struct {
+	Voucher Voucher
+	Code    VoucherCode
+}
+
+// AppliedVoucher records the voucher redeemed on a transaction. Name is the
+// voucher's as it was at checkout.
+type AppliedVoucher struct {
+	ID            int    `json:"id"`
+	TransactionID int    `json:"transaction_id"`
+	VoucherID     int    `json:"voucher_id"`
+	VoucherCodeID int    `json:"voucher_code_id"`
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	Amount        int    `json:"amount"`
+}
+
+// VoucherValidationRequest takes the same voucher_code and items as a
+// checkout. Without items only the code itself is checked.
+type VoucherValidationRequest struct {
+	VoucherCode string         `json:"voucher_code"`
+	Items       []CheckoutItem `json:"items"`
+}
+
+// VoucherPreview is what a checkout of the previewed items would be charged
+// at current prices and promotions, before service charge and tax.
+// DiscountAmount includes VoucherDiscount.
+type VoucherPreview struct {
+	VoucherCode     string `json:"voucher_code"`
+	VoucherID       int    `json:"voucher_id"`
+	Name            string `json:"name"`
+	RemainingUses   int    `json:"remaining_uses"`
+	Subtotal        int    `json:"subtotal"`
+	DiscountAmount  int    `json:"discount_amount"`
+	VoucherDiscount int    `json:"voucher_discount"`
+	TotalAmount     int    `json:"total_amount"`
+}
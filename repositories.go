@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+
+	"simple-cashier-api/repositories"
+	"simple-cashier-api/repositories/memory"
+	"simple-cashier-api/services"
+)
+
+// Repositories bundles one storage backend's implementation of every
+// repository the services depend on.
+type Repositories struct {
+	Products       services.ProductRepository
+	Categories     services.CategoryRepository
+	Transactions   services.TransactionRepository
+	Inventory      services.InventoryRepository
+	Suppliers      services.SupplierRepository
+	PurchaseOrders services.PurchaseOrderRepository
+	Promotions     services.PromotionRepository
+	Vouchers       services.VoucherRepository
+	TaxClasses     services.TaxClassRepository
+	Users          services.UserRepository
+	Terminals      services.TerminalRepository
+	Shifts         services.ShiftRepository
+	ZReports       services.ZReportRepository
+	Idempotency    services.IdempotencyRepository
+}
+
+func newPostgresRepositories(db *sql.DB) Repositories {
+	return Repositories{
+		Products:       repositories.NewProductRepository(db),
+		Categories:     repositories.NewCategoryRepository(db),
+		Transactions:   repositories.NewTransactionRepository(db),
+		Inventory:      repositories.NewInventoryRepository(db),
+		Suppliers:      repositories.NewSupplierRepository(db),
+		PurchaseOrders: repositories.NewPurchaseOrderRepository(db),
+		Promotions:     repositories.NewPromotionRepository(db),
+		Vouchers:       repositories.NewVoucherRepository(db),
+		TaxClasses:     repositories.NewTaxClassRepository(db),
+		Users:          repositories.NewUserRepository(db),
+		Terminals:      repositories.NewTerminalRepository(db),
+		Shifts:         repositories.NewShiftRepository(db),
+		ZReports:       repositories.NewZReportRepository(db),
+		Idempotency:    repositories.NewIdempotencyRepository(db),
+	}
+}
+
+func newMemoryRepositories(store *memory.Store) Repositories {
+	return Repositories{
+		Products:       memory.NewProductRepository(store),
+		Categories:     memory.NewCategoryRepository(store),
+		Transactions:   memory.NewTransactionRepository(store),
+		Inventory:      memory.NewInventoryRepository(store),
+		Suppliers:      memory.NewSupplierRepository(store),
+		PurchaseOrders: memory.NewPurchaseOrderRepository(store),
+		Promotions:     memory.NewPromotionRepository(store),
+		Vouchers:       memory.NewVoucherRepository(store),
+		TaxClasses:     memory.NewTaxClassRepository(store),
+		Users:          memory.NewUserRepository(store),
+		Terminals:      memory.NewTerminalRepository(store),
+		Shifts:         memory.NewShiftRepository(store),
+		ZReports:       memory.NewZReportRepository(store),
+		Idempotency:    memory.NewIdempotencyRepository(store),
+	}
+}
@@ -2,7 +2,7 @@ package repositories
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
 
 	"simple-cashier-api/models"
 )
@@ -15,11 +15,33 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
-	query := "SELECT id, name, description FROM categories"
-	rows, err := repo.db.Query(query)
+var categorySortColumns = map[string]string{
+	"id":   "id",
+	"name": "name",
+}
+
+func (repo *CategoryRepository) GetAll(filter models.CategoryFilter) ([]models.Category, int, error) {
+	where := ""
+	args := []any{}
+	if filter.Name != "" {
+		where = " WHERE name ILIKE $1"
+		args = append(args, "%"+filter.Name+"%")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM categories"+where, args...).Scan(&total)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	query := "SELECT id, name, description FROM categories" + where +
+		orderBy(filter.Sort, categorySortColumns) +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -28,17 +50,23 @@ func (repo *CategoryRepository) GetAll() ([]models.Category, error) {
 		var c models.Category
 		err := rows.Scan(&c.ID, &c.Name, &c.Description)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		categories = append(categories, c)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
 
-	return categories, nil
+	return categories, total, nil
 }
 
 func (repo *CategoryRepository) Create(category *models.Category) error {
 	query := "INSERT INTO categories (name, description) VALUES ($1, $2) RETURNING id"
 	err := repo.db.QueryRow(query, category.Name, category.Description).Scan(&category.ID)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrCategoryNameTaken
+	}
 	return err
 }
 
@@ -48,7 +76,7 @@ func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
 	var c models.Category
 	err := repo.db.QueryRow(query, id).Scan(&c.ID, &c.Name, &c.Description)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("category not found")
+		return nil, ErrCategoryNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -60,6 +88,9 @@ func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
 func (repo *CategoryRepository) Update(category *models.Category) error {
 	query := "UPDATE categories SET name = $1, description = $2 WHERE id = $3"
 	result, err := repo.db.Exec(query, category.Name, category.Description, category.ID)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrCategoryNameTaken
+	}
 	if err != nil {
 		return err
 	}
@@ -70,7 +101,7 @@ func (repo *CategoryRepository) Update(category *models.Category) error {
 	}
 
 	if rows == 0 {
-		return errors.New("category not found")
+		return ErrCategoryNotFound
 	}
 
 	return nil
@@ -88,7 +119,7 @@ func (repo *CategoryRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return errors.New("category not found")
+		return ErrCategoryNotFound
 	}
 
 	return err
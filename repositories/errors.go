@@ -0,0 +1,225 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"simple-cashier-api/models"
+)
+
+// Error kinds. Every domain error wraps exactly one of these, which is what
+// the handlers use to pick the HTTP status.
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrConflict          = errors.New("conflict")
+	ErrValidation        = errors.New("validation failed")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrForbidden         = errors.New("forbidden")
+)
+
+var (
+	ErrProductNotFound       = Errorf(ErrNotFound, "product not found")
+	ErrCategoryNotFound      = Errorf(ErrNotFound, "category not found")
+	ErrTransactionNotFound   = Errorf(ErrNotFound, "transaction not found")
+	ErrTransactionVoided     = Errorf(ErrConflict, "transaction already voided")
+	ErrTransactionRefunded   = Errorf(ErrConflict, "transaction has refunds and can no longer be voided")
+	ErrVoidWindowClosed      = Errorf(ErrConflict, "transaction can only be voided on the day it was made")
+	ErrProductInUse          = Errorf(ErrConflict, "product is referenced by existing transactions, purchase orders or bundles")
+	ErrInvalidRefund         = Errorf(ErrValidation, "invalid refund")
+	ErrSupplierNotFound      = Errorf(ErrNotFound, "supplier not found")
+	ErrSupplierInUse         = Errorf(ErrConflict, "supplier is referenced by existing purchase orders")
+	ErrPurchaseOrderNotFound = Errorf(ErrNotFound, "purchase order not found")
+	ErrPromotionNotFound     = Errorf(ErrNotFound, "promotion not found")
+	ErrVoucherNotFound       = Errorf(ErrNotFound, "voucher not found")
+	ErrVoucherInUse          = Errorf(ErrConflict, "voucher has been redeemed and can only be deactivated")
+	ErrTaxClassNotFound      = Errorf(ErrNotFound, "tax class not found")
+	ErrTaxClassInUse         = Errorf(ErrConflict, "tax class is assigned to products")
+	ErrUserNotFound          = Errorf(ErrNotFound, "user not found")
+	ErrTerminalNotFound      = Errorf(ErrNotFound, "terminal not found")
+	ErrShiftNotFound         = Errorf(ErrNotFound, "shift not found")
+	ErrNoOpenShift           = Errorf(ErrConflict, "no shift is open at this terminal")
+	ErrShiftAlreadyOpen      = Errorf(ErrConflict, "a shift is already open at this terminal")
+	ErrCashierShiftOpen      = Errorf(ErrConflict, "the cashier already has a shift open")
+	ErrShiftClosed           = Errorf(ErrConflict, "shift is already closed")
+	ErrZReportNotFound       = Errorf(ErrNotFound, "z report not found")
+	ErrIdempotencyKeyClaimed = Errorf(ErrConflict, "idempotency key already belongs to another checkout")
+
+	ErrCategoryNameTaken = NewValidationError(models.FieldError{
+		Field:   "name",
+		Rule:    "unique",
+		Message: "a category with this name already exists",
+	})
+	ErrUnknownCategory = NewValidationError(models.FieldError{
+		Field:   "category_id",
+		Rule:    "exists",
+		Message: "category does not exist",
+	})
+	ErrUnknownTaxClass = NewValidationError(models.FieldError{
+		Field:   "tax_class_id",
+		Rule:    "exists",
+		Message: "tax class does not exist",
+	})
+	ErrTaxClassNameTaken = NewValidationError(models.FieldError{
+		Field:   "name",
+		Rule:    "unique",
+		Message: "a tax class with this name already exists",
+	})
+	ErrUsernameTaken = NewValidationError(models.FieldError{
+		Field:   "username",
+		Rule:    "unique",
+		Message: "this username is already taken",
+	})
+	ErrTerminalCodeTaken = NewValidationError(models.FieldError{
+		Field:   "code",
+		Rule:    "unique",
+		Message: "a terminal with this code already exists",
+	})
+	ErrSKUTaken = NewValidationError(models.FieldError{
+		Field:   "sku",
+		Rule:    "unique",
+		Message: "another product already has this SKU",
+	})
+	ErrBarcodeTaken = NewValidationError(models.FieldError{
+		Field:   "barcodes",
+		Rule:    "unique",
+		Message: "a barcode is already assigned to another product",
+	})
+	ErrSupplierNameTaken = NewValidationError(models.FieldError{
+		Field:   "name",
+		Rule:    "unique",
+		Message: "a supplier with this name already exists",
+	})
+	ErrUnknownSupplier = NewValidationError(models.FieldError{
+		Field:   "supplier_id",
+		Rule:    "exists",
+		Message: "supplier does not exist",
+	})
+	ErrUnknownPurchaseProduct = NewValidationError(models.FieldError{
+		Field:   "items",
+		Rule:    "exists",
+		Message: "an item refers to a product that does not exist",
+	})
+	ErrUnknownPromotionProduct = NewValidationError(models.FieldError{
+		Field:   "product_ids",
+		Rule:    "exists",
+		Message: "a product does not exist",
+	})
+	ErrUnknownPromotionCategory = NewValidationError(models.FieldError{
+		Field:   "category_ids",
+		Rule:    "exists",
+		Message: "a category does not exist",
+	})
+	ErrUnknownBundleProduct = NewValidationError(models.FieldError{
+		Field:   "bundle_items",
+		Rule:    "exists",
+		Message: "an item refers to a product that does not exist",
+	})
+	ErrUnknownVoucherProduct = NewValidationError(models.FieldError{
+		Field:   "product_ids",
+		Rule:    "exists",
+		Message: "a product does not exist",
+	})
+	ErrUnknownVoucherCategory = NewValidationError(models.FieldError{
+		Field:   "category_ids",
+		Rule:    "exists",
+		Message: "a category does not exist",
+	})
+	ErrUnknownVoucherCode = NewValidationError(models.FieldError{
+		Field:   "voucher_code",
+		Rule:    "exists",
+		Message: "voucher code does not exist",
+	})
+)
+
+// PurchaseOrderStatusError is returned when a purchase order is not in a status
+// that allows the requested change.
+func PurchaseOrderStatusError(status string) error {
+	return Errorf(ErrConflict, "purchase order is %s", status)
+}
+
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// Errorf formats an error of the given kind. The message is kept as is, while
+// errors.Is still matches both the kind and anything wrapped with %w.
+func Errorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+// InsufficientStockError is returned by CreateTransaction when one or more
+// items ask for more than the locked stock. It lists every offending product,
+// not only the first one found.
+type InsufficientStockError struct {
+	Items []models.StockShortage
+}
+
+func (e *InsufficientStockError) Error() string {
+	parts := make([]string, len(e.Items))
+	for i, item := range e.Items {
+		parts[i] = fmt.Sprintf("product id %d (requested %d, available %d)", item.ProductID, item.Requested, item.Available)
+	}
+	return "insufficient stock: " + strings.Join(parts, ", ")
+}
+
+func (e *InsufficientStockError) Unwrap() error { return ErrInsufficientStock }
+
+func (e *InsufficientStockError) Details() any { return e.Items }
+
+// PermissionError is returned when the user's role lacks a permission and
+// no approval stood in for it.
+type PermissionError struct {
+	Denied models.PermissionDenied
+}
+
+func (e *PermissionError) Error() string { return "missing permission " + e.Denied.Permission }
+
+func (e *PermissionError) Unwrap() error { return ErrForbidden }
+
+func (e *PermissionError) Details() any { return e.Denied }
+
+// ValidationError lists every invalid field of a request, so a client can
+// fix them all in one go.
+type ValidationError struct {
+	Fields []models.FieldError
+}
+
+func NewValidationError(fields ...models.FieldError) *ValidationError {
+	return &ValidationError{Fields: fields}
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
+
+func (e *ValidationError) Details() any { return e.Fields }
+
+// PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+func hasPQCode(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
+
+func hasPQConstraint(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Constraint == constraint
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims key for a new request. When the key is already taken it
+// returns the existing record and false instead.
+func (repo *IdempotencyRepository) Reserve(key string, fingerprint string, expiresAt time.Time) (*models.IdempotencyKey, bool, error) {
+	_, err := repo.db.Exec("DELETE FROM idempotency_keys WHERE key = $1 AND expires_at <= now()", key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	k := models.IdempotencyKey{Key: key, Fingerprint: fingerprint}
+	err = repo.db.QueryRow(
+		`INSERT INTO idempotency_keys (key, fingerprint, expires_at) VALUES ($1, $2, $3)
+						ON CONFLICT (key) DO NOTHING
+						RETURNING created_at, expires_at`,
+		key, fingerprint, expiresAt,
+	).Scan(&k.CreatedAt, &k.ExpiresAt)
+	if err == nil {
+		return &k, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	existing, err := repo.get(key)
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+func (repo *IdempotencyRepository) get(key string) (*models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	var transactionID sql.NullInt64
+	var statusCode sql.NullInt64
+
+	err := repo.db.QueryRow(
+		"SELECT key, fingerprint, transaction_id, status_code, response_body, created_at, expires_at FROM idempotency_keys WHERE key = $1",
+		key,
+	).Scan(&k.Key, &k.Fingerprint, &transactionID, &statusCode, &k.ResponseBody, &k.CreatedAt, &k.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if transactionID.Valid {
+		val := int(transactionID.Int64)
+		k.TransactionID = &val
+	}
+	k.StatusCode = int(statusCode.Int64)
+
+	return &k, nil
+}
+
+// claimIdempotencyKey records the checkout transactionID on its reserved key
+// and keeps the key until claim.ExpiresAt. A key that already has a
+// transaction, or whose reservation lapsed and was purged, fails with
+// ErrIdempotencyKeyClaimed, so no key ever books two checkouts.
+func claimIdempotencyKey(tx *sql.Tx, claim *models.IdempotencyClaim, transactionID int) error {
+	result, err := tx.Exec(
+		"UPDATE idempotency_keys SET transaction_id = $1, expires_at = $2 WHERE key = $3 AND transaction_id IS NULL",
+		transactionID, claim.ExpiresAt, claim.Key,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrIdempotencyKeyClaimed
+	}
+	return nil
+}
+
+func (repo *IdempotencyRepository) Complete(key string, transactionID int, statusCode int, responseBody []byte) error {
+	_, err := repo.db.Exec(
+		"UPDATE idempotency_keys SET transaction_id = $1, status_code = $2, response_body = $3 WHERE key = $4",
+		transactionID, statusCode, responseBody, key,
+	)
+	return err
+}
+
+// Release forgets a key whose request failed, so the client can retry it. A
+// key holding a checkout is kept.
+func (repo *IdempotencyRepository) Release(key string) error {
+	_, err := repo.db.Exec("DELETE FROM idempotency_keys WHERE key = $1 AND transaction_id IS NULL AND status_code IS NULL", key)
+	return err
+}
+
+func (repo *IdempotencyRepository) DeleteExpired() (int64, error) {
+	result, err := repo.db.Exec("DELETE FROM idempotency_keys WHERE expires_at <= now()")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+func TestIdempotencyReserveCompleteAndExpire(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewIdempotencyRepository(db)
+
+	_, created, err := repo.Reserve("key-1", "fp-a", time.Now().Add(time.Hour))
+	if err != nil || !created {
+		t.Fatalf("first reserve: created = %v, err = %v", created, err)
+	}
+
+	existing, created, err := repo.Reserve("key-1", "fp-a", time.Now().Add(time.Hour))
+	if err != nil || created {
+		t.Fatalf("second reserve: created = %v, err = %v", created, err)
+	}
+	if existing.Completed() {
+		t.Fatalf("in-flight key reported as completed")
+	}
+
+	if err := repo.Release("key-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, created, _ := repo.Reserve("key-1", "fp-b", time.Now().Add(-time.Second)); !created {
+		t.Fatalf("released key could not be reserved again")
+	}
+
+	// key-1 is now expired, so the next reservation replaces it.
+	if _, created, _ := repo.Reserve("key-1", "fp-c", time.Now().Add(time.Hour)); !created {
+		t.Fatalf("expired key could not be reserved again")
+	}
+
+	product := createTestProduct(t, db, "Indomie Goreng", 3500, 10)
+	claim := &models.IdempotencyClaim{Key: "key-1", ExpiresAt: time.Now().Add(time.Hour)}
+	transactions := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+	trx, err := transactions.CreateTransaction([]models.CheckoutItem{{ProductID: product, Quantity: 1}}, "", claim, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	// The key holds the committed checkout, so it is neither released nor
+	// claimed by a second one.
+	if err := repo.Release("key-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	claimed, created, err := repo.Reserve("key-1", "fp-c", time.Now().Add(time.Hour))
+	if err != nil || created || claimed.Completed() || claimed.TransactionID == nil || *claimed.TransactionID != trx.ID {
+		t.Fatalf("claimed key: got %+v, created = %v, err = %v", claimed, created, err)
+	}
+	if _, err := transactions.CreateTransaction([]models.CheckoutItem{{ProductID: product, Quantity: 1}}, "", claim, till); !errors.Is(err, ErrIdempotencyKeyClaimed) {
+		t.Errorf("second checkout on the key: got %v, want ErrIdempotencyKeyClaimed", err)
+	}
+	if err := repo.Complete("key-1", trx.ID, 200, []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	stored, created, err := repo.Reserve("key-1", "fp-c", time.Now().Add(time.Hour))
+	if err != nil || created {
+		t.Fatalf("reserve after complete: created = %v, err = %v", created, err)
+	}
+	if !stored.Completed() || string(stored.ResponseBody) != `{"id":1}` || *stored.TransactionID != trx.ID {
+		t.Errorf("unexpected stored key %+v", stored)
+	}
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"simple-cashier-api/models"
+)
+
+type InventoryRepository struct {
+	db *sql.DB
+}
+
+func NewInventoryRepository(db *sql.DB) *InventoryRepository {
+	return &InventoryRepository{db: db}
+}
+
+// AdjustStock posts a manual movement. The product row is locked so the
+// balance check and the update see the same stock.
+func (repo *InventoryRepository) AdjustStock(productID int, adj models.StockAdjustment) (*models.InventoryMovement, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var stock int
+	err = tx.QueryRow("SELECT stock FROM products WHERE id = $1 AND archived_at IS NULL FOR UPDATE", productID).Scan(&stock)
+	if err == sql.ErrNoRows {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stock+adj.Quantity < 0 {
+		return nil, errNegativeStock(stock)
+	}
+
+	movement := models.InventoryMovement{
+		ProductID:  productID,
+		Type:       adj.Type,
+		Quantity:   adj.Quantity,
+		Reason:     adj.Reason,
+		Actor:      adj.Actor,
+		ApprovedBy: adj.ApprovedBy,
+	}
+	if err := moveStock(tx, &movement); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &movement, nil
+}
+
+// GetStockHistory returns the movements of a product, newest first. The
+// ledger of an archived product can still be read.
+func (repo *InventoryRepository) GetStockHistory(filter models.StockHistoryFilter) ([]models.InventoryMovement, int, error) {
+	var total int
+	err := repo.db.QueryRow(
+		`SELECT (SELECT count(*) FROM inventory_movements WHERE product_id = p.id)
+		 FROM products p WHERE p.id = $1`,
+		filter.ProductID,
+	).Scan(&total)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT id, product_id, type, quantity, balance, reason, actor, approved_by, reference_type, reference_id, created_at
+		 FROM inventory_movements
+		 WHERE product_id = $1
+		 ORDER BY id DESC
+		 LIMIT $2 OFFSET $3`,
+		filter.ProductID, filter.Limit, (filter.Page-1)*filter.Limit,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	movements := make([]models.InventoryMovement, 0)
+	for rows.Next() {
+		var m models.InventoryMovement
+		var referenceType sql.NullString
+		var referenceID sql.NullInt64
+		err := rows.Scan(&m.ID, &m.ProductID, &m.Type, &m.Quantity, &m.Balance, &m.Reason, &m.Actor, &m.ApprovedBy, &referenceType, &referenceID, &m.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		m.ReferenceType = referenceType.String
+		if referenceID.Valid {
+			id := int(referenceID.Int64)
+			m.ReferenceID = &id
+		}
+		movements = append(movements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return movements, total, nil
+}
+
+// moveStock applies m.Quantity to the product's stock and records it in the
+// ledger, filling in m's ID, Balance and CreatedAt. It is the only place that
+// writes products.stock after a product is created, and it expects the
+// product row to be locked by the caller.
+func moveStock(tx *sql.Tx, m *models.InventoryMovement) error {
+	err := tx.QueryRow("UPDATE products SET stock = stock + $1 WHERE id = $2 RETURNING stock", m.Quantity, m.ProductID).Scan(&m.Balance)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.QueryRow(
+		`INSERT INTO inventory_movements (product_id, type, quantity, balance, reason, actor, approved_by, reference_type, reference_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, created_at`,
+		m.ProductID, m.Type, m.Quantity, m.Balance, m.Reason, m.Actor, m.ApprovedBy, nullIfEmpty(m.ReferenceType), m.ReferenceID,
+	).Scan(&m.ID, &m.CreatedAt)
+}
+
+func errNegativeStock(stock int) error {
+	return NewValidationError(models.FieldError{
+		Field:   "quantity",
+		Rule:    "min",
+		Message: fmt.Sprintf("would take the stock of %d below zero", stock),
+	})
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"strings"
+
+	"simple-cashier-api/models"
+)
+
+// orderBy builds an ORDER BY clause from sort, looking every field up in
+// columns so nothing from the request reaches the SQL text. The id column is
+// always the last key, which keeps page boundaries stable.
+func orderBy(sort []models.SortField, columns map[string]string) string {
+	keys := make([]string, 0, len(sort)+1)
+	sortedByID := false
+	for _, s := range sort {
+		column, ok := columns[s.Field]
+		if !ok {
+			continue
+		}
+		if s.Desc {
+			column += " DESC"
+		}
+		keys = append(keys, column)
+		sortedByID = sortedByID || s.Field == "id"
+	}
+	if !sortedByID {
+		keys = append(keys, columns["id"])
+	}
+	return " ORDER BY " + strings.Join(keys, ", ")
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type CategoryRepository struct {
+	store *Store
+}
+
+func NewCategoryRepository(store *Store) *CategoryRepository {
+	return &CategoryRepository{store: store}
+}
+
+var categorySortKeys = map[string]func(a, b models.Category) int{
+	"id":   func(a, b models.Category) int { return cmp.Compare(a.ID, b.ID) },
+	"name": func(a, b models.Category) int { return strings.Compare(a.Name, b.Name) },
+}
+
+func (repo *CategoryRepository) GetAll(filter models.CategoryFilter) ([]models.Category, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	categories := make([]models.Category, 0, len(repo.store.categories))
+	for _, c := range repo.store.categories {
+		if filter.Name != "" && !strings.Contains(strings.ToLower(c.Name), strings.ToLower(filter.Name)) {
+			continue
+		}
+		categories = append(categories, c)
+	}
+
+	sortRows(categories, filter.Sort, categorySortKeys)
+	return paginate(categories, filter.Page, filter.Limit), len(categories), nil
+}
+
+func (repo *CategoryRepository) Create(category *models.Category) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkCategoryName(category); err != nil {
+		return err
+	}
+
+	category.ID = repo.store.nextID("categories")
+	repo.store.categories[category.ID] = *category
+	return nil
+}
+
+func (repo *CategoryRepository) GetByID(id int) (*models.Category, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	c, ok := repo.store.categories[id]
+	if !ok {
+		return nil, repositories.ErrCategoryNotFound
+	}
+
+	return &c, nil
+}
+
+func (repo *CategoryRepository) Update(category *models.Category) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.categories[category.ID]; !ok {
+		return repositories.ErrCategoryNotFound
+	}
+	if err := repo.store.checkCategoryName(category); err != nil {
+		return err
+	}
+
+	repo.store.categories[category.ID] = *category
+	return nil
+}
+
+func (repo *CategoryRepository) Delete(id int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.categories[id]; !ok {
+		return repositories.ErrCategoryNotFound
+	}
+
+	delete(repo.store.categories, id)
+
+	// ON DELETE SET NULL
+	for productID, p := range repo.store.products {
+		if p.CategoryID != nil && *p.CategoryID == id {
+			p.CategoryID = nil
+			repo.store.products[productID] = p
+		}
+	}
+
+	// ON DELETE CASCADE on promotion_categories
+	for promotionID, p := range repo.store.promotions {
+		if slices.Contains(p.CategoryIDs, id) {
+			p.CategoryIDs = slices.DeleteFunc(slices.Clone(p.CategoryIDs), func(categoryID int) bool { return categoryID == id })
+			repo.store.promotions[promotionID] = p
+		}
+	}
+	// ON DELETE CASCADE on voucher_categories
+	for voucherID, v := range repo.store.vouchers {
+		if slices.Contains(v.CategoryIDs, id) {
+			v.CategoryIDs = slices.DeleteFunc(slices.Clone(v.CategoryIDs), func(categoryID int) bool { return categoryID == id })
+			repo.store.vouchers[voucherID] = v
+		}
+	}
+
+	return nil
+}
+
+// checkCategoryName enforces the case-insensitive categories_name_key index.
+func (s *Store) checkCategoryName(category *models.Category) error {
+	for id, c := range s.categories {
+		if id != category.ID && strings.EqualFold(c.Name, category.Name) {
+			return repositories.ErrCategoryNameTaken
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+type IdempotencyRepository struct {
+	store *Store
+}
+
+func NewIdempotencyRepository(store *Store) *IdempotencyRepository {
+	return &IdempotencyRepository{store: store}
+}
+
+func (repo *IdempotencyRepository) Reserve(key string, fingerprint string, expiresAt time.Time) (*models.IdempotencyKey, bool, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := s.idempotencyKeys[key]; ok {
+		if existing.ExpiresAt.After(now) {
+			k := *existing
+			return &k, false, nil
+		}
+		delete(s.idempotencyKeys, key)
+	}
+
+	k := models.IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   expiresAt,
+	}
+	stored := k
+	s.idempotencyKeys[key] = &stored
+
+	return &k, true, nil
+}
+
+func (repo *IdempotencyRepository) Complete(key string, transactionID int, statusCode int, responseBody []byte) error {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.idempotencyKeys[key]; ok {
+		k.TransactionID = &transactionID
+		k.StatusCode = statusCode
+		k.ResponseBody = append([]byte(nil), responseBody...)
+	}
+	return nil
+}
+
+func (repo *IdempotencyRepository) Release(key string) error {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.idempotencyKeys[key]; ok && k.TransactionID == nil && !k.Completed() {
+		delete(s.idempotencyKeys, key)
+	}
+	return nil
+}
+
+func (repo *IdempotencyRepository) DeleteExpired() (int64, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	now := time.Now()
+	for key, k := range s.idempotencyKeys {
+		if !k.ExpiresAt.After(now) {
+			delete(s.idempotencyKeys, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
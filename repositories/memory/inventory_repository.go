@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type InventoryRepository struct {
+	store *Store
+}
+
+func NewInventoryRepository(store *Store) *InventoryRepository {
+	return &InventoryRepository{store: store}
+}
+
+func (repo *InventoryRepository) AdjustStock(productID int, adj models.StockAdjustment) (*models.InventoryMovement, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[productID]
+	if !ok {
+		return nil, repositories.ErrProductNotFound
+	}
+	if p.Stock+adj.Quantity < 0 {
+		return nil, repositories.NewValidationError(models.FieldError{
+			Field:   "quantity",
+			Rule:    "min",
+			Message: fmt.Sprintf("would take the stock of %d below zero", p.Stock),
+		})
+	}
+
+	movement := s.moveStock(models.InventoryMovement{
+		ProductID:  productID,
+		Type:       adj.Type,
+		Quantity:   adj.Quantity,
+		Reason:     adj.Reason,
+		Actor:      adj.Actor,
+		ApprovedBy: adj.ApprovedBy,
+	})
+	return &movement, nil
+}
+
+func (repo *InventoryRepository) GetStockHistory(filter models.StockHistoryFilter) ([]models.InventoryMovement, int, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasHistory(filter.ProductID) {
+		return nil, 0, repositories.ErrProductNotFound
+	}
+
+	movements := make([]models.InventoryMovement, 0)
+	for i := len(s.movements) - 1; i >= 0; i-- {
+		if s.movements[i].ProductID == filter.ProductID {
+			movements = append(movements, copyMovement(s.movements[i]))
+		}
+	}
+
+	return paginate(movements, filter.Page, filter.Limit), len(movements), nil
+}
+
+// moveStock mirrors the PostgreSQL moveStock: it applies m.Quantity to the
+// product and appends m to the ledger. The caller must hold the write lock.
+func (s *Store) moveStock(m models.InventoryMovement) models.InventoryMovement {
+	p := s.products[m.ProductID]
+	p.Stock += m.Quantity
+	s.products[m.ProductID] = p
+
+	m.ID = s.nextID("inventory_movements")
+	m.Balance = p.Stock
+	m.CreatedAt = time.Now()
+	m.ReferenceID = copyIntPtr(m.ReferenceID)
+	s.movements = append(s.movements, m)
+
+	return copyMovement(m)
+}
+
+func copyMovement(m models.InventoryMovement) models.InventoryMovement {
+	m.ReferenceID = copyIntPtr(m.ReferenceID)
+	return m
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"slices"
+
+	"simple-cashier-api/models"
+)
+
+// sortRows orders rows the way repositories.orderBy does: by the requested
+// keys and then by id. keys must have an "id" entry.
+func sortRows[T any](rows []T, sort []models.SortField, keys map[string]func(a, b T) int) {
+	slices.SortFunc(rows, func(a, b T) int {
+		for _, s := range sort {
+			compare, ok := keys[s.Field]
+			if !ok {
+				continue
+			}
+			if c := compare(a, b); c != 0 {
+				if s.Desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return keys["id"](a, b)
+	})
+}
+
+// paginate returns the rows of the given 1-based page.
+func paginate[T any](rows []T, page, limit int) []T {
+	offset := (page - 1) * limit
+	if offset >= len(rows) {
+		return make([]T, 0)
+	}
+	return rows[offset:min(offset+limit, len(rows))]
+}
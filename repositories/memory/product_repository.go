@@ -0,0 +1,337 @@
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type ProductRepository struct {
+	store *Store
+}
+
+func NewProductRepository(store *Store) *ProductRepository {
+	return &ProductRepository{store: store}
+}
+
+var productSortKeys = map[string]func(a, b models.ProductDetail) int{
+	"id":    func(a, b models.ProductDetail) int { return cmp.Compare(a.ID, b.ID) },
+	"name":  func(a, b models.ProductDetail) int { return strings.Compare(a.Name, b.Name) },
+	"price": func(a, b models.ProductDetail) int { return cmp.Compare(a.Price, b.Price) },
+	"stock": func(a, b models.ProductDetail) int { return cmp.Compare(a.Stock, b.Stock) },
+}
+
+func (repo *ProductRepository) GetAll(filter models.ProductFilter) ([]models.ProductDetail, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	products := make([]models.ProductDetail, 0)
+	for _, p := range repo.store.products {
+		if !matchesProductFilter(p, filter) {
+			continue
+		}
+		products = append(products, repo.store.productDetail(p))
+	}
+
+	sortRows(products, filter.Sort, productSortKeys)
+	return paginate(products, filter.Page, filter.Limit), len(products), nil
+}
+
+func matchesProductFilter(p models.Product, filter models.ProductFilter) bool {
+	if filter.Name != "" && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.CategoryID != nil && (p.CategoryID == nil || *p.CategoryID != *filter.CategoryID) {
+		return false
+	}
+	if filter.MinPrice != nil && p.Price < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && p.Price > *filter.MaxPrice {
+		return false
+	}
+	if filter.InStock != nil && *filter.InStock != (p.Stock > 0) {
+		return false
+	}
+	if filter.LowStock != nil && *filter.LowStock != (p.Stock <= filter.LowStockThreshold) {
+		return false
+	}
+	return true
+}
+
+func (repo *ProductRepository) Create(product *models.Product) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkProduct(product); err != nil {
+		return err
+	}
+
+	product.ID = repo.store.nextID("products")
+
+	saved := *product
+	saved.Stock = 0
+	repo.store.saveProduct(saved)
+	repo.store.recordPriceChange(product.ID, nil, product.Price)
+	if product.Stock != 0 {
+		repo.store.moveStock(models.InventoryMovement{
+			ProductID: product.ID,
+			Type:      models.MovementTypeOpening,
+			Quantity:  product.Stock,
+			Reason:    "Initial stock",
+		})
+	}
+	return nil
+}
+
+func (repo *ProductRepository) GetByID(id int) (*models.ProductDetail, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	p, ok := repo.store.products[id]
+	if !ok {
+		return nil, repositories.ErrProductNotFound
+	}
+
+	detail := repo.store.productDetail(p)
+	return &detail, nil
+}
+
+func (repo *ProductRepository) GetByBarcode(barcode string) (*models.ProductDetail, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	id, ok := repo.store.barcodes[barcode]
+	if !ok {
+		return nil, repositories.ErrProductNotFound
+	}
+
+	detail := repo.store.productDetail(repo.store.products[id])
+	return &detail, nil
+}
+
+func (repo *ProductRepository) GetBySKU(sku string) (*models.ProductDetail, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	for _, id := range sortedKeys(repo.store.products) {
+		if p := repo.store.products[id]; p.SKU != "" && p.SKU == sku {
+			detail := repo.store.productDetail(p)
+			return &detail, nil
+		}
+	}
+	return nil, repositories.ErrProductNotFound
+}
+
+func (repo *ProductRepository) Update(update *models.ProductUpdate) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	product := &update.Product
+	current, ok := repo.store.products[product.ID]
+	if !ok {
+		return repositories.ErrProductNotFound
+	}
+	product.Stock = current.Stock
+	if update.CostPrice == nil {
+		product.CostPrice = current.CostPrice
+	}
+	if err := repo.store.checkProduct(product); err != nil {
+		return err
+	}
+
+	repo.store.saveProduct(*product)
+	if product.Price != current.Price {
+		repo.store.recordPriceChange(product.ID, &current.Price, product.Price)
+	}
+	if update.Stock != nil && *update.Stock != current.Stock {
+		repo.store.moveStock(models.InventoryMovement{
+			ProductID:  product.ID,
+			Type:       models.MovementTypeAdjustment,
+			Quantity:   *update.Stock - current.Stock,
+			Reason:     repositories.StockEditReason,
+			Actor:      update.Actor,
+			ApprovedBy: update.ApprovedBy,
+		})
+		product.Stock = *update.Stock
+	}
+	return nil
+}
+
+func (repo *ProductRepository) Delete(id int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.products[id]; !ok {
+		return repositories.ErrProductNotFound
+	}
+
+	for _, t := range repo.store.transactions {
+		for _, d := range t.Details {
+			if d.ProductID == id {
+				return repositories.ErrProductInUse
+			}
+		}
+	}
+	for _, po := range repo.store.purchaseOrders {
+		for _, item := range po.Items {
+			if item.ProductID == id {
+				return repositories.ErrProductInUse
+			}
+		}
+	}
+	for _, p := range repo.store.promotions {
+		for _, item := range p.BundleItems {
+			if item.ProductID == id {
+				return repositories.ErrProductInUse
+			}
+		}
+	}
+
+	// ON DELETE CASCADE on product_barcodes, promotion_products and
+	// voucher_products, which archiveProduct deletes too
+	for promotionID, p := range repo.store.promotions {
+		if slices.Contains(p.ProductIDs, id) {
+			p.ProductIDs = slices.DeleteFunc(slices.Clone(p.ProductIDs), func(productID int) bool { return productID == id })
+			repo.store.promotions[promotionID] = p
+		}
+	}
+	for voucherID, v := range repo.store.vouchers {
+		if slices.Contains(v.ProductIDs, id) {
+			v.ProductIDs = slices.DeleteFunc(slices.Clone(v.ProductIDs), func(productID int) bool { return productID == id })
+			repo.store.vouchers[voucherID] = v
+		}
+	}
+	for _, barcode := range repo.store.products[id].Barcodes {
+		delete(repo.store.barcodes, barcode)
+	}
+
+	// A product with stock movements is archived, like archiveProduct does:
+	// out of the catalogue, with its ledger and price history kept.
+	if slices.ContainsFunc(repo.store.movements, func(m models.InventoryMovement) bool { return m.ProductID == id }) {
+		archived := repo.store.products[id]
+		archived.SKU, archived.TaxClassID, archived.Barcodes = "", nil, []string{}
+		repo.store.archivedProducts[id] = archived
+		delete(repo.store.products, id)
+		return nil
+	}
+
+	// ON DELETE CASCADE on product_price_history
+	repo.store.priceHistory = slices.DeleteFunc(repo.store.priceHistory, func(c models.PriceChange) bool {
+		return c.ProductID == id
+	})
+	delete(repo.store.products, id)
+	return nil
+}
+
+// GetPriceHistory returns the price changes of a product, newest first.
+func (repo *ProductRepository) GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PriceChange, int, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasHistory(filter.ProductID) {
+		return nil, 0, repositories.ErrProductNotFound
+	}
+
+	changes := make([]models.PriceChange, 0)
+	for i := len(s.priceHistory) - 1; i >= 0; i-- {
+		if c := s.priceHistory[i]; c.ProductID == filter.ProductID {
+			c.OldPrice = copyIntPtr(c.OldPrice)
+			changes = append(changes, c)
+		}
+	}
+
+	return paginate(changes, filter.Page, filter.Limit), len(changes), nil
+}
+
+// recordPriceChange mirrors the PostgreSQL recordPriceChange. The caller must
+// hold the write lock.
+func (s *Store) recordPriceChange(productID int, oldPrice *int, newPrice int) {
+	s.priceHistory = append(s.priceHistory, models.PriceChange{
+		ID:        s.nextID("product_price_history"),
+		ProductID: productID,
+		OldPrice:  copyIntPtr(oldPrice),
+		NewPrice:  newPrice,
+		ChangedAt: time.Now(),
+	})
+}
+
+// checkProduct enforces the category and tax class foreign keys and the
+// unique SKU and barcode constraints.
+func (s *Store) checkProduct(product *models.Product) error {
+	if product.CategoryID != nil {
+		if _, ok := s.categories[*product.CategoryID]; !ok {
+			return repositories.ErrUnknownCategory
+		}
+	}
+	if product.TaxClassID != nil {
+		if _, ok := s.taxClasses[*product.TaxClassID]; !ok {
+			return repositories.ErrUnknownTaxClass
+		}
+	}
+	if product.SKU != "" {
+		for id, p := range s.products {
+			if id != product.ID && p.SKU == product.SKU {
+				return repositories.ErrSKUTaken
+			}
+		}
+	}
+	for _, barcode := range product.Barcodes {
+		if id, ok := s.barcodes[barcode]; ok && id != product.ID {
+			return repositories.ErrBarcodeTaken
+		}
+	}
+	return nil
+}
+
+// saveProduct stores p and reindexes its barcodes, which become exactly
+// p.Barcodes.
+func (s *Store) saveProduct(p models.Product) {
+	for _, barcode := range s.products[p.ID].Barcodes {
+		delete(s.barcodes, barcode)
+	}
+	for _, barcode := range p.Barcodes {
+		s.barcodes[barcode] = p.ID
+	}
+
+	p.CategoryID = copyIntPtr(p.CategoryID)
+	p.TaxClassID = copyIntPtr(p.TaxClassID)
+	p.Barcodes = sortedBarcodes(p.Barcodes)
+	s.products[p.ID] = p
+}
+
+func sortedBarcodes(barcodes []string) []string {
+	sorted := slices.Clone(barcodes)
+	if sorted == nil {
+		sorted = []string{}
+	}
+	slices.Sort(sorted)
+	return sorted
+}
+
+func (s *Store) productDetail(p models.Product) models.ProductDetail {
+	detail := models.ProductDetail{
+		ID:         p.ID,
+		Name:       p.Name,
+		SKU:        p.SKU,
+		Barcodes:   slices.Clone(p.Barcodes),
+		Price:      p.Price,
+		CostPrice:  p.CostPrice,
+		Stock:      p.Stock,
+		CategoryID: copyIntPtr(p.CategoryID),
+		TaxClassID: copyIntPtr(p.TaxClassID),
+	}
+
+	if p.CategoryID != nil {
+		if c, ok := s.categories[*p.CategoryID]; ok {
+			detail.Category = &c
+		}
+	}
+
+	return detail
+}
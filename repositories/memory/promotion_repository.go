@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type PromotionRepository struct {
+	store *Store
+}
+
+func NewPromotionRepository(store *Store) *PromotionRepository {
+	return &PromotionRepository{store: store}
+}
+
+func (repo *PromotionRepository) GetAll(filter models.PromotionFilter) ([]models.Promotion, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	promotions := make([]models.Promotion, 0)
+	for _, id := range sortedKeys(repo.store.promotions) {
+		p := repo.store.promotions[id]
+		if filter.Active != nil && p.Active != *filter.Active {
+			continue
+		}
+		promotions = append(promotions, copyPromotion(p))
+	}
+
+	return paginate(promotions, filter.Page, filter.Limit), len(promotions), nil
+}
+
+// GetActive returns the promotions that apply at the given time, by id.
+func (repo *PromotionRepository) GetActive(at time.Time) ([]models.Promotion, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	promotions := make([]models.Promotion, 0)
+	for _, id := range sortedKeys(repo.store.promotions) {
+		p := repo.store.promotions[id]
+		if !p.Active || p.StartsAt != nil && at.Before(*p.StartsAt) || p.EndsAt != nil && !at.Before(*p.EndsAt) {
+			continue
+		}
+		promotions = append(promotions, copyPromotion(p))
+	}
+	return promotions, nil
+}
+
+func (repo *PromotionRepository) Create(promotion *models.Promotion) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkPromotion(promotion); err != nil {
+		return err
+	}
+
+	promotion.ID = repo.store.nextID("promotions")
+	repo.store.promotions[promotion.ID] = copyPromotion(*promotion)
+	return nil
+}
+
+func (repo *PromotionRepository) GetByID(id int) (*models.Promotion, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	p, ok := repo.store.promotions[id]
+	if !ok {
+		return nil, repositories.ErrPromotionNotFound
+	}
+
+	p = copyPromotion(p)
+	return &p, nil
+}
+
+func (repo *PromotionRepository) Update(promotion *models.Promotion) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.promotions[promotion.ID]; !ok {
+		return repositories.ErrPromotionNotFound
+	}
+	if err := repo.store.checkPromotion(promotion); err != nil {
+		return err
+	}
+
+	repo.store.promotions[promotion.ID] = copyPromotion(*promotion)
+	return nil
+}
+
+func (repo *PromotionRepository) Delete(id int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.promotions[id]; !ok {
+		return repositories.ErrPromotionNotFound
+	}
+
+	delete(repo.store.promotions, id)
+
+	// ON DELETE SET NULL on transaction_promotions
+	for _, t := range repo.store.transactions {
+		for i, applied := range t.Promotions {
+			if applied.PromotionID != nil && *applied.PromotionID == id {
+				t.Promotions[i].PromotionID = nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkPromotion enforces the foreign keys of the promotion's scopes.
+func (s *Store) checkPromotion(promotion *models.Promotion) error {
+	for _, id := range promotion.ProductIDs {
+		if _, ok := s.products[id]; !ok {
+			return repositories.ErrUnknownPromotionProduct
+		}
+	}
+	for _, id := range promotion.CategoryIDs {
+		if _, ok := s.categories[id]; !ok {
+			return repositories.ErrUnknownPromotionCategory
+		}
+	}
+	for _, item := range promotion.BundleItems {
+		if _, ok := s.products[item.ProductID]; !ok {
+			return repositories.ErrUnknownBundleProduct
+		}
+	}
+	return nil
+}
+
+// copyPromotion returns p with its own scope slices, sorted like the
+// PostgreSQL repository returns them.
+func copyPromotion(p models.Promotion) models.Promotion {
+	p.ProductIDs = slices.Sorted(slices.Values(p.ProductIDs))
+	p.CategoryIDs = slices.Sorted(slices.Values(p.CategoryIDs))
+	p.BundleItems = slices.Clone(p.BundleItems)
+	slices.SortFunc(p.BundleItems, func(a, b models.BundleItem) int { return a.ProductID - b.ProductID })
+	p.StartsAt = copyTimePtr(p.StartsAt)
+	p.EndsAt = copyTimePtr(p.EndsAt)
+
+	if p.ProductIDs == nil {
+		p.ProductIDs = []int{}
+	}
+	if p.CategoryIDs == nil {
+		p.CategoryIDs = []int{}
+	}
+	if p.BundleItems == nil {
+		p.BundleItems = []models.BundleItem{}
+	}
+	return p
+}
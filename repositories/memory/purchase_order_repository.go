@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type PurchaseOrderRepository struct {
+	store *Store
+}
+
+func NewPurchaseOrderRepository(store *Store) *PurchaseOrderRepository {
+	return &PurchaseOrderRepository{store: store}
+}
+
+func (repo *PurchaseOrderRepository) GetAll(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, int, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := sortedKeys(s.purchaseOrders)
+	orders := make([]models.PurchaseOrder, 0)
+	for i := len(ids) - 1; i >= 0; i-- {
+		po := s.purchaseOrders[ids[i]]
+		if filter.Status != "" && po.Status != filter.Status {
+			continue
+		}
+		if filter.SupplierID != nil && po.SupplierID != *filter.SupplierID {
+			continue
+		}
+		orders = append(orders, s.purchaseOrderDetail(po))
+	}
+
+	return paginate(orders, filter.Page, filter.Limit), len(orders), nil
+}
+
+func (repo *PurchaseOrderRepository) GetByID(id int) (*models.PurchaseOrder, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	po, ok := s.purchaseOrders[id]
+	if !ok {
+		return nil, repositories.ErrPurchaseOrderNotFound
+	}
+
+	detail := s.purchaseOrderDetail(po)
+	return &detail, nil
+}
+
+func (repo *PurchaseOrderRepository) Create(po *models.PurchaseOrder) error {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkPurchaseOrder(po); err != nil {
+		return err
+	}
+
+	stored := &models.PurchaseOrder{
+		ID:         s.nextID("purchase_orders"),
+		SupplierID: po.SupplierID,
+		Status:     models.PurchaseOrderStatusDraft,
+		Notes:      po.Notes,
+		TotalCost:  po.TotalCost,
+		CreatedAt:  time.Now(),
+	}
+	stored.Items = s.newPurchaseOrderItems(stored.ID, po.Items)
+	s.purchaseOrders[stored.ID] = stored
+
+	*po = s.purchaseOrderDetail(stored)
+	return nil
+}
+
+func (repo *PurchaseOrderRepository) Update(po *models.PurchaseOrder) error {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.purchaseOrders[po.ID]
+	if !ok {
+		return repositories.ErrPurchaseOrderNotFound
+	}
+	if stored.Status != models.PurchaseOrderStatusDraft {
+		return repositories.PurchaseOrderStatusError(stored.Status)
+	}
+	if err := s.checkPurchaseOrder(po); err != nil {
+		return err
+	}
+
+	stored.SupplierID = po.SupplierID
+	stored.Notes = po.Notes
+	stored.TotalCost = po.TotalCost
+	stored.Items = s.newPurchaseOrderItems(stored.ID, po.Items)
+
+	*po = s.purchaseOrderDetail(stored)
+	return nil
+}
+
+func (repo *PurchaseOrderRepository) SetStatus(id int, from []string, to string) (*models.PurchaseOrder, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	po, ok := s.purchaseOrders[id]
+	if !ok {
+		return nil, repositories.ErrPurchaseOrderNotFound
+	}
+	if !slices.Contains(from, po.Status) {
+		return nil, repositories.PurchaseOrderStatusError(po.Status)
+	}
+
+	po.Status = to
+	if to == models.PurchaseOrderStatusOrdered {
+		now := time.Now()
+		po.OrderedAt = &now
+	}
+
+	detail := s.purchaseOrderDetail(po)
+	return &detail, nil
+}
+
+// Receive mirrors the PostgreSQL implementation: everything is checked
+// before any stock moves.
+func (repo *PurchaseOrderRepository) Receive(id int, req models.ReceiveRequest) (*models.PurchaseOrder, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	po, ok := s.purchaseOrders[id]
+	if !ok {
+		return nil, repositories.ErrPurchaseOrderNotFound
+	}
+	if po.Status != models.PurchaseOrderStatusOrdered && po.Status != models.PurchaseOrderStatusPartiallyReceived {
+		return nil, repositories.PurchaseOrderStatusError(po.Status)
+	}
+
+	index := make(map[int]int, len(po.Items))
+	for i, item := range po.Items {
+		index[item.ID] = i
+	}
+
+	var fields []models.FieldError
+	for i, r := range req.Items {
+		j, ok := index[r.ItemID]
+		if !ok {
+			fields = append(fields, models.FieldError{
+				Field:   fmt.Sprintf("items[%d].item_id", i),
+				Rule:    "exists",
+				Message: "is not an item of this purchase order",
+			})
+			continue
+		}
+		if remaining := po.Items[j].Quantity - po.Items[j].ReceivedQuantity; r.Quantity > remaining {
+			fields = append(fields, models.FieldError{
+				Field:   fmt.Sprintf("items[%d].quantity", i),
+				Rule:    "max",
+				Message: fmt.Sprintf("only %d unit(s) are still expected", remaining),
+			})
+		}
+	}
+	if len(fields) > 0 {
+		return nil, repositories.NewValidationError(fields...)
+	}
+
+	received := slices.Clone(req.Items)
+	slices.SortFunc(received, func(a, b models.ReceiveItem) int {
+		return po.Items[index[a.ItemID]].ProductID - po.Items[index[b.ItemID]].ProductID
+	})
+	for _, r := range received {
+		item := &po.Items[index[r.ItemID]]
+		item.ReceivedQuantity += r.Quantity
+
+		p := s.products[item.ProductID]
+		p.CostPrice = repositories.MovingAverageCost(p.Stock, p.CostPrice, r.Quantity, item.UnitCost)
+		s.products[item.ProductID] = p
+		s.moveStock(models.InventoryMovement{
+			ProductID:     item.ProductID,
+			Type:          models.MovementTypeRestock,
+			Quantity:      r.Quantity,
+			Reason:        fmt.Sprintf("Received on purchase order %d", id),
+			Actor:         req.Actor,
+			ReferenceType: models.ReferenceTypePurchaseOrder,
+			ReferenceID:   &id,
+		})
+	}
+
+	po.Status = models.PurchaseOrderStatusReceived
+	for _, item := range po.Items {
+		if item.ReceivedQuantity < item.Quantity {
+			po.Status = models.PurchaseOrderStatusPartiallyReceived
+			break
+		}
+	}
+	if po.Status == models.PurchaseOrderStatusReceived {
+		now := time.Now()
+		po.ReceivedAt = &now
+	}
+
+	detail := s.purchaseOrderDetail(po)
+	return &detail, nil
+}
+
+// checkPurchaseOrder enforces the supplier and product foreign keys.
+func (s *Store) checkPurchaseOrder(po *models.PurchaseOrder) error {
+	if _, ok := s.suppliers[po.SupplierID]; !ok {
+		return repositories.ErrUnknownSupplier
+	}
+	for _, item := range po.Items {
+		if _, ok := s.products[item.ProductID]; !ok {
+			return repositories.ErrUnknownPurchaseProduct
+		}
+	}
+	return nil
+}
+
+func (s *Store) newPurchaseOrderItems(orderID int, items []models.PurchaseOrderItem) []models.PurchaseOrderItem {
+	stored := make([]models.PurchaseOrderItem, len(items))
+	for i, item := range items {
+		stored[i] = models.PurchaseOrderItem{
+			ID:              s.nextID("purchase_order_items"),
+			PurchaseOrderID: orderID,
+			ProductID:       item.ProductID,
+			Quantity:        item.Quantity,
+			UnitCost:        item.UnitCost,
+		}
+	}
+	return stored
+}
+
+// purchaseOrderDetail returns a copy of po with the supplier and product
+// names and subtotals filled in, as the PostgreSQL queries return it.
+func (s *Store) purchaseOrderDetail(po *models.PurchaseOrder) models.PurchaseOrder {
+	detail := *po
+	detail.SupplierName = s.suppliers[po.SupplierID].Name
+	detail.OrderedAt = copyTimePtr(po.OrderedAt)
+	detail.ReceivedAt = copyTimePtr(po.ReceivedAt)
+	detail.Items = make([]models.PurchaseOrderItem, len(po.Items))
+	for i, item := range po.Items {
+		item.ProductName = s.products[item.ProductID].Name
+		item.Subtotal = item.Quantity * item.UnitCost
+		detail.Items[i] = item
+	}
+	return detail
+}
+
+func copyTimePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
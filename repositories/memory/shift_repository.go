@@ -0,0 +1,307 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type ShiftRepository struct {
+	store *Store
+}
+
+func NewShiftRepository(store *Store) *ShiftRepository {
+	return &ShiftRepository{store: store}
+}
+
+// GetAll lists shifts, newest first.
+func (repo *ShiftRepository) GetAll(filter models.ShiftFilter) ([]models.Shift, int, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := sortedKeys(s.shifts)
+	slices.Reverse(ids)
+
+	shifts := make([]models.Shift, 0)
+	for _, id := range ids {
+		shift := s.shifts[id]
+		if filter.TerminalID != nil && shift.TerminalID != *filter.TerminalID {
+			continue
+		}
+		if filter.UserID != nil && (shift.UserID == nil || *shift.UserID != *filter.UserID) {
+			continue
+		}
+		if filter.Status != "" && shift.Status != filter.Status {
+			continue
+		}
+		shifts = append(shifts, s.shiftView(shift))
+	}
+
+	return paginate(shifts, filter.Page, filter.Limit), len(shifts), nil
+}
+
+func (repo *ShiftRepository) GetByID(id int) (*models.Shift, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shift, ok := s.shifts[id]
+	if !ok {
+		return nil, repositories.ErrShiftNotFound
+	}
+	view := s.shiftView(shift)
+	return &view, nil
+}
+
+func (repo *ShiftRepository) GetOpen(terminalID int) (*models.Shift, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shift := s.openShift(terminalID)
+	if shift == nil {
+		return nil, repositories.ErrNoOpenShift
+	}
+	view := s.shiftView(shift)
+	return &view, nil
+}
+
+func (repo *ShiftRepository) Open(shift *models.Shift) error {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.terminals[shift.TerminalID]; !ok {
+		return repositories.ErrTerminalNotFound
+	}
+	if s.openShift(shift.TerminalID) != nil {
+		return repositories.ErrShiftAlreadyOpen
+	}
+	for _, other := range s.shifts {
+		if other.Status == models.ShiftStatusOpen && shift.UserID != nil && other.UserID != nil && *other.UserID == *shift.UserID {
+			return repositories.ErrCashierShiftOpen
+		}
+	}
+
+	stored := models.Shift{
+		ID:           s.nextID("shifts"),
+		TerminalID:   shift.TerminalID,
+		UserID:       copyIntPtr(shift.UserID),
+		Cashier:      shift.Cashier,
+		Status:       models.ShiftStatusOpen,
+		OpeningFloat: shift.OpeningFloat,
+		OpenedAt:     time.Now(),
+		CashCounts:   make([]models.CashCount, 0),
+		Movements:    make([]models.CashMovement, 0),
+	}
+	s.shifts[stored.ID] = &stored
+
+	*shift = s.shiftView(&stored)
+	return nil
+}
+
+func (repo *ShiftRepository) AddCashMovement(shiftID int, m *models.CashMovement) error {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shift, ok := s.shifts[shiftID]
+	if !ok {
+		return repositories.ErrShiftNotFound
+	}
+	if shift.Status != models.ShiftStatusOpen {
+		return repositories.ErrShiftClosed
+	}
+
+	m.ID = s.nextID("shift_cash_movements")
+	m.ShiftID = shiftID
+	m.CreatedAt = time.Now()
+	stored := *m
+	stored.UserID = copyIntPtr(m.UserID)
+	shift.Movements = append(shift.Movements, stored)
+	return nil
+}
+
+// Close mirrors the PostgreSQL implementation: the cash figures are frozen
+// on the shift with the counts and their variance.
+func (repo *ShiftRepository) Close(id int, counts []models.CashCount, note, closedBy string,
+	summarize func(*models.Shift, *models.ShiftSales) models.ShiftReport) (*models.Shift, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shift, ok := s.shifts[id]
+	if !ok {
+		return nil, repositories.ErrShiftNotFound
+	}
+	if shift.Status != models.ShiftStatusOpen {
+		return nil, repositories.ErrShiftClosed
+	}
+
+	*shift = s.shiftView(shift)
+
+	counted := 0
+	shift.CashCounts = make([]models.CashCount, 0, len(counts))
+	for _, c := range counts {
+		c.Amount = c.Denomination * c.Count
+		counted += c.Amount
+		shift.CashCounts = append(shift.CashCounts, c)
+	}
+	slices.SortFunc(shift.CashCounts, func(a, b models.CashCount) int { return b.Denomination - a.Denomination })
+
+	variance := counted - shift.ExpectedCash
+	now := time.Now()
+	shift.Status = models.ShiftStatusClosed
+	shift.CountedCash = &counted
+	shift.Variance = &variance
+	shift.Note = note
+	shift.ClosedBy = closedBy
+	shift.ClosedAt = &now
+
+	view := s.shiftView(shift)
+	sales := s.shiftSales(id)
+	report := summarize(&view, &sales)
+	s.insertZReport(&report)
+	return &view, nil
+}
+
+func (repo *ShiftRepository) GetSales(id int) (*models.ShiftSales, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.shifts[id]; !ok {
+		return nil, repositories.ErrShiftNotFound
+	}
+	sales := s.shiftSales(id)
+	return &sales, nil
+}
+
+// shiftSales mirrors the PostgreSQL getShiftSales. The caller must hold the
+// lock.
+func (s *Store) shiftSales(shiftID int) models.ShiftSales {
+	sales := models.ShiftSales{}
+	payments := map[string]*models.PaymentTotal{}
+	givenBack := map[string]int{}
+	for _, id := range sortedKeys(s.transactions) {
+		t := s.transactions[id]
+		for _, r := range t.Refunds {
+			if r.ShiftID == nil || *r.ShiftID != shiftID {
+				continue
+			}
+			for method, amount := range repositories.RefundByMethod(r.TotalAmount, r.CashAmount, t.Payments) {
+				givenBack[method] += amount
+			}
+			if r.Type == models.RefundTypeVoid {
+				sales.VoidCount++
+				sales.Voids += r.TotalAmount
+			} else {
+				sales.RefundCount++
+				sales.Refunds += r.TotalAmount
+			}
+			for _, d := range r.Details {
+				sales.GivenBackServiceCharge += d.ServiceCharge
+				sales.GivenBackTax += d.TaxAmount
+			}
+		}
+
+		if t.ShiftID == nil || *t.ShiftID != shiftID {
+			continue
+		}
+		if sales.FirstTransactionID == 0 {
+			sales.FirstTransactionID = t.ID
+		}
+		sales.LastTransactionID = t.ID
+		kept := t.Status != models.TransactionStatusVoided
+		if kept {
+			sales.Transactions++
+			sales.KeptTotal += t.TotalAmount
+		}
+		sales.Subtotal += t.Subtotal
+		sales.Discounts += t.DiscountAmount
+		sales.ServiceCharge += t.ServiceCharge
+		sales.Tax += t.TaxAmount
+		sales.Total += t.TotalAmount
+
+		paidWith := map[string]bool{}
+		for _, p := range t.Payments {
+			total, ok := payments[p.Method]
+			if !ok {
+				total = &models.PaymentTotal{Method: p.Method}
+				payments[p.Method] = total
+			}
+			total.Amount += p.Amount
+			if kept && !paidWith[p.Method] {
+				total.Transactions++
+			}
+			paidWith[p.Method] = true
+		}
+		if paidWith[models.PaymentMethodCash] {
+			payments[models.PaymentMethodCash].Amount -= t.ChangeAmount
+		}
+	}
+
+	sales.Payments = repositories.NetPayments(payments, givenBack)
+	return sales
+}
+
+// openShift returns the open shift of a terminal, or nil. The caller must
+// hold the lock.
+func (s *Store) openShift(terminalID int) *models.Shift {
+	for _, shift := range s.shifts {
+		if shift.TerminalID == terminalID && shift.Status == models.ShiftStatusOpen {
+			return shift
+		}
+	}
+	return nil
+}
+
+// shiftView returns a copy of shift with the cash figures of an open shift
+// computed from its transactions, refunds and movements, like shiftSelect
+// does.
+func (s *Store) shiftView(shift *models.Shift) models.Shift {
+	view := *shift
+	view.UserID = copyIntPtr(shift.UserID)
+	view.CountedCash = copyIntPtr(shift.CountedCash)
+	view.Variance = copyIntPtr(shift.Variance)
+	view.CashCounts = append(make([]models.CashCount, 0, len(shift.CashCounts)), shift.CashCounts...)
+	view.Movements = make([]models.CashMovement, len(shift.Movements))
+	view.CashIn, view.CashOut = 0, 0
+	for i, m := range shift.Movements {
+		m.UserID = copyIntPtr(m.UserID)
+		view.Movements[i] = m
+		if m.Type == models.CashMovementIn {
+			view.CashIn += m.Amount
+		} else {
+			view.CashOut += m.Amount
+		}
+	}
+	if shift.ClosedAt != nil {
+		closedAt := *shift.ClosedAt
+		view.ClosedAt = &closedAt
+	}
+	if shift.Status != models.ShiftStatusOpen {
+		return view
+	}
+
+	view.CashSales, view.CashRefunds, view.TransactionCount = 0, 0, 0
+	for _, t := range s.transactions {
+		for _, r := range t.Refunds {
+			if r.ShiftID != nil && *r.ShiftID == shift.ID {
+				view.CashRefunds += r.CashAmount
+			}
+		}
+		if t.ShiftID == nil || *t.ShiftID != shift.ID {
+			continue
+		}
+		view.CashSales += cashKept(t)
+		if t.Status != models.TransactionStatusVoided {
+			view.TransactionCount++
+		}
+	}
+	view.ExpectedCash = view.OpeningFloat + view.CashSales - view.CashRefunds + view.CashIn - view.CashOut
+	return view
+}
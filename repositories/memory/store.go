@@ -0,0 +1,98 @@
+// Package memory is an in-memory, concurrency-safe implementation of the
+// repositories with the same semantics as the PostgreSQL ones. It is selected
+// with DB_DRIVER=memory for demos and tests that must run without a database.
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"simple-cashier-api/models"
+)
+
+// Store holds every table behind a single lock, which gives checkout, voids
+// and refunds the same all-or-nothing behaviour as a database transaction.
+type Store struct {
+	mu sync.RWMutex
+
+	categories map[int]models.Category
+	products   map[int]models.Product
+	// archivedProducts are out of the catalogue but keep their stock and
+	// price history, like products.archived_at.
+	archivedProducts map[int]models.Product
+	barcodes         map[string]int
+	transactions     map[int]*models.Transaction
+	idempotencyKeys  map[string]*models.IdempotencyKey
+	movements        []models.InventoryMovement
+	priceHistory     []models.PriceChange
+	suppliers        map[int]models.Supplier
+	purchaseOrders   map[int]*models.PurchaseOrder
+	promotions       map[int]models.Promotion
+	vouchers         map[int]models.Voucher
+	voucherCodes     map[int]models.VoucherCode
+	codes            map[string]int
+	taxClasses       map[int]models.TaxClass
+	users            map[int]models.User
+	terminals        map[int]models.Terminal
+	shifts           map[int]*models.Shift
+	zReports         map[int]models.ShiftReport
+
+	sequences map[string]int
+}
+
+func NewStore() *Store {
+	return &Store{
+		categories:       map[int]models.Category{},
+		products:         map[int]models.Product{},
+		archivedProducts: map[int]models.Product{},
+		barcodes:         map[string]int{},
+		transactions:     map[int]*models.Transaction{},
+		idempotencyKeys:  map[string]*models.IdempotencyKey{},
+		suppliers:        map[int]models.Supplier{},
+		purchaseOrders:   map[int]*models.PurchaseOrder{},
+		promotions:       map[int]models.Promotion{},
+		vouchers:         map[int]models.Voucher{},
+		voucherCodes:     map[int]models.VoucherCode{},
+		codes:            map[string]int{},
+		taxClasses:       map[int]models.TaxClass{},
+		users:            map[int]models.User{},
+		terminals:        map[int]models.Terminal{},
+		shifts:           map[int]*models.Shift{},
+		zReports:         map[int]models.ShiftReport{},
+		sequences:        map[string]int{},
+	}
+}
+
+// nextID works like a SERIAL column. The caller must hold the write lock.
+func (s *Store) nextID(table string) int {
+	s.sequences[table]++
+	return s.sequences[table]
+}
+
+// hasHistory reports whether id is a product whose stock and price history
+// can be read, archived ones included. The caller must hold the lock.
+func (s *Store) hasHistory(id int) bool {
+	_, ok := s.products[id]
+	if !ok {
+		_, ok = s.archivedProducts[id]
+	}
+	return ok
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func copyIntPtr(p *int) *int {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"cmp"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type SupplierRepository struct {
+	store *Store
+}
+
+func NewSupplierRepository(store *Store) *SupplierRepository {
+	return &SupplierRepository{store: store}
+}
+
+var supplierSortKeys = map[string]func(a, b models.Supplier) int{
+	"id":   func(a, b models.Supplier) int { return cmp.Compare(a.ID, b.ID) },
+	"name": func(a, b models.Supplier) int { return strings.Compare(a.Name, b.Name) },
+}
+
+func (repo *SupplierRepository) GetAll(filter models.SupplierFilter) ([]models.Supplier, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	suppliers := make([]models.Supplier, 0, len(repo.store.suppliers))
+	for _, s := range repo.store.suppliers {
+		if filter.Name != "" && !strings.Contains(strings.ToLower(s.Name), strings.ToLower(filter.Name)) {
+			continue
+		}
+		suppliers = append(suppliers, s)
+	}
+
+	sortRows(suppliers, filter.Sort, supplierSortKeys)
+	return paginate(suppliers, filter.Page, filter.Limit), len(suppliers), nil
+}
+
+func (repo *SupplierRepository) Create(supplier *models.Supplier) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkSupplierName(supplier); err != nil {
+		return err
+	}
+
+	supplier.ID = repo.store.nextID("suppliers")
+	repo.store.suppliers[supplier.ID] = *supplier
+	return nil
+}
+
+func (repo *SupplierRepository) GetByID(id int) (*models.Supplier, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	s, ok := repo.store.suppliers[id]
+	if !ok {
+		return nil, repositories.ErrSupplierNotFound
+	}
+
+	return &s, nil
+}
+
+func (repo *SupplierRepository) Update(supplier *models.Supplier) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.suppliers[supplier.ID]; !ok {
+		return repositories.ErrSupplierNotFound
+	}
+	if err := repo.store.checkSupplierName(supplier); err != nil {
+		return err
+	}
+
+	repo.store.suppliers[supplier.ID] = *supplier
+	return nil
+}
+
+func (repo *SupplierRepository) Delete(id int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.suppliers[id]; !ok {
+		return repositories.ErrSupplierNotFound
+	}
+	for _, po := range repo.store.purchaseOrders {
+		if po.SupplierID == id {
+			return repositories.ErrSupplierInUse
+		}
+	}
+
+	delete(repo.store.suppliers, id)
+	return nil
+}
+
+// checkSupplierName enforces the case-insensitive suppliers_name_key index.
+func (s *Store) checkSupplierName(supplier *models.Supplier) error {
+	for id, other := range s.suppliers {
+		if id != supplier.ID && strings.EqualFold(other.Name, supplier.Name) {
+			return repositories.ErrSupplierNameTaken
+		}
+	}
+	return nil
+}
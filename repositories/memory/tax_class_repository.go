@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type TaxClassRepository struct {
+	store *Store
+}
+
+func NewTaxClassRepository(store *Store) *TaxClassRepository {
+	return &TaxClassRepository{store: store}
+}
+
+func (repo *TaxClassRepository) GetAll(filter models.TaxClassFilter) ([]models.TaxClass, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	classes := make([]models.TaxClass, 0, len(repo.store.taxClasses))
+	for _, id := range sortedKeys(repo.store.taxClasses) {
+		classes = append(classes, repo.store.taxClasses[id])
+	}
+
+	return paginate(classes, filter.Page, filter.Limit), len(classes), nil
+}
+
+func (repo *TaxClassRepository) GetRates() (map[int]int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	rates := make(map[int]int, len(repo.store.taxClasses))
+	for id, c := range repo.store.taxClasses {
+		rates[id] = c.RateBP
+	}
+	return rates, nil
+}
+
+func (repo *TaxClassRepository) Create(class *models.TaxClass) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkTaxClassName(class); err != nil {
+		return err
+	}
+
+	class.ID = repo.store.nextID("tax_classes")
+	repo.store.taxClasses[class.ID] = *class
+	return nil
+}
+
+func (repo *TaxClassRepository) GetByID(id int) (*models.TaxClass, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	c, ok := repo.store.taxClasses[id]
+	if !ok {
+		return nil, repositories.ErrTaxClassNotFound
+	}
+
+	return &c, nil
+}
+
+func (repo *TaxClassRepository) Update(class *models.TaxClass) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.taxClasses[class.ID]; !ok {
+		return repositories.ErrTaxClassNotFound
+	}
+	if err := repo.store.checkTaxClassName(class); err != nil {
+		return err
+	}
+
+	repo.store.taxClasses[class.ID] = *class
+	return nil
+}
+
+func (repo *TaxClassRepository) Delete(id int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.taxClasses[id]; !ok {
+		return repositories.ErrTaxClassNotFound
+	}
+	for _, p := range repo.store.products {
+		if p.TaxClassID != nil && *p.TaxClassID == id {
+			return repositories.ErrTaxClassInUse
+		}
+	}
+
+	delete(repo.store.taxClasses, id)
+	return nil
+}
+
+// checkTaxClassName enforces the case-insensitive tax_classes_name_key index.
+func (s *Store) checkTaxClassName(class *models.TaxClass) error {
+	for id, other := range s.taxClasses {
+		if id != class.ID && strings.EqualFold(other.Name, class.Name) {
+			return repositories.ErrTaxClassNameTaken
+		}
+	}
+	return nil
+}
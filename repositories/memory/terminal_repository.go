@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type TerminalRepository struct {
+	store *Store
+}
+
+func NewTerminalRepository(store *Store) *TerminalRepository {
+	return &TerminalRepository{store: store}
+}
+
+func (repo *TerminalRepository) GetAll(filter models.TerminalFilter) ([]models.Terminal, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	terminals := make([]models.Terminal, 0)
+	for _, id := range sortedKeys(repo.store.terminals) {
+		t := repo.store.terminals[id]
+		if filter.Active != nil && t.Active != *filter.Active {
+			continue
+		}
+		terminals = append(terminals, t)
+	}
+
+	return paginate(terminals, filter.Page, filter.Limit), len(terminals), nil
+}
+
+func (repo *TerminalRepository) Create(terminal *models.Terminal) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkTerminalCode(terminal); err != nil {
+		return err
+	}
+
+	terminal.ID = repo.store.nextID("terminals")
+	terminal.CreatedAt = time.Now()
+	stored := *terminal
+	stored.Key = ""
+	repo.store.terminals[terminal.ID] = stored
+	return nil
+}
+
+func (repo *TerminalRepository) GetByID(id int) (*models.Terminal, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	t, ok := repo.store.terminals[id]
+	if !ok {
+		return nil, repositories.ErrTerminalNotFound
+	}
+	return &t, nil
+}
+
+func (repo *TerminalRepository) GetByKeyHash(keyHash string) (*models.Terminal, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	for _, t := range repo.store.terminals {
+		if t.KeyHash == keyHash {
+			return &t, nil
+		}
+	}
+	return nil, repositories.ErrTerminalNotFound
+}
+
+func (repo *TerminalRepository) Update(terminal *models.Terminal) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	existing, ok := repo.store.terminals[terminal.ID]
+	if !ok {
+		return repositories.ErrTerminalNotFound
+	}
+	if err := repo.store.checkTerminalCode(terminal); err != nil {
+		return err
+	}
+
+	terminal.KeyHash = existing.KeyHash
+	terminal.CreatedAt = existing.CreatedAt
+	repo.store.terminals[terminal.ID] = *terminal
+	return nil
+}
+
+// checkTerminalCode enforces the terminals_code_key index.
+func (s *Store) checkTerminalCode(terminal *models.Terminal) error {
+	for id, other := range s.terminals {
+		if id != terminal.ID && other.Code == terminal.Code {
+			return repositories.ErrTerminalCodeTaken
+		}
+	}
+	return nil
+}
@@ -0,0 +1,653 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type TransactionRepository struct {
+	store *Store
+}
+
+func NewTransactionRepository(store *Store) *TransactionRepository {
+	return &TransactionRepository{store: store}
+}
+
+// CreateTransaction mirrors the PostgreSQL implementation. The store's write
+// lock is held for the whole checkout, so finalize must not call back into
+// the store.
+func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem, voucherCode string, claim *models.IdempotencyClaim,
+	finalize repositories.CheckoutFinalizer) (*models.Transaction, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requested := map[int]int{}
+	for _, item := range items {
+		requested[item.ProductID] += item.Quantity
+	}
+	productIDs := sortedKeys(requested)
+
+	shortages := make([]models.StockShortage, 0)
+	for _, id := range productIDs {
+		p, ok := s.products[id]
+		if !ok {
+			return nil, repositories.Errorf(repositories.ErrValidation, "product id %d not found", id)
+		}
+		if requested[id] > p.Stock {
+			shortages = append(shortages, models.StockShortage{
+				ProductID:   id,
+				ProductName: p.Name,
+				Requested:   requested[id],
+				Available:   p.Stock,
+			})
+		}
+	}
+	if len(shortages) > 0 {
+		return nil, &repositories.InsufficientStockError{Items: shortages}
+	}
+
+	trx := models.Transaction{
+		Status:     models.TransactionStatusCompleted,
+		Details:    make([]models.TransactionDetail, 0, len(items)),
+		Promotions: make([]models.AppliedPromotion, 0),
+		Payments:   make([]models.Payment, 0),
+	}
+	for _, item := range items {
+		p := s.products[item.ProductID]
+		subtotal := p.Price * item.Quantity
+		trx.Subtotal += subtotal
+		trx.TotalAmount += subtotal
+
+		trx.Details = append(trx.Details, models.TransactionDetail{
+			ProductID:   item.ProductID,
+			ProductName: p.Name,
+			CategoryID:  copyIntPtr(p.CategoryID),
+			TaxClassID:  copyIntPtr(p.TaxClassID),
+			Quantity:    item.Quantity,
+			UnitPrice:   p.Price,
+			UnitCost:    p.CostPrice,
+			Subtotal:    subtotal,
+			Total:       subtotal,
+		})
+	}
+
+	var voucher *models.RedeemableVoucher
+	if voucherCode != "" {
+		var err error
+		if voucher, err = s.lookupVoucherCode(voucherCode); err != nil {
+			return nil, err
+		}
+	}
+
+	if finalize != nil {
+		if err := finalize(&trx, voucher); err != nil {
+			return nil, err
+		}
+	}
+
+	if trx.TerminalID == nil {
+		return nil, repositories.ErrNoOpenShift
+	}
+	shift := s.openShift(*trx.TerminalID)
+	if shift == nil {
+		return nil, repositories.ErrNoOpenShift
+	}
+	shiftID := shift.ID
+	trx.ShiftID = &shiftID
+
+	var key *models.IdempotencyKey
+	if claim != nil {
+		k, ok := s.idempotencyKeys[claim.Key]
+		if !ok || k.TransactionID != nil {
+			return nil, repositories.ErrIdempotencyKeyClaimed
+		}
+		key = k
+	}
+
+	trx.ID = s.nextID("transactions")
+	if key != nil {
+		id := trx.ID
+		key.TransactionID = &id
+		key.ExpiresAt = claim.ExpiresAt
+	}
+	trx.CreatedAt = time.Now()
+
+	for _, id := range productIDs {
+		s.moveStock(models.InventoryMovement{
+			ProductID:     id,
+			Type:          models.MovementTypeSale,
+			Quantity:      -requested[id],
+			Actor:         trx.Cashier,
+			ReferenceType: models.ReferenceTypeTransaction,
+			ReferenceID:   &trx.ID,
+		})
+	}
+	for i := range trx.Details {
+		trx.Details[i].ID = s.nextID("transaction_details")
+		trx.Details[i].TransactionID = trx.ID
+		trx.Details[i].CategoryID = nil
+		trx.Details[i].TaxClassID = nil
+	}
+	for i := range trx.Promotions {
+		trx.Promotions[i].ID = s.nextID("transaction_promotions")
+		trx.Promotions[i].TransactionID = trx.ID
+	}
+	if trx.Voucher != nil {
+		s.redeemVoucher(&trx)
+	}
+	for i := range trx.Payments {
+		trx.Payments[i].ID = s.nextID("transaction_payments")
+		trx.Payments[i].TransactionID = trx.ID
+		trx.Payments[i].CreatedAt = trx.CreatedAt
+	}
+
+	stored := copyTransaction(trx)
+	s.transactions[trx.ID] = &stored
+
+	return &trx, nil
+}
+
+func (repo *TransactionRepository) GetTransactions(filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := make([]*models.Transaction, 0)
+	for _, t := range s.transactions {
+		if filter.StartDate != nil && dateOf(t.CreatedAt) < dateOf(*filter.StartDate) {
+			continue
+		}
+		if filter.EndDate != nil && dateOf(t.CreatedAt) > dateOf(*filter.EndDate) {
+			continue
+		}
+		if filter.MinTotal != nil && t.TotalAmount < *filter.MinTotal {
+			continue
+		}
+		if filter.MaxTotal != nil && t.TotalAmount > *filter.MaxTotal {
+			continue
+		}
+		if filter.ProductID != nil && !containsProduct(t, *filter.ProductID) {
+			continue
+		}
+		if filter.UserID != nil && (t.UserID == nil || *t.UserID != *filter.UserID) {
+			continue
+		}
+		if filter.ShiftID != nil && (t.ShiftID == nil || *t.ShiftID != *filter.ShiftID) {
+			continue
+		}
+		matched = append(matched, t)
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	transactions := make([]models.Transaction, 0)
+	offset := (filter.Page - 1) * filter.Limit
+	for i := offset; i < len(matched) && i < offset+filter.Limit; i++ {
+		transactions = append(transactions, s.transactionView(matched[i]))
+	}
+
+	return transactions, len(matched), nil
+}
+
+func (repo *TransactionRepository) GetTransactionByID(id int) (*models.Transaction, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, repositories.ErrTransactionNotFound
+	}
+
+	view := s.transactionView(t)
+	return &view, nil
+}
+
+func (repo *TransactionRepository) VoidTransaction(id int, reason string, by models.Actor) (*models.Refund, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, repositories.ErrTransactionNotFound
+	}
+	switch {
+	case t.Status == models.TransactionStatusVoided:
+		return nil, repositories.ErrTransactionVoided
+	case t.Status != models.TransactionStatusCompleted:
+		return nil, repositories.ErrTransactionRefunded
+	case dateOf(t.CreatedAt) != dateOf(time.Now()):
+		return nil, repositories.ErrVoidWindowClosed
+	}
+
+	lines := refundableLines(t)
+	refundDetails := make([]models.RefundDetail, 0, len(t.Details))
+	for _, d := range t.Details {
+		refundDetails = append(refundDetails, lines[d.ID].refund(d.ID, d.Quantity))
+	}
+
+	refund, err := s.insertRefund(t, models.RefundTypeVoid, reason, by, refundDetails)
+	if err != nil {
+		return nil, err
+	}
+	t.Status = models.TransactionStatusVoided
+
+	return refund, nil
+}
+
+func (repo *TransactionRepository) RefundTransaction(id int, req models.RefundRequest, by models.Actor) (*models.Refund, error) {
+	s := repo.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.transactions[id]
+	if !ok {
+		return nil, repositories.ErrTransactionNotFound
+	}
+	if t.Status == models.TransactionStatusVoided {
+		return nil, repositories.ErrTransactionVoided
+	}
+
+	lines := refundableLines(t)
+	requested := map[int]int{}
+	for _, item := range req.Items {
+		if _, ok := lines[item.DetailID]; !ok {
+			return nil, fmt.Errorf("%w: detail id %d does not belong to transaction %d", repositories.ErrInvalidRefund, item.DetailID, id)
+		}
+		requested[item.DetailID] += item.Quantity
+	}
+
+	refundDetails := make([]models.RefundDetail, 0, len(requested))
+	for _, detailID := range sortedKeys(requested) {
+		quantity := requested[detailID]
+		l := lines[detailID]
+		if remaining := l.quantity - l.refundedQty; quantity > remaining {
+			return nil, fmt.Errorf("%w: detail id %d has only %d refundable unit(s), requested %d", repositories.ErrInvalidRefund, detailID, remaining, quantity)
+		}
+
+		refundDetails = append(refundDetails, l.refund(detailID, quantity))
+	}
+
+	refund, err := s.insertRefund(t, models.RefundTypeRefund, req.Reason, by, refundDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Status = models.TransactionStatusRefunded
+	for _, l := range lines {
+		if l.refundedQty < l.quantity {
+			t.Status = models.TransactionStatusPartiallyRefunded
+			break
+		}
+	}
+
+	return refund, nil
+}
+
+func (repo *TransactionRepository) GetTransactionReport(startDate time.Time, endDate time.Time) (*models.TransactionReport, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, end := dateOf(startDate), dateOf(endDate)
+	inRange := func(t time.Time) bool {
+		d := dateOf(t)
+		return start <= d && d <= end
+	}
+
+	var report models.TransactionReport
+	sold := map[int]int{}
+	profits := map[int]*models.ProductProfit{}
+	book := func(productID, quantity, revenue, cost int) {
+		p, ok := profits[productID]
+		if !ok {
+			p = &models.ProductProfit{ProductID: productID}
+			profits[productID] = p
+		}
+		p.QuantitySold += quantity
+		p.Revenue += revenue
+		p.Cost += cost
+	}
+	type promotionKey struct {
+		id         int
+		name, kind string
+	}
+	usage := map[promotionKey]*models.PromotionUsage{}
+	taxes := map[int]int{}
+	for _, id := range sortedKeys(s.transactions) {
+		t := s.transactions[id]
+		if inRange(t.CreatedAt) {
+			for _, d := range t.Details {
+				revenue := d.Total - d.TaxAmount - d.ServiceCharge
+				report.TotalRevenue += revenue
+				report.TotalTax += d.TaxAmount
+				report.TotalService += d.ServiceCharge
+				taxes[d.TaxRateBP] += d.TaxAmount
+				sold[d.ProductID] += d.Quantity
+				book(d.ProductID, d.Quantity, revenue, d.Quantity*d.UnitCost)
+			}
+			if t.Status != models.TransactionStatusVoided {
+				report.TotalTransaksi++
+			}
+			if t.Status != models.TransactionStatusVoided && t.Status != models.TransactionStatusRefunded {
+				for _, p := range t.Promotions {
+					key := promotionKey{name: p.Name, kind: p.Type}
+					if p.PromotionID != nil {
+						key.id = *p.PromotionID
+					}
+					u, ok := usage[key]
+					if !ok {
+						u = &models.PromotionUsage{PromotionID: copyIntPtr(p.PromotionID), Name: p.Name, Type: p.Type}
+						usage[key] = u
+					}
+					u.Transactions++
+					u.DiscountAmount += p.Amount
+				}
+			}
+		}
+
+		// Refunds are netted out on the day they were made.
+		for _, r := range t.Refunds {
+			if !inRange(r.CreatedAt) {
+				continue
+			}
+			for _, d := range r.Details {
+				revenue := d.Amount - d.TaxAmount - d.ServiceCharge
+				report.TotalRevenue -= revenue
+				report.TotalTax -= d.TaxAmount
+				report.TotalService -= d.ServiceCharge
+				sold[d.ProductID] -= d.Quantity
+				var line models.TransactionDetail
+				for _, td := range t.Details {
+					if td.ID == d.TransactionDetailID {
+						line = td
+					}
+				}
+				taxes[line.TaxRateBP] -= d.TaxAmount
+				book(d.ProductID, -d.Quantity, -revenue, -d.Quantity*line.UnitCost)
+			}
+		}
+	}
+
+	report.Products = make([]models.ProductProfit, 0, len(profits))
+	for _, id := range sortedKeys(profits) {
+		p := *profits[id]
+		product := s.products[id]
+		p.Name = product.Name
+		p.CategoryID = copyIntPtr(product.CategoryID)
+		if product.CategoryID != nil {
+			p.CategoryName = s.categories[*product.CategoryID].Name
+		}
+		report.Products = append(report.Products, p)
+	}
+
+	report.Taxes = make([]models.TaxCollected, 0, len(taxes))
+	for _, rate := range sortedKeys(taxes) {
+		if taxes[rate] != 0 {
+			report.Taxes = append(report.Taxes, models.TaxCollected{TaxRateBP: rate, TaxAmount: taxes[rate]})
+		}
+	}
+
+	report.Promotions = make([]models.PromotionUsage, 0, len(usage))
+	for _, u := range usage {
+		report.Promotions = append(report.Promotions, *u)
+	}
+	// Ordered like the PostgreSQL query: by promotion id, deleted ones last.
+	sort.Slice(report.Promotions, func(i, j int) bool {
+		a, b := report.Promotions[i], report.Promotions[j]
+		if (a.PromotionID == nil) != (b.PromotionID == nil) {
+			return b.PromotionID == nil
+		}
+		if a.PromotionID != nil && *a.PromotionID != *b.PromotionID {
+			return *a.PromotionID < *b.PromotionID
+		}
+		return a.Name < b.Name
+	})
+
+	best := 0
+	for _, qty := range sold {
+		best = max(best, qty)
+	}
+
+	var bestSellingProducts []models.BestSellingProduct
+	if best > 0 {
+		for _, id := range sortedKeys(sold) {
+			if sold[id] == best {
+				bestSellingProducts = append(bestSellingProducts, models.BestSellingProduct{
+					Nama:         s.products[id].Name,
+					QuantitySold: best,
+				})
+			}
+		}
+	}
+
+	if len(bestSellingProducts) == 1 {
+		report.ProdukTerlaris = bestSellingProducts[0]
+	} else {
+		report.ProdukTerlaris = bestSellingProducts
+	}
+
+	return &report, nil
+}
+
+type refundableLine struct {
+	productID       int
+	quantity        int
+	total           int
+	serviceCharge   int
+	taxAmount       int
+	refundedQty     int
+	refundedAmount  int
+	refundedService int
+	refundedTax     int
+}
+
+// refundableLines returns t's detail lines with what has already been given
+// back, keyed by detail id.
+func refundableLines(t *models.Transaction) map[int]*refundableLine {
+	lines := map[int]*refundableLine{}
+	for _, d := range t.Details {
+		lines[d.ID] = &refundableLine{
+			productID:     d.ProductID,
+			quantity:      d.Quantity,
+			total:         d.Total,
+			serviceCharge: d.ServiceCharge,
+			taxAmount:     d.TaxAmount,
+		}
+	}
+	for _, r := range t.Refunds {
+		for _, d := range r.Details {
+			l := lines[d.TransactionDetailID]
+			l.refundedQty += d.Quantity
+			l.refundedAmount += d.Amount
+			l.refundedService += d.ServiceCharge
+			l.refundedTax += d.TaxAmount
+		}
+	}
+	return lines
+}
+
+// refund mirrors the PostgreSQL refundableLine.refund.
+func (l *refundableLine) refund(detailID, quantity int) models.RefundDetail {
+	d := models.RefundDetail{TransactionDetailID: detailID, ProductID: l.productID, Quantity: quantity}
+	if l.refundedQty+quantity == l.quantity {
+		d.Amount = l.total - l.refundedAmount
+		d.ServiceCharge = l.serviceCharge - l.refundedService
+		d.TaxAmount = l.taxAmount - l.refundedTax
+	} else {
+		d.Amount = l.total * quantity / l.quantity
+		d.ServiceCharge = l.serviceCharge * quantity / l.quantity
+		d.TaxAmount = l.taxAmount * quantity / l.quantity
+	}
+
+	l.refundedQty += quantity
+	l.refundedAmount += d.Amount
+	l.refundedService += d.ServiceCharge
+	l.refundedTax += d.TaxAmount
+	return d
+}
+
+// insertRefund records the refund on t, paid out by the open shift at by's
+// terminal, and puts the refunded quantities back into stock. The caller must
+// hold the write lock.
+func (s *Store) insertRefund(t *models.Transaction, refundType string, reason string, by models.Actor, details []models.RefundDetail) (*models.Refund, error) {
+	if by.TerminalID == nil {
+		return nil, repositories.ErrNoOpenShift
+	}
+	shift := s.openShift(*by.TerminalID)
+	if shift == nil {
+		return nil, repositories.ErrNoOpenShift
+	}
+	shiftID := shift.ID
+
+	refund := models.Refund{
+		ID:            s.nextID("refunds"),
+		TransactionID: t.ID,
+		Type:          refundType,
+		Reason:        reason,
+		UserID:        by.UserID(),
+		Cashier:       by.Username(),
+		ApproverID:    by.ApproverID(),
+		ApprovedBy:    by.ApprovedBy(),
+		ShiftID:       &shiftID,
+		CreatedAt:     time.Now(),
+		Details:       make([]models.RefundDetail, 0, len(details)),
+	}
+
+	names := make(map[int]string, len(t.Details))
+	for _, td := range t.Details {
+		names[td.ID] = td.ProductName
+	}
+
+	restock := map[int]int{}
+	for _, d := range details {
+		d.ID = s.nextID("refund_details")
+		d.RefundID = refund.ID
+		d.ProductName = names[d.TransactionDetailID]
+		refund.TotalAmount += d.Amount
+		refund.Details = append(refund.Details, d)
+		restock[d.ProductID] += d.Quantity
+	}
+
+	// Cash goes back first, up to the cash the transaction kept.
+	cash := cashKept(t)
+	for _, r := range t.Refunds {
+		cash -= r.CashAmount
+	}
+	refund.CashAmount = max(min(refund.TotalAmount, cash), 0)
+
+	for _, productID := range sortedKeys(restock) {
+		s.moveStock(models.InventoryMovement{
+			ProductID:     productID,
+			Type:          refundType,
+			Quantity:      restock[productID],
+			Reason:        reason,
+			Actor:         refund.Cashier,
+			ApprovedBy:    refund.ApprovedBy,
+			ReferenceType: models.ReferenceTypeRefund,
+			ReferenceID:   &refund.ID,
+		})
+	}
+
+	t.Refunds = append(t.Refunds, copyRefund(refund))
+	return &refund, nil
+}
+
+// cashKept is the cash t took less the change given.
+func cashKept(t *models.Transaction) int {
+	cash := -t.ChangeAmount
+	for _, p := range t.Payments {
+		if p.Method == models.PaymentMethodCash {
+			cash += p.Amount
+		}
+	}
+	return cash
+}
+
+// transactionView returns a copy of t shaped like the PostgreSQL repository
+// returns it, with the refunded quantities filled in. Names and prices are
+// the ones recorded at checkout.
+func (s *Store) transactionView(t *models.Transaction) models.Transaction {
+	view := copyTransaction(*t)
+
+	refunded := map[int]int{}
+	for _, r := range view.Refunds {
+		for _, d := range r.Details {
+			refunded[d.TransactionDetailID] += d.Quantity
+		}
+	}
+
+	for i := range view.Details {
+		view.Details[i].RefundedQty = refunded[view.Details[i].ID]
+	}
+
+	return view
+}
+
+func copyTransaction(t models.Transaction) models.Transaction {
+	t.UserID = copyIntPtr(t.UserID)
+	t.TerminalID = copyIntPtr(t.TerminalID)
+	t.ShiftID = copyIntPtr(t.ShiftID)
+	t.ApproverID = copyIntPtr(t.ApproverID)
+	details := make([]models.TransactionDetail, len(t.Details))
+	for i, d := range t.Details {
+		d.OriginalPrice = copyIntPtr(d.OriginalPrice)
+		details[i] = d
+	}
+	t.Details = details
+	promotions := make([]models.AppliedPromotion, len(t.Promotions))
+	for i, p := range t.Promotions {
+		p.PromotionID = copyIntPtr(p.PromotionID)
+		promotions[i] = p
+	}
+	t.Promotions = promotions
+	if t.Voucher != nil {
+		v := *t.Voucher
+		t.Voucher = &v
+	}
+	t.Payments = append(make([]models.Payment, 0, len(t.Payments)), t.Payments...)
+
+	if t.Refunds != nil {
+		refunds := make([]models.Refund, len(t.Refunds))
+		for i, r := range t.Refunds {
+			refunds[i] = copyRefund(r)
+		}
+		t.Refunds = refunds
+	}
+
+	return t
+}
+
+func copyRefund(r models.Refund) models.Refund {
+	r.UserID = copyIntPtr(r.UserID)
+	r.ApproverID = copyIntPtr(r.ApproverID)
+	r.ShiftID = copyIntPtr(r.ShiftID)
+	r.Details = append(make([]models.RefundDetail, 0, len(r.Details)), r.Details...)
+	return r
+}
+
+func containsProduct(t *models.Transaction, productID int) bool {
+	for _, d := range t.Details {
+		if d.ProductID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// dateOf truncates t to a local calendar date, like DATE() does in the
+// database session.
+func dateOf(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
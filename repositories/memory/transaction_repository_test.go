@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+func createTestProduct(t *testing.T, store *Store, name string, price, stock int) int {
+	t.Helper()
+
+	p := models.Product{Name: name, Price: price, Stock: stock}
+	if err := NewProductRepository(store).Create(&p); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	return p.ID
+}
+
+// openTestShift registers a terminal with an open shift and returns a
+// checkout finalizer that rings transactions up at it.
+func openTestShift(t *testing.T, store *Store) func(*models.Transaction, *models.RedeemableVoucher) error {
+	t.Helper()
+
+	terminal := models.Terminal{Code: "KASIR-1", Name: "Kasir 1", Active: true, KeyHash: "test"}
+	if err := NewTerminalRepository(store).Create(&terminal); err != nil {
+		t.Fatalf("create terminal: %v", err)
+	}
+	if err := NewShiftRepository(store).Open(&models.Shift{TerminalID: terminal.ID}); err != nil {
+		t.Fatalf("open shift: %v", err)
+	}
+	return func(trx *models.Transaction, _ *models.RedeemableVoucher) error {
+		trx.TerminalID = &terminal.ID
+		return nil
+	}
+}
+
+func productStock(t *testing.T, store *Store, id int) int {
+	t.Helper()
+
+	p, err := NewProductRepository(store).GetByID(id)
+	if err != nil {
+		t.Fatalf("get product: %v", err)
+	}
+	return p.Stock
+}
+
+func TestCreateTransactionConcurrentCheckoutsNeverOversell(t *testing.T) {
+	store := NewStore()
+	repo := NewTransactionRepository(store)
+	till := openTestShift(t, store)
+
+	const stock = 10
+	const buyers = 25
+	id := createTestProduct(t, store, "Indomie Goreng", 3500, stock)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: id, Quantity: 1}}, "", nil, till)
+
+			var stockErr *repositories.InsufficientStockError
+			if err != nil && !errors.As(err, &stockErr) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != stock {
+		t.Errorf("succeeded = %d, want %d", succeeded, stock)
+	}
+	if got := productStock(t, store, id); got != 0 {
+		t.Errorf("stock = %d, want 0", got)
+	}
+}
+
+func TestCreateTransactionAbortedByFinalizerLeavesNoTrace(t *testing.T) {
+	store := NewStore()
+	repo := NewTransactionRepository(store)
+	till := openTestShift(t, store)
+	id := createTestProduct(t, store, "Indomie Goreng", 3500, 10)
+
+	_, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: id, Quantity: 2}}, "", nil, func(trx *models.Transaction, _ *models.RedeemableVoucher) error {
+		return errors.New("declined")
+	})
+	if err == nil {
+		t.Fatal("expected finalizer error")
+	}
+	if got := productStock(t, store, id); got != 10 {
+		t.Errorf("stock = %d, want 10", got)
+	}
+
+	_, total, _ := repo.GetTransactions(models.TransactionFilter{Page: 1, Limit: 10})
+	if total != 0 {
+		t.Errorf("aborted checkout stored %d transaction(s)", total)
+	}
+
+	_, err = repo.CreateTransaction([]models.CheckoutItem{{ProductID: 99, Quantity: 1}}, "", nil, till)
+	if err == nil || err.Error() != "product id 99 not found" {
+		t.Errorf("unknown product: got %v", err)
+	}
+}
+
+func TestCreateTransactionClaimsIdempotencyKey(t *testing.T) {
+	store := NewStore()
+	repo := NewTransactionRepository(store)
+	keys := NewIdempotencyRepository(store)
+	till := openTestShift(t, store)
+	id := createTestProduct(t, store, "Indomie Goreng", 3500, 10)
+
+	if _, _, err := keys.Reserve("1:key-1", "fp", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	claim := &models.IdempotencyClaim{Key: "1:key-1", ExpiresAt: time.Now().Add(time.Hour)}
+	trx, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: id, Quantity: 1}}, "", claim, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	if err := keys.Release("1:key-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	stored, created, err := keys.Reserve("1:key-1", "fp", time.Now().Add(time.Minute))
+	if err != nil || created || stored.TransactionID == nil || *stored.TransactionID != trx.ID || !stored.ExpiresAt.Equal(claim.ExpiresAt) {
+		t.Fatalf("claimed key: got %+v, created = %v, err = %v", stored, created, err)
+	}
+
+	if _, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: id, Quantity: 1}}, "", claim, till); !errors.Is(err, repositories.ErrIdempotencyKeyClaimed) {
+		t.Errorf("second checkout on the key: got %v, want ErrIdempotencyKeyClaimed", err)
+	}
+	if got := productStock(t, store, id); got != 9 {
+		t.Errorf("stock = %d, want 9", got)
+	}
+}
+
+func TestRefundAndVoidRestoreStockAndNetReport(t *testing.T) {
+	store := NewStore()
+	repo := NewTransactionRepository(store)
+	till := openTestShift(t, store)
+
+	indomie := createTestProduct(t, store, "Indomie Goreng", 3500, 10)
+	aqua := createTestProduct(t, store, "Aqua 600ml", 2000, 10)
+
+	trx, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: indomie, Quantity: 4}, {ProductID: aqua, Quantity: 2}}, "", nil, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	refund, err := repo.RefundTransaction(trx.ID, models.RefundRequest{
+		Items: []models.RefundItem{{DetailID: trx.Details[0].ID, Quantity: 3}},
+	}, models.Actor{TerminalID: trx.TerminalID})
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if refund.TotalAmount != 10500 || productStock(t, store, indomie) != 9 {
+		t.Errorf("refund amount = %d, stock = %d", refund.TotalAmount, productStock(t, store, indomie))
+	}
+
+	if _, err := repo.VoidTransaction(trx.ID, "", models.Actor{TerminalID: trx.TerminalID}); !errors.Is(err, repositories.ErrTransactionRefunded) {
+		t.Errorf("void after refund: got %v", err)
+	}
+
+	now := time.Now()
+	report, err := repo.GetTransactionReport(now, now)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if report.TotalRevenue != 18000-10500 {
+		t.Errorf("revenue = %d, want %d", report.TotalRevenue, 18000-10500)
+	}
+
+	other, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: aqua, Quantity: 3}}, "", nil, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if _, err := repo.VoidTransaction(other.ID, "mistake", models.Actor{TerminalID: other.TerminalID}); err != nil {
+		t.Fatalf("void: %v", err)
+	}
+	if got := productStock(t, store, aqua); got != 8 {
+		t.Errorf("aqua stock = %d, want 8", got)
+	}
+
+	stored, err := repo.GetTransactionByID(other.ID)
+	if err != nil || stored.Status != models.TransactionStatusVoided || len(stored.Refunds) != 1 {
+		t.Errorf("voided transaction = %+v, err = %v", stored, err)
+	}
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{store: store}
+}
+
+func (repo *UserRepository) GetAll(filter models.UserFilter) ([]models.User, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	users := make([]models.User, 0)
+	for _, id := range sortedKeys(repo.store.users) {
+		u := repo.store.users[id]
+		if filter.Role != "" && u.Role != filter.Role {
+			continue
+		}
+		if filter.Active != nil && u.Active != *filter.Active {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	return paginate(users, filter.Page, filter.Limit), len(users), nil
+}
+
+func (repo *UserRepository) Create(user *models.User) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkUsername(user); err != nil {
+		return err
+	}
+
+	user.ID = repo.store.nextID("users")
+	user.CreatedAt = time.Now()
+	repo.store.users[user.ID] = *user
+	return nil
+}
+
+func (repo *UserRepository) GetByID(id int) (*models.User, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	u, ok := repo.store.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	return &u, nil
+}
+
+func (repo *UserRepository) GetByUsername(username string) (*models.User, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	for _, u := range repo.store.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, repositories.ErrUserNotFound
+}
+
+func (repo *UserRepository) Update(user *models.User) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	existing, ok := repo.store.users[user.ID]
+	if !ok {
+		return repositories.ErrUserNotFound
+	}
+	if err := repo.store.checkUsername(user); err != nil {
+		return err
+	}
+
+	user.CreatedAt = existing.CreatedAt
+	repo.store.users[user.ID] = *user
+	return nil
+}
+
+func (repo *UserRepository) RecordPINFailure(id int) (int, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	u, ok := repo.store.users[id]
+	if !ok {
+		return 0, repositories.ErrUserNotFound
+	}
+	u.PINFailures++
+	repo.store.users[id] = u
+	return u.PINFailures, nil
+}
+
+func (repo *UserRepository) LockPIN(id int, until time.Time) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if u, ok := repo.store.users[id]; ok {
+		u.PINFailures = 0
+		u.PINLockedUntil = until
+		repo.store.users[id] = u
+	}
+	return nil
+}
+
+func (repo *UserRepository) ResetPINFailures(id int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if u, ok := repo.store.users[id]; ok {
+		u.PINFailures = 0
+		repo.store.users[id] = u
+	}
+	return nil
+}
+
+// checkUsername enforces the users_username_key index.
+func (s *Store) checkUsername(user *models.User) error {
+	for id, other := range s.users {
+		if id != user.ID && other.Username == user.Username {
+			return repositories.ErrUsernameTaken
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type VoucherRepository struct {
+	store *Store
+}
+
+func NewVoucherRepository(store *Store) *VoucherRepository {
+	return &VoucherRepository{store: store}
+}
+
+func (repo *VoucherRepository) GetAll(filter models.VoucherFilter) ([]models.Voucher, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	vouchers := make([]models.Voucher, 0)
+	for _, id := range sortedKeys(repo.store.vouchers) {
+		v := repo.store.vouchers[id]
+		if filter.Active != nil && v.Active != *filter.Active {
+			continue
+		}
+		vouchers = append(vouchers, repo.store.voucherView(v))
+	}
+
+	return paginate(vouchers, filter.Page, filter.Limit), len(vouchers), nil
+}
+
+func (repo *VoucherRepository) Create(voucher *models.Voucher) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if err := repo.store.checkVoucher(voucher); err != nil {
+		return err
+	}
+
+	voucher.ID = repo.store.nextID("vouchers")
+	voucher.Redemptions, voucher.CodeCount = 0, 0
+	repo.store.vouchers[voucher.ID] = copyVoucher(*voucher)
+	return nil
+}
+
+func (repo *VoucherRepository) GetByID(id int) (*models.Voucher, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	v, ok := repo.store.vouchers[id]
+	if !ok {
+		return nil, repositories.ErrVoucherNotFound
+	}
+
+	v = repo.store.voucherView(v)
+	return &v, nil
+}
+
+func (repo *VoucherRepository) Update(voucher *models.Voucher) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	existing, ok := repo.store.vouchers[voucher.ID]
+	if !ok {
+		return repositories.ErrVoucherNotFound
+	}
+	if err := repo.store.checkVoucher(voucher); err != nil {
+		return err
+	}
+
+	voucher.Redemptions = existing.Redemptions
+	voucher.CodeCount = repo.store.voucherView(existing).CodeCount
+	repo.store.vouchers[voucher.ID] = copyVoucher(*voucher)
+	return nil
+}
+
+func (repo *VoucherRepository) Delete(id int) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	v, ok := repo.store.vouchers[id]
+	if !ok {
+		return repositories.ErrVoucherNotFound
+	}
+	if v.Redemptions > 0 {
+		return repositories.ErrVoucherInUse
+	}
+
+	// ON DELETE CASCADE on voucher_codes
+	for codeID, c := range repo.store.voucherCodes {
+		if c.VoucherID == id {
+			delete(repo.store.codes, c.Code)
+			delete(repo.store.voucherCodes, codeID)
+		}
+	}
+	delete(repo.store.vouchers, id)
+	return nil
+}
+
+// AddCodes stores the given codes for the voucher and returns the ones that
+// were new, like ON CONFLICT (code) DO NOTHING.
+func (repo *VoucherRepository) AddCodes(voucherID int, codes []string) ([]models.VoucherCode, error) {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.vouchers[voucherID]; !ok {
+		return nil, repositories.ErrVoucherNotFound
+	}
+
+	now := time.Now()
+	added := make([]models.VoucherCode, 0, len(codes))
+	for _, code := range codes {
+		if _, taken := repo.store.codes[code]; taken {
+			continue
+		}
+		c := models.VoucherCode{
+			ID:        repo.store.nextID("voucher_codes"),
+			VoucherID: voucherID,
+			Code:      code,
+			CreatedAt: now,
+		}
+		repo.store.voucherCodes[c.ID] = c
+		repo.store.codes[code] = c.ID
+		added = append(added, c)
+	}
+	return added, nil
+}
+
+func (repo *VoucherRepository) GetCodes(filter models.VoucherCodeFilter) ([]models.VoucherCode, int, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	if _, ok := repo.store.vouchers[filter.VoucherID]; !ok {
+		return nil, 0, repositories.ErrVoucherNotFound
+	}
+
+	codes := make([]models.VoucherCode, 0)
+	for _, id := range sortedKeys(repo.store.voucherCodes) {
+		if c := repo.store.voucherCodes[id]; c.VoucherID == filter.VoucherID {
+			codes = append(codes, c)
+		}
+	}
+
+	return paginate(codes, filter.Page, filter.Limit), len(codes), nil
+}
+
+func (repo *VoucherRepository) GetByCode(code string) (*models.RedeemableVoucher, error) {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	return repo.store.lookupVoucherCode(code)
+}
+
+// lookupVoucherCode finds an upper case code and its voucher. The caller must
+// hold the lock.
+func (s *Store) lookupVoucherCode(code string) (*models.RedeemableVoucher, error) {
+	id, ok := s.codes[code]
+	if !ok {
+		return nil, repositories.ErrUnknownVoucherCode
+	}
+
+	c := s.voucherCodes[id]
+	return &models.RedeemableVoucher{Voucher: s.voucherView(s.vouchers[c.VoucherID]), Code: c}, nil
+}
+
+// redeemVoucher counts the use of the voucher applied to trx. The caller must
+// hold the write lock.
+func (s *Store) redeemVoucher(trx *models.Transaction) {
+	trx.Voucher.ID = s.nextID("voucher_redemptions")
+	trx.Voucher.TransactionID = trx.ID
+
+	c := s.voucherCodes[trx.Voucher.VoucherCodeID]
+	c.Redemptions++
+	s.voucherCodes[c.ID] = c
+
+	v := s.vouchers[trx.Voucher.VoucherID]
+	v.Redemptions++
+	s.vouchers[v.ID] = v
+}
+
+// checkVoucher enforces the foreign keys of the voucher's scopes.
+func (s *Store) checkVoucher(voucher *models.Voucher) error {
+	for _, id := range voucher.ProductIDs {
+		if _, ok := s.products[id]; !ok {
+			return repositories.ErrUnknownVoucherProduct
+		}
+	}
+	for _, id := range voucher.CategoryIDs {
+		if _, ok := s.categories[id]; !ok {
+			return repositories.ErrUnknownVoucherCategory
+		}
+	}
+	return nil
+}
+
+// voucherView returns a copy of v with its number of codes.
+func (s *Store) voucherView(v models.Voucher) models.Voucher {
+	v = copyVoucher(v)
+	v.CodeCount = 0
+	for _, c := range s.voucherCodes {
+		if c.VoucherID == v.ID {
+			v.CodeCount++
+		}
+	}
+	return v
+}
+
+// copyVoucher returns v with its own scope slices, sorted like the
+// PostgreSQL repository returns them.
+func copyVoucher(v models.Voucher) models.Voucher {
+	v.ProductIDs = slices.Sorted(slices.Values(v.ProductIDs))
+	v.CategoryIDs = slices.Sorted(slices.Values(v.CategoryIDs))
+	v.StartsAt = copyTimePtr(v.StartsAt)
+	v.EndsAt = copyTimePtr(v.EndsAt)
+
+	if v.ProductIDs == nil {
+		v.ProductIDs = []int{}
+	}
+	if v.CategoryIDs == nil {
+		v.CategoryIDs = []int{}
+	}
+	return v
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"slices"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type ZReportRepository struct {
+	store *Store
+}
+
+func NewZReportRepository(store *Store) *ZReportRepository {
+	return &ZReportRepository{store: store}
+}
+
+// GetAll lists Z reports, newest first.
+func (repo *ZReportRepository) GetAll(filter models.ZReportFilter) ([]models.ShiftReport, int, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	numbers := sortedKeys(s.zReports)
+	slices.Reverse(numbers)
+
+	reports := make([]models.ShiftReport, 0)
+	for _, number := range numbers {
+		r := s.zReports[number]
+		if filter.TerminalID != nil && r.TerminalID != *filter.TerminalID {
+			continue
+		}
+		if filter.StartDate != nil && dateOf(*r.ClosedAt) < dateOf(*filter.StartDate) {
+			continue
+		}
+		if filter.EndDate != nil && dateOf(*r.ClosedAt) > dateOf(*filter.EndDate) {
+			continue
+		}
+		reports = append(reports, copyShiftReport(r))
+	}
+
+	return paginate(reports, filter.Page, filter.Limit), len(reports), nil
+}
+
+func (repo *ZReportRepository) GetByNumber(number int) (*models.ShiftReport, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.zReports[number]
+	if !ok {
+		return nil, repositories.ErrZReportNotFound
+	}
+	report := copyShiftReport(r)
+	return &report, nil
+}
+
+func (repo *ZReportRepository) GetByShift(shiftID int) (*models.ShiftReport, error) {
+	s := repo.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, r := range s.zReports {
+		if r.ShiftID == shiftID {
+			report := copyShiftReport(r)
+			return &report, nil
+		}
+	}
+	return nil, repositories.ErrZReportNotFound
+}
+
+// insertZReport stores report as the next Z report in sequence. The caller
+// must hold the lock.
+func (s *Store) insertZReport(report *models.ShiftReport) {
+	report.Type = models.ShiftReportZ
+	report.Number = len(s.zReports) + 1
+	report.CreatedAt = time.Now()
+	s.zReports[report.Number] = copyShiftReport(*report)
+}
+
+func copyShiftReport(r models.ShiftReport) models.ShiftReport {
+	if r.ClosedAt != nil {
+		closedAt := *r.ClosedAt
+		r.ClosedAt = &closedAt
+	}
+	r.CountedCash = copyIntPtr(r.CountedCash)
+	r.Variance = copyIntPtr(r.Variance)
+	r.Payments = append(make([]models.PaymentTotal, 0, len(r.Payments)), r.Payments...)
+	return r
+}
@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 
 	"simple-cashier-api/models"
 )
@@ -15,141 +18,447 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (repo *ProductRepository) GetAll(nameFilter string) ([]models.ProductDetail, error) {
-	query := `SELECT p.id, p.name, p.price, p.stock,
-	                 p.category_id,
-	                 c.id, c.name, c.description
-	          FROM products p
-	          LEFT JOIN categories c ON c.id = p.category_id`
+// productSelect reads a product with its category, tax class and barcodes. Callers
+// append their WHERE clause, which leaves out archived products, and scan the
+// row with scanProductDetail.
+const productSelect = `SELECT p.id, p.name, p.sku, p.price, p.cost_price, p.stock,
+                              p.category_id, p.tax_class_id,
+                              c.id, c.name, c.description,
+                              ARRAY(SELECT b.barcode FROM product_barcodes b WHERE b.product_id = p.id ORDER BY b.barcode)
+                       FROM products p
+                       LEFT JOIN categories c ON c.id = p.category_id`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanProductDetail(row rowScanner) (*models.ProductDetail, error) {
+	var p models.ProductDetail
+	var sku sql.NullString
+	var categoryID sql.NullInt64
+	var taxClassID sql.NullInt64
+	var catID sql.NullInt64
+	var catName sql.NullString
+	var catDesc sql.NullString
+
+	err := row.Scan(
+		&p.ID, &p.Name, &sku, &p.Price, &p.CostPrice, &p.Stock,
+		&categoryID, &taxClassID,
+		&catID, &catName, &catDesc,
+		pq.Array(&p.Barcodes),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p.SKU = sku.String
+	if p.Barcodes == nil {
+		p.Barcodes = []string{}
+	}
+
+	if categoryID.Valid {
+		val := int(categoryID.Int64)
+		p.CategoryID = &val
+	}
+
+	if taxClassID.Valid {
+		val := int(taxClassID.Int64)
+		p.TaxClassID = &val
+	}
+
+	if catID.Valid && catName.Valid {
+		p.Category = &models.Category{
+			ID:          int(catID.Int64),
+			Name:        catName.String,
+			Description: catDesc.String,
+		}
+	}
+
+	return &p, nil
+}
+
+var productSortColumns = map[string]string{
+	"id":    "p.id",
+	"name":  "p.name",
+	"price": "p.price",
+	"stock": "p.stock",
+}
 
+func (repo *ProductRepository) GetAll(filter models.ProductFilter) ([]models.ProductDetail, int, error) {
+	conditions := []string{"p.archived_at IS NULL"}
 	args := []any{}
-	if nameFilter != "" {
-		query += " WHERE p.name ILIKE $1"
-		args = append(args, "%"+nameFilter+"%")
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Name != "" {
+		addCondition("p.name ILIKE $%d", "%"+filter.Name+"%")
+	}
+	if filter.CategoryID != nil {
+		addCondition("p.category_id = $%d", *filter.CategoryID)
+	}
+	if filter.MinPrice != nil {
+		addCondition("p.price >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		addCondition("p.price <= $%d", *filter.MaxPrice)
 	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			conditions = append(conditions, "p.stock > 0")
+		} else {
+			conditions = append(conditions, "p.stock <= 0")
+		}
+	}
+	if filter.LowStock != nil {
+		if *filter.LowStock {
+			addCondition("p.stock <= $%d", filter.LowStockThreshold)
+		} else {
+			addCondition("p.stock > $%d", filter.LowStockThreshold)
+		}
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM products p"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := productSelect + where +
+		orderBy(filter.Sort, productSortColumns) +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
 
 	rows, err := repo.db.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	products := make([]models.ProductDetail, 0)
 	for rows.Next() {
-		var p models.ProductDetail
-		var categoryID sql.NullInt64
-		var catID sql.NullInt64
-		var catName sql.NullString
-		var catDesc sql.NullString
-
-		err := rows.Scan(
-			&p.ID, &p.Name, &p.Price, &p.Stock,
-			&categoryID,
-			&catID, &catName, &catDesc,
-		)
+		p, err := scanProductDetail(rows)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
+		products = append(products, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
 
-		if categoryID.Valid {
-			val := int(categoryID.Int64)
-			p.CategoryID = &val
-		}
+	return products, total, nil
+}
 
-		if catID.Valid && catName.Valid {
-			p.Category = &models.Category{
-				ID:          int(catID.Int64),
-				Name:        catName.String,
-				Description: catDesc.String,
-			}
-		}
+func (repo *ProductRepository) Create(product *models.Product) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The initial stock is booked as an opening movement rather than written
+	// directly, so the ledger starts with the product.
+	query := "INSERT INTO products (name, sku, price, cost_price, stock, category_id, tax_class_id) VALUES ($1, $2, $3, $4, 0, $5, $6) RETURNING id"
+	err = tx.QueryRow(query, product.Name, nullIfEmpty(product.SKU), product.Price, product.CostPrice, product.CategoryID, product.TaxClassID).Scan(&product.ID)
+	if err != nil {
+		return productWriteError(err)
+	}
 
-		products = append(products, p)
+	if err := replaceBarcodes(tx, product.ID, product.Barcodes); err != nil {
+		return productWriteError(err)
 	}
 
-	return products, nil
-}
+	if err := recordPriceChange(tx, product.ID, nil, product.Price); err != nil {
+		return err
+	}
 
-func (repo *ProductRepository) Create(product *models.Product) error {
-	query := "INSERT INTO products (name, price, stock, category_id) VALUES ($1, $2, $3, $4) RETURNING id"
-	err := repo.db.QueryRow(query, product.Name, product.Price, product.Stock, product.CategoryID).Scan(&product.ID)
-	return err
+	if product.Stock != 0 {
+		err := moveStock(tx, &models.InventoryMovement{
+			ProductID: product.ID,
+			Type:      models.MovementTypeOpening,
+			Quantity:  product.Stock,
+			Reason:    "Initial stock",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func (repo *ProductRepository) GetByID(id int) (*models.ProductDetail, error) {
-	query := `SELECT p.id, p.name, p.price, p.stock,
-									 p.category_id,
-									 c.id AS category_id,
-									 c.name AS category_name,
-									 c.description AS category_description
-    FROM products p
-    LEFT JOIN categories c ON c.id = p.category_id
-    WHERE p.id = $1`
+	return repo.getOne("p.id = $1", id)
+}
 
-	var p models.ProductDetail
-	var categoryID sql.NullInt64
-	var catID sql.NullInt64
-	var catName sql.NullString
-	var catDesc sql.NullString
+// GetByBarcode finds the product a scanned code belongs to. The code must
+// already be normalised to 13 digits.
+func (repo *ProductRepository) GetByBarcode(barcode string) (*models.ProductDetail, error) {
+	return repo.getOne("p.id = (SELECT product_id FROM product_barcodes WHERE barcode = $1)", barcode)
+}
 
-	err := repo.db.QueryRow(query, id).Scan(&p.ID, &p.Name, &p.Price, &p.Stock, &categoryID, &catID, &catName, &catDesc)
+func (repo *ProductRepository) GetBySKU(sku string) (*models.ProductDetail, error) {
+	return repo.getOne("p.sku = $1", sku)
+}
 
+func (repo *ProductRepository) getOne(condition string, arg any) (*models.ProductDetail, error) {
+	p, err := scanProductDetail(repo.db.QueryRow(productSelect+" WHERE p.archived_at IS NULL AND "+condition, arg))
 	if err == sql.ErrNoRows {
-		return nil, errors.New("product not found")
+		return nil, ErrProductNotFound
 	}
 	if err != nil {
 		return nil, err
 	}
+	return p, nil
+}
 
-	if categoryID.Valid {
-		val := int(categoryID.Int64)
-		p.CategoryID = &val
+// Update saves a product's details. A changed stock is booked as an
+// adjustment, see moveStock, and a missing one or cost is left as it is and
+// reported back on the product; see models.ProductUpdate.
+func (repo *ProductRepository) Update(update *models.ProductUpdate) error {
+	product := &update.Product
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	if catID.Valid && catName.Valid {
-		p.Category = &models.Category{
-			ID:          int(catID.Int64),
-			Name:        catName.String,
-			Description: catDesc.String,
+	var stock, price, costPrice int
+	err = tx.QueryRow("SELECT stock, price, cost_price FROM products WHERE id = $1 AND archived_at IS NULL FOR UPDATE", product.ID).Scan(&stock, &price, &costPrice)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	product.Stock = stock
+	if update.CostPrice == nil {
+		product.CostPrice = costPrice
+	}
+
+	query := "UPDATE products SET name = $1, sku = $2, price = $3, cost_price = $4, category_id = $5, tax_class_id = $6 WHERE id = $7"
+	_, err = tx.Exec(query, product.Name, nullIfEmpty(product.SKU), product.Price, product.CostPrice, product.CategoryID, product.TaxClassID, product.ID)
+	if err != nil {
+		return productWriteError(err)
+	}
+
+	if err := replaceBarcodes(tx, product.ID, product.Barcodes); err != nil {
+		return productWriteError(err)
+	}
+
+	if product.Price != price {
+		if err := recordPriceChange(tx, product.ID, &price, product.Price); err != nil {
+			return err
 		}
 	}
 
-	return &p, nil
+	if update.Stock != nil && *update.Stock != stock {
+		err := moveStock(tx, &models.InventoryMovement{
+			ProductID:  product.ID,
+			Type:       models.MovementTypeAdjustment,
+			Quantity:   *update.Stock - stock,
+			Reason:     StockEditReason,
+			Actor:      update.Actor,
+			ApprovedBy: update.ApprovedBy,
+		})
+		if err != nil {
+			return err
+		}
+		product.Stock = *update.Stock
+	}
+
+	return tx.Commit()
 }
 
-func (repo *ProductRepository) Update(product *models.Product) error {
-	query := "UPDATE products SET name = $1, price = $2, stock = $3, category_id = $4 WHERE id = $5"
-	result, err := repo.db.Exec(query, product.Name, product.Price, product.Stock, product.CategoryID, product.ID)
+// Delete removes a product. One with stock movements is archived instead, so
+// its stock ledger is kept; see archiveProduct.
+func (repo *ProductRepository) Delete(id int) error {
+	tx, err := repo.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	var hasLedger bool
+	err = tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM inventory_movements WHERE product_id = p.id)
+		 FROM products p WHERE p.id = $1 AND p.archived_at IS NULL FOR UPDATE`,
+		id,
+	).Scan(&hasLedger)
+	if err == sql.ErrNoRows {
+		return ErrProductNotFound
+	}
 	if err != nil {
 		return err
 	}
 
-	if rows == 0 {
-		return errors.New("product not found")
+	if hasLedger {
+		err = archiveProduct(tx, id)
+	} else {
+		_, err = tx.Exec("DELETE FROM products WHERE id = $1", id)
+	}
+	if hasPQCode(err, pgForeignKeyViolation) {
+		return ErrProductInUse
+	}
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-func (repo *ProductRepository) Delete(id int) error {
-	query := "DELETE FROM products WHERE id = $1"
-	result, err := repo.db.Exec(query, id)
+// archiveProduct takes a product out of the catalogue but keeps its row for
+// its stock ledger. Like deleting it, this is refused while transactions,
+// purchase orders or bundles refer to it, and takes it out of promotion and
+// voucher scopes. Its SKU and barcodes are freed for other products.
+func archiveProduct(tx *sql.Tx, id int) error {
+	var inUse bool
+	err := tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM transaction_details WHERE product_id = $1)
+		     OR EXISTS (SELECT 1 FROM purchase_order_items WHERE product_id = $1)
+		     OR EXISTS (SELECT 1 FROM promotion_bundle_items WHERE product_id = $1)`,
+		id,
+	).Scan(&inUse)
 	if err != nil {
 		return err
 	}
-	rows, err := result.RowsAffected()
+	if inUse {
+		return ErrProductInUse
+	}
+
+	for _, query := range []string{
+		"UPDATE products SET archived_at = now(), sku = NULL, tax_class_id = NULL WHERE id = $1",
+		"DELETE FROM product_barcodes WHERE product_id = $1",
+		"DELETE FROM promotion_products WHERE product_id = $1",
+		"DELETE FROM voucher_products WHERE product_id = $1",
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refuseArchivedProducts returns unknown when any of ids is an archived
+// product, which foreign keys alone would accept.
+func refuseArchivedProducts(tx *sql.Tx, ids []int, unknown error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var archived bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM products WHERE id = ANY($1) AND archived_at IS NOT NULL)", pq.Array(ids)).Scan(&archived)
 	if err != nil {
 		return err
 	}
+	if archived {
+		return unknown
+	}
+	return nil
+}
+
+// GetPriceHistory returns the price changes of a product, newest first.
+func (repo *ProductRepository) GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PriceChange, int, error) {
+	var total int
+	err := repo.db.QueryRow(
+		`SELECT (SELECT count(*) FROM product_price_history WHERE product_id = p.id)
+		 FROM products p WHERE p.id = $1`,
+		filter.ProductID,
+	).Scan(&total)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
 
-	if rows == 0 {
-		return errors.New("product not found")
+	rows, err := repo.db.Query(
+		`SELECT id, product_id, old_price, new_price, changed_at
+		 FROM product_price_history
+		 WHERE product_id = $1
+		 ORDER BY id DESC
+		 LIMIT $2 OFFSET $3`,
+		filter.ProductID, filter.Limit, (filter.Page-1)*filter.Limit,
+	)
+	if err != nil {
+		return nil, 0, err
 	}
+	defer rows.Close()
 
+	changes := make([]models.PriceChange, 0)
+	for rows.Next() {
+		var c models.PriceChange
+		var oldPrice sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.ProductID, &oldPrice, &c.NewPrice, &c.ChangedAt); err != nil {
+			return nil, 0, err
+		}
+		if oldPrice.Valid {
+			price := int(oldPrice.Int64)
+			c.OldPrice = &price
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return changes, total, nil
+}
+
+// recordPriceChange appends to the product's price history. oldPrice is nil
+// when the product is created.
+func recordPriceChange(tx *sql.Tx, productID int, oldPrice *int, newPrice int) error {
+	_, err := tx.Exec(
+		"INSERT INTO product_price_history (product_id, old_price, new_price) VALUES ($1, $2, $3)",
+		productID, oldPrice, newPrice,
+	)
 	return err
 }
+
+// StockEditReason is recorded on the adjustment booked when a product update
+// changes its stock.
+const StockEditReason = "Stock changed by product update"
+
+// replaceBarcodes makes barcodes the complete set of codes of the product.
+func replaceBarcodes(tx *sql.Tx, productID int, barcodes []string) error {
+	_, err := tx.Exec("DELETE FROM product_barcodes WHERE product_id = $1", productID)
+	if err != nil {
+		return err
+	}
+	if len(barcodes) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO product_barcodes (barcode, product_id) SELECT unnest($1::text[]), $2",
+		pq.Array(barcodes), productID,
+	)
+	return err
+}
+
+// productWriteError turns constraint violations on products and
+// product_barcodes into field errors.
+func productWriteError(err error) error {
+	switch {
+	case hasPQConstraint(err, "products_category_id_fkey"):
+		return ErrUnknownCategory
+	case hasPQConstraint(err, "products_tax_class_id_fkey"):
+		return ErrUnknownTaxClass
+	case hasPQConstraint(err, "products_sku_key"):
+		return ErrSKUTaken
+	case hasPQConstraint(err, "product_barcodes_pkey"):
+		return ErrBarcodeTaken
+	}
+	return err
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,290 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"simple-cashier-api/models"
+)
+
+type PromotionRepository struct {
+	db *sql.DB
+}
+
+func NewPromotionRepository(db *sql.DB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+const promotionSelect = `SELECT id, name, type, value, buy_quantity, get_quantity, bundle_price, min_spend,
+                                starts_at, ends_at, active
+                         FROM promotions`
+
+func scanPromotion(row rowScanner) (*models.Promotion, error) {
+	var p models.Promotion
+	var startsAt, endsAt pq.NullTime
+	err := row.Scan(&p.ID, &p.Name, &p.Type, &p.Value, &p.BuyQuantity, &p.GetQuantity, &p.BundlePrice, &p.MinSpend,
+		&startsAt, &endsAt, &p.Active)
+	if err != nil {
+		return nil, err
+	}
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	return &p, nil
+}
+
+func (repo *PromotionRepository) GetAll(filter models.PromotionFilter) ([]models.Promotion, int, error) {
+	where := ""
+	args := []any{}
+	if filter.Active != nil {
+		where = " WHERE active = $1"
+		args = append(args, *filter.Active)
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM promotions"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := promotionSelect + where + fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	promotions, err := repo.query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return promotions, total, nil
+}
+
+// GetActive returns the promotions that apply at the given time, by id.
+func (repo *PromotionRepository) GetActive(at time.Time) ([]models.Promotion, error) {
+	return repo.query(promotionSelect+
+		` WHERE active AND (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR $1 < ends_at)
+		  ORDER BY id`, at)
+}
+
+// query runs a promotionSelect query and loads the scopes of every row.
+func (repo *PromotionRepository) query(query string, args ...any) ([]models.Promotion, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := make([]models.Promotion, 0)
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := repo.loadScopes(promotions); err != nil {
+		return nil, err
+	}
+	return promotions, nil
+}
+
+// loadScopes fills in the products, categories and bundle items of
+// promotions.
+func (repo *PromotionRepository) loadScopes(promotions []models.Promotion) error {
+	ids := make([]int, len(promotions))
+	index := make(map[int]*models.Promotion, len(promotions))
+	for i := range promotions {
+		p := &promotions[i]
+		p.ProductIDs = make([]int, 0)
+		p.CategoryIDs = make([]int, 0)
+		p.BundleItems = make([]models.BundleItem, 0)
+		ids[i] = p.ID
+		index[p.ID] = p
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT promotion_id, 'product', product_id, 0 FROM promotion_products WHERE promotion_id = ANY($1)
+		 UNION ALL
+		 SELECT promotion_id, 'category', category_id, 0 FROM promotion_categories WHERE promotion_id = ANY($1)
+		 UNION ALL
+		 SELECT promotion_id, 'bundle', product_id, quantity FROM promotion_bundle_items WHERE promotion_id = ANY($1)
+		 ORDER BY 1, 2, 3`,
+		pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var promotionID, id, quantity int
+		var kind string
+		if err := rows.Scan(&promotionID, &kind, &id, &quantity); err != nil {
+			return err
+		}
+
+		p := index[promotionID]
+		switch kind {
+		case "product":
+			p.ProductIDs = append(p.ProductIDs, id)
+		case "category":
+			p.CategoryIDs = append(p.CategoryIDs, id)
+		case "bundle":
+			p.BundleItems = append(p.BundleItems, models.BundleItem{ProductID: id, Quantity: quantity})
+		}
+	}
+
+	return rows.Err()
+}
+
+func (repo *PromotionRepository) Create(promotion *models.Promotion) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO promotions (name, type, value, buy_quantity, get_quantity, bundle_price, min_spend, starts_at, ends_at, active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		promotion.Name, promotion.Type, promotion.Value, promotion.BuyQuantity, promotion.GetQuantity,
+		promotion.BundlePrice, promotion.MinSpend, promotion.StartsAt, promotion.EndsAt, promotion.Active,
+	).Scan(&promotion.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := insertPromotionScopes(tx, promotion); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *PromotionRepository) GetByID(id int) (*models.Promotion, error) {
+	promotions, err := repo.query(promotionSelect+" WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		return nil, ErrPromotionNotFound
+	}
+	return &promotions[0], nil
+}
+
+// Update replaces the promotion, scopes included. Transactions keep the
+// discounts they were given.
+func (repo *PromotionRepository) Update(promotion *models.Promotion) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`UPDATE promotions SET name = $1, type = $2, value = $3, buy_quantity = $4, get_quantity = $5,
+		        bundle_price = $6, min_spend = $7, starts_at = $8, ends_at = $9, active = $10
+		 WHERE id = $11`,
+		promotion.Name, promotion.Type, promotion.Value, promotion.BuyQuantity, promotion.GetQuantity,
+		promotion.BundlePrice, promotion.MinSpend, promotion.StartsAt, promotion.EndsAt, promotion.Active, promotion.ID,
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPromotionNotFound
+	}
+
+	for _, table := range []string{"promotion_products", "promotion_categories", "promotion_bundle_items"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE promotion_id = $1", promotion.ID); err != nil {
+			return err
+		}
+	}
+	if err := insertPromotionScopes(tx, promotion); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the promotion. Transactions it was applied to keep its name
+// and discount.
+func (repo *PromotionRepository) Delete(id int) error {
+	result, err := repo.db.Exec("DELETE FROM promotions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrPromotionNotFound
+	}
+
+	return nil
+}
+
+func insertPromotionScopes(tx *sql.Tx, promotion *models.Promotion) error {
+	if err := refuseArchivedProducts(tx, promotion.ProductIDs, ErrUnknownPromotionProduct); err != nil {
+		return err
+	}
+	bundled := make([]int, 0, len(promotion.BundleItems))
+	for _, item := range promotion.BundleItems {
+		bundled = append(bundled, item.ProductID)
+	}
+	if err := refuseArchivedProducts(tx, bundled, ErrUnknownBundleProduct); err != nil {
+		return err
+	}
+
+	for _, productID := range promotion.ProductIDs {
+		_, err := tx.Exec("INSERT INTO promotion_products (promotion_id, product_id) VALUES ($1, $2)", promotion.ID, productID)
+		if err != nil {
+			return promotionWriteError(err)
+		}
+	}
+	for _, categoryID := range promotion.CategoryIDs {
+		_, err := tx.Exec("INSERT INTO promotion_categories (promotion_id, category_id) VALUES ($1, $2)", promotion.ID, categoryID)
+		if err != nil {
+			return promotionWriteError(err)
+		}
+	}
+	for _, item := range promotion.BundleItems {
+		_, err := tx.Exec(
+			"INSERT INTO promotion_bundle_items (promotion_id, product_id, quantity) VALUES ($1, $2, $3)",
+			promotion.ID, item.ProductID, item.Quantity,
+		)
+		if err != nil {
+			return promotionWriteError(err)
+		}
+	}
+	return nil
+}
+
+// promotionWriteError turns foreign key violations into field errors.
+func promotionWriteError(err error) error {
+	switch {
+	case hasPQConstraint(err, "promotion_products_product_id_fkey"):
+		return ErrUnknownPromotionProduct
+	case hasPQConstraint(err, "promotion_categories_category_id_fkey"):
+		return ErrUnknownPromotionCategory
+	case hasPQConstraint(err, "promotion_bundle_items_product_id_fkey"):
+		return ErrUnknownBundleProduct
+	}
+	return err
+}
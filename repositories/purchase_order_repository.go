@@ -0,0 +1,468 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"simple-cashier-api/models"
+)
+
+type PurchaseOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) *PurchaseOrderRepository {
+	return &PurchaseOrderRepository{db: db}
+}
+
+const purchaseOrderSelect = `SELECT po.id, po.supplier_id, s.name, po.status, po.notes, po.total_cost,
+                                    po.created_at, po.ordered_at, po.received_at
+                             FROM purchase_orders po
+                             JOIN suppliers s ON s.id = po.supplier_id`
+
+func scanPurchaseOrder(row rowScanner) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	var orderedAt, receivedAt pq.NullTime
+	err := row.Scan(&po.ID, &po.SupplierID, &po.SupplierName, &po.Status, &po.Notes, &po.TotalCost,
+		&po.CreatedAt, &orderedAt, &receivedAt)
+	if err != nil {
+		return nil, err
+	}
+	if orderedAt.Valid {
+		po.OrderedAt = &orderedAt.Time
+	}
+	if receivedAt.Valid {
+		po.ReceivedAt = &receivedAt.Time
+	}
+	return &po, nil
+}
+
+// GetAll lists purchase orders, newest first.
+func (repo *PurchaseOrderRepository) GetAll(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, int, error) {
+	conditions := make([]string, 0)
+	args := []any{}
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Status != "" {
+		addCondition("po.status = $%d", filter.Status)
+	}
+	if filter.SupplierID != nil {
+		addCondition("po.supplier_id = $%d", *filter.SupplierID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM purchase_orders po"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := purchaseOrderSelect + where +
+		fmt.Sprintf(" ORDER BY po.id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	orders := make([]models.PurchaseOrder, 0)
+	ids := make([]int, 0)
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		orders = append(orders, *po)
+		ids = append(ids, po.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	items, err := repo.getItems(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range orders {
+		orders[i].Items = items[orders[i].ID]
+	}
+
+	return orders, total, nil
+}
+
+func (repo *PurchaseOrderRepository) GetByID(id int) (*models.PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(repo.db.QueryRow(purchaseOrderSelect+" WHERE po.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := repo.getItems([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	po.Items = items[id]
+
+	return po, nil
+}
+
+// getItems loads the items of the given purchase orders, keyed by purchase
+// order id.
+func (repo *PurchaseOrderRepository) getItems(orderIDs []int) (map[int][]models.PurchaseOrderItem, error) {
+	items := map[int][]models.PurchaseOrderItem{}
+	for _, id := range orderIDs {
+		items[id] = make([]models.PurchaseOrderItem, 0)
+	}
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT i.id, i.purchase_order_id, i.product_id, p.name, i.quantity, i.received_quantity, i.unit_cost
+		 FROM purchase_order_items i
+		 JOIN products p ON p.id = i.product_id
+		 WHERE i.purchase_order_id = ANY($1)
+		 ORDER BY i.purchase_order_id, i.id`,
+		pq.Array(orderIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.PurchaseOrderItem
+		err := rows.Scan(&i.ID, &i.PurchaseOrderID, &i.ProductID, &i.ProductName, &i.Quantity, &i.ReceivedQuantity, &i.UnitCost)
+		if err != nil {
+			return nil, err
+		}
+		i.Subtotal = i.Quantity * i.UnitCost
+
+		items[i.PurchaseOrderID] = append(items[i.PurchaseOrderID], i)
+	}
+
+	return items, rows.Err()
+}
+
+// Create stores po as a draft and reloads it, so product and supplier names
+// are filled in.
+func (repo *PurchaseOrderRepository) Create(po *models.PurchaseOrder) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		"INSERT INTO purchase_orders (supplier_id, status, notes, total_cost) VALUES ($1, $2, $3, $4) RETURNING id",
+		po.SupplierID, models.PurchaseOrderStatusDraft, po.Notes, po.TotalCost,
+	).Scan(&po.ID)
+	if err != nil {
+		return purchaseOrderWriteError(err)
+	}
+
+	if err := insertPurchaseOrderItems(tx, po.ID, po.Items); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return repo.reload(po)
+}
+
+// Update replaces the supplier, notes and items of a draft.
+func (repo *PurchaseOrderRepository) Update(po *models.PurchaseOrder) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockPurchaseOrder(tx, po.ID)
+	if err != nil {
+		return err
+	}
+	if status != models.PurchaseOrderStatusDraft {
+		return PurchaseOrderStatusError(status)
+	}
+
+	_, err = tx.Exec(
+		"UPDATE purchase_orders SET supplier_id = $1, notes = $2, total_cost = $3 WHERE id = $4",
+		po.SupplierID, po.Notes, po.TotalCost, po.ID,
+	)
+	if err != nil {
+		return purchaseOrderWriteError(err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM purchase_order_items WHERE purchase_order_id = $1", po.ID); err != nil {
+		return err
+	}
+	if err := insertPurchaseOrderItems(tx, po.ID, po.Items); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return repo.reload(po)
+}
+
+func (repo *PurchaseOrderRepository) reload(po *models.PurchaseOrder) error {
+	loaded, err := repo.GetByID(po.ID)
+	if err != nil {
+		return err
+	}
+	*po = *loaded
+	return nil
+}
+
+// SetStatus moves a purchase order to status to, provided its current status
+// is one of from.
+func (repo *PurchaseOrderRepository) SetStatus(id int, from []string, to string) (*models.PurchaseOrder, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, err := lockPurchaseOrder(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(from, status) {
+		return nil, PurchaseOrderStatusError(status)
+	}
+
+	query := "UPDATE purchase_orders SET status = $1 WHERE id = $2"
+	if to == models.PurchaseOrderStatusOrdered {
+		query = "UPDATE purchase_orders SET status = $1, ordered_at = now() WHERE id = $2"
+	}
+	if _, err := tx.Exec(query, to, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.GetByID(id)
+}
+
+// Receive books delivered quantities: each one is added to the product's
+// stock as a restock movement referencing the purchase order, and to the
+// item's received quantity. The product's cost price becomes the moving
+// average of the stock on hand and the units received. The order becomes received once every item is
+// complete.
+func (repo *PurchaseOrderRepository) Receive(id int, req models.ReceiveRequest) (*models.PurchaseOrder, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, err := lockPurchaseOrder(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.PurchaseOrderStatusOrdered && status != models.PurchaseOrderStatusPartiallyReceived {
+		return nil, PurchaseOrderStatusError(status)
+	}
+
+	rows, err := tx.Query(
+		"SELECT id, product_id, quantity, received_quantity, unit_cost FROM purchase_order_items WHERE purchase_order_id = $1",
+		id)
+	if err != nil {
+		return nil, err
+	}
+	lines := map[int]*receivableLine{}
+	for rows.Next() {
+		var l receivableLine
+		if err := rows.Scan(&l.id, &l.productID, &l.quantity, &l.received, &l.unitCost); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		lines[l.id] = &l
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	receipts, err := matchReceipt(lines, req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range receipts {
+		_, err := tx.Exec("UPDATE purchase_order_items SET received_quantity = received_quantity + $1 WHERE id = $2", l.receiving, l.id)
+		if err != nil {
+			return nil, err
+		}
+
+		var stock, cost int
+		err = tx.QueryRow("SELECT stock, cost_price FROM products WHERE id = $1 FOR UPDATE", l.productID).Scan(&stock, &cost)
+		if err != nil {
+			return nil, err
+		}
+		cost = MovingAverageCost(stock, cost, l.receiving, l.unitCost)
+		if _, err := tx.Exec("UPDATE products SET cost_price = $1 WHERE id = $2", cost, l.productID); err != nil {
+			return nil, err
+		}
+
+		err = moveStock(tx, &models.InventoryMovement{
+			ProductID:     l.productID,
+			Type:          models.MovementTypeRestock,
+			Quantity:      l.receiving,
+			Reason:        fmt.Sprintf("Received on purchase order %d", id),
+			Actor:         req.Actor,
+			ReferenceType: models.ReferenceTypePurchaseOrder,
+			ReferenceID:   &id,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if receivedInFull(lines) {
+		_, err = tx.Exec("UPDATE purchase_orders SET status = $1, received_at = now() WHERE id = $2", models.PurchaseOrderStatusReceived, id)
+	} else {
+		_, err = tx.Exec("UPDATE purchase_orders SET status = $1 WHERE id = $2", models.PurchaseOrderStatusPartiallyReceived, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.GetByID(id)
+}
+
+// lockPurchaseOrder locks the purchase order row, so status changes and
+// receipts of the same order are serialised, and returns its status.
+func lockPurchaseOrder(tx *sql.Tx, id int) (string, error) {
+	var status string
+	err := tx.QueryRow("SELECT status FROM purchase_orders WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrPurchaseOrderNotFound
+	}
+	return status, err
+}
+
+func insertPurchaseOrderItems(tx *sql.Tx, orderID int, items []models.PurchaseOrderItem) error {
+	productIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	if err := refuseArchivedProducts(tx, productIDs, ErrUnknownPurchaseProduct); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		_, err := tx.Exec(
+			"INSERT INTO purchase_order_items (purchase_order_id, product_id, quantity, unit_cost) VALUES ($1, $2, $3, $4)",
+			orderID, item.ProductID, item.Quantity, item.UnitCost,
+		)
+		if err != nil {
+			return purchaseOrderWriteError(err)
+		}
+	}
+	return nil
+}
+
+// purchaseOrderWriteError turns foreign key violations into field errors.
+func purchaseOrderWriteError(err error) error {
+	switch {
+	case hasPQConstraint(err, "purchase_orders_supplier_id_fkey"):
+		return ErrUnknownSupplier
+	case hasPQConstraint(err, "purchase_order_items_product_id_fkey"):
+		return ErrUnknownPurchaseProduct
+	}
+	return err
+}
+
+// receivableLine is a purchase order item locked for receiving.
+type receivableLine struct {
+	id        int
+	productID int
+	quantity  int
+	received  int
+	receiving int
+	unitCost  int
+}
+
+// matchReceipt checks the received quantities against the outstanding ones
+// and returns the lines being received, in ascending product id order so
+// product rows are locked in the same order as checkout locks them.
+func matchReceipt(lines map[int]*receivableLine, items []models.ReceiveItem) ([]*receivableLine, error) {
+	var fields []models.FieldError
+	receipts := make([]*receivableLine, 0, len(items))
+	for i, item := range items {
+		l, ok := lines[item.ItemID]
+		if !ok {
+			fields = append(fields, models.FieldError{
+				Field:   fmt.Sprintf("items[%d].item_id", i),
+				Rule:    "exists",
+				Message: "is not an item of this purchase order",
+			})
+			continue
+		}
+		if remaining := l.quantity - l.received; item.Quantity > remaining {
+			fields = append(fields, models.FieldError{
+				Field:   fmt.Sprintf("items[%d].quantity", i),
+				Rule:    "max",
+				Message: fmt.Sprintf("only %d unit(s) are still expected", remaining),
+			})
+			continue
+		}
+		l.receiving = item.Quantity
+		receipts = append(receipts, l)
+	}
+	if len(fields) > 0 {
+		return nil, NewValidationError(fields...)
+	}
+
+	for _, l := range receipts {
+		l.received += l.receiving
+	}
+	slices.SortFunc(receipts, func(a, b *receivableLine) int { return a.productID - b.productID })
+	return receipts, nil
+}
+
+func receivedInFull(lines map[int]*receivableLine) bool {
+	for _, l := range lines {
+		if l.received < l.quantity {
+			return false
+		}
+	}
+	return true
+}
+
+// MovingAverageCost is the unit cost after receiving quantity units at
+// unitCost on top of stock units at cost, rounded to the nearest unit.
+func MovingAverageCost(stock, cost, quantity, unitCost int) int {
+	stock = max(stock, 0)
+	units := stock + quantity
+	if units <= 0 {
+		return cost
+	}
+	return (stock*cost + quantity*unitCost + units/2) / units
+}
@@ -0,0 +1,25 @@
+package repositories
+
+import "testing"
+
+func TestMovingAverageCost(t *testing.T) {
+	tests := []struct {
+		name                            string
+		stock, cost, quantity, unitCost int
+		want                            int
+	}{
+		{"empty shelf takes the new cost", 0, 0, 24, 1500, 1500},
+		{"weighted by units", 10, 3000, 30, 2600, 2700},
+		{"rounded to nearest", 5, 0, 40, 2800, 2489},
+		{"negative stock counts as none", -3, 1000, 10, 1200, 1200},
+		{"nothing received keeps the cost", 0, 900, 0, 0, 900},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MovingAverageCost(tt.stock, tt.cost, tt.quantity, tt.unitCost); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,589 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"simple-cashier-api/models"
+)
+
+type ShiftRepository struct {
+	db *sql.DB
+}
+
+func NewShiftRepository(db *sql.DB) *ShiftRepository {
+	return &ShiftRepository{db: db}
+}
+
+// shiftSelect computes the cash figures of open shifts from their
+// transactions, the refunds paid out on them and their cash movements;
+// closed shifts keep the ones they closed with. Voided sales stay in
+// cash_sales, as their cash was taken, and the void pays it out again under
+// cash_refunds of the shift it was made on.
+const shiftSelect = `SELECT s.id, s.terminal_id, s.user_id, s.cashier, s.status, s.opening_float,
+                            COALESCE(s.cash_sales, sales.cash_sales), COALESCE(s.cash_refunds, paid.cash_refunds),
+                            COALESCE(s.transaction_count, sales.transaction_count),
+                            COALESCE(m.cash_in, 0), COALESCE(m.cash_out, 0),
+                            s.expected_cash, s.counted_cash, s.variance, s.note, s.closed_by, s.opened_at, s.closed_at
+                     FROM shifts s
+                     CROSS JOIN LATERAL (
+                         SELECT COALESCE(SUM(p.cash - t.change_amount), 0) AS cash_sales,
+                                count(*) FILTER (WHERE t.status <> 'voided') AS transaction_count
+                         FROM transactions t
+                         CROSS JOIN LATERAL (
+                             SELECT COALESCE(SUM(amount), 0) AS cash FROM transaction_payments
+                             WHERE transaction_id = t.id AND method = 'cash'
+                         ) p
+                         WHERE t.shift_id = s.id
+                     ) sales
+                     CROSS JOIN LATERAL (
+                         SELECT COALESCE(SUM(cash_amount), 0) AS cash_refunds FROM refunds WHERE shift_id = s.id
+                     ) paid
+                     CROSS JOIN LATERAL (
+                         SELECT SUM(amount) FILTER (WHERE type = 'cash_in') AS cash_in,
+                                SUM(amount) FILTER (WHERE type = 'cash_out') AS cash_out
+                         FROM shift_cash_movements WHERE shift_id = s.id
+                     ) m`
+
+func scanShift(row rowScanner) (*models.Shift, error) {
+	var s models.Shift
+	var expected sql.NullInt64
+	var closedAt pq.NullTime
+	err := row.Scan(&s.ID, &s.TerminalID, &s.UserID, &s.Cashier, &s.Status, &s.OpeningFloat,
+		&s.CashSales, &s.CashRefunds, &s.TransactionCount, &s.CashIn, &s.CashOut,
+		&expected, &s.CountedCash, &s.Variance, &s.Note, &s.ClosedBy, &s.OpenedAt, &closedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.ExpectedCash = s.OpeningFloat + s.CashSales - s.CashRefunds + s.CashIn - s.CashOut
+	if expected.Valid {
+		s.ExpectedCash = int(expected.Int64)
+	}
+	if closedAt.Valid {
+		s.ClosedAt = &closedAt.Time
+	}
+	return &s, nil
+}
+
+// GetAll lists shifts, newest first.
+func (repo *ShiftRepository) GetAll(filter models.ShiftFilter) ([]models.Shift, int, error) {
+	conditions := make([]string, 0)
+	args := []any{}
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.TerminalID != nil {
+		addCondition("s.terminal_id = $%d", *filter.TerminalID)
+	}
+	if filter.UserID != nil {
+		addCondition("s.user_id = $%d", *filter.UserID)
+	}
+	if filter.Status != "" {
+		addCondition("s.status = $%d", filter.Status)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM shifts s"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := shiftSelect + where + fmt.Sprintf(" ORDER BY s.id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	shifts := make([]models.Shift, 0)
+	ids := make([]int, 0)
+	for rows.Next() {
+		s, err := scanShift(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		shifts = append(shifts, *s)
+		ids = append(ids, s.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	counts, err := repo.getCashCounts(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	movements, err := repo.getMovements(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range shifts {
+		shifts[i].CashCounts = counts[shifts[i].ID]
+		shifts[i].Movements = movements[shifts[i].ID]
+	}
+
+	return shifts, total, nil
+}
+
+func (repo *ShiftRepository) GetByID(id int) (*models.Shift, error) {
+	s, err := scanShift(repo.db.QueryRow(shiftSelect+" WHERE s.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrShiftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := repo.getCashCounts([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	s.CashCounts = counts[id]
+
+	movements, err := repo.getMovements([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	s.Movements = movements[id]
+
+	return s, nil
+}
+
+// GetOpen returns the open shift of a terminal, or ErrNoOpenShift.
+func (repo *ShiftRepository) GetOpen(terminalID int) (*models.Shift, error) {
+	var id int
+	err := repo.db.QueryRow("SELECT id FROM shifts WHERE terminal_id = $1 AND status = 'open'", terminalID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoOpenShift
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetByID(id)
+}
+
+// GetSales sums up the transactions rung up in a shift and the voids and
+// refunds made in it.
+func (repo *ShiftRepository) GetSales(id int) (*models.ShiftSales, error) {
+	var exists bool
+	if err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM shifts WHERE id = $1)", id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrShiftNotFound
+	}
+	return getShiftSales(repo.db, id)
+}
+
+// queryer is what *sql.DB and *sql.Tx have in common.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// getShiftSales sums up a shift's sales, voided ones included, the voids and
+// refunds made in it and the payments taken, cash net of the change given
+// and every method net of what the voids and refunds gave back by it.
+func getShiftSales(q queryer, shiftID int) (*models.ShiftSales, error) {
+	var s models.ShiftSales
+	var first, last sql.NullInt64
+	var change int
+	err := q.QueryRow(
+		`SELECT count(*) FILTER (WHERE status <> 'voided'), COALESCE(SUM(total_amount) FILTER (WHERE status <> 'voided'), 0),
+		        MIN(id), MAX(id), COALESCE(SUM(subtotal), 0), COALESCE(SUM(discount_amount), 0),
+		        COALESCE(SUM(service_charge), 0), COALESCE(SUM(tax_amount), 0), COALESCE(SUM(total_amount), 0),
+		        COALESCE(SUM(change_amount), 0)
+		 FROM transactions WHERE shift_id = $1`,
+		shiftID,
+	).Scan(&s.Transactions, &s.KeptTotal, &first, &last, &s.Subtotal, &s.Discounts, &s.ServiceCharge, &s.Tax, &s.Total, &change)
+	if err != nil {
+		return nil, err
+	}
+	s.FirstTransactionID, s.LastTransactionID = int(first.Int64), int(last.Int64)
+
+	err = q.QueryRow(
+		`SELECT COALESCE(SUM(r.total_amount) FILTER (WHERE r.type = 'refund'), 0), count(*) FILTER (WHERE r.type = 'refund'),
+		        COALESCE(SUM(r.total_amount) FILTER (WHERE r.type = 'void'), 0), count(*) FILTER (WHERE r.type = 'void'),
+		        COALESCE(SUM(d.service_charge), 0), COALESCE(SUM(d.tax_amount), 0)
+		 FROM refunds r
+		 CROSS JOIN LATERAL (
+		     SELECT SUM(service_charge) AS service_charge, SUM(tax_amount) AS tax_amount FROM refund_details WHERE refund_id = r.id
+		 ) d
+		 WHERE r.shift_id = $1`,
+		shiftID,
+	).Scan(&s.Refunds, &s.RefundCount, &s.Voids, &s.VoidCount, &s.GivenBackServiceCharge, &s.GivenBackTax)
+	if err != nil {
+		return nil, err
+	}
+
+	payments := map[string]*models.PaymentTotal{}
+	rows, err := q.Query(
+		`SELECT p.method, count(DISTINCT p.transaction_id) FILTER (WHERE t.status <> 'voided'), SUM(p.amount)
+		 FROM transaction_payments p
+		 JOIN transactions t ON t.id = p.transaction_id
+		 WHERE t.shift_id = $1
+		 GROUP BY p.method`,
+		shiftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.PaymentTotal
+		if err := rows.Scan(&p.Method, &p.Transactions, &p.Amount); err != nil {
+			return nil, err
+		}
+		// Only cash is over-tendered, so all change is given in cash.
+		if p.Method == models.PaymentMethodCash {
+			p.Amount -= change
+		}
+		payments[p.Method] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	givenBack, err := getShiftGivenBack(q, shiftID)
+	if err != nil {
+		return nil, err
+	}
+	s.Payments = NetPayments(payments, givenBack)
+	return &s, nil
+}
+
+// getShiftGivenBack totals what the voids and refunds made in a shift gave
+// back by each payment method, see RefundByMethod.
+func getShiftGivenBack(q queryer, shiftID int) (map[string]int, error) {
+	type refund struct{ transactionID, total, cash int }
+	var refunds []refund
+	var transactionIDs []int
+
+	rows, err := q.Query("SELECT transaction_id, total_amount, cash_amount FROM refunds WHERE shift_id = $1 ORDER BY id", shiftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r refund
+		if err := rows.Scan(&r.transactionID, &r.total, &r.cash); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, r)
+		transactionIDs = append(transactionIDs, r.transactionID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paidWith := map[int][]models.Payment{}
+	rows, err = q.Query(
+		"SELECT transaction_id, method, amount FROM transaction_payments WHERE transaction_id = ANY($1) ORDER BY id",
+		pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(&p.TransactionID, &p.Method, &p.Amount); err != nil {
+			return nil, err
+		}
+		paidWith[p.TransactionID] = append(paidWith[p.TransactionID], p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	givenBack := map[string]int{}
+	for _, r := range refunds {
+		for method, amount := range RefundByMethod(r.total, r.cash, paidWith[r.transactionID]) {
+			givenBack[method] += amount
+		}
+	}
+	return givenBack, nil
+}
+
+// RefundByMethod splits the total a void or refund gave back over the
+// payment methods of its transaction: cash, its cash part, in cash and the
+// rest over the other methods in proportion to what they took, the last one
+// taking what rounding leaves.
+func RefundByMethod(total, cash int, payments []models.Payment) map[string]int {
+	byMethod := map[string]int{}
+	if cash > 0 {
+		byMethod[models.PaymentMethodCash] = cash
+	}
+
+	rest, taken := total-cash, 0
+	var others []models.Payment
+	for _, p := range payments {
+		if p.Method != models.PaymentMethodCash {
+			others = append(others, p)
+			taken += p.Amount
+		}
+	}
+	if rest <= 0 {
+		return byMethod
+	}
+	if taken == 0 {
+		byMethod[models.PaymentMethodCash] += rest
+		return byMethod
+	}
+
+	left := rest
+	for i, p := range others {
+		share := rest * p.Amount / taken
+		if i == len(others)-1 {
+			share = left
+		}
+		byMethod[p.Method] += share
+		left -= share
+	}
+	return byMethod
+}
+
+// NetPayments takes what was given back by each method off what it took, and
+// lists the methods with anything left to report.
+func NetPayments(taken map[string]*models.PaymentTotal, givenBack map[string]int) []models.PaymentTotal {
+	for method, amount := range givenBack {
+		p, ok := taken[method]
+		if !ok {
+			p = &models.PaymentTotal{Method: method}
+			taken[method] = p
+		}
+		p.Amount -= amount
+	}
+
+	payments := make([]models.PaymentTotal, 0, len(taken))
+	for _, method := range slices.Sorted(maps.Keys(taken)) {
+		if p := taken[method]; p.Transactions != 0 || p.Amount != 0 {
+			payments = append(payments, *p)
+		}
+	}
+	return payments
+}
+
+// getCashCounts loads the closing counts of the given shifts, largest
+// denomination first, keyed by shift id.
+func (repo *ShiftRepository) getCashCounts(shiftIDs []int) (map[int][]models.CashCount, error) {
+	counts := map[int][]models.CashCount{}
+	for _, id := range shiftIDs {
+		counts[id] = make([]models.CashCount, 0)
+	}
+	if len(shiftIDs) == 0 {
+		return counts, nil
+	}
+
+	rows, err := repo.db.Query(
+		"SELECT shift_id, denomination, count FROM shift_cash_counts WHERE shift_id = ANY($1) ORDER BY shift_id, denomination DESC",
+		pq.Array(shiftIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shiftID int
+		var c models.CashCount
+		if err := rows.Scan(&shiftID, &c.Denomination, &c.Count); err != nil {
+			return nil, err
+		}
+		c.Amount = c.Denomination * c.Count
+		counts[shiftID] = append(counts[shiftID], c)
+	}
+
+	return counts, rows.Err()
+}
+
+// getMovements loads the cash movements of the given shifts, keyed by shift
+// id.
+func (repo *ShiftRepository) getMovements(shiftIDs []int) (map[int][]models.CashMovement, error) {
+	movements := map[int][]models.CashMovement{}
+	for _, id := range shiftIDs {
+		movements[id] = make([]models.CashMovement, 0)
+	}
+	if len(shiftIDs) == 0 {
+		return movements, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT id, shift_id, type, amount, reason, user_id, actor, created_at
+		 FROM shift_cash_movements WHERE shift_id = ANY($1) ORDER BY shift_id, id`,
+		pq.Array(shiftIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.CashMovement
+		if err := rows.Scan(&m.ID, &m.ShiftID, &m.Type, &m.Amount, &m.Reason, &m.UserID, &m.Actor, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		movements[m.ShiftID] = append(movements[m.ShiftID], m)
+	}
+
+	return movements, rows.Err()
+}
+
+// Open starts a shift at shift.TerminalID and reloads it.
+func (repo *ShiftRepository) Open(shift *models.Shift) error {
+	err := repo.db.QueryRow(
+		"INSERT INTO shifts (terminal_id, user_id, cashier, opening_float) VALUES ($1, $2, $3, $4) RETURNING id",
+		shift.TerminalID, shift.UserID, shift.Cashier, shift.OpeningFloat,
+	).Scan(&shift.ID)
+	switch {
+	case hasPQConstraint(err, "shifts_open_terminal_key"):
+		return ErrShiftAlreadyOpen
+	case hasPQConstraint(err, "shifts_open_user_key"):
+		return ErrCashierShiftOpen
+	case hasPQConstraint(err, "shifts_terminal_id_fkey"):
+		return ErrTerminalNotFound
+	case err != nil:
+		return err
+	}
+
+	loaded, err := repo.GetByID(shift.ID)
+	if err != nil {
+		return err
+	}
+	*shift = *loaded
+	return nil
+}
+
+// AddCashMovement records cash put into or taken out of the drawer of an
+// open shift.
+func (repo *ShiftRepository) AddCashMovement(shiftID int, m *models.CashMovement) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := lockShift(tx, shiftID)
+	if err != nil {
+		return err
+	}
+	if status != models.ShiftStatusOpen {
+		return ErrShiftClosed
+	}
+
+	m.ShiftID = shiftID
+	err = tx.QueryRow(
+		"INSERT INTO shift_cash_movements (shift_id, type, amount, reason, user_id, actor) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		m.ShiftID, m.Type, m.Amount, m.Reason, m.UserID, m.Actor,
+	).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Close counts the drawer of an open shift. The cash figures and the
+// variance of counts against them are frozen on the shift, and its Z report,
+// made by summarize from the closed shift and its sales, is stored in the
+// same transaction. Checkouts, voids and refunds on the shift hold it
+// share-locked, so none is still being written while the figures are
+// computed.
+func (repo *ShiftRepository) Close(id int, counts []models.CashCount, note, closedBy string,
+	summarize func(*models.Shift, *models.ShiftSales) models.ShiftReport) (*models.Shift, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, err := lockShift(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.ShiftStatusOpen {
+		return nil, ErrShiftClosed
+	}
+
+	shift, err := scanShift(tx.QueryRow(shiftSelect+" WHERE s.id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	counted := 0
+	for _, c := range counts {
+		counted += c.Denomination * c.Count
+		_, err := tx.Exec("INSERT INTO shift_cash_counts (shift_id, denomination, count) VALUES ($1, $2, $3)", id, c.Denomination, c.Count)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(
+		`UPDATE shifts SET status = $1, cash_sales = $2, cash_refunds = $3, transaction_count = $4, expected_cash = $5, counted_cash = $6,
+		                   variance = $7, note = $8, closed_by = $9, closed_at = now()
+		 WHERE id = $10`,
+		models.ShiftStatusClosed, shift.CashSales, shift.CashRefunds, shift.TransactionCount, shift.ExpectedCash, counted,
+		counted-shift.ExpectedCash, note, closedBy, id)
+	if err != nil {
+		return nil, err
+	}
+
+	closed, err := scanShift(tx.QueryRow(shiftSelect+" WHERE s.id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	sales, err := getShiftSales(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	report := summarize(closed, sales)
+	if err := insertZReport(tx, &report); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.GetByID(id)
+}
+
+// lockShift locks the shift row, so cash movements and closing of the same
+// shift are serialised, and returns its status.
+func lockShift(tx *sql.Tx, id int) (string, error) {
+	var status string
+	err := tx.QueryRow("SELECT status FROM shifts WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", ErrShiftNotFound
+	}
+	return status, err
+}
+
+// lockOpenShift share-locks the open shift of a terminal for a checkout, void
+// or refund, so the shift cannot close until it commits, and returns its id.
+func lockOpenShift(tx *sql.Tx, terminalID *int) (*int, error) {
+	if terminalID == nil {
+		return nil, ErrNoOpenShift
+	}
+
+	var id int
+	err := tx.QueryRow("SELECT id FROM shifts WHERE terminal_id = $1 AND status = 'open' FOR SHARE", *terminalID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoOpenShift
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"maps"
+	"testing"
+
+	"simple-cashier-api/models"
+)
+
+func TestRefundByMethod(t *testing.T) {
+	paid := func(methods ...any) []models.Payment {
+		var payments []models.Payment
+		for i := 0; i < len(methods); i += 2 {
+			payments = append(payments, models.Payment{Method: methods[i].(string), Amount: methods[i+1].(int)})
+		}
+		return payments
+	}
+	cash, qris, card := models.PaymentMethodCash, models.PaymentMethodQRIS, models.PaymentMethodDebitCard
+
+	tests := []struct {
+		name        string
+		total, cash int
+		payments    []models.Payment
+		want        map[string]int
+	}{
+		{"all in cash", 5000, 5000, paid(cash, 10000), map[string]int{cash: 5000}},
+		{"cash first, the rest by QRIS", 30000, 10000, paid(cash, 10000, qris, 40000), map[string]int{cash: 10000, qris: 20000}},
+		{"split by what each method took", 10000, 0, paid(qris, 20000, card, 10000), map[string]int{qris: 6666, card: 3334}},
+		{"no other method takes it in cash", 3000, 1000, paid(cash, 1000), map[string]int{cash: 3000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RefundByMethod(tt.total, tt.cash, tt.payments); !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
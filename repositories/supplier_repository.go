@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"simple-cashier-api/models"
+)
+
+type SupplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) *SupplierRepository {
+	return &SupplierRepository{db: db}
+}
+
+var supplierSortColumns = map[string]string{
+	"id":   "id",
+	"name": "name",
+}
+
+const supplierSelect = "SELECT id, name, contact_name, phone, email, address FROM suppliers"
+
+func scanSupplier(row rowScanner) (*models.Supplier, error) {
+	var s models.Supplier
+	if err := row.Scan(&s.ID, &s.Name, &s.ContactName, &s.Phone, &s.Email, &s.Address); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (repo *SupplierRepository) GetAll(filter models.SupplierFilter) ([]models.Supplier, int, error) {
+	where := ""
+	args := []any{}
+	if filter.Name != "" {
+		where = " WHERE name ILIKE $1"
+		args = append(args, "%"+filter.Name+"%")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM suppliers"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := supplierSelect + where +
+		orderBy(filter.Sort, supplierSortColumns) +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	suppliers := make([]models.Supplier, 0)
+	for rows.Next() {
+		s, err := scanSupplier(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		suppliers = append(suppliers, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return suppliers, total, nil
+}
+
+func (repo *SupplierRepository) Create(supplier *models.Supplier) error {
+	query := `INSERT INTO suppliers (name, contact_name, phone, email, address)
+	          VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	err := repo.db.QueryRow(query, supplier.Name, supplier.ContactName, supplier.Phone, supplier.Email, supplier.Address).Scan(&supplier.ID)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrSupplierNameTaken
+	}
+	return err
+}
+
+func (repo *SupplierRepository) GetByID(id int) (*models.Supplier, error) {
+	s, err := scanSupplier(repo.db.QueryRow(supplierSelect+" WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrSupplierNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (repo *SupplierRepository) Update(supplier *models.Supplier) error {
+	query := `UPDATE suppliers SET name = $1, contact_name = $2, phone = $3, email = $4, address = $5
+	          WHERE id = $6`
+	result, err := repo.db.Exec(query, supplier.Name, supplier.ContactName, supplier.Phone, supplier.Email, supplier.Address, supplier.ID)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrSupplierNameTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrSupplierNotFound
+	}
+
+	return nil
+}
+
+func (repo *SupplierRepository) Delete(id int) error {
+	result, err := repo.db.Exec("DELETE FROM suppliers WHERE id = $1", id)
+	if hasPQCode(err, pgForeignKeyViolation) {
+		return ErrSupplierInUse
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrSupplierNotFound
+	}
+
+	return nil
+}
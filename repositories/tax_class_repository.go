@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"simple-cashier-api/models"
+)
+
+type TaxClassRepository struct {
+	db *sql.DB
+}
+
+func NewTaxClassRepository(db *sql.DB) *TaxClassRepository {
+	return &TaxClassRepository{db: db}
+}
+
+const taxClassSelect = "SELECT id, name, rate_bp FROM tax_classes"
+
+func scanTaxClass(row rowScanner) (*models.TaxClass, error) {
+	var c models.TaxClass
+	if err := row.Scan(&c.ID, &c.Name, &c.RateBP); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (repo *TaxClassRepository) GetAll(filter models.TaxClassFilter) ([]models.TaxClass, int, error) {
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM tax_classes").Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.db.Query(taxClassSelect+" ORDER BY id LIMIT $1 OFFSET $2", filter.Limit, (filter.Page-1)*filter.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	classes := make([]models.TaxClass, 0)
+	for rows.Next() {
+		c, err := scanTaxClass(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		classes = append(classes, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return classes, total, nil
+}
+
+// GetRates maps every tax class id to its rate, for pricing a checkout.
+func (repo *TaxClassRepository) GetRates() (map[int]int, error) {
+	rows, err := repo.db.Query("SELECT id, rate_bp FROM tax_classes")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make(map[int]int)
+	for rows.Next() {
+		var id, rate int
+		if err := rows.Scan(&id, &rate); err != nil {
+			return nil, err
+		}
+		rates[id] = rate
+	}
+	return rates, rows.Err()
+}
+
+func (repo *TaxClassRepository) Create(class *models.TaxClass) error {
+	query := "INSERT INTO tax_classes (name, rate_bp) VALUES ($1, $2) RETURNING id"
+	err := repo.db.QueryRow(query, class.Name, class.RateBP).Scan(&class.ID)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrTaxClassNameTaken
+	}
+	return err
+}
+
+func (repo *TaxClassRepository) GetByID(id int) (*models.TaxClass, error) {
+	c, err := scanTaxClass(repo.db.QueryRow(taxClassSelect+" WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaxClassNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update changes the rate for future checkouts only: transactions keep the
+// rate each line was taxed at.
+func (repo *TaxClassRepository) Update(class *models.TaxClass) error {
+	result, err := repo.db.Exec("UPDATE tax_classes SET name = $1, rate_bp = $2 WHERE id = $3", class.Name, class.RateBP, class.ID)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrTaxClassNameTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrTaxClassNotFound
+	}
+
+	return nil
+}
+
+func (repo *TaxClassRepository) Delete(id int) error {
+	result, err := repo.db.Exec("DELETE FROM tax_classes WHERE id = $1", id)
+	if hasPQCode(err, pgForeignKeyViolation) {
+		return ErrTaxClassInUse
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrTaxClassNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"simple-cashier-api/models"
+)
+
+type TerminalRepository struct {
+	db *sql.DB
+}
+
+func NewTerminalRepository(db *sql.DB) *TerminalRepository {
+	return &TerminalRepository{db: db}
+}
+
+const terminalSelect = "SELECT id, code, name, active, key_hash, created_at FROM terminals"
+
+func scanTerminal(row rowScanner) (*models.Terminal, error) {
+	var t models.Terminal
+	if err := row.Scan(&t.ID, &t.Code, &t.Name, &t.Active, &t.KeyHash, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (repo *TerminalRepository) GetAll(filter models.TerminalFilter) ([]models.Terminal, int, error) {
+	where := ""
+	args := []any{}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		where = " WHERE active = $1"
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM terminals"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := terminalSelect + where + fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	terminals := make([]models.Terminal, 0)
+	for rows.Next() {
+		t, err := scanTerminal(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		terminals = append(terminals, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return terminals, total, nil
+}
+
+func (repo *TerminalRepository) Create(terminal *models.Terminal) error {
+	err := repo.db.QueryRow(
+		"INSERT INTO terminals (code, name, active, key_hash) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		terminal.Code, terminal.Name, terminal.Active, terminal.KeyHash,
+	).Scan(&terminal.ID, &terminal.CreatedAt)
+	if hasPQConstraint(err, "terminals_code_key") {
+		return ErrTerminalCodeTaken
+	}
+	return err
+}
+
+func (repo *TerminalRepository) GetByID(id int) (*models.Terminal, error) {
+	t, err := scanTerminal(repo.db.QueryRow(terminalSelect+" WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrTerminalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetByKeyHash finds the terminal a PIN login comes from.
+func (repo *TerminalRepository) GetByKeyHash(keyHash string) (*models.Terminal, error) {
+	t, err := scanTerminal(repo.db.QueryRow(terminalSelect+" WHERE key_hash = $1", keyHash))
+	if err == sql.ErrNoRows {
+		return nil, ErrTerminalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Update changes the code, name and active flag. The key is kept.
+func (repo *TerminalRepository) Update(terminal *models.Terminal) error {
+	err := repo.db.QueryRow(
+		"UPDATE terminals SET code = $1, name = $2, active = $3 WHERE id = $4 RETURNING key_hash, created_at",
+		terminal.Code, terminal.Name, terminal.Active, terminal.ID,
+	).Scan(&terminal.KeyHash, &terminal.CreatedAt)
+	if hasPQConstraint(err, "terminals_code_key") {
+		return ErrTerminalCodeTaken
+	}
+	if err == sql.ErrNoRows {
+		return ErrTerminalNotFound
+	}
+	return err
+}
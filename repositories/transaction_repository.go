@@ -3,6 +3,8 @@ package repositories
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"simple-cashier-api/models"
@@ -18,118 +20,916 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem) (*models.Transaction, error) {
+// CheckoutFinalizer completes a priced transaction draft before it is written,
+// e.g. by settling payments against TotalAmount. It runs inside the database
+// transaction while the product rows are locked; returning an error aborts
+// the checkout. voucher is the locked voucher code asked for, or nil; setting
+// trx.Voucher redeems it.
+type CheckoutFinalizer func(trx *models.Transaction, voucher *models.RedeemableVoucher) error
+
+// CreateTransaction checks out items, redeeming voucherCode when it is not
+// empty. The code must already be upper case. The transaction is booked to
+// the open shift of the terminal finalize sets, and refused with
+// ErrNoOpenShift when there is none. A non-nil claim records the transaction
+// on its idempotency key in the same database transaction.
+func (repo *TransactionRepository) CreateTransaction(items []models.CheckoutItem, voucherCode string, claim *models.IdempotencyClaim,
+	finalize CheckoutFinalizer) (*models.Transaction, error) {
 	tx, err := repo.db.Begin()
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	totalAmount := 0
-	details := make([]models.TransactionDetail, 0)
-
+	requested := map[int]int{}
 	for _, item := range items {
-		var productPrice, stock int
-		var productName string
+		requested[item.ProductID] += item.Quantity
+	}
 
-		err := tx.QueryRow("SELECT name, price, stock FROM products WHERE id = $1", item.ProductID).Scan(&productName, &productPrice, &stock)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return nil, fmt.Errorf("product id %d not found", item.ProductID)
-			}
-			return nil, err
-		}
+	productIDs := make([]int, 0, len(requested))
+	for id := range requested {
+		productIDs = append(productIDs, id)
+	}
+	sort.Ints(productIDs)
 
-		subtotal := productPrice * item.Quantity
-		totalAmount += subtotal
+	// Lock every product row in ascending id order, so concurrent checkouts
+	// touching the same products always queue instead of deadlocking.
+	rows, err := tx.Query("SELECT id, name, price, cost_price, stock, category_id, tax_class_id FROM products WHERE id = ANY($1) AND archived_at IS NULL ORDER BY id FOR UPDATE", pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
 
-		_, err = tx.Exec("UPDATE products SET stock = stock - $1 WHERE id = $2", item.Quantity, item.ProductID)
-		if err != nil {
+	type lockedProduct struct {
+		name       string
+		price      int
+		cost       int
+		stock      int
+		categoryID *int
+		taxClassID *int
+	}
+	products := map[int]lockedProduct{}
+	for rows.Next() {
+		var id int
+		var p lockedProduct
+		var categoryID, taxClassID sql.NullInt64
+		if err := rows.Scan(&id, &p.name, &p.price, &p.cost, &p.stock, &categoryID, &taxClassID); err != nil {
+			rows.Close()
 			return nil, err
 		}
+		if categoryID.Valid {
+			cid := int(categoryID.Int64)
+			p.categoryID = &cid
+		}
+		if taxClassID.Valid {
+			tid := int(taxClassID.Int64)
+			p.taxClassID = &tid
+		}
+		products[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	shortages := make([]models.StockShortage, 0)
+	for _, id := range productIDs {
+		p, ok := products[id]
+		if !ok {
+			return nil, Errorf(ErrValidation, "product id %d not found", id)
+		}
+		if requested[id] > p.stock {
+			shortages = append(shortages, models.StockShortage{
+				ProductID:   id,
+				ProductName: p.name,
+				Requested:   requested[id],
+				Available:   p.stock,
+			})
+		}
+	}
+	if len(shortages) > 0 {
+		return nil, &InsufficientStockError{Items: shortages}
+	}
 
-		details = append(details, models.TransactionDetail{
+	trx := models.Transaction{
+		Status:     models.TransactionStatusCompleted,
+		Details:    make([]models.TransactionDetail, 0, len(items)),
+		Promotions: make([]models.AppliedPromotion, 0),
+		Payments:   make([]models.Payment, 0),
+	}
+	for _, item := range items {
+		p := products[item.ProductID]
+		subtotal := p.price * item.Quantity
+		trx.Subtotal += subtotal
+		trx.TotalAmount += subtotal
+
+		trx.Details = append(trx.Details, models.TransactionDetail{
 			ProductID:   item.ProductID,
-			ProductName: productName,
+			ProductName: p.name,
+			CategoryID:  p.categoryID,
+			TaxClassID:  p.taxClassID,
 			Quantity:    item.Quantity,
+			UnitPrice:   p.price,
+			UnitCost:    p.cost,
 			Subtotal:    subtotal,
+			Total:       subtotal,
 		})
 	}
 
-	var transactionID int
-	var createdAt time.Time
-	err = tx.QueryRow("INSERT INTO transactions (total_amount) VALUES ($1) RETURNING id, created_at", totalAmount).Scan(&transactionID, &createdAt)
+	var voucher *models.RedeemableVoucher
+	if voucherCode != "" {
+		if voucher, err = lockVoucherCode(tx, voucherCode); err != nil {
+			return nil, err
+		}
+	}
+
+	if finalize != nil {
+		if err := finalize(&trx, voucher); err != nil {
+			return nil, err
+		}
+	}
+
+	if trx.ShiftID, err = lockOpenShift(tx, trx.TerminalID); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO transactions (user_id, cashier, terminal_id, shift_id, approver_id, approved_by, subtotal, discount_amount, service_charge, tax_amount, prices_include_tax,
+		                           total_amount, paid_amount, change_amount, status)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id, created_at`,
+		trx.UserID, trx.Cashier, trx.TerminalID, trx.ShiftID, trx.ApproverID, trx.ApprovedBy, trx.Subtotal, trx.DiscountAmount, trx.ServiceCharge, trx.TaxAmount, trx.PricesIncludeTax, trx.TotalAmount, trx.PaidAmount, trx.ChangeAmount, trx.Status,
+	).Scan(&trx.ID, &trx.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	txIDs := make([]int, len(details))
-	productIDs := make([]int, len(details))
-	quantities := make([]int, len(details))
-	subtotals := make([]int, len(details))
+	for _, id := range productIDs {
+		err := moveStock(tx, &models.InventoryMovement{
+			ProductID:     id,
+			Type:          models.MovementTypeSale,
+			Quantity:      -requested[id],
+			Actor:         trx.Cashier,
+			ReferenceType: models.ReferenceTypeTransaction,
+			ReferenceID:   &trx.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	txIDs := make([]int, len(trx.Details))
+	detailProductIDs := make([]int, len(trx.Details))
+	names := make([]string, len(trx.Details))
+	quantities := make([]int, len(trx.Details))
+	unitPrices := make([]int, len(trx.Details))
+	originalPrices := make([]sql.NullInt64, len(trx.Details))
+	unitCosts := make([]int, len(trx.Details))
+	discounts := make([]int, len(trx.Details))
+	subtotals := make([]int, len(trx.Details))
+	serviceCharges := make([]int, len(trx.Details))
+	taxRates := make([]int, len(trx.Details))
+	taxAmounts := make([]int, len(trx.Details))
+	totals := make([]int, len(trx.Details))
 
-	for i, d := range details {
-		txIDs[i] = transactionID
-		productIDs[i] = d.ProductID
+	for i, d := range trx.Details {
+		txIDs[i] = trx.ID
+		detailProductIDs[i] = d.ProductID
+		names[i] = d.ProductName
 		quantities[i] = d.Quantity
+		unitPrices[i] = d.UnitPrice
+		if d.OriginalPrice != nil {
+			originalPrices[i] = sql.NullInt64{Int64: int64(*d.OriginalPrice), Valid: true}
+		}
+		unitCosts[i] = d.UnitCost
+		discounts[i] = d.Discount
 		subtotals[i] = d.Subtotal
+		serviceCharges[i] = d.ServiceCharge
+		taxRates[i] = d.TaxRateBP
+		taxAmounts[i] = d.TaxAmount
+		totals[i] = d.Total
 	}
 
-	rows, err := tx.Query(
-		`INSERT INTO transaction_details (transaction_id, product_id, quantity, subtotal)
-						SELECT * FROM unnest($1::int[], $2::int[], $3::int[], $4::int[])
-						RETURNING id, transaction_id, product_id, quantity, subtotal`,
-		pq.Array(txIDs), pq.Array(productIDs), pq.Array(quantities), pq.Array(subtotals))
+	rows, err = tx.Query(
+		`INSERT INTO transaction_details (transaction_id, product_id, product_name, quantity, unit_price, unit_cost, discount, subtotal,
+						                                 service_charge, tax_rate_bp, tax_amount, total, original_price)
+						SELECT * FROM unnest($1::int[], $2::int[], $3::text[], $4::int[], $5::int[], $6::int[], $7::int[], $8::int[],
+						                     $9::int[], $10::int[], $11::int[], $12::int[], $13::int[])
+						RETURNING id`,
+		pq.Array(txIDs), pq.Array(detailProductIDs), pq.Array(names), pq.Array(quantities), pq.Array(unitPrices), pq.Array(unitCosts),
+		pq.Array(discounts), pq.Array(subtotals), pq.Array(serviceCharges), pq.Array(taxRates), pq.Array(taxAmounts), pq.Array(totals),
+		pq.Array(originalPrices))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; rows.Next(); i++ {
+		if err := rows.Scan(&trx.Details[i].ID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		trx.Details[i].TransactionID = trx.ID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range trx.Promotions {
+		p := &trx.Promotions[i]
+		p.TransactionID = trx.ID
+		err := tx.QueryRow(
+			"INSERT INTO transaction_promotions (transaction_id, promotion_id, name, type, amount) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			p.TransactionID, p.PromotionID, p.Name, p.Type, p.Amount,
+		).Scan(&p.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if trx.Voucher != nil {
+		if err := redeemVoucher(tx, &trx); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range trx.Payments {
+		p := &trx.Payments[i]
+		p.TransactionID = trx.ID
+		err := tx.QueryRow(
+			"INSERT INTO transaction_payments (transaction_id, method, amount, reference) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+			p.TransactionID, p.Method, p.Amount, p.Reference,
+		).Scan(&p.ID, &p.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if claim != nil {
+		if err := claimIdempotencyKey(tx, claim, trx.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &trx, nil
+}
+
+func (repo *TransactionRepository) GetTransactions(filter models.TransactionFilter) ([]models.Transaction, int, error) {
+	conditions := make([]string, 0)
+	args := []any{}
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.StartDate != nil {
+		addCondition("DATE($%d) <= DATE(t.created_at)", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		addCondition("DATE(t.created_at) <= DATE($%d)", *filter.EndDate)
+	}
+	if filter.MinTotal != nil {
+		addCondition("t.total_amount >= $%d", *filter.MinTotal)
+	}
+	if filter.MaxTotal != nil {
+		addCondition("t.total_amount <= $%d", *filter.MaxTotal)
+	}
+	if filter.ProductID != nil {
+		addCondition("EXISTS (SELECT 1 FROM transaction_details f WHERE f.transaction_id = t.id AND f.product_id = $%d)", *filter.ProductID)
+	}
+	if filter.UserID != nil {
+		addCondition("t.user_id = $%d", *filter.UserID)
+	}
+	if filter.ShiftID != nil {
+		addCondition("t.shift_id = $%d", *filter.ShiftID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM transactions t"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := "SELECT t.id, t.user_id, t.cashier, t.terminal_id, t.shift_id, t.approver_id, t.approved_by, t.subtotal, t.discount_amount, t.service_charge, t.tax_amount, t.prices_include_tax, t.total_amount, t.paid_amount, t.change_amount, t.status, t.created_at FROM transactions t" + where +
+		fmt.Sprintf(" ORDER BY t.created_at DESC, t.id DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	transactions := make([]models.Transaction, 0)
+	ids := make([]int, 0)
+	for rows.Next() {
+		var t models.Transaction
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Cashier, &t.TerminalID, &t.ShiftID, &t.ApproverID, &t.ApprovedBy, &t.Subtotal, &t.DiscountAmount, &t.ServiceCharge, &t.TaxAmount, &t.PricesIncludeTax, &t.TotalAmount, &t.PaidAmount, &t.ChangeAmount, &t.Status, &t.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		transactions = append(transactions, t)
+		ids = append(ids, t.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	details, err := repo.getDetails(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	promotions, err := repo.getPromotions(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	vouchers, err := repo.getVouchers(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	payments, err := repo.getPayments(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	refunds, err := repo.getRefunds(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range transactions {
+		transactions[i].Details = details[transactions[i].ID]
+		transactions[i].Promotions = promotions[transactions[i].ID]
+		transactions[i].Voucher = vouchers[transactions[i].ID]
+		transactions[i].Payments = payments[transactions[i].ID]
+		transactions[i].Refunds = refunds[transactions[i].ID]
+	}
+
+	return transactions, total, nil
+}
+
+func (repo *TransactionRepository) GetTransactionByID(id int) (*models.Transaction, error) {
+	var t models.Transaction
+	err := repo.db.QueryRow(
+		`SELECT id, user_id, cashier, terminal_id, shift_id, approver_id, approved_by, subtotal, discount_amount, service_charge, tax_amount, prices_include_tax,
+		        total_amount, paid_amount, change_amount, status, created_at
+		 FROM transactions WHERE id = $1`,
+		id,
+	).Scan(&t.ID, &t.UserID, &t.Cashier, &t.TerminalID, &t.ShiftID, &t.ApproverID, &t.ApprovedBy, &t.Subtotal, &t.DiscountAmount, &t.ServiceCharge, &t.TaxAmount, &t.PricesIncludeTax, &t.TotalAmount, &t.PaidAmount, &t.ChangeAmount, &t.Status, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := repo.getDetails([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	t.Details = details[id]
+
+	promotions, err := repo.getPromotions([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	t.Promotions = promotions[id]
+
+	vouchers, err := repo.getVouchers([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	t.Voucher = vouchers[id]
+
+	payments, err := repo.getPayments([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	t.Payments = payments[id]
+
+	refunds, err := repo.getRefunds([]int{id})
+	if err != nil {
+		return nil, err
+	}
+	t.Refunds = refunds[id]
+
+	return &t, nil
+}
+
+// getDetails loads the detail lines of the given transactions, keyed by
+// transaction id.
+func (repo *TransactionRepository) getDetails(transactionIDs []int) (map[int][]models.TransactionDetail, error) {
+	details := map[int][]models.TransactionDetail{}
+	for _, id := range transactionIDs {
+		details[id] = make([]models.TransactionDetail, 0)
+	}
+	if len(transactionIDs) == 0 {
+		return details, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT td.id, td.transaction_id, td.product_id, td.product_name, td.quantity, td.unit_price, td.original_price, td.unit_cost, td.discount, td.subtotal,
+						       td.service_charge, td.tax_rate_bp, td.tax_amount, td.total,
+						       coalesce((SELECT sum(rd.quantity) FROM refund_details rd WHERE rd.transaction_detail_id = td.id), 0)
+						FROM transaction_details td
+						WHERE td.transaction_id = ANY($1)
+						ORDER BY td.transaction_id, td.id`,
+		pq.Array(transactionIDs))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var insertedDetails []models.TransactionDetail
 	for rows.Next() {
 		var d models.TransactionDetail
-		err = rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.Quantity, &d.Subtotal)
+		err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.ProductName, &d.Quantity, &d.UnitPrice, &d.OriginalPrice, &d.UnitCost, &d.Discount, &d.Subtotal,
+			&d.ServiceCharge, &d.TaxRateBP, &d.TaxAmount, &d.Total, &d.RefundedQty)
+		if err != nil {
+			return nil, err
+		}
+
+		details[d.TransactionID] = append(details[d.TransactionID], d)
+	}
+
+	return details, rows.Err()
+}
+
+func (repo *TransactionRepository) getPromotions(transactionIDs []int) (map[int][]models.AppliedPromotion, error) {
+	promotions := map[int][]models.AppliedPromotion{}
+	for _, id := range transactionIDs {
+		promotions[id] = make([]models.AppliedPromotion, 0)
+	}
+	if len(transactionIDs) == 0 {
+		return promotions, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT id, transaction_id, promotion_id, name, type, amount
+						FROM transaction_promotions
+						WHERE transaction_id = ANY($1)
+						ORDER BY transaction_id, id`,
+		pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.AppliedPromotion
+		var promotionID sql.NullInt64
+		if err := rows.Scan(&p.ID, &p.TransactionID, &promotionID, &p.Name, &p.Type, &p.Amount); err != nil {
+			return nil, err
+		}
+		if promotionID.Valid {
+			id := int(promotionID.Int64)
+			p.PromotionID = &id
+		}
+		promotions[p.TransactionID] = append(promotions[p.TransactionID], p)
+	}
+
+	return promotions, rows.Err()
+}
+
+// getVouchers loads the voucher redeemed on each of the given transactions
+// that had one.
+func (repo *TransactionRepository) getVouchers(transactionIDs []int) (map[int]*models.AppliedVoucher, error) {
+	vouchers := map[int]*models.AppliedVoucher{}
+	if len(transactionIDs) == 0 {
+		return vouchers, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT id, transaction_id, voucher_id, voucher_code_id, code, name, amount
+						FROM voucher_redemptions
+						WHERE transaction_id = ANY($1)`,
+		pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.AppliedVoucher
+		if err := rows.Scan(&v.ID, &v.TransactionID, &v.VoucherID, &v.VoucherCodeID, &v.Code, &v.Name, &v.Amount); err != nil {
+			return nil, err
+		}
+		vouchers[v.TransactionID] = &v
+	}
+
+	return vouchers, rows.Err()
+}
+
+func (repo *TransactionRepository) getPayments(transactionIDs []int) (map[int][]models.Payment, error) {
+	payments := map[int][]models.Payment{}
+	for _, id := range transactionIDs {
+		payments[id] = make([]models.Payment, 0)
+	}
+	if len(transactionIDs) == 0 {
+		return payments, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT id, transaction_id, method, amount, reference, created_at
+						FROM transaction_payments
+						WHERE transaction_id = ANY($1)
+						ORDER BY transaction_id, id`,
+		pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Payment
+		if err := rows.Scan(&p.ID, &p.TransactionID, &p.Method, &p.Amount, &p.Reference, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		payments[p.TransactionID] = append(payments[p.TransactionID], p)
+	}
+
+	return payments, rows.Err()
+}
+
+func (repo *TransactionRepository) getRefunds(transactionIDs []int) (map[int][]models.Refund, error) {
+	refunds := map[int][]models.Refund{}
+	if len(transactionIDs) == 0 {
+		return refunds, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT r.id, r.transaction_id, r.type, r.reason, r.user_id, r.cashier, r.approver_id, r.approved_by, r.shift_id, r.total_amount,
+						       r.cash_amount, r.created_at,
+						       rd.id, rd.transaction_detail_id, rd.product_id, td.product_name, rd.quantity, rd.amount, rd.service_charge, rd.tax_amount
+						FROM refunds r
+						JOIN refund_details rd ON rd.refund_id = r.id
+						JOIN transaction_details td ON td.id = rd.transaction_detail_id
+						WHERE r.transaction_id = ANY($1)
+						ORDER BY r.transaction_id, r.id, rd.id`,
+		pq.Array(transactionIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Refund
+		var d models.RefundDetail
+		err := rows.Scan(
+			&r.ID, &r.TransactionID, &r.Type, &r.Reason, &r.UserID, &r.Cashier, &r.ApproverID, &r.ApprovedBy, &r.ShiftID, &r.TotalAmount,
+			&r.CashAmount, &r.CreatedAt,
+			&d.ID, &d.TransactionDetailID, &d.ProductID, &d.ProductName, &d.Quantity, &d.Amount, &d.ServiceCharge, &d.TaxAmount,
+		)
 		if err != nil {
 			return nil, err
 		}
+		d.RefundID = r.ID
+
+		list := refunds[r.TransactionID]
+		if len(list) == 0 || list[len(list)-1].ID != r.ID {
+			r.Details = make([]models.RefundDetail, 0)
+			list = append(list, r)
+		}
+		list[len(list)-1].Details = append(list[len(list)-1].Details, d)
+		refunds[r.TransactionID] = list
+	}
+
+	return refunds, rows.Err()
+}
+
+// refundableLine is a transaction detail line locked for a void or refund,
+// with what has already been given back.
+type refundableLine struct {
+	productID       int
+	quantity        int
+	total           int
+	serviceCharge   int
+	taxAmount       int
+	refundedQty     int
+	refundedAmount  int
+	refundedService int
+	refundedTax     int
+}
+
+// lockForRefund locks the transaction row, so voids and refunds of the same
+// transaction are serialised, and loads its detail lines.
+func lockForRefund(tx *sql.Tx, transactionID int) (string, bool, map[int]*refundableLine, []int, error) {
+	var status string
+	var sameDay bool
+	err := tx.QueryRow(
+		"SELECT status, DATE(created_at) = CURRENT_DATE FROM transactions WHERE id = $1 FOR UPDATE",
+		transactionID,
+	).Scan(&status, &sameDay)
+	if err == sql.ErrNoRows {
+		return "", false, nil, nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return "", false, nil, nil, err
+	}
+
+	rows, err := tx.Query(
+		`SELECT td.id, td.product_id, td.quantity, td.total, td.service_charge, td.tax_amount,
+						       coalesce(sum(rd.quantity), 0), coalesce(sum(rd.amount), 0),
+						       coalesce(sum(rd.service_charge), 0), coalesce(sum(rd.tax_amount), 0)
+						FROM transaction_details td
+						LEFT JOIN refund_details rd ON rd.transaction_detail_id = td.id
+						WHERE td.transaction_id = $1
+						GROUP BY td.id
+						ORDER BY td.id`,
+		transactionID)
+	if err != nil {
+		return "", false, nil, nil, err
+	}
+	defer rows.Close()
+
+	lines := map[int]*refundableLine{}
+	order := make([]int, 0)
+	for rows.Next() {
+		var id int
+		var l refundableLine
+		err := rows.Scan(&id, &l.productID, &l.quantity, &l.total, &l.serviceCharge, &l.taxAmount,
+			&l.refundedQty, &l.refundedAmount, &l.refundedService, &l.refundedTax)
+		if err != nil {
+			return "", false, nil, nil, err
+		}
+		lines[id] = &l
+		order = append(order, id)
+	}
+
+	return status, sameDay, lines, order, rows.Err()
+}
+
+// refund gives back quantity units of the line: their share of what was
+// charged for it, and of the service charge and tax that included. The last
+// units refunded take whatever is left, so rounding never leaks money.
+func (l *refundableLine) refund(detailID, quantity int) models.RefundDetail {
+	d := models.RefundDetail{TransactionDetailID: detailID, ProductID: l.productID, Quantity: quantity}
+	if l.refundedQty+quantity == l.quantity {
+		d.Amount = l.total - l.refundedAmount
+		d.ServiceCharge = l.serviceCharge - l.refundedService
+		d.TaxAmount = l.taxAmount - l.refundedTax
+	} else {
+		d.Amount = l.total * quantity / l.quantity
+		d.ServiceCharge = l.serviceCharge * quantity / l.quantity
+		d.TaxAmount = l.taxAmount * quantity / l.quantity
+	}
+
+	l.refundedQty += quantity
+	l.refundedAmount += d.Amount
+	l.refundedService += d.ServiceCharge
+	l.refundedTax += d.TaxAmount
+	return d
+}
+
+// VoidTransaction gives back the whole transaction, recorded as done by.
+func (repo *TransactionRepository) VoidTransaction(id int, reason string, by models.Actor) (*models.Refund, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-		insertedDetails = append(insertedDetails, d)
+	status, sameDay, lines, order, err := lockForRefund(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case status == models.TransactionStatusVoided:
+		return nil, ErrTransactionVoided
+	case status != models.TransactionStatusCompleted:
+		return nil, ErrTransactionRefunded
+	case !sameDay:
+		return nil, ErrVoidWindowClosed
+	}
+
+	refundDetails := make([]models.RefundDetail, 0, len(order))
+	for _, detailID := range order {
+		l := lines[detailID]
+		refundDetails = append(refundDetails, l.refund(detailID, l.quantity))
+	}
+
+	refund, err := insertRefund(tx, id, models.RefundTypeVoid, reason, by, refundDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("UPDATE transactions SET status = $1 WHERE id = $2", models.TransactionStatusVoided, id); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	return &models.Transaction{
-		ID:          transactionID,
-		CreatedAt:   createdAt,
-		TotalAmount: totalAmount,
-		Details:     insertedDetails,
-	}, nil
+	return refund, nil
+}
+
+func (repo *TransactionRepository) RefundTransaction(id int, req models.RefundRequest, by models.Actor) (*models.Refund, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	status, _, lines, order, err := lockForRefund(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if status == models.TransactionStatusVoided {
+		return nil, ErrTransactionVoided
+	}
+
+	requested := map[int]int{}
+	for _, item := range req.Items {
+		if _, ok := lines[item.DetailID]; !ok {
+			return nil, fmt.Errorf("%w: detail id %d does not belong to transaction %d", ErrInvalidRefund, item.DetailID, id)
+		}
+		requested[item.DetailID] += item.Quantity
+	}
+
+	refundDetails := make([]models.RefundDetail, 0, len(requested))
+	for _, detailID := range order {
+		quantity, ok := requested[detailID]
+		if !ok {
+			continue
+		}
+
+		l := lines[detailID]
+		if remaining := l.quantity - l.refundedQty; quantity > remaining {
+			return nil, fmt.Errorf("%w: detail id %d has only %d refundable unit(s), requested %d", ErrInvalidRefund, detailID, remaining, quantity)
+		}
+
+		refundDetails = append(refundDetails, l.refund(detailID, quantity))
+	}
+
+	refund, err := insertRefund(tx, id, models.RefundTypeRefund, req.Reason, by, refundDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	newStatus := models.TransactionStatusRefunded
+	for _, l := range lines {
+		if l.refundedQty < l.quantity {
+			newStatus = models.TransactionStatusPartiallyRefunded
+			break
+		}
+	}
+	if _, err := tx.Exec("UPDATE transactions SET status = $1 WHERE id = $2", newStatus, id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// insertRefund records the refund and its lines on the open shift at by's
+// terminal, which pays it out, and puts the refunded quantities back into
+// stock, locking products in ascending id order like CreateTransaction does.
+// The caller must hold the transaction locked, see lockForRefund.
+func insertRefund(tx *sql.Tx, transactionID int, refundType string, reason string, by models.Actor, details []models.RefundDetail) (*models.Refund, error) {
+	shiftID, err := lockOpenShift(tx, by.TerminalID)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := models.Refund{
+		TransactionID: transactionID,
+		Type:          refundType,
+		Reason:        reason,
+		UserID:        by.UserID(),
+		Cashier:       by.Username(),
+		ApproverID:    by.ApproverID(),
+		ApprovedBy:    by.ApprovedBy(),
+		ShiftID:       shiftID,
+		Details:       make([]models.RefundDetail, 0, len(details)),
+	}
+	for _, d := range details {
+		refund.TotalAmount += d.Amount
+	}
+
+	// Cash goes back first, up to the cash the transaction kept.
+	err = tx.QueryRow(
+		`SELECT GREATEST(LEAST($2,
+		            (SELECT COALESCE(SUM(amount), 0) FROM transaction_payments WHERE transaction_id = t.id AND method = 'cash')
+		            - t.change_amount
+		            - (SELECT COALESCE(SUM(cash_amount), 0) FROM refunds WHERE transaction_id = t.id)), 0)
+		 FROM transactions t WHERE t.id = $1`,
+		transactionID, refund.TotalAmount,
+	).Scan(&refund.CashAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(
+		`INSERT INTO refunds (transaction_id, type, reason, user_id, cashier, approver_id, approved_by, shift_id, total_amount, cash_amount)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at`,
+		transactionID, refundType, reason, refund.UserID, refund.Cashier, refund.ApproverID, refund.ApprovedBy, refund.ShiftID,
+		refund.TotalAmount, refund.CashAmount,
+	).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	restock := map[int]int{}
+	for _, d := range details {
+		d.RefundID = refund.ID
+		err := tx.QueryRow(
+			`INSERT INTO refund_details (refund_id, transaction_detail_id, product_id, quantity, amount, service_charge, tax_amount)
+							VALUES ($1, $2, $3, $4, $5, $6, $7)
+							RETURNING id, (SELECT product_name FROM transaction_details WHERE id = $2)`,
+			d.RefundID, d.TransactionDetailID, d.ProductID, d.Quantity, d.Amount, d.ServiceCharge, d.TaxAmount,
+		).Scan(&d.ID, &d.ProductName)
+		if err != nil {
+			return nil, err
+		}
+
+		refund.Details = append(refund.Details, d)
+		restock[d.ProductID] += d.Quantity
+	}
+
+	productIDs := make([]int, 0, len(restock))
+	for productID := range restock {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Ints(productIDs)
+
+	for _, productID := range productIDs {
+		err := moveStock(tx, &models.InventoryMovement{
+			ProductID:     productID,
+			Type:          refundType,
+			Quantity:      restock[productID],
+			Reason:        reason,
+			Actor:         refund.Cashier,
+			ApprovedBy:    refund.ApprovedBy,
+			ReferenceType: models.ReferenceTypeRefund,
+			ReferenceID:   &refund.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &refund, nil
 }
 
 func (repo *TransactionRepository) GetTransactionReport(startDate time.Time, endDate time.Time) (*models.TransactionReport, error) {
 	var r models.TransactionReport
 
+	// Refunds are netted out on the day they were made, not on the day of the
+	// original sale, so a closed day's figures never change afterwards.
 	err := repo.db.QueryRow(
-		`SELECT coalesce(sum(td.subtotal), 0) as total_revenue, count(DISTINCT t.id) as total_transaksi
-						FROM transactions t
-						LEFT JOIN transaction_details td ON t.id = td.transaction_id
-						WHERE DATE($1) <= DATE(t.created_at) AND DATE(t.created_at) <= DATE($2)`,
+		`WITH lines AS (
+						  SELECT td.total - td.tax_amount - td.service_charge AS revenue, td.tax_amount, td.service_charge
+						  FROM transactions t
+						  JOIN transaction_details td ON t.id = td.transaction_id
+						  WHERE DATE($1) <= DATE(t.created_at) AND DATE(t.created_at) <= DATE($2)
+						  UNION ALL
+						  SELECT -(rd.amount - rd.tax_amount - rd.service_charge), -rd.tax_amount, -rd.service_charge
+						  FROM refunds r
+						  JOIN refund_details rd ON r.id = rd.refund_id
+						  WHERE DATE($1) <= DATE(r.created_at) AND DATE(r.created_at) <= DATE($2)
+						)
+						SELECT
+						  (SELECT coalesce(sum(revenue), 0) FROM lines) as total_revenue,
+						  (SELECT coalesce(sum(tax_amount), 0) FROM lines) as total_tax,
+						  (SELECT coalesce(sum(service_charge), 0) FROM lines) as total_service_charge,
+						  (SELECT count(*)
+						   FROM transactions t
+						   WHERE DATE($1) <= DATE(t.created_at) AND DATE(t.created_at) <= DATE($2)
+						     AND t.status <> 'voided') as total_transaksi`,
 		&startDate, &endDate,
-	).Scan(&r.TotalRevenue, &r.TotalTransaksi)
+	).Scan(&r.TotalRevenue, &r.TotalTax, &r.TotalService, &r.TotalTransaksi)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Taxes, err = repo.getTaxesCollected(startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 
 	rows, err := repo.db.Query(
-		`WITH ranked_sales AS (
-  						SELECT p.name as nama, coalesce(sum(td.quantity), 0) as qty_terjual, RANK() OVER (ORDER BY COALESCE(SUM(td.quantity), 0) DESC) as sales_rank
+		`WITH movements AS (
+  						SELECT td.product_id, td.quantity
   						FROM transactions t
-  						LEFT JOIN transaction_details td ON t.id = td.transaction_id
-  						JOIN products p ON td.product_id = p.id
+  						JOIN transaction_details td ON t.id = td.transaction_id
   						WHERE DATE($1) <= DATE(t.created_at) AND DATE(t.created_at) <= DATE($2)
+  						UNION ALL
+  						SELECT rd.product_id, -rd.quantity
+  						FROM refunds r
+  						JOIN refund_details rd ON r.id = rd.refund_id
+  						WHERE DATE($1) <= DATE(r.created_at) AND DATE(r.created_at) <= DATE($2)
+						), ranked_sales AS (
+  						SELECT p.name as nama, sum(m.quantity) as qty_terjual, RANK() OVER (ORDER BY sum(m.quantity) DESC) as sales_rank
+  						FROM movements m
+  						JOIN products p ON m.product_id = p.id
 							GROUP BY p.id, p.name
+							HAVING sum(m.quantity) > 0
 						)
 						SELECT nama, qty_terjual
 						FROM ranked_sales
@@ -152,19 +952,142 @@ func (repo *TransactionRepository) GetTransactionReport(startDate time.Time, end
 	}
 
 	if len(bestSellingProducts) == 1 {
-		return &models.TransactionReport{
-			TotalRevenue:   r.TotalRevenue,
-			TotalTransaksi: r.TotalTransaksi,
-			ProdukTerlaris: models.BestSellingProduct{
-				Nama:         bestSellingProducts[0].Nama,
-				QuantitySold: bestSellingProducts[0].QuantitySold,
-			},
-		}, nil
-	}
-
-	return &models.TransactionReport{
-		TotalRevenue:   r.TotalRevenue,
-		TotalTransaksi: r.TotalTransaksi,
-		ProdukTerlaris: bestSellingProducts,
-	}, nil
+		r.ProdukTerlaris = bestSellingProducts[0]
+	} else {
+		r.ProdukTerlaris = bestSellingProducts
+	}
+
+	r.Products, err = repo.getProductProfits(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Promotions, err = repo.getPromotionUsage(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// getTaxesCollected totals the tax of the period's sales less the tax given
+// back by its refunds, by the rate it was charged at.
+func (repo *TransactionRepository) getTaxesCollected(startDate time.Time, endDate time.Time) ([]models.TaxCollected, error) {
+	rows, err := repo.db.Query(
+		`WITH lines AS (
+						  SELECT td.tax_rate_bp, td.tax_amount
+						  FROM transactions t
+						  JOIN transaction_details td ON t.id = td.transaction_id
+						  WHERE DATE($1) <= DATE(t.created_at) AND DATE(t.created_at) <= DATE($2)
+						  UNION ALL
+						  SELECT td.tax_rate_bp, -rd.tax_amount
+						  FROM refunds r
+						  JOIN refund_details rd ON r.id = rd.refund_id
+						  JOIN transaction_details td ON td.id = rd.transaction_detail_id
+						  WHERE DATE($1) <= DATE(r.created_at) AND DATE(r.created_at) <= DATE($2)
+						)
+						SELECT tax_rate_bp, sum(tax_amount)
+						FROM lines
+						GROUP BY tax_rate_bp
+						HAVING sum(tax_amount) <> 0
+						ORDER BY tax_rate_bp`,
+		&startDate, &endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taxes := make([]models.TaxCollected, 0)
+	for rows.Next() {
+		var t models.TaxCollected
+		if err := rows.Scan(&t.TaxRateBP, &t.TaxAmount); err != nil {
+			return nil, err
+		}
+		taxes = append(taxes, t)
+	}
+
+	return taxes, rows.Err()
+}
+
+// getPromotionUsage totals the discounts each promotion gave on the period's
+// transactions that were kept, see models.PromotionUsage.
+func (repo *TransactionRepository) getPromotionUsage(startDate time.Time, endDate time.Time) ([]models.PromotionUsage, error) {
+	rows, err := repo.db.Query(
+		`SELECT tp.promotion_id, tp.name, tp.type, count(DISTINCT tp.transaction_id), sum(tp.amount)
+						FROM transactions t
+						JOIN transaction_promotions tp ON tp.transaction_id = t.id
+						WHERE DATE($1) <= DATE(t.created_at) AND DATE(t.created_at) <= DATE($2)
+						  AND t.status NOT IN ('voided', 'refunded')
+						GROUP BY tp.promotion_id, tp.name, tp.type
+						ORDER BY tp.promotion_id, tp.name`,
+		&startDate, &endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make([]models.PromotionUsage, 0)
+	for rows.Next() {
+		var u models.PromotionUsage
+		var promotionID sql.NullInt64
+		if err := rows.Scan(&promotionID, &u.Name, &u.Type, &u.Transactions, &u.DiscountAmount); err != nil {
+			return nil, err
+		}
+		if promotionID.Valid {
+			id := int(promotionID.Int64)
+			u.PromotionID = &id
+		}
+		usage = append(usage, u)
+	}
+
+	return usage, rows.Err()
+}
+
+// getProductProfits returns each product's net quantity, revenue and cost
+// for the report period. Revenue excludes tax and service charge. A refund
+// takes back the unit cost of the line it refunds.
+func (repo *TransactionRepository) getProductProfits(startDate time.Time, endDate time.Time) ([]models.ProductProfit, error) {
+	rows, err := repo.db.Query(
+		`WITH lines AS (
+						  SELECT td.product_id, td.quantity, td.total - td.tax_amount - td.service_charge AS revenue, td.quantity * td.unit_cost AS cost
+						  FROM transactions t
+						  JOIN transaction_details td ON t.id = td.transaction_id
+						  WHERE DATE($1) <= DATE(t.created_at) AND DATE(t.created_at) <= DATE($2)
+						  UNION ALL
+						  SELECT rd.product_id, -rd.quantity, -(rd.amount - rd.tax_amount - rd.service_charge), -rd.quantity * td.unit_cost
+						  FROM refunds r
+						  JOIN refund_details rd ON r.id = rd.refund_id
+						  JOIN transaction_details td ON td.id = rd.transaction_detail_id
+						  WHERE DATE($1) <= DATE(r.created_at) AND DATE(r.created_at) <= DATE($2)
+						)
+						SELECT p.id, p.name, p.category_id, c.name, sum(l.quantity), sum(l.revenue), sum(l.cost)
+						FROM lines l
+						JOIN products p ON p.id = l.product_id
+						LEFT JOIN categories c ON c.id = p.category_id
+						GROUP BY p.id, c.name
+						ORDER BY p.id`,
+		&startDate, &endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]models.ProductProfit, 0)
+	for rows.Next() {
+		var p models.ProductProfit
+		var categoryID sql.NullInt64
+		var categoryName sql.NullString
+		err := rows.Scan(&p.ProductID, &p.Name, &categoryID, &categoryName, &p.QuantitySold, &p.Revenue, &p.Cost)
+		if err != nil {
+			return nil, err
+		}
+		if categoryID.Valid {
+			id := int(categoryID.Int64)
+			p.CategoryID = &id
+		}
+		p.CategoryName = categoryName.String
+		products = append(products, p)
+	}
+
+	return products, rows.Err()
 }
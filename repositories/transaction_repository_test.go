@@ -0,0 +1,351 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"simple-cashier-api/database"
+	"simple-cashier-api/models"
+)
+
+// openTestDB connects to the throwaway PostgreSQL database in TEST_DB_CONN,
+// migrates it and empties every table. Tests are skipped when it is not set.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	conn := os.Getenv("TEST_DB_CONN")
+	if conn == "" {
+		t.Skip("TEST_DB_CONN not set, skipping PostgreSQL test")
+	}
+
+	db, err := database.InitDB(conn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := db.Exec("TRUNCATE transaction_details, transactions, shifts, terminals, products, categories RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	return db
+}
+
+func createTestProduct(t *testing.T, db *sql.DB, name string, price, stock int) int {
+	t.Helper()
+
+	p := models.Product{Name: name, Price: price, Stock: stock}
+	if err := NewProductRepository(db).Create(&p); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	return p.ID
+}
+
+// openTestShift registers a terminal with an open shift and returns a
+// checkout finalizer that rings transactions up at it.
+func openTestShift(t *testing.T, db *sql.DB) func(*models.Transaction, *models.RedeemableVoucher) error {
+	t.Helper()
+
+	terminal := models.Terminal{Code: "KASIR-1", Name: "Kasir 1", Active: true, KeyHash: "test"}
+	if err := NewTerminalRepository(db).Create(&terminal); err != nil {
+		t.Fatalf("create terminal: %v", err)
+	}
+	if err := NewShiftRepository(db).Open(&models.Shift{TerminalID: terminal.ID}); err != nil {
+		t.Fatalf("open shift: %v", err)
+	}
+	return func(trx *models.Transaction, _ *models.RedeemableVoucher) error {
+		trx.TerminalID = &terminal.ID
+		return nil
+	}
+}
+
+func productStock(t *testing.T, db *sql.DB, id int) int {
+	t.Helper()
+
+	var stock int
+	if err := db.QueryRow("SELECT stock FROM products WHERE id = $1", id).Scan(&stock); err != nil {
+		t.Fatalf("read stock: %v", err)
+	}
+	return stock
+}
+
+func TestCreateTransactionRejectsInsufficientStock(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+
+	indomie := createTestProduct(t, db, "Indomie Goreng", 3500, 5)
+	aqua := createTestProduct(t, db, "Aqua 600ml", 2000, 1)
+	teh := createTestProduct(t, db, "Teh Botol", 4000, 10)
+
+	_, err := repo.CreateTransaction([]models.CheckoutItem{
+		{ProductID: aqua, Quantity: 2},
+		{ProductID: indomie, Quantity: 4},
+		{ProductID: teh, Quantity: 1},
+		{ProductID: indomie, Quantity: 2},
+	}, "", nil, till)
+
+	var stockErr *InsufficientStockError
+	if !errors.As(err, &stockErr) {
+		t.Fatalf("expected InsufficientStockError, got %v", err)
+	}
+	if len(stockErr.Items) != 2 {
+		t.Fatalf("expected 2 offending products, got %+v", stockErr.Items)
+	}
+	if got := stockErr.Items[0]; got.ProductID != indomie || got.Requested != 6 || got.Available != 5 {
+		t.Errorf("unexpected shortage for indomie: %+v", got)
+	}
+	if got := stockErr.Items[1]; got.ProductID != aqua || got.Requested != 2 || got.Available != 1 {
+		t.Errorf("unexpected shortage for aqua: %+v", got)
+	}
+
+	for id, want := range map[int]int{indomie: 5, aqua: 1, teh: 10} {
+		if got := productStock(t, db, id); got != want {
+			t.Errorf("product %d stock = %d, want %d (rejected checkout must not touch stock)", id, got, want)
+		}
+	}
+}
+
+func TestCreateTransactionConcurrentCheckoutsNeverOversell(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+
+	const stock = 10
+	const buyers = 25
+	id := createTestProduct(t, db, "Indomie Goreng", 3500, stock)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded, rejected := 0, 0
+
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: id, Quantity: 1}}, "", nil, till)
+
+			mu.Lock()
+			defer mu.Unlock()
+			var stockErr *InsufficientStockError
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.As(err, &stockErr):
+				rejected++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != stock || rejected != buyers-stock {
+		t.Errorf("succeeded = %d, rejected = %d, want %d and %d", succeeded, rejected, stock, buyers-stock)
+	}
+	if got := productStock(t, db, id); got != 0 {
+		t.Errorf("stock = %d, want 0", got)
+	}
+}
+
+func TestCreateTransactionOppositeItemOrderDoesNotDeadlock(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+
+	a := createTestProduct(t, db, "Indomie Goreng", 3500, 50)
+	b := createTestProduct(t, db, "Aqua 600ml", 2000, 50)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		items := []models.CheckoutItem{{ProductID: a, Quantity: 1}, {ProductID: b, Quantity: 1}}
+		if i%2 == 1 {
+			items[0], items[1] = items[1], items[0]
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.CreateTransaction(items, "", nil, till); err != nil {
+				t.Errorf("checkout: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := productStock(t, db, a); got != 30 {
+		t.Errorf("stock a = %d, want 30", got)
+	}
+	if got := productStock(t, db, b); got != 30 {
+		t.Errorf("stock b = %d, want 30", got)
+	}
+}
+
+func TestGetTransactionsFilters(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+
+	indomie := createTestProduct(t, db, "Indomie Goreng", 3500, 100)
+	aqua := createTestProduct(t, db, "Aqua 600ml", 2000, 100)
+
+	small, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: aqua, Quantity: 1}}, "", nil, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	large, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: indomie, Quantity: 4}, {ProductID: aqua, Quantity: 1}}, "", nil, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	minTotal := 10000
+	got, total, err := repo.GetTransactions(models.TransactionFilter{MinTotal: &minTotal, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 1 || len(got) != 1 || got[0].ID != large.ID {
+		t.Fatalf("min_total filter returned %+v (total %d), want only transaction %d", got, total, large.ID)
+	}
+	if d := got[0].Details[0]; d.ProductName != "Indomie Goreng" || d.UnitPrice != 3500 {
+		t.Errorf("unexpected detail %+v", d)
+	}
+
+	got, total, err = repo.GetTransactions(models.TransactionFilter{ProductID: &aqua, Page: 1, Limit: 1})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if total != 2 || len(got) != 1 || got[0].ID != large.ID {
+		t.Fatalf("product filter page 1 returned %+v (total %d)", got, total)
+	}
+
+	byID, err := repo.GetTransactionByID(small.ID)
+	if err != nil {
+		t.Fatalf("get by id: %v", err)
+	}
+	if byID.TotalAmount != 2000 || len(byID.Details) != 1 {
+		t.Errorf("unexpected transaction %+v", byID)
+	}
+}
+
+func TestRefundRestoresStockAndNetsReport(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+
+	indomie := createTestProduct(t, db, "Indomie Goreng", 3500, 10)
+	aqua := createTestProduct(t, db, "Aqua 600ml", 2000, 10)
+
+	trx, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: indomie, Quantity: 4}, {ProductID: aqua, Quantity: 2}}, "", nil, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	refund, err := repo.RefundTransaction(trx.ID, models.RefundRequest{
+		Items: []models.RefundItem{{DetailID: trx.Details[0].ID, Quantity: 3}},
+	}, models.Actor{TerminalID: trx.TerminalID})
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if refund.TotalAmount != 10500 {
+		t.Errorf("refund amount = %d, want 10500", refund.TotalAmount)
+	}
+	if got := productStock(t, db, indomie); got != 9 {
+		t.Errorf("indomie stock = %d, want 9", got)
+	}
+
+	_, err = repo.RefundTransaction(trx.ID, models.RefundRequest{
+		Items: []models.RefundItem{{DetailID: trx.Details[0].ID, Quantity: 2}},
+	}, models.Actor{TerminalID: trx.TerminalID})
+	if !errors.Is(err, ErrInvalidRefund) {
+		t.Errorf("refunding more than sold: got %v, want ErrInvalidRefund", err)
+	}
+
+	if _, err := repo.VoidTransaction(trx.ID, "", models.Actor{TerminalID: trx.TerminalID}); !errors.Is(err, ErrTransactionRefunded) {
+		t.Errorf("voiding a refunded transaction: got %v, want ErrTransactionRefunded", err)
+	}
+
+	now := time.Now()
+	report, err := repo.GetTransactionReport(now, now)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if report.TotalRevenue != 18000-10500 {
+		t.Errorf("revenue = %d, want %d", report.TotalRevenue, 18000-10500)
+	}
+	best, ok := report.ProdukTerlaris.(models.BestSellingProduct)
+	if !ok || best.Nama != "Aqua 600ml" || best.QuantitySold != 2 {
+		t.Errorf("best seller = %+v, want Aqua 600ml x2", report.ProdukTerlaris)
+	}
+}
+
+func TestVoidTransactionRestoresAllStock(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+
+	indomie := createTestProduct(t, db, "Indomie Goreng", 3500, 10)
+	trx, err := repo.CreateTransaction([]models.CheckoutItem{{ProductID: indomie, Quantity: 4}}, "", nil, till)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	refund, err := repo.VoidTransaction(trx.ID, "wrong item scanned", models.Actor{TerminalID: trx.TerminalID})
+	if err != nil {
+		t.Fatalf("void: %v", err)
+	}
+	if refund.Type != models.RefundTypeVoid || refund.TotalAmount != trx.TotalAmount {
+		t.Errorf("unexpected void refund %+v", refund)
+	}
+	if got := productStock(t, db, indomie); got != 10 {
+		t.Errorf("stock = %d, want 10", got)
+	}
+
+	if _, err := repo.VoidTransaction(trx.ID, "", models.Actor{TerminalID: trx.TerminalID}); !errors.Is(err, ErrTransactionVoided) {
+		t.Errorf("second void: got %v, want ErrTransactionVoided", err)
+	}
+}
+
+func TestCreateTransactionFinalizerPaymentsAndRollback(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewTransactionRepository(db)
+	till := openTestShift(t, db)
+
+	indomie := createTestProduct(t, db, "Indomie Goreng", 3500, 10)
+	items := []models.CheckoutItem{{ProductID: indomie, Quantity: 2}}
+
+	_, err := repo.CreateTransaction(items, "", nil, func(trx *models.Transaction, _ *models.RedeemableVoucher) error {
+		return errors.New("declined")
+	})
+	if err == nil || err.Error() != "declined" {
+		t.Fatalf("expected finalizer error, got %v", err)
+	}
+	if got := productStock(t, db, indomie); got != 10 {
+		t.Fatalf("stock = %d after aborted checkout, want 10", got)
+	}
+
+	trx, err := repo.CreateTransaction(items, "", nil, func(trx *models.Transaction, _ *models.RedeemableVoucher) error {
+		till(trx, nil)
+		trx.PaidAmount = 10000
+		trx.ChangeAmount = 10000 - trx.TotalAmount
+		trx.Payments = []models.Payment{{Method: models.PaymentMethodCash, Amount: 10000}}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	stored, err := repo.GetTransactionByID(trx.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.ChangeAmount != 3000 || len(stored.Payments) != 1 || stored.Payments[0].Amount != 10000 {
+		t.Errorf("unexpected stored payments %+v (change %d)", stored.Payments, stored.ChangeAmount)
+	}
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+const userSelect = "SELECT id, username, name, role, active, password_hash, pin_hash, pin_failures, pin_locked_until, created_at FROM users"
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var u models.User
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&u.ID, &u.Username, &u.Name, &u.Role, &u.Active, &u.PasswordHash, &u.PINHash, &u.PINFailures, &lockedUntil, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+	u.PINLockedUntil = lockedUntil.Time
+	return &u, nil
+}
+
+func (repo *UserRepository) GetAll(filter models.UserFilter) ([]models.User, int, error) {
+	conditions := make([]string, 0)
+	args := []any{}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("role = $%d", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conditions = append(conditions, fmt.Sprintf("active = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM users"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := userSelect + where + fmt.Sprintf(" ORDER BY id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (repo *UserRepository) Create(user *models.User) error {
+	err := repo.db.QueryRow(
+		"INSERT INTO users (username, name, role, active, password_hash, pin_hash) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		user.Username, user.Name, user.Role, user.Active, user.PasswordHash, user.PINHash,
+	).Scan(&user.ID, &user.CreatedAt)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+func (repo *UserRepository) GetByID(id int) (*models.User, error) {
+	u, err := scanUser(repo.db.QueryRow(userSelect+" WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// GetByUsername looks up a login. username must already be lower case.
+func (repo *UserRepository) GetByUsername(username string) (*models.User, error) {
+	u, err := scanUser(repo.db.QueryRow(userSelect+" WHERE username = $1", username))
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (repo *UserRepository) Update(user *models.User) error {
+	err := repo.db.QueryRow(
+		`UPDATE users SET username = $1, name = $2, role = $3, active = $4, password_hash = $5, pin_hash = $6, pin_failures = $7,
+		                  pin_locked_until = $8
+		 WHERE id = $9 RETURNING created_at`,
+		user.Username, user.Name, user.Role, user.Active, user.PasswordHash, user.PINHash, user.PINFailures,
+		sql.NullTime{Time: user.PINLockedUntil, Valid: !user.PINLockedUntil.IsZero()}, user.ID,
+	).Scan(&user.CreatedAt)
+	if hasPQCode(err, pgUniqueViolation) {
+		return ErrUsernameTaken
+	}
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// RecordPINFailure counts a failed PIN attempt and returns how many there
+// have been in a row.
+func (repo *UserRepository) RecordPINFailure(id int) (int, error) {
+	var failures int
+	err := repo.db.QueryRow("UPDATE users SET pin_failures = pin_failures + 1 WHERE id = $1 RETURNING pin_failures", id).Scan(&failures)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	return failures, err
+}
+
+// ResetPINFailures clears the count after a successful PIN attempt.
+func (repo *UserRepository) ResetPINFailures(id int) error {
+	_, err := repo.db.Exec("UPDATE users SET pin_failures = 0 WHERE id = $1", id)
+	return err
+}
+
+// LockPIN refuses the user's PIN until until, and starts the count of failed
+// attempts afresh for when it opens again.
+func (repo *UserRepository) LockPIN(id int, until time.Time) error {
+	_, err := repo.db.Exec("UPDATE users SET pin_failures = 0, pin_locked_until = $1 WHERE id = $2", until, id)
+	return err
+}
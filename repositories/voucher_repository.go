@@ -0,0 +1,338 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"simple-cashier-api/models"
+)
+
+type VoucherRepository struct {
+	db *sql.DB
+}
+
+func NewVoucherRepository(db *sql.DB) *VoucherRepository {
+	return &VoucherRepository{db: db}
+}
+
+// voucherSelect reads a voucher with its scopes and number of codes. Callers
+// append their WHERE clause and scan the row with scanVoucher.
+const voucherSelect = `SELECT v.id, v.name, v.type, v.value, v.max_discount, v.min_purchase, v.starts_at, v.ends_at,
+                              v.uses_per_code, v.max_uses, v.active, v.redemptions,
+                              (SELECT count(*) FROM voucher_codes c WHERE c.voucher_id = v.id),
+                              ARRAY(SELECT vp.product_id FROM voucher_products vp WHERE vp.voucher_id = v.id ORDER BY vp.product_id),
+                              ARRAY(SELECT vc.category_id FROM voucher_categories vc WHERE vc.voucher_id = v.id ORDER BY vc.category_id)
+                       FROM vouchers v`
+
+func scanVoucher(row rowScanner) (*models.Voucher, error) {
+	var v models.Voucher
+	var startsAt, endsAt pq.NullTime
+	var productIDs, categoryIDs pq.Int64Array
+	err := row.Scan(&v.ID, &v.Name, &v.Type, &v.Value, &v.MaxDiscount, &v.MinPurchase, &startsAt, &endsAt,
+		&v.UsesPerCode, &v.MaxUses, &v.Active, &v.Redemptions, &v.CodeCount, &productIDs, &categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	if startsAt.Valid {
+		v.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		v.EndsAt = &endsAt.Time
+	}
+	v.ProductIDs = make([]int, len(productIDs))
+	for i, id := range productIDs {
+		v.ProductIDs[i] = int(id)
+	}
+	v.CategoryIDs = make([]int, len(categoryIDs))
+	for i, id := range categoryIDs {
+		v.CategoryIDs[i] = int(id)
+	}
+	return &v, nil
+}
+
+func (repo *VoucherRepository) GetAll(filter models.VoucherFilter) ([]models.Voucher, int, error) {
+	where := ""
+	args := []any{}
+	if filter.Active != nil {
+		where = " WHERE v.active = $1"
+		args = append(args, *filter.Active)
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM vouchers v"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := voucherSelect + where + fmt.Sprintf(" ORDER BY v.id LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	vouchers := make([]models.Voucher, 0)
+	for rows.Next() {
+		v, err := scanVoucher(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		vouchers = append(vouchers, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return vouchers, total, nil
+}
+
+func (repo *VoucherRepository) Create(voucher *models.Voucher) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO vouchers (name, type, value, max_discount, min_purchase, starts_at, ends_at, uses_per_code, max_uses, active)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		voucher.Name, voucher.Type, voucher.Value, voucher.MaxDiscount, voucher.MinPurchase, voucher.StartsAt, voucher.EndsAt,
+		voucher.UsesPerCode, voucher.MaxUses, voucher.Active,
+	).Scan(&voucher.ID)
+	if err != nil {
+		return err
+	}
+	voucher.Redemptions, voucher.CodeCount = 0, 0
+
+	if err := insertVoucherScopes(tx, voucher); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *VoucherRepository) GetByID(id int) (*models.Voucher, error) {
+	v, err := scanVoucher(repo.db.QueryRow(voucherSelect+" WHERE v.id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, ErrVoucherNotFound
+	}
+	return v, err
+}
+
+// Update replaces the voucher, scopes included. Its codes and redemption
+// counts are kept.
+func (repo *VoucherRepository) Update(voucher *models.Voucher) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`UPDATE vouchers SET name = $1, type = $2, value = $3, max_discount = $4, min_purchase = $5, starts_at = $6,
+		        ends_at = $7, uses_per_code = $8, max_uses = $9, active = $10
+		 WHERE id = $11
+		 RETURNING redemptions, (SELECT count(*) FROM voucher_codes WHERE voucher_id = $11)`,
+		voucher.Name, voucher.Type, voucher.Value, voucher.MaxDiscount, voucher.MinPurchase, voucher.StartsAt,
+		voucher.EndsAt, voucher.UsesPerCode, voucher.MaxUses, voucher.Active, voucher.ID,
+	).Scan(&voucher.Redemptions, &voucher.CodeCount)
+	if err == sql.ErrNoRows {
+		return ErrVoucherNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []string{"voucher_products", "voucher_categories"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE voucher_id = $1", voucher.ID); err != nil {
+			return err
+		}
+	}
+	if err := insertVoucherScopes(tx, voucher); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a voucher and its codes. Once a code has been redeemed the
+// voucher can only be deactivated.
+func (repo *VoucherRepository) Delete(id int) error {
+	result, err := repo.db.Exec("DELETE FROM vouchers WHERE id = $1", id)
+	if hasPQCode(err, pgForeignKeyViolation) {
+		return ErrVoucherInUse
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrVoucherNotFound
+	}
+
+	return nil
+}
+
+// AddCodes stores the given codes for the voucher and returns the ones that
+// were new. Codes already taken, by this voucher or another, are skipped, so
+// the caller can generate replacements.
+func (repo *VoucherRepository) AddCodes(voucherID int, codes []string) ([]models.VoucherCode, error) {
+	rows, err := repo.db.Query(
+		`INSERT INTO voucher_codes (voucher_id, code)
+		 SELECT $1, unnest($2::text[])
+		 ON CONFLICT (code) DO NOTHING
+		 RETURNING id, voucher_id, code, redemptions, created_at`,
+		voucherID, pq.Array(codes))
+	if hasPQCode(err, pgForeignKeyViolation) {
+		return nil, ErrVoucherNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	added, err := scanVoucherCodes(rows)
+	if hasPQCode(err, pgForeignKeyViolation) {
+		return nil, ErrVoucherNotFound
+	}
+	return added, err
+}
+
+func (repo *VoucherRepository) GetCodes(filter models.VoucherCodeFilter) ([]models.VoucherCode, int, error) {
+	var total int
+	err := repo.db.QueryRow(
+		"SELECT (SELECT count(*) FROM voucher_codes WHERE voucher_id = $1) FROM vouchers WHERE id = $1",
+		filter.VoucherID,
+	).Scan(&total)
+	if err == sql.ErrNoRows {
+		return nil, 0, ErrVoucherNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT id, voucher_id, code, redemptions, created_at FROM voucher_codes
+		 WHERE voucher_id = $1 ORDER BY id LIMIT $2 OFFSET $3`,
+		filter.VoucherID, filter.Limit, (filter.Page-1)*filter.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	codes, err := scanVoucherCodes(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return codes, total, nil
+}
+
+// GetByCode looks up an upper case code and its voucher without locking
+// them, for previews.
+func (repo *VoucherRepository) GetByCode(code string) (*models.RedeemableVoucher, error) {
+	var rv models.RedeemableVoucher
+	err := repo.db.QueryRow(
+		"SELECT id, voucher_id, code, redemptions, created_at FROM voucher_codes WHERE code = $1", code,
+	).Scan(&rv.Code.ID, &rv.Code.VoucherID, &rv.Code.Code, &rv.Code.Redemptions, &rv.Code.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownVoucherCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := scanVoucher(repo.db.QueryRow(voucherSelect+" WHERE v.id = $1", rv.Code.VoucherID))
+	if err != nil {
+		return nil, err
+	}
+	rv.Voucher = *v
+	return &rv, nil
+}
+
+func scanVoucherCodes(rows *sql.Rows) ([]models.VoucherCode, error) {
+	codes := make([]models.VoucherCode, 0)
+	for rows.Next() {
+		var c models.VoucherCode
+		if err := rows.Scan(&c.ID, &c.VoucherID, &c.Code, &c.Redemptions, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// lockVoucherCode locks an upper case code and then its voucher for a
+// checkout. Every checkout locks in this order, after the products.
+func lockVoucherCode(tx *sql.Tx, code string) (*models.RedeemableVoucher, error) {
+	var rv models.RedeemableVoucher
+	err := tx.QueryRow(
+		"SELECT id, voucher_id, code, redemptions, created_at FROM voucher_codes WHERE code = $1 FOR UPDATE", code,
+	).Scan(&rv.Code.ID, &rv.Code.VoucherID, &rv.Code.Code, &rv.Code.Redemptions, &rv.Code.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownVoucherCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := scanVoucher(tx.QueryRow(voucherSelect+" WHERE v.id = $1 FOR UPDATE OF v", rv.Code.VoucherID))
+	if err != nil {
+		return nil, err
+	}
+	rv.Voucher = *v
+	return &rv, nil
+}
+
+// redeemVoucher records the voucher applied to trx and counts the use
+// against the locked code and voucher.
+func redeemVoucher(tx *sql.Tx, trx *models.Transaction) error {
+	v := trx.Voucher
+	v.TransactionID = trx.ID
+	err := tx.QueryRow(
+		`INSERT INTO voucher_redemptions (transaction_id, voucher_id, voucher_code_id, code, name, amount)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		v.TransactionID, v.VoucherID, v.VoucherCodeID, v.Code, v.Name, v.Amount,
+	).Scan(&v.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE voucher_codes SET redemptions = redemptions + 1 WHERE id = $1", v.VoucherCodeID); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE vouchers SET redemptions = redemptions + 1 WHERE id = $1", v.VoucherID)
+	return err
+}
+
+func insertVoucherScopes(tx *sql.Tx, voucher *models.Voucher) error {
+	if err := refuseArchivedProducts(tx, voucher.ProductIDs, ErrUnknownVoucherProduct); err != nil {
+		return err
+	}
+	for _, productID := range voucher.ProductIDs {
+		_, err := tx.Exec("INSERT INTO voucher_products (voucher_id, product_id) VALUES ($1, $2)", voucher.ID, productID)
+		if hasPQConstraint(err, "voucher_products_product_id_fkey") {
+			return ErrUnknownVoucherProduct
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, categoryID := range voucher.CategoryIDs {
+		_, err := tx.Exec("INSERT INTO voucher_categories (voucher_id, category_id) VALUES ($1, $2)", voucher.ID, categoryID)
+		if hasPQConstraint(err, "voucher_categories_category_id_fkey") {
+			return ErrUnknownVoucherCategory
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
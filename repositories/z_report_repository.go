@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"simple-cashier-api/models"
+)
+
+type ZReportRepository struct {
+	db *sql.DB
+}
+
+func NewZReportRepository(db *sql.DB) *ZReportRepository {
+	return &ZReportRepository{db: db}
+}
+
+const zReportSelect = `SELECT number, shift_id, terminal_id, cashier, opened_at, closed_at,
+                              gross_sales, discounts, refunds, refund_count, voids, void_count,
+                              service_charge, tax, net_sales, transaction_count, average_basket,
+                              first_receipt, last_receipt, opening_float, expected_cash, counted_cash, variance, created_at
+                       FROM z_reports`
+
+func scanZReport(row rowScanner) (*models.ShiftReport, error) {
+	r := models.ShiftReport{Type: models.ShiftReportZ}
+	var closedAt time.Time
+	err := row.Scan(&r.Number, &r.ShiftID, &r.TerminalID, &r.Cashier, &r.OpenedAt, &closedAt,
+		&r.GrossSales, &r.Discounts, &r.Refunds, &r.RefundCount, &r.Voids, &r.VoidCount,
+		&r.ServiceCharge, &r.Tax, &r.NetSales, &r.TransactionCount, &r.AverageBasket,
+		&r.FirstReceipt, &r.LastReceipt, &r.OpeningFloat, &r.ExpectedCash, &r.CountedCash, &r.Variance, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	r.ClosedAt = &closedAt
+	return &r, nil
+}
+
+// GetAll lists Z reports, newest first.
+func (repo *ZReportRepository) GetAll(filter models.ZReportFilter) ([]models.ShiftReport, int, error) {
+	conditions := make([]string, 0)
+	args := []any{}
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.TerminalID != nil {
+		addCondition("terminal_id = $%d", *filter.TerminalID)
+	}
+	if filter.StartDate != nil {
+		addCondition("DATE($%d) <= DATE(closed_at)", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		addCondition("DATE(closed_at) <= DATE($%d)", *filter.EndDate)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT count(*) FROM z_reports"+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := zReportSelect + where + fmt.Sprintf(" ORDER BY number DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	reports := make([]models.ShiftReport, 0)
+	numbers := make([]int, 0)
+	for rows.Next() {
+		r, err := scanZReport(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reports = append(reports, *r)
+		numbers = append(numbers, r.Number)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	payments, err := repo.getPayments(numbers)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range reports {
+		reports[i].Payments = payments[reports[i].Number]
+	}
+
+	return reports, total, nil
+}
+
+func (repo *ZReportRepository) GetByNumber(number int) (*models.ShiftReport, error) {
+	return repo.get(" WHERE number = $1", number)
+}
+
+func (repo *ZReportRepository) GetByShift(shiftID int) (*models.ShiftReport, error) {
+	return repo.get(" WHERE shift_id = $1", shiftID)
+}
+
+func (repo *ZReportRepository) get(where string, arg int) (*models.ShiftReport, error) {
+	r, err := scanZReport(repo.db.QueryRow(zReportSelect+where, arg))
+	if err == sql.ErrNoRows {
+		return nil, ErrZReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := repo.getPayments([]int{r.Number})
+	if err != nil {
+		return nil, err
+	}
+	r.Payments = payments[r.Number]
+
+	return r, nil
+}
+
+// getPayments loads the payment breakdowns of the given reports, keyed by
+// report number.
+func (repo *ZReportRepository) getPayments(numbers []int) (map[int][]models.PaymentTotal, error) {
+	payments := map[int][]models.PaymentTotal{}
+	for _, number := range numbers {
+		payments[number] = make([]models.PaymentTotal, 0)
+	}
+	if len(numbers) == 0 {
+		return payments, nil
+	}
+
+	rows, err := repo.db.Query(
+		`SELECT z_report_number, method, transactions, amount FROM z_report_payments
+		 WHERE z_report_number = ANY($1) ORDER BY z_report_number, position`,
+		pq.Array(numbers))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var number int
+		var p models.PaymentTotal
+		if err := rows.Scan(&number, &p.Method, &p.Transactions, &p.Amount); err != nil {
+			return nil, err
+		}
+		payments[number] = append(payments[number], p)
+	}
+
+	return payments, rows.Err()
+}
+
+// insertZReport stores report as the next Z report in sequence, in the
+// transaction closing its shift. The table is locked against other writers
+// while the number is taken, so numbers have no gaps even when two shifts
+// close at once.
+func insertZReport(tx *sql.Tx, report *models.ShiftReport) error {
+	if _, err := tx.Exec("LOCK TABLE z_reports IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return err
+	}
+
+	report.Type = models.ShiftReportZ
+	err := tx.QueryRow(
+		`INSERT INTO z_reports (number, shift_id, terminal_id, cashier, opened_at, closed_at,
+		                        gross_sales, discounts, refunds, refund_count, voids, void_count,
+		                        service_charge, tax, net_sales, transaction_count, average_basket,
+		                        first_receipt, last_receipt, opening_float, expected_cash, counted_cash, variance)
+		 SELECT COALESCE(MAX(number), 0) + 1, $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+		        $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22
+		 FROM z_reports
+		 RETURNING number, created_at`,
+		report.ShiftID, report.TerminalID, report.Cashier, report.OpenedAt, report.ClosedAt,
+		report.GrossSales, report.Discounts, report.Refunds, report.RefundCount, report.Voids, report.VoidCount,
+		report.ServiceCharge, report.Tax, report.NetSales, report.TransactionCount, report.AverageBasket,
+		report.FirstReceipt, report.LastReceipt, report.OpeningFloat, report.ExpectedCash, report.CountedCash, report.Variance,
+	).Scan(&report.Number, &report.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range report.Payments {
+		_, err := tx.Exec(
+			"INSERT INTO z_report_payments (z_report_number, position, method, transactions, amount) VALUES ($1, $2, $3, $4, $5)",
+			report.Number, i, p.Method, p.Transactions, p.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"simple-cashier-api/handlers"
+	"simple-cashier-api/models"
+	"simple-cashier-api/services"
+)
+
+// newRouter wires the services and handlers on top of repos and registers
+// every route. Everything under /api/ needs an access token except the
+// logins and refresh, and each route names the permissions it needs per
+// method and action. Unknown paths get the same JSON error envelope as the
+// API.
+func newRouter(config Config, repos Repositories) http.Handler {
+	mux := http.NewServeMux()
+
+	authorizer := services.NewAuthorizer(config.RolePermissions)
+	authService := services.NewAuthService(repos.Users, repos.Terminals, []byte(config.AuthTokenSecret), config.AccessTokenTTL, config.RefreshTokenTTL)
+	approvals := services.NewApprovalService(authService, authorizer)
+	guard := func(permissionFor func(*http.Request) string, handler http.HandlerFunc) http.HandlerFunc {
+		return handlers.Authorize(approvals, permissionFor, handler)
+	}
+	// crud guards reads with read, creates and updates with write, and
+	// deletes with del when the resource can be deleted.
+	crud := func(read, write, del string) handlers.MethodPermissions {
+		permissions := handlers.MethodPermissions{http.MethodGet: read, http.MethodPost: write, http.MethodPut: write}
+		if del != "" {
+			permissions[http.MethodDelete] = del
+		}
+		return permissions
+	}
+
+	authHandler := handlers.NewAuthHandler(authService, authorizer)
+
+	mux.HandleFunc("/api/auth/me", authHandler.HandleMe)
+
+	userService := services.NewUserService(repos.Users)
+	userHandler := handlers.NewUserHandler(userService)
+
+	users := handlers.ByMethod(crud(models.PermUserRead, models.PermUserWrite, ""))
+	mux.HandleFunc("/api/users", guard(users, userHandler.HandleUsers))
+	mux.HandleFunc("/api/users/", guard(users, userHandler.HandleUserByID))
+
+	terminalService := services.NewTerminalService(repos.Terminals)
+	terminalHandler := handlers.NewTerminalHandler(terminalService)
+
+	terminals := handlers.ByMethod(crud(models.PermTerminalRead, models.PermTerminalWrite, ""))
+	mux.HandleFunc("/api/terminals", guard(terminals, terminalHandler.HandleTerminals))
+	mux.HandleFunc("/api/terminals/", guard(terminals, terminalHandler.HandleTerminalByID))
+
+	productService := services.NewProductService(repos.Products, config.LowStockThreshold)
+	inventoryService := services.NewInventoryService(repos.Inventory)
+	productHandler := handlers.NewProductHandler(productService, inventoryService, approvals)
+
+	products := crud(models.PermProductRead, models.PermProductWrite, models.PermProductDelete)
+	mux.HandleFunc("/api/products", guard(handlers.ByMethod(products), productHandler.HandleProducts))
+	mux.HandleFunc("/api/products/", guard(handlers.ByAction("/api/products/", map[string]handlers.MethodPermissions{
+		"":                  products,
+		"stock-adjustments": {http.MethodPost: models.PermInventoryAdjust},
+		"stock-history":     {http.MethodGet: models.PermInventoryRead},
+		"price-history":     {http.MethodGet: models.PermProductRead},
+	}), productHandler.HandleProductByID))
+	mux.HandleFunc("/api/products/lookup", guard(handlers.ByMethod(handlers.MethodPermissions{
+		http.MethodGet: models.PermProductRead,
+	}), productHandler.HandleLookup))
+
+	categoryService := services.NewCategoryService(repos.Categories)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+
+	categories := handlers.ByMethod(crud(models.PermCategoryRead, models.PermCategoryWrite, models.PermCategoryDelete))
+	mux.HandleFunc("/api/categories", guard(categories, categoryHandler.HandleCategories))
+	mux.HandleFunc("/api/categories/", guard(categories, categoryHandler.HandleCategoryByID))
+
+	supplierService := services.NewSupplierService(repos.Suppliers)
+	supplierHandler := handlers.NewSupplierHandler(supplierService)
+
+	suppliers := handlers.ByMethod(crud(models.PermSupplierRead, models.PermSupplierWrite, models.PermSupplierDelete))
+	mux.HandleFunc("/api/suppliers", guard(suppliers, supplierHandler.HandleSuppliers))
+	mux.HandleFunc("/api/suppliers/", guard(suppliers, supplierHandler.HandleSupplierByID))
+
+	purchaseOrderService := services.NewPurchaseOrderService(repos.PurchaseOrders)
+	purchaseOrderHandler := handlers.NewPurchaseOrderHandler(purchaseOrderService)
+
+	purchaseOrders := crud(models.PermPurchaseOrderRead, models.PermPurchaseOrderWrite, "")
+	mux.HandleFunc("/api/purchase-orders", guard(handlers.ByMethod(purchaseOrders), purchaseOrderHandler.HandlePurchaseOrders))
+	mux.HandleFunc("/api/purchase-orders/", guard(handlers.ByAction("/api/purchase-orders/", map[string]handlers.MethodPermissions{
+		"":        purchaseOrders,
+		"order":   {http.MethodPost: models.PermPurchaseOrderWrite},
+		"cancel":  {http.MethodPost: models.PermPurchaseOrderWrite},
+		"receive": {http.MethodPost: models.PermPurchaseOrderReceive},
+	}), purchaseOrderHandler.HandlePurchaseOrderByID))
+
+	promotionService := services.NewPromotionService(repos.Promotions)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+
+	promotions := handlers.ByMethod(crud(models.PermPromotionRead, models.PermPromotionWrite, models.PermPromotionDelete))
+	mux.HandleFunc("/api/promotions", guard(promotions, promotionHandler.HandlePromotions))
+	mux.HandleFunc("/api/promotions/", guard(promotions, promotionHandler.HandlePromotionByID))
+
+	voucherService := services.NewVoucherService(repos.Vouchers, repos.Products, repos.Promotions)
+	voucherHandler := handlers.NewVoucherHandler(voucherService)
+
+	vouchers := crud(models.PermVoucherRead, models.PermVoucherWrite, models.PermVoucherDelete)
+	mux.HandleFunc("/api/vouchers", guard(handlers.ByMethod(vouchers), voucherHandler.HandleVouchers))
+	mux.HandleFunc("/api/vouchers/validate", guard(handlers.ByMethod(handlers.MethodPermissions{
+		http.MethodPost: models.PermVoucherValidate,
+	}), voucherHandler.HandleValidate))
+	mux.HandleFunc("/api/vouchers/", guard(handlers.ByAction("/api/vouchers/", map[string]handlers.MethodPermissions{
+		"":      vouchers,
+		"codes": {http.MethodGet: models.PermVoucherRead, http.MethodPost: models.PermVoucherWrite},
+	}), voucherHandler.HandleVoucherByID))
+
+	taxClassService := services.NewTaxClassService(repos.TaxClasses)
+	taxClassHandler := handlers.NewTaxClassHandler(taxClassService)
+
+	taxClasses := handlers.ByMethod(crud(models.PermTaxClassRead, models.PermTaxClassWrite, models.PermTaxClassDelete))
+	mux.HandleFunc("/api/tax-classes", guard(taxClasses, taxClassHandler.HandleTaxClasses))
+	mux.HandleFunc("/api/tax-classes/", guard(taxClasses, taxClassHandler.HandleTaxClassByID))
+
+	receiptSettings := models.ReceiptSettings{
+		StoreName:    config.ReceiptStoreName,
+		StoreAddress: config.ReceiptStoreAddress,
+		Footer:       config.ReceiptFooter,
+		Width:        services.ReceiptWidths[config.ReceiptWidth],
+	}
+	shiftReports := services.NewShiftReportService(repos.Shifts, repos.ZReports, receiptSettings)
+	shiftService := services.NewShiftService(repos.Shifts)
+	shiftHandler := handlers.NewShiftHandler(shiftService, shiftReports)
+
+	mux.HandleFunc("/api/shifts", guard(handlers.ByMethod(handlers.MethodPermissions{
+		http.MethodGet:  models.PermShiftRead,
+		http.MethodPost: models.PermShiftOperate,
+	}), shiftHandler.HandleShifts))
+	mux.HandleFunc("/api/shifts/current", guard(handlers.ByMethod(handlers.MethodPermissions{
+		http.MethodGet: models.PermShiftOperate,
+	}), shiftHandler.HandleCurrent))
+	mux.HandleFunc("/api/shifts/", guard(handlers.ByAction("/api/shifts/", map[string]handlers.MethodPermissions{
+		"":               {http.MethodGet: models.PermShiftRead},
+		"cash-movements": {http.MethodPost: models.PermShiftOperate},
+		"close":          {http.MethodPost: models.PermShiftOperate},
+		"report":         {http.MethodGet: models.PermShiftOperate},
+	}), shiftHandler.HandleShiftByID))
+
+	zReports := handlers.ByMethod(handlers.MethodPermissions{http.MethodGet: models.PermShiftRead})
+	mux.HandleFunc("/api/z-reports", guard(zReports, shiftHandler.HandleZReports))
+	mux.HandleFunc("/api/z-reports/", guard(zReports, shiftHandler.HandleZReportByNumber))
+
+	taxes := models.TaxSettings{
+		TaxRateBP:        config.TaxRateBP,
+		ServiceChargeBP:  config.ServiceChargeBP,
+		PricesIncludeTax: config.PricesIncludeTax,
+	}
+	transactionService := services.NewTransactionService(repos.Transactions, repos.Products, repos.Promotions, repos.TaxClasses, taxes,
+		config.DiscountApprovalPercent)
+	idempotencyService := services.NewIdempotencyService(repos.Idempotency, config.IdempotencyKeyTTL)
+	receiptService := services.NewReceiptService(repos.Transactions, receiptSettings)
+	transactionHandler := handlers.NewTransactionHandler(transactionService, idempotencyService, receiptService, approvals)
+
+	mux.HandleFunc("/api/checkout", guard(handlers.ByMethod(handlers.MethodPermissions{
+		http.MethodPost: models.PermTransactionCreate,
+	}), transactionHandler.HandleCheckout))
+	mux.HandleFunc("/api/transactions", guard(handlers.ByMethod(handlers.MethodPermissions{
+		http.MethodGet: models.PermTransactionRead,
+	}), transactionHandler.HandleTransactions))
+	mux.HandleFunc("/api/transactions/", guard(handlers.ByAction("/api/transactions/", map[string]handlers.MethodPermissions{
+		"":        {http.MethodGet: models.PermTransactionRead},
+		"receipt": {http.MethodGet: models.PermTransactionRead},
+		"void":    {http.MethodPost: models.PermTransactionVoid},
+		"refunds": {http.MethodPost: models.PermTransactionRefund},
+	}), transactionHandler.HandleTransactionByID))
+
+	report := handlers.ByMethod(handlers.MethodPermissions{http.MethodGet: models.PermReportRead})
+	mux.HandleFunc("/api/report/hari-ini", guard(report, transactionHandler.HandleGetTodaysReport))
+	mux.HandleFunc("/api/report", guard(report, transactionHandler.HandleGetRangeDateTransactionReport))
+
+	mux.HandleFunc("/", handlers.NotFound)
+
+	root := http.NewServeMux()
+
+	root.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"status":  "OK",
+			"message": "API running",
+		})
+	})
+
+	root.HandleFunc("/api/auth/login", authHandler.HandleLogin)
+	root.HandleFunc("/api/auth/pin-login", authHandler.HandlePINLogin)
+	root.HandleFunc("/api/auth/refresh", authHandler.HandleRefresh)
+	root.Handle("/api/", handlers.Authenticate(authService, mux))
+	root.HandleFunc("/", handlers.NotFound)
+
+	return handlers.RequestID(root)
+}
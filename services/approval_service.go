@@ -0,0 +1,72 @@
+package services
+
+import (
+	"slices"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+var (
+	ErrInvalidApproval   = repositories.Errorf(repositories.ErrForbidden, "invalid approver username or PIN")
+	ErrApproverPINLocked = repositories.Errorf(repositories.ErrForbidden, "approver's PIN is locked after too many failed attempts")
+)
+
+// ApprovalService lets a supervisor stand in for a permission the signed-in
+// user lacks, by entering their username and PIN at the till. Approval
+// failures are 403s, not 401s, so the cashier stays signed in.
+type ApprovalService struct {
+	auth       *AuthService
+	authorizer *Authorizer
+}
+
+func NewApprovalService(auth *AuthService, authorizer *Authorizer) *ApprovalService {
+	return &ApprovalService{auth: auth, authorizer: authorizer}
+}
+
+// Require checks that user may do what permission covers. It returns nil
+// when their own role holds it, and otherwise the approver, who must hold it
+// themselves. Only models.ApprovablePermissions can be approved.
+func (s *ApprovalService) Require(user *models.User, permission string, approval *models.Approval) (*models.User, error) {
+	return s.RequireAll(user, []string{permission}, approval)
+}
+
+// RequireAll is Require for a request needing several permissions. The
+// approver's PIN is checked once, and they must hold every permission
+// user's role lacks.
+func (s *ApprovalService) RequireAll(user *models.User, permissions []string, approval *models.Approval) (*models.User, error) {
+	var missing []string
+	for _, permission := range permissions {
+		if s.authorizer.Can(user.Role, permission) {
+			continue
+		}
+		approvable := slices.Contains(models.ApprovablePermissions, permission)
+		if approval == nil || !approvable {
+			return nil, &repositories.PermissionError{Denied: models.PermissionDenied{
+				Permission: permission,
+				Role:       user.Role,
+				Approvable: approvable,
+			}}
+		}
+		missing = append(missing, permission)
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	approver, locked, err := s.auth.checkPIN(approval.Username, approval.PIN)
+	switch {
+	case err != nil:
+		return nil, err
+	case locked:
+		return nil, ErrApproverPINLocked
+	case approver == nil:
+		return nil, ErrInvalidApproval
+	}
+	for _, permission := range missing {
+		if !s.authorizer.Can(approver.Role, permission) {
+			return nil, repositories.Errorf(repositories.ErrForbidden, "%s may not approve %s", approver.Username, permission)
+		}
+	}
+	return approver, nil
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+// Token types, carried in the typ claim so a refresh token can't be used as
+// an access token or the other way round.
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+// MaxPINFailures failed PIN attempts in a row lock a user's PIN for
+// PINLockout, or until an admin sets a new one, so a few digits can't be
+// guessed at the till. The lockout ends on its own, so a cashier typing
+// wrong approval PINs cannot lock a supervisor out for good.
+const (
+	MaxPINFailures = 5
+	PINLockout     = 15 * time.Minute
+)
+
+var (
+	ErrInvalidCredentials = repositories.Errorf(repositories.ErrUnauthorized, "invalid username or password")
+	ErrInvalidPIN         = repositories.Errorf(repositories.ErrUnauthorized, "invalid username or PIN")
+	ErrPINLocked          = repositories.Errorf(repositories.ErrUnauthorized, "PIN is locked after too many failed attempts, try again later or ask an admin to set a new one")
+	ErrUnknownTerminal    = repositories.Errorf(repositories.ErrUnauthorized, "unknown or inactive terminal")
+	ErrInvalidToken       = repositories.Errorf(repositories.ErrUnauthorized, "invalid or expired token")
+)
+
+// dummyPasswordHash is compared against when the username is unknown, so a
+// failed login takes as long whether or not the user exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+// tokenClaims.Terminal is the id of the terminal a PIN login was made at.
+type tokenClaims struct {
+	Type     string `json:"typ"`
+	Terminal int    `json:"trm,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AuthService logs users in and checks their tokens. Tokens are HS256 JWTs
+// signed with secret, naming the user id as subject. The user, and the
+// terminal of a PIN login, are loaded on every check, so deactivating either
+// or changing the user's role takes effect on the next request.
+type AuthService struct {
+	users      UserRepository
+	terminals  TerminalRepository
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewAuthService(users UserRepository, terminals TerminalRepository, secret []byte, accessTTL, refreshTTL time.Duration) *AuthService {
+	return &AuthService{users: users, terminals: terminals, secret: secret, accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+func (s *AuthService) Login(req models.LoginRequest) (*models.TokenResponse, error) {
+	user, err := s.users.GetByUsername(strings.ToLower(strings.TrimSpace(req.Username)))
+	if errors.Is(err, repositories.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil || !user.Active {
+		return nil, ErrInvalidCredentials
+	}
+	return s.issue(user, nil)
+}
+
+// PINLogin signs a user in at a registered, active terminal with their PIN.
+// The tokens are bound to the terminal: they stop working once it is
+// deactivated, and what the user does is recorded against it.
+func (s *AuthService) PINLogin(req models.PINLoginRequest) (*models.TokenResponse, error) {
+	terminal, err := s.terminals.GetByKeyHash(hashTerminalKey(req.TerminalKey))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUnknownTerminal
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !terminal.Active {
+		return nil, ErrUnknownTerminal
+	}
+
+	user, locked, err := s.checkPIN(req.Username, req.PIN)
+	switch {
+	case err != nil:
+		return nil, err
+	case locked:
+		return nil, ErrPINLocked
+	case user == nil:
+		return nil, ErrInvalidPIN
+	}
+	return s.issue(user, terminal)
+}
+
+// checkPIN returns the active user username names when pin is theirs, and
+// nil otherwise. A wrong PIN counts against the user, and locked reports
+// that their PIN is locked, which the last allowed failure does for
+// PINLockout.
+func (s *AuthService) checkPIN(username, pin string) (user *models.User, locked bool, err error) {
+	user, err = s.users.GetByUsername(strings.ToLower(strings.TrimSpace(username)))
+	if errors.Is(err, repositories.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(pin))
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if !user.Active || user.PINHash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(pin))
+		return nil, false, nil
+	}
+	if time.Now().Before(user.PINLockedUntil) {
+		return nil, true, nil
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PINHash), []byte(pin)) != nil {
+		failures, err := s.users.RecordPINFailure(user.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		if failures < MaxPINFailures {
+			return nil, false, nil
+		}
+		if err := s.users.LockPIN(user.ID, time.Now().Add(PINLockout)); err != nil {
+			return nil, false, err
+		}
+		return nil, true, nil
+	}
+	if user.PINFailures > 0 {
+		if err := s.users.ResetPINFailures(user.ID); err != nil {
+			return nil, false, err
+		}
+		user.PINFailures = 0
+	}
+	return user, false, nil
+}
+
+// Refresh trades a refresh token for a new token pair, bound to the same
+// terminal as the old one.
+func (s *AuthService) Refresh(req models.RefreshRequest) (*models.TokenResponse, error) {
+	user, terminal, err := s.verify(req.RefreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(user, terminal)
+}
+
+// Authenticate returns who an access token was issued to: an active user,
+// and the active terminal they signed in at with a PIN, if they did.
+func (s *AuthService) Authenticate(token string) (models.Actor, error) {
+	user, terminal, err := s.verify(token, tokenTypeAccess)
+	if err != nil {
+		return models.Actor{}, err
+	}
+
+	actor := models.Actor{User: user}
+	if terminal != nil {
+		actor.TerminalID = &terminal.ID
+	}
+	return actor, nil
+}
+
+func (s *AuthService) verify(token, tokenType string) (*models.User, *models.Terminal, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.Type != tokenType {
+		return nil, nil, ErrInvalidToken
+	}
+
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	user, err := s.users.GetByID(id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.Active {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if claims.Terminal == 0 {
+		return user, nil, nil
+	}
+	terminal, err := s.terminals.GetByID(claims.Terminal)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !terminal.Active {
+		return nil, nil, ErrInvalidToken
+	}
+	return user, terminal, nil
+}
+
+// issue signs a token pair for user, bound to terminal when it is not nil.
+func (s *AuthService) issue(user *models.User, terminal *models.Terminal) (*models.TokenResponse, error) {
+	now := time.Now()
+	access, err := s.sign(user, terminal, tokenTypeAccess, now, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.sign(user, terminal, tokenTypeRefresh, now, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+		User:         *user,
+		Terminal:     terminal,
+	}, nil
+}
+
+func (s *AuthService) sign(user *models.User, terminal *models.Terminal, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	claims := tokenClaims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(user.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	if terminal != nil {
+		claims.Terminal = terminal.ID
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+	"simple-cashier-api/repositories/memory"
+)
+
+func TestAuthServiceTokens(t *testing.T) {
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	user := models.User{Username: "kasir", Name: "Kasir", Role: models.RoleCashier, Active: true, Password: "rahasia123"}
+	if err := NewUserService(users).Create(&user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	if user.Password != "" || user.PasswordHash == "" {
+		t.Fatalf("password not hashed: %+v", user)
+	}
+
+	auth := NewAuthService(users, memory.NewTerminalRepository(store), []byte("secret"), time.Minute, time.Hour)
+	tokens, err := auth.Login(models.LoginRequest{Username: "kasir", Password: "rahasia123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if got, err := auth.Authenticate(tokens.AccessToken); err != nil || got.User.ID != user.ID || got.TerminalID != nil {
+		t.Errorf("Authenticate = %+v, %v", got, err)
+	}
+
+	sign := func(secret string, method jwt.SigningMethod, claims tokenClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+	valid := func(tokenType string, expiresAt time.Time) tokenClaims {
+		return tokenClaims{Type: tokenType, RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "1",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		}}
+	}
+
+	rejected := map[string]string{
+		"refresh token":  tokens.RefreshToken,
+		"other secret":   sign("other", jwt.SigningMethodHS256, valid(tokenTypeAccess, time.Now().Add(time.Minute))),
+		"expired":        sign("secret", jwt.SigningMethodHS256, valid(tokenTypeAccess, time.Now().Add(-time.Minute))),
+		"no expiry":      sign("secret", jwt.SigningMethodHS256, tokenClaims{Type: tokenTypeAccess, RegisteredClaims: jwt.RegisteredClaims{Subject: "1"}}),
+		"HS512":          sign("secret", jwt.SigningMethodHS512, valid(tokenTypeAccess, time.Now().Add(time.Minute))),
+		"unknown user":   sign("secret", jwt.SigningMethodHS256, tokenClaims{Type: tokenTypeAccess, RegisteredClaims: jwt.RegisteredClaims{Subject: "99", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}}),
+		"tampered token": tokens.AccessToken + "x",
+	}
+	for name, token := range rejected {
+		if _, err := auth.Authenticate(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: got %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestAuthServicePINLogin(t *testing.T) {
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	user := models.User{Username: "kasir", Name: "Kasir", Role: models.RoleCashier, Active: true, Password: "rahasia123", PIN: "1234"}
+	if err := NewUserService(users).Create(&user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	terminals := NewTerminalService(memory.NewTerminalRepository(store))
+	terminal := models.Terminal{Code: "kasir-1", Name: "Kasir 1", Active: true}
+	if err := terminals.Create(&terminal); err != nil {
+		t.Fatalf("create terminal: %v", err)
+	}
+	if terminal.Code != "KASIR-1" || terminal.Key == "" || terminal.KeyHash != hashTerminalKey(terminal.Key) {
+		t.Fatalf("unexpected terminal %+v", terminal)
+	}
+
+	auth := NewAuthService(users, memory.NewTerminalRepository(store), []byte("secret"), time.Minute, time.Hour)
+	if _, err := auth.PINLogin(models.PINLoginRequest{TerminalKey: "nope", Username: "kasir", PIN: "1234"}); !errors.Is(err, ErrUnknownTerminal) {
+		t.Errorf("unknown terminal: got %v, want ErrUnknownTerminal", err)
+	}
+
+	tokens, err := auth.PINLogin(models.PINLoginRequest{TerminalKey: terminal.Key, Username: "Kasir", PIN: "1234"})
+	if err != nil {
+		t.Fatalf("PIN login: %v", err)
+	}
+	actor, err := auth.Authenticate(tokens.AccessToken)
+	if err != nil || actor.TerminalID == nil || *actor.TerminalID != terminal.ID {
+		t.Fatalf("Authenticate = %+v, %v", actor, err)
+	}
+
+	for i := 1; i < MaxPINFailures; i++ {
+		if _, err := auth.PINLogin(models.PINLoginRequest{TerminalKey: terminal.Key, Username: "kasir", PIN: "0000"}); !errors.Is(err, ErrInvalidPIN) {
+			t.Fatalf("wrong PIN %d: got %v, want ErrInvalidPIN", i, err)
+		}
+	}
+	if _, err := auth.PINLogin(models.PINLoginRequest{TerminalKey: terminal.Key, Username: "kasir", PIN: "0000"}); !errors.Is(err, ErrPINLocked) {
+		t.Errorf("last wrong PIN: got %v, want ErrPINLocked", err)
+	}
+	if _, err := auth.PINLogin(models.PINLoginRequest{TerminalKey: terminal.Key, Username: "kasir", PIN: "1234"}); !errors.Is(err, ErrPINLocked) {
+		t.Errorf("right PIN while locked: got %v, want ErrPINLocked", err)
+	}
+	if err := users.LockPIN(user.ID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("end lockout: %v", err)
+	}
+	if _, err := auth.PINLogin(models.PINLoginRequest{TerminalKey: terminal.Key, Username: "kasir", PIN: "1234"}); err != nil {
+		t.Errorf("right PIN after the lockout: %v", err)
+	}
+
+	terminal.Active = false
+	if err := terminals.Update(&terminal); err != nil {
+		t.Fatalf("deactivate terminal: %v", err)
+	}
+	if _, err := auth.Authenticate(tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token of a deactivated terminal: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestApprovalServiceRequire(t *testing.T) {
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	cashier := models.User{Username: "kasir", Name: "Kasir", Role: models.RoleCashier, Active: true, Password: "rahasia123", PIN: "1111"}
+	supervisor := models.User{Username: "spv", Name: "Supervisor", Role: models.RoleSupervisor, Active: true, Password: "rahasia123", PIN: "2222"}
+	for _, u := range []*models.User{&cashier, &supervisor} {
+		if err := NewUserService(users).Create(u); err != nil {
+			t.Fatalf("create %s: %v", u.Username, err)
+		}
+	}
+	approvals := NewApprovalService(NewAuthService(users, memory.NewTerminalRepository(store), []byte("secret"), time.Minute, time.Hour), NewAuthorizer(nil))
+
+	if approver, err := approvals.Require(&supervisor, models.PermTransactionVoid, nil); approver != nil || err != nil {
+		t.Errorf("own permission: got %v, %v", approver, err)
+	}
+
+	_, err := approvals.Require(&cashier, models.PermTransactionVoid, nil)
+	var denied *repositories.PermissionError
+	if !errors.As(err, &denied) || !denied.Denied.Approvable {
+		t.Errorf("no approval: got %v, want an approvable PermissionError", err)
+	}
+	if _, err := approvals.Require(&cashier, models.PermUserWrite, &models.Approval{Username: "spv", PIN: "2222"}); !errors.As(err, &denied) || denied.Denied.Approvable {
+		t.Errorf("unapprovable permission: got %v, want a PermissionError", err)
+	}
+	if _, err := approvals.Require(&cashier, models.PermTransactionVoid, &models.Approval{Username: "spv", PIN: "9999"}); !errors.Is(err, ErrInvalidApproval) {
+		t.Errorf("wrong PIN: got %v, want ErrInvalidApproval", err)
+	}
+	if _, err := approvals.Require(&cashier, models.PermTransactionVoid, &models.Approval{Username: "kasir", PIN: "1111"}); !errors.Is(err, repositories.ErrForbidden) {
+		t.Errorf("cashier approving: got %v, want ErrForbidden", err)
+	}
+
+	before, _ := users.GetByID(supervisor.ID)
+	both := []string{models.PermTransactionPriceOverride, models.PermTransactionDiscount}
+	if _, err := approvals.RequireAll(&cashier, both, &models.Approval{Username: "spv", PIN: "9999"}); !errors.Is(err, ErrInvalidApproval) {
+		t.Errorf("wrong PIN for two permissions: got %v, want ErrInvalidApproval", err)
+	}
+	if after, _ := users.GetByID(supervisor.ID); after.PINFailures != before.PINFailures+1 {
+		t.Errorf("wrong PIN for two permissions: PIN failures went from %d to %d, want one more", before.PINFailures, after.PINFailures)
+	}
+
+	approver, err := approvals.Require(&cashier, models.PermTransactionVoid, &models.Approval{Username: "spv", PIN: "2222"})
+	if err != nil || approver == nil || approver.ID != supervisor.ID {
+		t.Errorf("supervisor approval: got %v, %v", approver, err)
+	}
+}
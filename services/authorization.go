@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"simple-cashier-api/models"
+)
+
+// DefaultRolePermissions is what cashiers and supervisors may do unless
+// ROLE_PERMISSIONS_FILE says otherwise. Cashiers ring up sales in their
+// shifts; supervisors also run the floor: voids, refunds, price overrides and
+// discounts, shift review, stock, receiving and reports.
+var DefaultRolePermissions = map[string][]string{
+	models.RoleCashier: {
+		models.PermProductRead,
+		models.PermCategoryRead,
+		models.PermPromotionRead,
+		models.PermVoucherValidate,
+		models.PermTaxClassRead,
+		models.PermTransactionCreate,
+		models.PermTransactionRead,
+		models.PermShiftOperate,
+	},
+	models.RoleSupervisor: {
+		models.PermProductRead,
+		models.PermProductWrite,
+		models.PermCategoryRead,
+		models.PermCategoryWrite,
+		models.PermInventoryRead,
+		models.PermInventoryAdjust,
+		models.PermSupplierRead,
+		models.PermPurchaseOrderRead,
+		models.PermPurchaseOrderReceive,
+		models.PermPromotionRead,
+		models.PermVoucherRead,
+		models.PermVoucherValidate,
+		models.PermTaxClassRead,
+		models.PermTransactionCreate,
+		models.PermTransactionRead,
+		models.PermTransactionVoid,
+		models.PermTransactionRefund,
+		models.PermTransactionPriceOverride,
+		models.PermTransactionDiscount,
+		models.PermShiftOperate,
+		models.PermShiftRead,
+		models.PermReportRead,
+	},
+}
+
+// ValidateRolePermissions checks role definitions read from configuration:
+// only cashier and supervisor can be redefined, with known permissions.
+func ValidateRolePermissions(roles map[string][]string) error {
+	for role, permissions := range roles {
+		if role == models.RoleAdmin {
+			return fmt.Errorf("role %s always has every permission and cannot be redefined", role)
+		}
+		if !slices.Contains(models.Roles, role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		for _, p := range permissions {
+			if !slices.Contains(models.Permissions, p) {
+				return fmt.Errorf("role %s: unknown permission %q", role, p)
+			}
+		}
+	}
+	return nil
+}
+
+// Authorizer answers whether a role holds a permission.
+type Authorizer struct {
+	roles map[string][]string
+}
+
+// NewAuthorizer grants admins every permission and uses
+// DefaultRolePermissions for the other roles overrides leaves out. overrides
+// must have passed ValidateRolePermissions, which keeps admins out of it.
+func NewAuthorizer(overrides map[string][]string) *Authorizer {
+	roles := map[string][]string{models.RoleAdmin: models.Permissions}
+	for role, permissions := range DefaultRolePermissions {
+		roles[role] = permissions
+	}
+	for role, permissions := range overrides {
+		roles[role] = permissions
+	}
+	return &Authorizer{roles: roles}
+}
+
+func (a *Authorizer) Can(role, permission string) bool {
+	return slices.Contains(a.roles[role], permission)
+}
+
+// Permissions lists what role may do, in the order of models.Permissions.
+func (a *Authorizer) Permissions(role string) []string {
+	permissions := make([]string, 0)
+	for _, p := range models.Permissions {
+		if a.Can(role, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"simple-cashier-api/models"
+)
+
+func TestDefaultRolePermissionsAreKnown(t *testing.T) {
+	if err := ValidateRolePermissions(DefaultRolePermissions); err != nil {
+		t.Fatalf("default roles: %v", err)
+	}
+
+	authz := NewAuthorizer(nil)
+	if got := authz.Permissions(models.RoleAdmin); !slices.Equal(got, models.Permissions) {
+		t.Errorf("admin permissions = %v, want all", got)
+	}
+	if authz.Can(models.RoleCashier, models.PermProductWrite) || !authz.Can(models.RoleCashier, models.PermTransactionCreate) {
+		t.Errorf("unexpected cashier permissions %v", authz.Permissions(models.RoleCashier))
+	}
+	if authz.Can("owner", models.PermProductRead) {
+		t.Error("unknown role has permissions")
+	}
+}
+
+func TestValidateRolePermissions(t *testing.T) {
+	cases := []struct {
+		name  string
+		roles map[string][]string
+		ok    bool
+	}{
+		{"override", map[string][]string{models.RoleCashier: {models.PermReportRead}}, true},
+		{"empty role", map[string][]string{models.RoleSupervisor: {}}, true},
+		{"admin", map[string][]string{models.RoleAdmin: {models.PermReportRead}}, false},
+		{"unknown role", map[string][]string{"owner": {models.PermReportRead}}, false},
+		{"unknown permission", map[string][]string{models.RoleCashier: {"report:write"}}, false},
+	}
+	for _, tc := range cases {
+		if err := ValidateRolePermissions(tc.roles); (err == nil) != tc.ok {
+			t.Errorf("%s: got %v, want ok=%v", tc.name, err, tc.ok)
+		}
+	}
+
+	authz := NewAuthorizer(map[string][]string{models.RoleCashier: {models.PermReportRead}})
+	if !authz.Can(models.RoleCashier, models.PermReportRead) || authz.Can(models.RoleCashier, models.PermProductRead) {
+		t.Errorf("override not applied: %v", authz.Permissions(models.RoleCashier))
+	}
+	if !authz.Can(models.RoleSupervisor, models.PermTransactionVoid) {
+		t.Error("supervisor lost its default permissions")
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"strings"
+)
+
+var errInvalidBarcode = errors.New("must be a valid EAN-13 or UPC-A code")
+
+// NormalizeBarcode checks the check digit of an EAN-13 or UPC-A code and
+// returns it as 13 digits. A UPC-A code is an EAN-13 code with a leading zero,
+// so both spellings of the same product find the same row.
+func NormalizeBarcode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 12 {
+		code = "0" + code
+	}
+	if len(code) != 13 {
+		return "", errInvalidBarcode
+	}
+
+	sum := 0
+	for i := 0; i < 13; i++ {
+		digit := int(code[i] - '0')
+		if digit < 0 || digit > 9 {
+			return "", errInvalidBarcode
+		}
+		if i == 12 {
+			break
+		}
+		// Weights alternate 1, 3, 1, ... from the left for 13 digits.
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	if check := (10 - sum%10) % 10; int(code[12]-'0') != check {
+		return "", errInvalidBarcode
+	}
+	return code, nil
+}
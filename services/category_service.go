@@ -2,22 +2,38 @@ package services
 
 import (
 	"simple-cashier-api/models"
-	"simple-cashier-api/repositories"
 )
 
 type CategoryService struct {
-	repo *repositories.CategoryRepository
+	repo CategoryRepository
 }
 
-func NewCategoryService(repo *repositories.CategoryRepository) *CategoryService {
+func NewCategoryService(repo CategoryRepository) *CategoryService {
 	return &CategoryService{repo: repo}
 }
 
-func (s *CategoryService) GetAll() ([]models.Category, error) {
-	return s.repo.GetAll()
+func (s *CategoryService) GetAll(filter models.CategoryFilter) (*models.Page[models.Category], error) {
+	var v validator
+	v.checkSort(filter.Sort, CategorySortFields)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	categories, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(categories, filter.Page, filter.Limit, total)
+	return &page, nil
 }
 
 func (s *CategoryService) Create(data *models.Category) error {
+	if err := validateCategory(data); err != nil {
+		return err
+	}
 	return s.repo.Create(data)
 }
 
@@ -26,6 +42,9 @@ func (s *CategoryService) GetByID(id int) (*models.Category, error) {
 }
 
 func (s *CategoryService) Update(category *models.Category) error {
+	if err := validateCategory(category); err != nil {
+		return err
+	}
 	return s.repo.Update(category)
 }
 
@@ -0,0 +1,57 @@
+package services
+
+import "simple-cashier-api/models"
+
+// ManualDiscountName labels a manual discount among a transaction's
+// promotions, on receipts and in reports.
+const ManualDiscountName = "Manual discount"
+
+// applyPriceOverrides reprices the lines whose item gives a unit price,
+// before any discount, keeping the product's price as OriginalPrice. The
+// draft's details are in the order of items.
+func applyPriceOverrides(trx *models.Transaction, items []models.CheckoutItem) {
+	for i, item := range items {
+		if item.UnitPrice == nil {
+			continue
+		}
+
+		d := &trx.Details[i]
+		original := d.UnitPrice
+		d.OriginalPrice = &original
+		d.UnitPrice = *item.UnitPrice
+
+		subtotal := d.UnitPrice * d.Quantity
+		trx.Subtotal += subtotal - d.Subtotal
+		trx.TotalAmount += subtotal - d.Subtotal
+		d.Subtotal, d.Total = subtotal, subtotal
+	}
+}
+
+// applyManualDiscount takes percent off what is left to pay after
+// promotions and the voucher. It is spread over the lines like a cart
+// promotion and recorded among the promotions, without a PromotionID.
+func applyManualDiscount(trx *models.Transaction, percent int) {
+	weights := make([]int, len(trx.Details))
+	base := 0
+	for i, d := range trx.Details {
+		weights[i] = d.Subtotal
+		base += d.Subtotal
+	}
+
+	amount := percentOf(base, percent)
+	if amount == 0 {
+		return
+	}
+
+	for i, discount := range allocate(amount, weights) {
+		trx.Details[i].Discount += discount
+		trx.Details[i].Subtotal -= discount
+	}
+	trx.Promotions = append(trx.Promotions, models.AppliedPromotion{
+		Name:   ManualDiscountName,
+		Type:   models.DiscountTypeManual,
+		Amount: amount,
+	})
+	trx.DiscountAmount += amount
+	trx.TotalAmount -= amount
+}
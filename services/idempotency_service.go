@@ -0,0 +1,99 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+type IdempotencyService struct {
+	repo IdempotencyRepository
+	ttl  time.Duration
+}
+
+var (
+	ErrIdempotencyKeyReused   = repositories.Errorf(repositories.ErrValidation, "idempotency key was already used with a different request")
+	ErrIdempotencyKeyInFlight = repositories.Errorf(repositories.ErrConflict, "a request with this idempotency key is still being processed")
+	ErrIdempotencyKeyInvalid  = repositories.Errorf(repositories.ErrValidation, "idempotency key must be 1 to 255 characters")
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyLease is how long a key is held for a checkout still in
+// flight. A request that dies before its checkout commits frees its key
+// after this; a committed checkout keeps it for the full TTL.
+const idempotencyLease = time.Minute
+
+func NewIdempotencyService(repo IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl}
+}
+
+// Fingerprint hashes the decoded request, so replays that only differ in
+// whitespace or key order are still recognised as the same request.
+func Fingerprint(request any) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// scopedKey stores keys per user, so one user's key never replays another
+// user's response.
+func scopedKey(userID int, key string) string {
+	return fmt.Sprintf("%d:%s", userID, key)
+}
+
+// Begin reserves the user's key for the request with the given fingerprint,
+// for a short lease. It returns the stored record when the original request
+// already checked out and should be replayed, or nil when the caller should
+// go ahead and process the request, passing Claim to the checkout.
+func (s *IdempotencyService) Begin(userID int, key string, fingerprint string) (*models.IdempotencyKey, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return nil, ErrIdempotencyKeyInvalid
+	}
+
+	record, created, err := s.repo.Reserve(scopedKey(userID, key), fingerprint, time.Now().Add(idempotencyLease))
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		return nil, nil
+	}
+
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !record.Completed() && record.TransactionID == nil {
+		return nil, ErrIdempotencyKeyInFlight
+	}
+
+	return record, nil
+}
+
+// Claim is what the checkout for the user's reserved key records on it, so
+// the key is kept for the full TTL once the checkout commits.
+func (s *IdempotencyService) Claim(userID int, key string) *models.IdempotencyClaim {
+	return &models.IdempotencyClaim{Key: scopedKey(userID, key), ExpiresAt: time.Now().Add(s.ttl)}
+}
+
+// Complete stores the response to replay for the user's key. Without it a
+// replay rebuilds the response from the transaction the checkout recorded.
+func (s *IdempotencyService) Complete(userID int, key string, transactionID int, statusCode int, responseBody []byte) error {
+	return s.repo.Complete(scopedKey(userID, key), transactionID, statusCode, responseBody)
+}
+
+func (s *IdempotencyService) Release(userID int, key string) error {
+	return s.repo.Release(scopedKey(userID, key))
+}
+
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	return s.repo.DeleteExpired()
+}
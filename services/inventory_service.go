@@ -0,0 +1,56 @@
+package services
+
+import (
+	"slices"
+	"strings"
+
+	"simple-cashier-api/models"
+)
+
+const MaxMovementReasonLength = 255
+
+type InventoryService struct {
+	repo InventoryRepository
+}
+
+func NewInventoryService(repo InventoryRepository) *InventoryService {
+	return &InventoryService{repo: repo}
+}
+
+// AdjustStock posts a manual movement. Restocks must add stock and damage or
+// loss must remove it; an adjustment may go either way but needs a reason.
+func (s *InventoryService) AdjustStock(productID int, adj models.StockAdjustment) (*models.InventoryMovement, error) {
+	adj.Reason = strings.TrimSpace(adj.Reason)
+
+	var v validator
+	v.check(slices.Contains(models.StockAdjustmentTypes, adj.Type), "type", RuleOneOf,
+		"must be one of %s", strings.Join(models.StockAdjustmentTypes, ", "))
+	v.check(adj.Quantity != 0, "quantity", RuleRequired, "must not be zero")
+	switch adj.Type {
+	case models.MovementTypeRestock:
+		v.check(adj.Quantity >= 0, "quantity", RuleMin, "must be positive for a restock")
+	case models.MovementTypeDamage, models.MovementTypeLoss:
+		v.check(adj.Quantity <= 0, "quantity", RuleMax, "must be negative for %s", adj.Type)
+	}
+	if adj.Type != models.MovementTypeRestock {
+		v.check(adj.Reason != "", "reason", RuleRequired, "must not be empty")
+	}
+	v.checkMaxLen(adj.Reason, "reason", MaxMovementReasonLength)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.AdjustStock(productID, adj)
+}
+
+func (s *InventoryService) GetStockHistory(filter models.StockHistoryFilter) (*models.Page[models.InventoryMovement], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	movements, total, err := s.repo.GetStockHistory(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(movements, filter.Page, filter.Limit, total)
+	return &page, nil
+}
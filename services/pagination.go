@@ -0,0 +1,54 @@
+package services
+
+import (
+	"slices"
+	"strings"
+
+	"simple-cashier-api/models"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Fields accepted by the sort query parameter of each listing.
+var (
+	ProductSortFields  = []string{"id", "name", "price", "stock"}
+	CategorySortFields = []string{"id", "name"}
+	SupplierSortFields = []string{"id", "name"}
+)
+
+// normalizePage applies the default limit and clamps it to MaxPageLimit.
+func normalizePage(page, limit int) (int, int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	return page, limit
+}
+
+func (v *validator) checkSort(sort []models.SortField, allowed []string) {
+	seen := make(map[string]bool, len(sort))
+	for _, s := range sort {
+		v.check(slices.Contains(allowed, s.Field), "sort", RuleOneOf,
+			"cannot sort by %q, use one of %s", s.Field, strings.Join(allowed, ", "))
+		v.check(!seen[s.Field], "sort", RuleUnique, "%q is listed more than once", s.Field)
+		seen[s.Field] = true
+	}
+}
+
+// checkRange validates an optional min/max pair of query parameters.
+func (v *validator) checkRange(min, max *int, minField, maxField string) {
+	if min != nil {
+		v.check(*min >= 0, minField, RuleMin, "must not be negative")
+	}
+	if min != nil && max != nil {
+		v.check(*min <= *max, maxField, RuleMin, "must not be less than %s", minField)
+	}
+}
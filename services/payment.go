@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+var (
+	ErrInvalidPayment      = repositories.Errorf(repositories.ErrValidation, "invalid payment")
+	ErrInsufficientPayment = repositories.Errorf(repositories.ErrValidation, "payments do not cover the total amount")
+)
+
+// validatePayments checks what can be checked before the total is known.
+func validatePayments(payments []models.CheckoutPayment) error {
+	if len(payments) == 0 {
+		return fmt.Errorf("%w: at least one payment is required", ErrInvalidPayment)
+	}
+
+	for i, p := range payments {
+		if !slices.Contains(models.PaymentMethods, p.Method) {
+			return fmt.Errorf("%w: payments[%d]: unknown method %q", ErrInvalidPayment, i, p.Method)
+		}
+		if p.Amount <= 0 {
+			return fmt.Errorf("%w: payments[%d]: amount must be greater than zero", ErrInvalidPayment, i)
+		}
+		if p.Method != models.PaymentMethodCash && p.Reference == "" {
+			return fmt.Errorf("%w: payments[%d]: reference is required for %s", ErrInvalidPayment, i, p.Method)
+		}
+	}
+
+	return nil
+}
+
+// settlePayments attaches payments to the priced transaction and computes the
+// change. Only cash can be over-tendered; card, QRIS and e-wallet payments
+// are charged exactly, so together they may not exceed the total.
+func settlePayments(trx *models.Transaction, payments []models.CheckoutPayment) error {
+	paid, nonCash := 0, 0
+	for _, p := range payments {
+		paid += p.Amount
+		if p.Method != models.PaymentMethodCash {
+			nonCash += p.Amount
+		}
+	}
+
+	if nonCash > trx.TotalAmount {
+		return fmt.Errorf("%w: non-cash payments total %d but the transaction total is %d", ErrInvalidPayment, nonCash, trx.TotalAmount)
+	}
+	if paid < trx.TotalAmount {
+		return fmt.Errorf("%w: paid %d of %d", ErrInsufficientPayment, paid, trx.TotalAmount)
+	}
+
+	trx.PaidAmount = paid
+	trx.ChangeAmount = paid - trx.TotalAmount
+	trx.Payments = make([]models.Payment, len(payments))
+	for i, p := range payments {
+		trx.Payments[i] = models.Payment{
+			Method:    p.Method,
+			Amount:    p.Amount,
+			Reference: p.Reference,
+		}
+	}
+
+	return nil
+}
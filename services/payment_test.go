@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"simple-cashier-api/models"
+)
+
+func TestSettlePayments(t *testing.T) {
+	cash := func(amount int) models.CheckoutPayment {
+		return models.CheckoutPayment{Method: models.PaymentMethodCash, Amount: amount}
+	}
+	qris := func(amount int) models.CheckoutPayment {
+		return models.CheckoutPayment{Method: models.PaymentMethodQRIS, Amount: amount, Reference: "QR-1"}
+	}
+
+	tests := []struct {
+		name       string
+		payments   []models.CheckoutPayment
+		wantChange int
+		wantErr    error
+	}{
+		{"exact cash", []models.CheckoutPayment{cash(10000)}, 0, nil},
+		{"cash with change", []models.CheckoutPayment{cash(20000)}, 10000, nil},
+		{"split qris and cash", []models.CheckoutPayment{qris(4000), cash(10000)}, 4000, nil},
+		{"short", []models.CheckoutPayment{cash(5000), qris(1000)}, 0, ErrInsufficientPayment},
+		{"qris over total", []models.CheckoutPayment{qris(12000)}, 0, ErrInvalidPayment},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePayments(tt.payments); err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+
+			trx := models.Transaction{TotalAmount: 10000}
+			err := settlePayments(&trx, tt.payments)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (trx.ChangeAmount != tt.wantChange || len(trx.Payments) != len(tt.payments)) {
+				t.Errorf("change = %d, payments = %d, want %d and %d", trx.ChangeAmount, len(trx.Payments), tt.wantChange, len(tt.payments))
+			}
+		})
+	}
+}
+
+func TestValidatePayments(t *testing.T) {
+	invalid := [][]models.CheckoutPayment{
+		nil,
+		{{Method: "cheque", Amount: 1000}},
+		{{Method: models.PaymentMethodCash, Amount: 0}},
+		{{Method: models.PaymentMethodDebitCard, Amount: 1000}},
+	}
+
+	for _, payments := range invalid {
+		if err := validatePayments(payments); !errors.Is(err, ErrInvalidPayment) {
+			t.Errorf("validatePayments(%+v) = %v, want ErrInvalidPayment", payments, err)
+		}
+	}
+}
@@ -6,18 +6,40 @@ import (
 )
 
 type ProductService struct {
-	repo *repositories.ProductRepository
+	repo              ProductRepository
+	lowStockThreshold int
 }
 
-func NewProductService(repo *repositories.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+// NewProductService creates the service. Products with at most
+// lowStockThreshold units left match the low_stock filter.
+func NewProductService(repo ProductRepository, lowStockThreshold int) *ProductService {
+	return &ProductService{repo: repo, lowStockThreshold: lowStockThreshold}
 }
 
-func (s *ProductService) GetAll(name string) ([]models.ProductDetail, error) {
-	return s.repo.GetAll(name)
+func (s *ProductService) GetAll(filter models.ProductFilter) (*models.Page[models.ProductDetail], error) {
+	var v validator
+	v.checkRange(filter.MinPrice, filter.MaxPrice, "min_price", "max_price")
+	v.checkSort(filter.Sort, ProductSortFields)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+	filter.LowStockThreshold = s.lowStockThreshold
+
+	products, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(products, filter.Page, filter.Limit, total)
+	return &page, nil
 }
 
 func (s *ProductService) Create(data *models.Product) error {
+	if err := validateProduct(data); err != nil {
+		return err
+	}
 	return s.repo.Create(data)
 }
 
@@ -25,10 +47,95 @@ func (s *ProductService) GetByID(id int) (*models.ProductDetail, error) {
 	return s.repo.GetByID(id)
 }
 
-func (s *ProductService) Update(product *models.Product) error {
-	return s.repo.Update(product)
+// Lookup finds a product by a scanned barcode or by SKU; exactly one of them
+// must be given.
+func (s *ProductService) Lookup(barcode, sku string) (*models.ProductDetail, error) {
+	if (barcode == "") == (sku == "") {
+		return nil, repositories.NewValidationError(models.FieldError{
+			Field:   "barcode",
+			Rule:    RuleOneOf,
+			Message: "exactly one of barcode or sku is required",
+		})
+	}
+	if sku != "" {
+		return s.repo.GetBySKU(normalizeSKU(sku))
+	}
+
+	normalized, err := NormalizeBarcode(barcode)
+	if err != nil {
+		return nil, repositories.NewValidationError(models.FieldError{Field: "barcode", Rule: RuleBarcode, Message: err.Error()})
+	}
+	return s.repo.GetByBarcode(normalized)
+}
+
+// ChangesStock reports whether update edits the product's stock. A stock sent
+// back unchanged is dropped from update, so it needs no inventory:adjust.
+func (s *ProductService) ChangesStock(update *models.ProductUpdate) (bool, error) {
+	if update.Stock == nil {
+		return false, nil
+	}
+	current, err := s.repo.GetByID(update.ID)
+	if err != nil {
+		return false, err
+	}
+	if *update.Stock == current.Stock {
+		update.Stock = nil
+		return false, nil
+	}
+	return true, nil
+}
+
+// Update saves update over the stored product. Of the optional fields,
+// sku, barcodes, category_id and tax_class_id are kept here when left out of
+// the body; stock and cost_price are kept by the repository, under the same
+// lock that books stock movements and receipts.
+func (s *ProductService) Update(update *models.ProductUpdate) error {
+	if update.Sent != nil {
+		current, err := s.repo.GetByID(update.ID)
+		if err != nil {
+			return err
+		}
+		keepUnsent(update, current)
+	}
+	if update.Stock != nil {
+		update.Product.Stock = *update.Stock
+	}
+	if update.CostPrice != nil {
+		update.Product.CostPrice = *update.CostPrice
+	}
+	if err := validateProduct(&update.Product); err != nil {
+		return err
+	}
+	return s.repo.Update(update)
+}
+
+func keepUnsent(update *models.ProductUpdate, current *models.ProductDetail) {
+	if !update.Sent["sku"] {
+		update.SKU = current.SKU
+	}
+	if !update.Sent["barcodes"] {
+		update.Barcodes = current.Barcodes
+	}
+	if !update.Sent["category_id"] {
+		update.CategoryID = current.CategoryID
+	}
+	if !update.Sent["tax_class_id"] {
+		update.TaxClassID = current.TaxClassID
+	}
 }
 
 func (s *ProductService) Delete(id int) error {
 	return s.repo.Delete(id)
 }
+
+func (s *ProductService) GetPriceHistory(filter models.PriceHistoryFilter) (*models.Page[models.PriceChange], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	changes, total, err := s.repo.GetPriceHistory(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(changes, filter.Page, filter.Limit, total)
+	return &page, nil
+}
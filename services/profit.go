@@ -0,0 +1,76 @@
+package services
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"simple-cashier-api/models"
+)
+
+// UncategorizedName labels the profit of products without a category.
+const UncategorizedName = "Uncategorized"
+
+// summarizeProfit fills in the gross profit and margin of every product line,
+// groups the lines by category and totals them. Products and categories are
+// ordered by gross profit, highest first.
+func summarizeProfit(report *models.TransactionReport) {
+	if report.Products == nil {
+		report.Products = make([]models.ProductProfit, 0)
+	}
+
+	categories := make([]models.CategoryProfit, 0)
+	index := map[int]int{}
+	for i := range report.Products {
+		p := &report.Products[i]
+		p.GrossProfit = p.Revenue - p.Cost
+		p.MarginPercent = marginPercent(p.GrossProfit, p.Revenue)
+
+		report.CostOfGoodsSold += p.Cost
+
+		key := 0
+		if p.CategoryID != nil {
+			key = *p.CategoryID
+		}
+		j, ok := index[key]
+		if !ok {
+			c := models.CategoryProfit{Name: UncategorizedName}
+			if p.CategoryID != nil {
+				id := *p.CategoryID
+				c.CategoryID = &id
+				c.Name = p.CategoryName
+			}
+			j = len(categories)
+			index[key] = j
+			categories = append(categories, c)
+		}
+		categories[j].Revenue += p.Revenue
+		categories[j].Cost += p.Cost
+	}
+
+	for i := range categories {
+		c := &categories[i]
+		c.GrossProfit = c.Revenue - c.Cost
+		c.MarginPercent = marginPercent(c.GrossProfit, c.Revenue)
+	}
+
+	report.GrossProfit = report.TotalRevenue - report.CostOfGoodsSold
+	report.MarginPercent = marginPercent(report.GrossProfit, report.TotalRevenue)
+
+	slices.SortStableFunc(report.Products, func(a, b models.ProductProfit) int {
+		return cmp.Compare(b.GrossProfit, a.GrossProfit)
+	})
+	slices.SortStableFunc(categories, func(a, b models.CategoryProfit) int {
+		return cmp.Compare(b.GrossProfit, a.GrossProfit)
+	})
+	report.Categories = categories
+}
+
+// marginPercent is profit as a percentage of revenue, rounded to two
+// decimals. It is 0 when there is no revenue.
+func marginPercent(profit, revenue int) float64 {
+	if revenue == 0 {
+		return 0
+	}
+	return math.Round(float64(profit)*10000/float64(revenue)) / 100
+}
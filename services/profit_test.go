@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"simple-cashier-api/models"
+)
+
+func TestSummarizeProfit(t *testing.T) {
+	drinks := 2
+	report := models.TransactionReport{
+		TotalRevenue: 30000,
+		Products: []models.ProductProfit{
+			{ProductID: 1, Name: "Indomie Goreng", Revenue: 14000, Cost: 11200},
+			{ProductID: 2, Name: "Aqua 600ml", CategoryID: &drinks, CategoryName: "Minuman", Revenue: 10000, Cost: 7500},
+			{ProductID: 3, Name: "Teh Botol", CategoryID: &drinks, CategoryName: "Minuman", Revenue: 6000, Cost: 3000},
+		},
+	}
+
+	summarizeProfit(&report)
+
+	if report.CostOfGoodsSold != 21700 || report.GrossProfit != 8300 || report.MarginPercent != 27.67 {
+		t.Errorf("totals = %d / %d / %v, want 21700 / 8300 / 27.67", report.CostOfGoodsSold, report.GrossProfit, report.MarginPercent)
+	}
+	if first := report.Products[0]; first.ProductID != 3 || first.GrossProfit != 3000 || first.MarginPercent != 50 {
+		t.Errorf("first product = %+v, want Teh Botol with 3000 profit at 50%%", first)
+	}
+
+	if len(report.Categories) != 2 {
+		t.Fatalf("got %d categories, want 2", len(report.Categories))
+	}
+	if c := report.Categories[0]; c.Name != "Minuman" || c.GrossProfit != 5500 || c.MarginPercent != 34.38 {
+		t.Errorf("first category = %+v, want Minuman with 5500 profit at 34.38%%", c)
+	}
+	if c := report.Categories[1]; c.CategoryID != nil || c.Name != UncategorizedName || c.GrossProfit != 2800 {
+		t.Errorf("second category = %+v, want uncategorized with 2800 profit", c)
+	}
+}
+
+func TestMarginPercentWithoutRevenue(t *testing.T) {
+	if got := marginPercent(-500, 0); got != 0 {
+		t.Errorf("got %v, want 0", got)
+	}
+}
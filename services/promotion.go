@@ -0,0 +1,192 @@
+package services
+
+import (
+	"cmp"
+	"slices"
+
+	"simple-cashier-api/models"
+)
+
+// applyPromotions discounts the priced checkout draft trx. Line promotions
+// run before cart promotions, each group in id order, so the same cart and
+// promotions always give the same result. A line takes at most one line
+// promotion; cart promotions stack, each on what the ones before it left.
+// MinSpend is checked against that running total too. Every discount is
+// spread over the lines it applies to, so the line subtotals always add up
+// to the total.
+func applyPromotions(trx *models.Transaction, promotions []models.Promotion) {
+	ordered := slices.Clone(promotions)
+	slices.SortStableFunc(ordered, func(a, b models.Promotion) int {
+		return cmp.Or(cmp.Compare(promotionStage(a.Type), promotionStage(b.Type)), cmp.Compare(a.ID, b.ID))
+	})
+
+	taken := make([]bool, len(trx.Details))
+	spend := trx.Subtotal
+	for _, p := range ordered {
+		if spend < p.MinSpend {
+			continue
+		}
+
+		amount := 0
+		for i, discount := range promotionDiscounts(p, trx.Details, taken) {
+			if discount == 0 {
+				continue
+			}
+			trx.Details[i].Discount += discount
+			trx.Details[i].Subtotal -= discount
+			taken[i] = taken[i] || promotionStage(p.Type) == 0
+			amount += discount
+		}
+		if amount == 0 {
+			continue
+		}
+
+		id := p.ID
+		trx.Promotions = append(trx.Promotions, models.AppliedPromotion{
+			PromotionID: &id,
+			Name:        p.Name,
+			Type:        p.Type,
+			Amount:      amount,
+		})
+		trx.DiscountAmount += amount
+		trx.TotalAmount -= amount
+		spend -= amount
+	}
+}
+
+// promotionStage orders line promotions (0) before cart promotions (1).
+func promotionStage(promotionType string) int {
+	switch promotionType {
+	case models.PromotionTypeCartPercentOff, models.PromotionTypeCartAmountOff:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// promotionDiscounts works out what p takes off each line. Lines already
+// discounted by a line promotion are skipped by other line promotions. Cart
+// promotions take their discount off the lines in scope only.
+func promotionDiscounts(p models.Promotion, details []models.TransactionDetail, taken []bool) []int {
+	discounts := make([]int, len(details))
+
+	switch p.Type {
+	case models.PromotionTypeBundle:
+		return bundleDiscounts(p, details, taken)
+	case models.PromotionTypeCartPercentOff, models.PromotionTypeCartAmountOff:
+		weights := make([]int, len(details))
+		total := 0
+		for i, d := range details {
+			if inPromotionScope(p, d) {
+				weights[i] = d.Subtotal
+				total += d.Subtotal
+			}
+		}
+		amount := min(p.Value, total)
+		if p.Type == models.PromotionTypeCartPercentOff {
+			amount = percentOf(total, p.Value)
+		}
+		return allocate(amount, weights)
+	}
+
+	for i, d := range details {
+		if taken[i] || !inPromotionScope(p, d) {
+			continue
+		}
+		switch p.Type {
+		case models.PromotionTypePercentOff:
+			discounts[i] = percentOf(d.Subtotal, p.Value)
+		case models.PromotionTypeAmountOff:
+			discounts[i] = min(p.Value*d.Quantity, d.Subtotal)
+		case models.PromotionTypeBuyXGetY:
+			free := d.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+			discounts[i] = min(free*d.UnitPrice, d.Subtotal)
+		}
+	}
+	return discounts
+}
+
+// bundleDiscounts sells every full set of the bundle's items for its price.
+// The saving is spread over the bundle's lines by their list price.
+func bundleDiscounts(p models.Promotion, details []models.TransactionDetail, taken []bool) []int {
+	discounts := make([]int, len(details))
+	if len(p.BundleItems) == 0 {
+		return discounts
+	}
+
+	weights := make([]int, len(details))
+	sets, regular := -1, 0
+	for _, item := range p.BundleItems {
+		i := slices.IndexFunc(details, func(d models.TransactionDetail) bool { return d.ProductID == item.ProductID })
+		if i < 0 || taken[i] {
+			return discounts
+		}
+		if n := details[i].Quantity / item.Quantity; sets < 0 || n < sets {
+			sets = n
+		}
+		weights[i] = item.Quantity * details[i].UnitPrice
+		regular += weights[i]
+	}
+	if sets == 0 || regular <= p.BundlePrice {
+		return discounts
+	}
+
+	return allocate(sets*(regular-p.BundlePrice), weights)
+}
+
+func inPromotionScope(p models.Promotion, d models.TransactionDetail) bool {
+	if len(p.ProductIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+	return slices.Contains(p.ProductIDs, d.ProductID) ||
+		d.CategoryID != nil && slices.Contains(p.CategoryIDs, *d.CategoryID)
+}
+
+// percentOf rounds half up.
+func percentOf(amount, percent int) int {
+	return (amount*percent + 50) / 100
+}
+
+// allocate splits amount in proportion to weights, capped at their sum. The
+// units lost to rounding down go to the largest remainders, earlier lines
+// first on a tie, so no share exceeds its weight.
+func allocate(amount int, weights []int) []int {
+	shares := make([]int, len(weights))
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return shares
+	}
+	amount = min(amount, total)
+
+	order := make([]int, 0, len(weights))
+	left := amount
+	for i, w := range weights {
+		shares[i] = amount * w / total
+		left -= shares[i]
+		if w > 0 {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(amount*weights[b]%total, amount*weights[a]%total)
+	})
+	for _, i := range order[:left] {
+		shares[i]++
+	}
+	return shares
+}
+
+// summarizePromotions totals the report's discounts and orders promotions by
+// the discount given, largest first.
+func summarizePromotions(report *models.TransactionReport) {
+	report.TotalDiscount = 0
+	for _, p := range report.Promotions {
+		report.TotalDiscount += p.DiscountAmount
+	}
+	slices.SortStableFunc(report.Promotions, func(a, b models.PromotionUsage) int {
+		return cmp.Or(cmp.Compare(b.DiscountAmount, a.DiscountAmount), cmp.Compare(a.Name, b.Name))
+	})
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"simple-cashier-api/models"
+)
+
+type PromotionService struct {
+	repo PromotionRepository
+}
+
+func NewPromotionService(repo PromotionRepository) *PromotionService {
+	return &PromotionService{repo: repo}
+}
+
+func (s *PromotionService) GetAll(filter models.PromotionFilter) (*models.Page[models.Promotion], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	promotions, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(promotions, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *PromotionService) Create(promotion *models.Promotion) error {
+	if err := validatePromotion(promotion); err != nil {
+		return err
+	}
+	return s.repo.Create(promotion)
+}
+
+func (s *PromotionService) GetByID(id int) (*models.Promotion, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *PromotionService) Update(promotion *models.Promotion) error {
+	if err := validatePromotion(promotion); err != nil {
+		return err
+	}
+	return s.repo.Update(promotion)
+}
+
+func (s *PromotionService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"simple-cashier-api/models"
+)
+
+// cart prices a draft like CreateTransaction does. Lines are (product id,
+// unit price, quantity); products 1 and 2 are in category 10.
+func cart(lines ...[3]int) *models.Transaction {
+	snacks := 10
+	trx := &models.Transaction{}
+	for _, l := range lines {
+		d := models.TransactionDetail{ProductID: l[0], UnitPrice: l[1], Quantity: l[2], Subtotal: l[1] * l[2]}
+		if l[0] <= 2 {
+			d.CategoryID = &snacks
+		}
+		trx.Details = append(trx.Details, d)
+		trx.Subtotal += d.Subtotal
+	}
+	trx.TotalAmount = trx.Subtotal
+	return trx
+}
+
+func TestApplyPromotions(t *testing.T) {
+	tests := []struct {
+		name       string
+		trx        *models.Transaction
+		promotions []models.Promotion
+		discounts  []int
+		applied    []int
+	}{
+		{
+			name: "percent off a category",
+			trx:  cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypePercentOff, Value: 10, CategoryIDs: []int{10}},
+			},
+			discounts: []int{700, 0},
+			applied:   []int{1},
+		},
+		{
+			name: "amount off per unit is capped at the line",
+			trx:  cart([3]int{3, 2000, 3}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypeAmountOff, Value: 2500},
+			},
+			discounts: []int{6000},
+			applied:   []int{1},
+		},
+		{
+			name: "buy two get one",
+			trx:  cart([3]int{1, 3500, 7}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypeBuyXGetY, BuyQuantity: 2, GetQuantity: 1, ProductIDs: []int{1}},
+			},
+			discounts: []int{7000},
+			applied:   []int{1},
+		},
+		{
+			name: "bundle price for full sets only",
+			trx:  cart([3]int{1, 3500, 3}, [3]int{3, 2000, 1}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypeBundle, BundlePrice: 8000, BundleItems: []models.BundleItem{
+					{ProductID: 1, Quantity: 2}, {ProductID: 3, Quantity: 1},
+				}},
+			},
+			discounts: []int{778, 222},
+			applied:   []int{1},
+		},
+		{
+			name: "a line takes one line promotion, the lower id first",
+			trx:  cart([3]int{1, 3500, 2}, [3]int{2, 5000, 1}),
+			promotions: []models.Promotion{
+				{ID: 2, Type: models.PromotionTypePercentOff, Value: 50},
+				{ID: 1, Type: models.PromotionTypePercentOff, Value: 10, ProductIDs: []int{1}},
+			},
+			discounts: []int{700, 2500},
+			applied:   []int{1, 2},
+		},
+		{
+			name: "cart promotions stack after line promotions",
+			trx:  cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypeCartAmountOff, Value: 1000},
+				{ID: 2, Type: models.PromotionTypeCartPercentOff, Value: 10},
+				{ID: 3, Type: models.PromotionTypePercentOff, Value: 10, ProductIDs: []int{1}},
+			},
+			// 9000 - 700 = 8300; -1000 = 7300; -10% = 6570
+			discounts: []int{700 + 759 + 554, 241 + 176},
+			applied:   []int{3, 1, 2},
+		},
+		{
+			name: "cart promotions only discount the lines in scope",
+			trx:  cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypeCartPercentOff, Value: 10, ProductIDs: []int{3}},
+				{ID: 2, Type: models.PromotionTypeCartAmountOff, Value: 5000, ProductIDs: []int{3}},
+			},
+			// 10% of 2000, then the 1800 left of it
+			discounts: []int{0, 200 + 1800},
+			applied:   []int{1, 2},
+		},
+		{
+			name: "minimum spend counts earlier discounts",
+			trx:  cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypePercentOff, Value: 10, ProductIDs: []int{1}},
+				{ID: 2, Type: models.PromotionTypeCartAmountOff, Value: 1000, MinSpend: 9000},
+			},
+			// 9000 - 700 = 8300 is below the minimum spend
+			discounts: []int{700, 0},
+			applied:   []int{1},
+		},
+		{
+			name: "below minimum spend",
+			trx:  cart([3]int{1, 3500, 2}),
+			promotions: []models.Promotion{
+				{ID: 1, Type: models.PromotionTypeCartAmountOff, Value: 1000, MinSpend: 10000},
+			},
+			discounts: []int{0},
+			applied:   []int{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			applyPromotions(tc.trx, tc.promotions)
+
+			discounts := make([]int, len(tc.trx.Details))
+			sum := 0
+			for i, d := range tc.trx.Details {
+				discounts[i] = d.Discount
+				sum += d.Subtotal
+				if d.Subtotal != d.UnitPrice*d.Quantity-d.Discount {
+					t.Errorf("line %d subtotal = %d, want list price less discount", i, d.Subtotal)
+				}
+			}
+			if !slices.Equal(discounts, tc.discounts) {
+				t.Errorf("discounts = %v, want %v", discounts, tc.discounts)
+			}
+			if sum != tc.trx.TotalAmount || tc.trx.Subtotal-tc.trx.DiscountAmount != tc.trx.TotalAmount {
+				t.Errorf("total %d does not add up: subtotal %d, discount %d, lines %d",
+					tc.trx.TotalAmount, tc.trx.Subtotal, tc.trx.DiscountAmount, sum)
+			}
+
+			applied := make([]int, 0, len(tc.trx.Promotions))
+			for _, p := range tc.trx.Promotions {
+				applied = append(applied, *p.PromotionID)
+			}
+			if !slices.Equal(applied, tc.applied) {
+				t.Errorf("applied = %v, want %v", applied, tc.applied)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	if got := allocate(100, []int{1000, 1000, 1000}); !slices.Equal(got, []int{34, 33, 33}) {
+		t.Errorf("allocate(100, 1:1:1) = %v, want [34 33 33]", got)
+	}
+	if got := allocate(500, []int{100, 0, 200}); !slices.Equal(got, []int{100, 0, 200}) {
+		t.Errorf("allocate beyond the weights = %v, want capped at [100 0 200]", got)
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"slices"
+	"strings"
+
+	"simple-cashier-api/models"
+)
+
+type PurchaseOrderService struct {
+	repo PurchaseOrderRepository
+}
+
+func NewPurchaseOrderService(repo PurchaseOrderRepository) *PurchaseOrderService {
+	return &PurchaseOrderService{repo: repo}
+}
+
+func (s *PurchaseOrderService) GetAll(filter models.PurchaseOrderFilter) (*models.Page[models.PurchaseOrder], error) {
+	var v validator
+	v.check(filter.Status == "" || slices.Contains(models.PurchaseOrderStatuses, filter.Status), "status", RuleOneOf,
+		"must be one of %s", strings.Join(models.PurchaseOrderStatuses, ", "))
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	orders, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(orders, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+// Create stores a new purchase order as a draft.
+func (s *PurchaseOrderService) Create(req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	po, err := newPurchaseOrder(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(po); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+func (s *PurchaseOrderService) GetByID(id int) (*models.PurchaseOrder, error) {
+	return s.repo.GetByID(id)
+}
+
+// Update replaces a draft. Orders that have been placed cannot change.
+func (s *PurchaseOrderService) Update(id int, req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	po, err := newPurchaseOrder(req)
+	if err != nil {
+		return nil, err
+	}
+	po.ID = id
+	if err := s.repo.Update(po); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+// Order places a draft with the supplier.
+func (s *PurchaseOrderService) Order(id int) (*models.PurchaseOrder, error) {
+	return s.repo.SetStatus(id, []string{models.PurchaseOrderStatusDraft}, models.PurchaseOrderStatusOrdered)
+}
+
+// Cancel closes an order that has not been received in full. Stock already
+// received stays.
+func (s *PurchaseOrderService) Cancel(id int) (*models.PurchaseOrder, error) {
+	return s.repo.SetStatus(id, []string{
+		models.PurchaseOrderStatusDraft,
+		models.PurchaseOrderStatusOrdered,
+		models.PurchaseOrderStatusPartiallyReceived,
+	}, models.PurchaseOrderStatusCancelled)
+}
+
+// Receive adds delivered quantities to stock.
+func (s *PurchaseOrderService) Receive(id int, req models.ReceiveRequest) (*models.PurchaseOrder, error) {
+	if err := validateReceipt(&req); err != nil {
+		return nil, err
+	}
+	return s.repo.Receive(id, req)
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"simple-cashier-api/models"
+)
+
+// ReceiptWidths maps the accepted receipt widths to characters per line. 58mm
+// and 80mm paper fit 32 and 48 characters in a thermal printer's default
+// font.
+var ReceiptWidths = map[string]int{
+	"32":   32,
+	"48":   48,
+	"58mm": 32,
+	"80mm": 48,
+}
+
+type ReceiptService struct {
+	transactions TransactionRepository
+	settings     models.ReceiptSettings
+}
+
+// DefaultReceiptWidth fits 58mm paper, the most common till printer.
+const DefaultReceiptWidth = 32
+
+func NewReceiptService(transactions TransactionRepository, settings models.ReceiptSettings) *ReceiptService {
+	if settings.Width == 0 {
+		settings.Width = DefaultReceiptWidth
+	}
+	return &ReceiptService{transactions: transactions, settings: settings}
+}
+
+// Render formats the stored transaction id as a receipt width characters
+// wide, or the configured width when it is 0.
+func (s *ReceiptService) Render(id int, format string, width int) (*models.Receipt, error) {
+	trx, err := s.transactions.GetTransactionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if width == 0 {
+		width = s.settings.Width
+	}
+
+	return renderReceipt(layoutReceipt(trx, s.settings, width), format, width)
+}
+
+// renderReceipt renders a receipt layout in format.
+func renderReceipt(lines []receiptLine, format string, width int) (*models.Receipt, error) {
+	switch format {
+	case models.ReceiptFormatHTML:
+		return renderReceiptHTML(lines, width)
+	case models.ReceiptFormatESCPOS:
+		return &models.Receipt{ContentType: "application/octet-stream", Body: renderReceiptESCPOS(lines, width)}, nil
+	default:
+		return &models.Receipt{ContentType: "text/plain; charset=utf-8", Body: []byte(renderReceiptText(lines, width))}, nil
+	}
+}
+
+// receiptLine is one line of the receipt layout, shared by every format. A
+// rule is a separator; a line with Right is a label and an amount.
+type receiptLine struct {
+	Left   string
+	Right  string
+	Center bool
+	Bold   bool
+	Rule   bool
+}
+
+var paymentLabels = map[string]string{
+	models.PaymentMethodCash:      "Cash",
+	models.PaymentMethodDebitCard: "Debit card",
+	models.PaymentMethodQRIS:      "QRIS",
+	models.PaymentMethodEWallet:   "E-wallet",
+}
+
+// layoutReceipt lays out trx as it was charged: each line at its list price,
+// then the promotions and voucher that discounted them, the totals, the
+// payments and the change. Voids and refunds made since are listed after
+// them.
+func layoutReceipt(trx *models.Transaction, settings models.ReceiptSettings, width int) []receiptLine {
+	lines := layoutStoreHeader(settings, width)
+	add := func(l receiptLine) { lines = append(lines, l) }
+	row := func(left string, amount int) { add(receiptLine{Left: left, Right: formatRupiah(amount)}) }
+	rule := func() { add(receiptLine{Rule: true}) }
+
+	rule()
+	add(receiptLine{Left: "No", Right: ReceiptNumber(trx.ID)})
+	add(receiptLine{Left: "Date", Right: trx.CreatedAt.Local().Format("02/01/2006 15:04")})
+	if trx.Cashier != "" {
+		add(receiptLine{Left: "Cashier", Right: trx.Cashier})
+	}
+	if trx.ApprovedBy != "" {
+		add(receiptLine{Left: "Approved by", Right: trx.ApprovedBy})
+	}
+	if trx.Status == models.TransactionStatusVoided {
+		add(receiptLine{Left: "*** VOID ***", Center: true, Bold: true})
+	}
+	rule()
+
+	for _, d := range trx.Details {
+		for _, name := range wrapText(d.ProductName, width) {
+			add(receiptLine{Left: name})
+		}
+		row(fmt.Sprintf("  %d x %s", d.Quantity, formatRupiah(d.UnitPrice)), d.UnitPrice*d.Quantity)
+	}
+	rule()
+
+	row("Subtotal", trx.Subtotal)
+	for _, p := range trx.Promotions {
+		row(p.Name, -p.Amount)
+	}
+	if v := trx.Voucher; v != nil {
+		row("Voucher "+v.Code, -v.Amount)
+	}
+	if trx.ServiceCharge > 0 {
+		row("Service charge", trx.ServiceCharge)
+	}
+	for _, tax := range taxesByRate(trx) {
+		if trx.PricesIncludeTax {
+			row("Incl. PPN "+formatRate(tax.TaxRateBP), tax.TaxAmount)
+		} else {
+			row("PPN "+formatRate(tax.TaxRateBP), tax.TaxAmount)
+		}
+	}
+	add(receiptLine{Left: "TOTAL", Right: formatRupiah(trx.TotalAmount), Bold: true})
+	rule()
+
+	for _, p := range trx.Payments {
+		label, ok := paymentLabels[p.Method]
+		if !ok {
+			label = p.Method
+		}
+		row(label, p.Amount)
+		if p.Reference != "" {
+			add(receiptLine{Left: "  Ref " + p.Reference})
+		}
+	}
+	row("Change", trx.ChangeAmount)
+
+	if len(trx.Refunds) > 0 {
+		rule()
+		for _, r := range trx.Refunds {
+			label := "Refund " + r.CreatedAt.Local().Format("02/01 15:04")
+			if r.Type == models.RefundTypeVoid {
+				label = "Void " + r.CreatedAt.Local().Format("02/01 15:04")
+			}
+			row(label, -r.TotalAmount)
+		}
+	}
+
+	if settings.Footer != "" {
+		rule()
+		for _, l := range strings.Split(settings.Footer, "\n") {
+			for _, wrapped := range wrapText(strings.TrimSpace(l), width) {
+				add(receiptLine{Left: wrapped, Center: true})
+			}
+		}
+	}
+	return lines
+}
+
+// layoutStoreHeader lays out the store's name and address, centred.
+func layoutStoreHeader(settings models.ReceiptSettings, width int) []receiptLine {
+	var lines []receiptLine
+	if settings.StoreName != "" {
+		lines = append(lines, receiptLine{Left: settings.StoreName, Center: true, Bold: true})
+	}
+	for _, l := range strings.Split(settings.StoreAddress, "\n") {
+		for _, wrapped := range wrapText(strings.TrimSpace(l), width) {
+			lines = append(lines, receiptLine{Left: wrapped, Center: true})
+		}
+	}
+	return lines
+}
+
+// ReceiptNumber is the number printed on a transaction's receipt.
+func ReceiptNumber(id int) string {
+	return fmt.Sprintf("%06d", id)
+}
+
+// taxesByRate totals the tax of trx's lines by rate, leaving out rates that
+// collected nothing.
+func taxesByRate(trx *models.Transaction) []models.TaxCollected {
+	var taxes []models.TaxCollected
+	for _, d := range trx.Details {
+		if d.TaxAmount == 0 {
+			continue
+		}
+		i := 0
+		for i < len(taxes) && taxes[i].TaxRateBP != d.TaxRateBP {
+			i++
+		}
+		if i == len(taxes) {
+			taxes = append(taxes, models.TaxCollected{TaxRateBP: d.TaxRateBP})
+		}
+		taxes[i].TaxAmount += d.TaxAmount
+	}
+	return taxes
+}
+
+// formatRupiah formats amount with dots between thousands, as in 10.323.
+func formatRupiah(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	digits := strconv.Itoa(amount)
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String()
+}
+
+// formatRate formats basis points as a percentage, as in 11% or 12.5%.
+func formatRate(bp int) string {
+	s := strconv.Itoa(bp / 100)
+	if frac := bp % 100; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%02d", frac), "0")
+	}
+	return s + "%"
+}
+
+// wrapText breaks s into lines of at most width characters, at spaces where
+// it can.
+func wrapText(s string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		for utf8.RuneCountInString(word) > width {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		switch {
+		case line == "":
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatLine pads l to width characters. A label too long to fit beside its
+// amount is cut short; the amount is always printed in full.
+func formatLine(l receiptLine, width int) string {
+	switch {
+	case l.Rule:
+		return strings.Repeat("-", width)
+	case l.Center:
+		pad := max(width-utf8.RuneCountInString(l.Left), 0) / 2
+		return strings.Repeat(" ", pad) + l.Left
+	case l.Right == "":
+		return l.Left
+	}
+
+	left := []rune(l.Left)
+	room := width - utf8.RuneCountInString(l.Right) - 1
+	if len(left) > room {
+		left = left[:max(room, 0)]
+	}
+	return string(left) + strings.Repeat(" ", max(width-len(left)-utf8.RuneCountInString(l.Right), 1)) + l.Right
+}
+
+func renderReceiptText(lines []receiptLine, width int) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(formatLine(l, width))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ESC/POS commands understood by practically every thermal receipt printer.
+const (
+	escposInit      = "\x1b@"
+	escposBoldOn    = "\x1bE\x01"
+	escposBoldOff   = "\x1bE\x00"
+	escposFeedLines = "\x1bd\x04"
+	escposCut       = "\x1dV\x41\x00"
+)
+
+// renderReceiptESCPOS renders the text layout with bold where the layout asks
+// for it, then feeds the paper past the cutter and cuts it. Printers default
+// to a code page without most non-ASCII letters, so those print as '?'.
+func renderReceiptESCPOS(lines []receiptLine, width int) []byte {
+	var b bytes.Buffer
+	b.WriteString(escposInit)
+	for _, l := range lines {
+		text := strings.Map(func(r rune) rune {
+			if r > 0x7e || r < 0x20 {
+				return '?'
+			}
+			return r
+		}, formatLine(l, width))
+
+		if l.Bold {
+			b.WriteString(escposBoldOn)
+		}
+		b.WriteString(text)
+		if l.Bold {
+			b.WriteString(escposBoldOff)
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString(escposFeedLines)
+	b.WriteString(escposCut)
+	return b.Bytes()
+}
+
+var receiptHTML = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Receipt</title>
+<style>
+body { margin: 0; }
+.receipt { width: {{.Width}}ch; margin: 0 auto; padding: 1ch; font: 14px/1.4 monospace; }
+.row { display: flex; justify-content: space-between; gap: 1ch; white-space: pre; }
+.row span:first-child { overflow: hidden; text-overflow: ellipsis; }
+.center { text-align: center; }
+.bold { font-weight: bold; }
+hr { border: 0; border-top: 1px dashed; }
+</style>
+</head>
+<body>
+<div class="receipt">
+{{- range .Lines}}
+{{- if .Rule}}
+<hr>
+{{- else if .Right}}
+<div class="row{{if .Bold}} bold{{end}}"><span>{{.Left}}</span><span>{{.Right}}</span></div>
+{{- else}}
+<div class="{{if .Center}}center{{else}}row{{end}}{{if .Bold}} bold{{end}}">{{.Left}}</div>
+{{- end}}
+{{- end}}
+</div>
+</body>
+</html>
+`))
+
+// renderReceiptHTML renders a standalone page that prints like the text
+// receipt. Every value is escaped by html/template.
+func renderReceiptHTML(lines []receiptLine, width int) (*models.Receipt, error) {
+	var b bytes.Buffer
+	err := receiptHTML.Execute(&b, struct {
+		Width int
+		Lines []receiptLine
+	}{width, lines})
+	if err != nil {
+		return nil, err
+	}
+	return &models.Receipt{ContentType: "text/html; charset=utf-8", Body: b.Bytes()}, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+func TestReceiptFormatting(t *testing.T) {
+	for amount, want := range map[int]string{0: "0", 999: "999", 1000: "1.000", 10323: "10.323", -1234567: "-1.234.567"} {
+		if got := formatRupiah(amount); got != want {
+			t.Errorf("formatRupiah(%d) = %q, want %q", amount, got, want)
+		}
+	}
+	for bp, want := range map[int]string{0: "0%", 1100: "11%", 1250: "12.5%", 1205: "12.05%"} {
+		if got := formatRate(bp); got != want {
+			t.Errorf("formatRate(%d) = %q, want %q", bp, got, want)
+		}
+	}
+
+	got := wrapText("Indomie Goreng Rasa Ayam Bawang Jumbo", 16)
+	if want := []string{"Indomie Goreng", "Rasa Ayam Bawang", "Jumbo"}; !slices.Equal(got, want) {
+		t.Errorf("wrapText = %q, want %q", got, want)
+	}
+	if got := wrapText("ABCDEFGHIJ", 4); !slices.Equal(got, []string{"ABCD", "EFGH", "IJ"}) {
+		t.Errorf("long word wrapped as %q", got)
+	}
+
+	line := formatLine(receiptLine{Left: "Voucher RAMADAN-7KQ2M9XWPA", Right: "-10.000"}, 24)
+	if line != "Voucher RAMADAN- -10.000" {
+		t.Errorf("formatLine = %q", line)
+	}
+}
+
+func TestLayoutReceipt(t *testing.T) {
+	trx := &models.Transaction{
+		ID: 12, Subtotal: 7000, TaxAmount: 385, PricesIncludeTax: true, TotalAmount: 7000,
+		PaidAmount: 10000, ChangeAmount: 3000, CreatedAt: time.Now(),
+		Details: []models.TransactionDetail{
+			{ProductName: "Kopi Susu", Quantity: 2, UnitPrice: 3500, Subtotal: 7000, TaxRateBP: 1100, TaxAmount: 385, Total: 7000},
+		},
+		Payments: []models.Payment{{Method: models.PaymentMethodCash, Amount: 10000}},
+		Refunds:  []models.Refund{{Type: models.RefundTypeVoid, TotalAmount: 7000, CreatedAt: time.Now()}},
+		Status:   models.TransactionStatusVoided,
+	}
+
+	text := renderReceiptText(layoutReceipt(trx, models.ReceiptSettings{StoreName: "Toko Maju"}, 32), 32)
+	for _, want := range []string{"000012", "*** VOID ***", "Incl. PPN 11%", "Void "} {
+		if !strings.Contains(text, want) {
+			t.Errorf("receipt lacks %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+// The services depend on these interfaces rather than on the PostgreSQL
+// repositories directly, so the in-memory backend in repositories/memory can
+// stand in for demos and tests.
+
+type ProductRepository interface {
+	GetAll(filter models.ProductFilter) ([]models.ProductDetail, int, error)
+	Create(product *models.Product) error
+	GetByID(id int) (*models.ProductDetail, error)
+	GetByBarcode(barcode string) (*models.ProductDetail, error)
+	GetBySKU(sku string) (*models.ProductDetail, error)
+	Update(update *models.ProductUpdate) error
+	Delete(id int) error
+	GetPriceHistory(filter models.PriceHistoryFilter) ([]models.PriceChange, int, error)
+}
+
+type CategoryRepository interface {
+	GetAll(filter models.CategoryFilter) ([]models.Category, int, error)
+	Create(category *models.Category) error
+	GetByID(id int) (*models.Category, error)
+	Update(category *models.Category) error
+	Delete(id int) error
+}
+
+type TransactionRepository interface {
+	CreateTransaction(items []models.CheckoutItem, voucherCode string, claim *models.IdempotencyClaim,
+		finalize repositories.CheckoutFinalizer) (*models.Transaction, error)
+	GetTransactions(filter models.TransactionFilter) ([]models.Transaction, int, error)
+	GetTransactionByID(id int) (*models.Transaction, error)
+	VoidTransaction(id int, reason string, by models.Actor) (*models.Refund, error)
+	RefundTransaction(id int, req models.RefundRequest, by models.Actor) (*models.Refund, error)
+	GetTransactionReport(startDate time.Time, endDate time.Time) (*models.TransactionReport, error)
+}
+
+type InventoryRepository interface {
+	AdjustStock(productID int, adj models.StockAdjustment) (*models.InventoryMovement, error)
+	GetStockHistory(filter models.StockHistoryFilter) ([]models.InventoryMovement, int, error)
+}
+
+type SupplierRepository interface {
+	GetAll(filter models.SupplierFilter) ([]models.Supplier, int, error)
+	Create(supplier *models.Supplier) error
+	GetByID(id int) (*models.Supplier, error)
+	Update(supplier *models.Supplier) error
+	Delete(id int) error
+}
+
+type PurchaseOrderRepository interface {
+	GetAll(filter models.PurchaseOrderFilter) ([]models.PurchaseOrder, int, error)
+	Create(po *models.PurchaseOrder) error
+	GetByID(id int) (*models.PurchaseOrder, error)
+	Update(po *models.PurchaseOrder) error
+	SetStatus(id int, from []string, to string) (*models.PurchaseOrder, error)
+	Receive(id int, req models.ReceiveRequest) (*models.PurchaseOrder, error)
+}
+
+type PromotionRepository interface {
+	GetAll(filter models.PromotionFilter) ([]models.Promotion, int, error)
+	GetActive(at time.Time) ([]models.Promotion, error)
+	Create(promotion *models.Promotion) error
+	GetByID(id int) (*models.Promotion, error)
+	Update(promotion *models.Promotion) error
+	Delete(id int) error
+}
+
+type VoucherRepository interface {
+	GetAll(filter models.VoucherFilter) ([]models.Voucher, int, error)
+	Create(voucher *models.Voucher) error
+	GetByID(id int) (*models.Voucher, error)
+	Update(voucher *models.Voucher) error
+	Delete(id int) error
+	AddCodes(voucherID int, codes []string) ([]models.VoucherCode, error)
+	GetCodes(filter models.VoucherCodeFilter) ([]models.VoucherCode, int, error)
+	GetByCode(code string) (*models.RedeemableVoucher, error)
+}
+
+type TaxClassRepository interface {
+	GetAll(filter models.TaxClassFilter) ([]models.TaxClass, int, error)
+	GetRates() (map[int]int, error)
+	Create(class *models.TaxClass) error
+	GetByID(id int) (*models.TaxClass, error)
+	Update(class *models.TaxClass) error
+	Delete(id int) error
+}
+
+type UserRepository interface {
+	GetAll(filter models.UserFilter) ([]models.User, int, error)
+	Create(user *models.User) error
+	GetByID(id int) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	Update(user *models.User) error
+	RecordPINFailure(id int) (int, error)
+	ResetPINFailures(id int) error
+	LockPIN(id int, until time.Time) error
+}
+
+type TerminalRepository interface {
+	GetAll(filter models.TerminalFilter) ([]models.Terminal, int, error)
+	Create(terminal *models.Terminal) error
+	GetByID(id int) (*models.Terminal, error)
+	GetByKeyHash(keyHash string) (*models.Terminal, error)
+	Update(terminal *models.Terminal) error
+}
+
+type ShiftRepository interface {
+	GetAll(filter models.ShiftFilter) ([]models.Shift, int, error)
+	GetByID(id int) (*models.Shift, error)
+	GetOpen(terminalID int) (*models.Shift, error)
+	Open(shift *models.Shift) error
+	AddCashMovement(shiftID int, m *models.CashMovement) error
+	Close(id int, counts []models.CashCount, note, closedBy string,
+		summarize func(*models.Shift, *models.ShiftSales) models.ShiftReport) (*models.Shift, error)
+	GetSales(id int) (*models.ShiftSales, error)
+}
+
+type ZReportRepository interface {
+	GetAll(filter models.ZReportFilter) ([]models.ShiftReport, int, error)
+	GetByNumber(number int) (*models.ShiftReport, error)
+	GetByShift(shiftID int) (*models.ShiftReport, error)
+}
+
+type IdempotencyRepository interface {
+	Reserve(key string, fingerprint string, expiresAt time.Time) (*models.IdempotencyKey, bool, error)
+	Complete(key string, transactionID int, statusCode int, responseBody []byte) error
+	Release(key string) error
+	DeleteExpired() (int64, error)
+}
+
+var (
+	_ ProductRepository       = (*repositories.ProductRepository)(nil)
+	_ CategoryRepository      = (*repositories.CategoryRepository)(nil)
+	_ TransactionRepository   = (*repositories.TransactionRepository)(nil)
+	_ InventoryRepository     = (*repositories.InventoryRepository)(nil)
+	_ SupplierRepository      = (*repositories.SupplierRepository)(nil)
+	_ PurchaseOrderRepository = (*repositories.PurchaseOrderRepository)(nil)
+	_ PromotionRepository     = (*repositories.PromotionRepository)(nil)
+	_ VoucherRepository       = (*repositories.VoucherRepository)(nil)
+	_ TaxClassRepository      = (*repositories.TaxClassRepository)(nil)
+	_ UserRepository          = (*repositories.UserRepository)(nil)
+	_ TerminalRepository      = (*repositories.TerminalRepository)(nil)
+	_ ShiftRepository         = (*repositories.ShiftRepository)(nil)
+	_ ZReportRepository       = (*repositories.ZReportRepository)(nil)
+	_ IdempotencyRepository   = (*repositories.IdempotencyRepository)(nil)
+)
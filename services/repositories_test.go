@@ -0,0 +1,23 @@
+package services
+
+import "simple-cashier-api/repositories/memory"
+
+// The in-memory backend implements the same interfaces as the PostgreSQL
+// repositories. The check lives in a test so the services package does not
+// depend on it.
+var (
+	_ ProductRepository       = (*memory.ProductRepository)(nil)
+	_ CategoryRepository      = (*memory.CategoryRepository)(nil)
+	_ TransactionRepository   = (*memory.TransactionRepository)(nil)
+	_ InventoryRepository     = (*memory.InventoryRepository)(nil)
+	_ SupplierRepository      = (*memory.SupplierRepository)(nil)
+	_ PurchaseOrderRepository = (*memory.PurchaseOrderRepository)(nil)
+	_ PromotionRepository     = (*memory.PromotionRepository)(nil)
+	_ VoucherRepository       = (*memory.VoucherRepository)(nil)
+	_ TaxClassRepository      = (*memory.TaxClassRepository)(nil)
+	_ UserRepository          = (*memory.UserRepository)(nil)
+	_ TerminalRepository      = (*memory.TerminalRepository)(nil)
+	_ ShiftRepository         = (*memory.ShiftRepository)(nil)
+	_ ZReportRepository       = (*memory.ZReportRepository)(nil)
+	_ IdempotencyRepository   = (*memory.IdempotencyRepository)(nil)
+)
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+// ShiftReportService makes X reports of open shifts and the Z reports of
+// closed ones, and prints them like receipts.
+type ShiftReportService struct {
+	shifts   ShiftRepository
+	zReports ZReportRepository
+	settings models.ReceiptSettings
+}
+
+func NewShiftReportService(shifts ShiftRepository, zReports ZReportRepository, settings models.ReceiptSettings) *ShiftReportService {
+	if settings.Width == 0 {
+		settings.Width = DefaultReceiptWidth
+	}
+	return &ShiftReportService{shifts: shifts, zReports: zReports, settings: settings}
+}
+
+// ForShift returns an X report of the shift while it is open, and its Z
+// report once it is closed. Only shifts of by's terminal are reported on;
+// other terminals' Z reports are for GetZReport, which needs shift:read.
+func (s *ShiftReportService) ForShift(shiftID int, by models.Actor) (*models.ShiftReport, error) {
+	if by.TerminalID == nil {
+		return nil, ErrNotAtTerminal
+	}
+	shift, err := s.shifts.GetByID(shiftID)
+	if err != nil {
+		return nil, err
+	}
+	if err := atTerminal(shift, by); err != nil {
+		return nil, err
+	}
+	if shift.Status == models.ShiftStatusOpen {
+		return s.build(shift)
+	}
+	return s.zReports.GetByShift(shift.ID)
+}
+
+func (s *ShiftReportService) GetZReports(filter models.ZReportFilter) (*models.Page[models.ShiftReport], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	reports, total, err := s.zReports.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(reports, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *ShiftReportService) GetZReport(number int) (*models.ShiftReport, error) {
+	return s.zReports.GetByNumber(number)
+}
+
+func (s *ShiftReportService) build(shift *models.Shift) (*models.ShiftReport, error) {
+	sales, err := s.shifts.GetSales(shift.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := summarizeShift(shift, sales)
+	return &report, nil
+}
+
+// summarizeShift makes the report of a shift from its sales.
+func summarizeShift(shift *models.Shift, sales *models.ShiftSales) models.ShiftReport {
+	report := models.ShiftReport{
+		Type:         models.ShiftReportX,
+		ShiftID:      shift.ID,
+		TerminalID:   shift.TerminalID,
+		Cashier:      shift.Cashier,
+		OpenedAt:     shift.OpenedAt,
+		ClosedAt:     shift.ClosedAt,
+		OpeningFloat: shift.OpeningFloat,
+		ExpectedCash: shift.ExpectedCash,
+		CountedCash:  shift.CountedCash,
+		Variance:     shift.Variance,
+		CreatedAt:    time.Now(),
+	}
+	if shift.Status == models.ShiftStatusClosed {
+		report.Type = models.ShiftReportZ
+	}
+
+	report.TransactionCount = sales.Transactions
+	report.GrossSales = sales.Subtotal
+	report.Discounts = sales.Discounts
+	report.Voids, report.VoidCount = sales.Voids, sales.VoidCount
+	report.Refunds, report.RefundCount = sales.Refunds, sales.RefundCount
+	report.ServiceCharge = sales.ServiceCharge - sales.GivenBackServiceCharge
+	report.Tax = sales.Tax - sales.GivenBackTax
+	report.NetSales = sales.Total - report.Voids - report.Refunds - report.ServiceCharge - report.Tax
+	if sales.Transactions > 0 {
+		report.AverageBasket = (sales.KeptTotal + sales.Transactions/2) / sales.Transactions
+	}
+	if sales.FirstTransactionID > 0 {
+		report.FirstReceipt = ReceiptNumber(sales.FirstTransactionID)
+		report.LastReceipt = ReceiptNumber(sales.LastTransactionID)
+	}
+
+	report.Payments = make([]models.PaymentTotal, 0, len(sales.Payments))
+	for _, method := range models.PaymentMethods {
+		for _, p := range sales.Payments {
+			if p.Method == method {
+				report.Payments = append(report.Payments, p)
+			}
+		}
+	}
+	return report
+}
+
+// ShiftReportFilename names a printed report: z-report-000012 for a Z report,
+// x-report-shift-3 for an X report.
+func ShiftReportFilename(report *models.ShiftReport) string {
+	if report.Type == models.ShiftReportZ {
+		return fmt.Sprintf("z-report-%06d", report.Number)
+	}
+	return fmt.Sprintf("x-report-shift-%d", report.ShiftID)
+}
+
+// Render prints report in format, width characters wide or the configured
+// width when it is 0.
+func (s *ShiftReportService) Render(report *models.ShiftReport, format string, width int) (*models.Receipt, error) {
+	if width == 0 {
+		width = s.settings.Width
+	}
+	return renderReceipt(layoutShiftReport(report, s.settings, width), format, width)
+}
+
+// layoutShiftReport lays out the sales, then the transactions and receipts,
+// the payment methods and the cash drawer.
+func layoutShiftReport(report *models.ShiftReport, settings models.ReceiptSettings, width int) []receiptLine {
+	lines := layoutStoreHeader(settings, width)
+	add := func(l receiptLine) { lines = append(lines, l) }
+	row := func(left string, amount int) { add(receiptLine{Left: left, Right: formatRupiah(amount)}) }
+	rule := func() { add(receiptLine{Rule: true}) }
+	date := func(t time.Time) string { return t.Local().Format("02/01/2006 15:04") }
+
+	rule()
+	if report.Type == models.ShiftReportZ {
+		add(receiptLine{Left: "Z REPORT", Center: true, Bold: true})
+		add(receiptLine{Left: "No", Right: fmt.Sprintf("%06d", report.Number)})
+	} else {
+		add(receiptLine{Left: "X REPORT", Center: true, Bold: true})
+	}
+	add(receiptLine{Left: "Shift", Right: fmt.Sprint(report.ShiftID)})
+	add(receiptLine{Left: "Terminal", Right: fmt.Sprint(report.TerminalID)})
+	if report.Cashier != "" {
+		add(receiptLine{Left: "Cashier", Right: report.Cashier})
+	}
+	add(receiptLine{Left: "Opened", Right: date(report.OpenedAt)})
+	if report.ClosedAt != nil {
+		add(receiptLine{Left: "Closed", Right: date(*report.ClosedAt)})
+	}
+	add(receiptLine{Left: "Printed", Right: date(time.Now())})
+	rule()
+
+	row("Gross sales", report.GrossSales)
+	row("Discounts", -report.Discounts)
+	row(fmt.Sprintf("Voids (%d)", report.VoidCount), -report.Voids)
+	row(fmt.Sprintf("Refunds (%d)", report.RefundCount), -report.Refunds)
+	if report.ServiceCharge != 0 {
+		row("Service charge", report.ServiceCharge)
+	}
+	row("PPN", report.Tax)
+	add(receiptLine{Left: "NET SALES", Right: formatRupiah(report.NetSales), Bold: true})
+	rule()
+
+	add(receiptLine{Left: "Transactions", Right: fmt.Sprint(report.TransactionCount)})
+	row("Average basket", report.AverageBasket)
+	if report.FirstReceipt != "" {
+		add(receiptLine{Left: "First receipt", Right: report.FirstReceipt})
+		add(receiptLine{Left: "Last receipt", Right: report.LastReceipt})
+	}
+	rule()
+
+	for _, p := range report.Payments {
+		label, ok := paymentLabels[p.Method]
+		if !ok {
+			label = p.Method
+		}
+		row(fmt.Sprintf("%s (%d)", label, p.Transactions), p.Amount)
+	}
+	if len(report.Payments) > 0 {
+		rule()
+	}
+
+	row("Opening float", report.OpeningFloat)
+	row("Expected cash", report.ExpectedCash)
+	if report.CountedCash != nil {
+		row("Counted cash", *report.CountedCash)
+	}
+	if report.Variance != nil {
+		add(receiptLine{Left: "VARIANCE", Right: formatRupiah(*report.Variance), Bold: true})
+	}
+	return lines
+}
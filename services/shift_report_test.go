@@ -0,0 +1,69 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+func TestSummarizeShift(t *testing.T) {
+	closedAt := time.Date(2026, 2, 8, 15, 0, 0, 0, time.UTC)
+	counted, variance := 240000, -1000
+	shift := &models.Shift{
+		ID: 3, TerminalID: 1, Cashier: "siti", Status: models.ShiftStatusClosed,
+		OpeningFloat: 200000, ExpectedCash: 241000, CountedCash: &counted, Variance: &variance,
+		OpenedAt: closedAt.Add(-8 * time.Hour), ClosedAt: &closedAt,
+	}
+	// Three sales: 20000 less 2000 off, 10000 and 3000, each plus 11% PPN.
+	// One of them, 3330, was voided, and a refund of 5550 was given by QRIS.
+	sales := &models.ShiftSales{
+		Transactions: 2, KeptTotal: 31080, FirstTransactionID: 10, LastTransactionID: 12,
+		Subtotal: 33000, Discounts: 2000, Tax: 3410, Total: 34410,
+		Payments: []models.PaymentTotal{
+			{Method: models.PaymentMethodQRIS, Transactions: 1, Amount: 550},
+			{Method: models.PaymentMethodCash, Transactions: 1, Amount: 24980},
+		},
+		Refunds: 5550, RefundCount: 1, Voids: 3330, VoidCount: 1, GivenBackTax: 880,
+	}
+
+	r := summarizeShift(shift, sales)
+	if r.Type != models.ShiftReportZ || r.GrossSales != 33000 || r.Discounts != 2000 || r.Tax != 2530 {
+		t.Errorf("gross %d, discounts %d, tax %d; want 33000, 2000, 2530", r.GrossSales, r.Discounts, r.Tax)
+	}
+	if r.Refunds != 5550 || r.RefundCount != 1 || r.Voids != 3330 || r.VoidCount != 1 {
+		t.Errorf("refunds %d (%d), voids %d (%d)", r.Refunds, r.RefundCount, r.Voids, r.VoidCount)
+	}
+	// 34410 charged, less the 3330 voided, the 5550 refunded and the 2530 of
+	// tax kept. The voided sale is left out of the count and the basket.
+	if r.NetSales != 23000 || r.TransactionCount != 2 || r.AverageBasket != 15540 {
+		t.Errorf("net %d, count %d, average %d; want 23000, 2, 15540", r.NetSales, r.TransactionCount, r.AverageBasket)
+	}
+	if r.FirstReceipt != "000010" || r.LastReceipt != "000012" {
+		t.Errorf("receipts %s to %s, want 000010 to 000012", r.FirstReceipt, r.LastReceipt)
+	}
+	want := []models.PaymentTotal{
+		{Method: models.PaymentMethodCash, Transactions: 1, Amount: 24980},
+		{Method: models.PaymentMethodQRIS, Transactions: 1, Amount: 550},
+	}
+	if len(r.Payments) != len(want) || r.Payments[0] != want[0] || r.Payments[1] != want[1] {
+		t.Errorf("payments = %+v, want %+v", r.Payments, want)
+	}
+
+	r.Number = 12
+	text := renderReceiptText(layoutShiftReport(&r, models.ReceiptSettings{StoreName: "Toko Maju"}, 32), 32)
+	for _, line := range []string{
+		"            Z REPORT",
+		"No                        000012",
+		"Voids (1)                 -3.330",
+		"Refunds (1)               -5.550",
+		"NET SALES                 23.000",
+		"Cash (1)                  24.980",
+		"VARIANCE                  -1.000",
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("report is missing %q:\n%s", line, text)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+const MaxCashMovementReasonLength = 255
+
+var (
+	ErrNotAtTerminal  = repositories.Errorf(repositories.ErrConflict, "sign in at a terminal with a PIN first")
+	ErrNoCurrentShift = repositories.Errorf(repositories.ErrNotFound, "no shift is open at this terminal")
+	ErrOtherTerminal  = repositories.Errorf(repositories.ErrForbidden, "shift belongs to another terminal")
+)
+
+// ShiftService runs the cash drawer sessions of the terminals. Shifts are
+// opened at the terminal the user signed in at with a PIN.
+type ShiftService struct {
+	repo ShiftRepository
+}
+
+func NewShiftService(repo ShiftRepository) *ShiftService {
+	return &ShiftService{repo: repo}
+}
+
+// Open starts a shift for by at their terminal with the float counted into
+// the drawer.
+func (s *ShiftService) Open(req models.OpenShiftRequest, by models.Actor) (*models.Shift, error) {
+	if by.TerminalID == nil {
+		return nil, ErrNotAtTerminal
+	}
+
+	var v validator
+	v.check(req.OpeningFloat >= 0, "opening_float", RuleMin, "must not be negative")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	shift := models.Shift{
+		TerminalID:   *by.TerminalID,
+		UserID:       by.UserID(),
+		Cashier:      by.Username(),
+		OpeningFloat: req.OpeningFloat,
+	}
+	if err := s.repo.Open(&shift); err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// Current returns the open shift of by's terminal.
+func (s *ShiftService) Current(by models.Actor) (*models.Shift, error) {
+	if by.TerminalID == nil {
+		return nil, ErrNotAtTerminal
+	}
+
+	shift, err := s.repo.GetOpen(*by.TerminalID)
+	if errors.Is(err, repositories.ErrNoOpenShift) {
+		return nil, ErrNoCurrentShift
+	}
+	return shift, err
+}
+
+func (s *ShiftService) GetAll(filter models.ShiftFilter) (*models.Page[models.Shift], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	shifts, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(shifts, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *ShiftService) GetByID(id int) (*models.Shift, error) {
+	return s.repo.GetByID(id)
+}
+
+// AddCashMovement records cash put into or taken out of the drawer outside
+// a sale, such as petty cash or a bank drop. It needs a reason, and the
+// shift must be at by's terminal.
+func (s *ShiftService) AddCashMovement(shiftID int, req models.CashMovementRequest, by models.Actor) (*models.CashMovement, error) {
+	if err := s.checkTerminal(shiftID, by); err != nil {
+		return nil, err
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	var v validator
+	v.check(slices.Contains(models.CashMovementTypes, req.Type), "type", RuleOneOf,
+		"must be one of %s", strings.Join(models.CashMovementTypes, ", "))
+	v.check(req.Amount > 0, "amount", RuleMin, "must be greater than zero")
+	v.check(req.Reason != "", "reason", RuleRequired, "must not be empty")
+	v.checkMaxLen(req.Reason, "reason", MaxCashMovementReasonLength)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	movement := models.CashMovement{
+		Type:   req.Type,
+		Amount: req.Amount,
+		Reason: req.Reason,
+		UserID: by.UserID(),
+		Actor:  by.Username(),
+	}
+	if err := s.repo.AddCashMovement(shiftID, &movement); err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// Close ends a shift with the drawer counted by denomination, and returns it
+// with the variance of the count against the expected cash. Its Z report is
+// stored with the close, so a closed shift always has one. Only a shift at
+// by's terminal can be closed.
+func (s *ShiftService) Close(id int, req models.CloseShiftRequest, by models.Actor) (*models.Shift, error) {
+	if err := s.checkTerminal(id, by); err != nil {
+		return nil, err
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+
+	var v validator
+	seen := map[int]bool{}
+	for i, c := range req.CashCounts {
+		field := fmt.Sprintf("cash_counts[%d]", i)
+		v.check(slices.Contains(models.Denominations, c.Denomination), field+".denomination", RuleOneOf,
+			"must be a rupiah note or coin: %s", formatDenominations())
+		v.check(!seen[c.Denomination], field+".denomination", RuleUnique, "is listed more than once")
+		v.check(c.Count >= 0, field+".count", RuleMin, "must not be negative")
+		seen[c.Denomination] = true
+	}
+	v.checkMaxLen(req.Note, "note", MaxNotesLength)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Close(id, req.CashCounts, req.Note, by.Username(), summarizeShift)
+}
+
+// checkTerminal makes sure shift id was opened at by's terminal, so nobody
+// moves cash in or out of another till's drawer.
+func (s *ShiftService) checkTerminal(id int, by models.Actor) error {
+	if by.TerminalID == nil {
+		return ErrNotAtTerminal
+	}
+	shift, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	return atTerminal(shift, by)
+}
+
+// atTerminal fails with ErrOtherTerminal unless shift is at by's terminal.
+func atTerminal(shift *models.Shift, by models.Actor) error {
+	if by.TerminalID == nil {
+		return ErrNotAtTerminal
+	}
+	if shift.TerminalID != *by.TerminalID {
+		return ErrOtherTerminal
+	}
+	return nil
+}
+
+func formatDenominations() string {
+	parts := make([]string, len(models.Denominations))
+	for i, d := range models.Denominations {
+		parts[i] = fmt.Sprint(d)
+	}
+	return strings.Join(parts, ", ")
+}
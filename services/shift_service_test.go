@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+	"simple-cashier-api/repositories/memory"
+)
+
+func TestShiftCloseComputesVariance(t *testing.T) {
+	store := memory.NewStore()
+	product := models.Product{Name: "Indomie Goreng", Price: 3500, Stock: 10}
+	if err := memory.NewProductRepository(store).Create(&product); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	terminal := models.Terminal{Code: "KASIR-1", Name: "Kasir 1", Active: true, KeyHash: "test"}
+	if err := memory.NewTerminalRepository(store).Create(&terminal); err != nil {
+		t.Fatalf("create terminal: %v", err)
+	}
+	reports := NewShiftReportService(memory.NewShiftRepository(store), memory.NewZReportRepository(store), models.ReceiptSettings{})
+	shifts := NewShiftService(memory.NewShiftRepository(store))
+	checkout := NewTransactionService(memory.NewTransactionRepository(store), memory.NewProductRepository(store), memory.NewPromotionRepository(store),
+		memory.NewTaxClassRepository(store), models.TaxSettings{}, 0)
+	cashier := models.Actor{User: &models.User{ID: 1, Username: "kasir", Role: models.RoleCashier}}
+
+	if _, err := shifts.Open(models.OpenShiftRequest{OpeningFloat: 200000}, cashier); !errors.Is(err, ErrNotAtTerminal) {
+		t.Fatalf("open without a terminal: got %v, want ErrNotAtTerminal", err)
+	}
+	cashier.TerminalID = &terminal.ID
+
+	shift, err := shifts.Open(models.OpenShiftRequest{OpeningFloat: 200000}, cashier)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := shifts.Open(models.OpenShiftRequest{}, cashier); !errors.Is(err, repositories.ErrShiftAlreadyOpen) {
+		t.Errorf("second open: got %v, want ErrShiftAlreadyOpen", err)
+	}
+
+	// 7000 in sales paid with 10000 leaves 7000 of cash in the drawer.
+	_, err = checkout.Checkout(models.CheckoutRequest{
+		Items:    []models.CheckoutItem{{ProductID: product.ID, Quantity: 2}},
+		Payments: []models.CheckoutPayment{{Method: models.PaymentMethodCash, Amount: 10000}},
+	}, cashier, nil)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if _, err := shifts.AddCashMovement(shift.ID, models.CashMovementRequest{Type: models.CashMovementOut, Amount: 50000, Reason: "Setor bank"}, cashier); err != nil {
+		t.Fatalf("cash out: %v", err)
+	}
+
+	_, err = shifts.Close(shift.ID, models.CloseShiftRequest{CashCounts: []models.CashCount{{Denomination: 75000, Count: 1}}}, cashier)
+	var validationErr *repositories.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "cash_counts[0].denomination" {
+		t.Fatalf("unknown denomination: got %v", err)
+	}
+
+	closed, err := shifts.Close(shift.ID, models.CloseShiftRequest{CashCounts: []models.CashCount{
+		{Denomination: 100000, Count: 1},
+		{Denomination: 50000, Count: 1},
+		{Denomination: 5000, Count: 1},
+		{Denomination: 1000, Count: 1},
+	}}, cashier)
+	if err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if closed.ExpectedCash != 157000 || *closed.CountedCash != 156000 || *closed.Variance != -1000 {
+		t.Errorf("expected %d, counted %d, variance %d; want 157000, 156000, -1000",
+			closed.ExpectedCash, *closed.CountedCash, *closed.Variance)
+	}
+	if closed.CashCounts[0].Amount != 100000 || closed.TransactionCount != 1 || closed.CashSales != 7000 {
+		t.Errorf("unexpected closed shift %+v", closed)
+	}
+
+	if z, err := reports.ForShift(shift.ID, cashier); err != nil || z.Type != models.ShiftReportZ || z.Number != 1 || z.NetSales != 7000 {
+		t.Errorf("z report = %+v, %v", z, err)
+	}
+
+	if _, err := shifts.AddCashMovement(shift.ID, models.CashMovementRequest{Type: models.CashMovementIn, Amount: 1000, Reason: "Kas kecil"}, cashier); !errors.Is(err, repositories.ErrShiftClosed) {
+		t.Errorf("movement on a closed shift: got %v, want ErrShiftClosed", err)
+	}
+	if _, err := shifts.Current(cashier); !errors.Is(err, ErrNoCurrentShift) {
+		t.Errorf("current after close: got %v, want ErrNoCurrentShift", err)
+	}
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"simple-cashier-api/models"
+)
+
+type SupplierService struct {
+	repo SupplierRepository
+}
+
+func NewSupplierService(repo SupplierRepository) *SupplierService {
+	return &SupplierService{repo: repo}
+}
+
+func (s *SupplierService) GetAll(filter models.SupplierFilter) (*models.Page[models.Supplier], error) {
+	var v validator
+	v.checkSort(filter.Sort, SupplierSortFields)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	suppliers, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(suppliers, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *SupplierService) Create(supplier *models.Supplier) error {
+	if err := validateSupplier(supplier); err != nil {
+		return err
+	}
+	return s.repo.Create(supplier)
+}
+
+func (s *SupplierService) GetByID(id int) (*models.Supplier, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *SupplierService) Update(supplier *models.Supplier) error {
+	if err := validateSupplier(supplier); err != nil {
+		return err
+	}
+	return s.repo.Update(supplier)
+}
+
+func (s *SupplierService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
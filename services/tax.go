@@ -0,0 +1,108 @@
+package services
+
+import (
+	"slices"
+
+	"simple-cashier-api/models"
+)
+
+// applyTaxes adds the service charge and tax to the checkout draft trx, after
+// its discounts. Each line is taxed at its tax class's rate, or at the
+// default rate when it has none or the class is unknown.
+//
+// Rounding happens once per tax rate and once for the service charge, half up
+// to the rupiah, and each rounded amount is then spread over the lines like a
+// cart discount. That keeps the receipt's totals equal to the sum of its lines
+// without a rounding error per line.
+//
+// With inclusive pricing a line's tax is carved out of its subtotal as
+// subtotal * rate / (1 + rate); otherwise it is added on top. The service
+// charge is a share of what the lines cost before tax and is itself taxed at
+// each line's rate.
+func applyTaxes(trx *models.Transaction, settings models.TaxSettings, rates map[int]int) {
+	details := trx.Details
+	groups := map[int][]int{}
+	for i := range details {
+		rate := settings.TaxRateBP
+		if id := details[i].TaxClassID; id != nil {
+			if classRate, ok := rates[*id]; ok {
+				rate = classRate
+			}
+		}
+		details[i].TaxRateBP = rate
+		details[i].ServiceCharge = 0
+		details[i].TaxAmount = 0
+		groups[rate] = append(groups[rate], i)
+	}
+	order := make([]int, 0, len(groups))
+	for rate := range groups {
+		order = append(order, rate)
+	}
+	slices.Sort(order)
+
+	net := make([]int, len(details))
+	for i, d := range details {
+		net[i] = d.Subtotal
+	}
+	if settings.PricesIncludeTax {
+		for _, rate := range order {
+			lines := groups[rate]
+			weights := make([]int, len(lines))
+			gross := 0
+			for j, i := range lines {
+				weights[j] = details[i].Subtotal
+				gross += details[i].Subtotal
+			}
+			tax := divideHalfUp(gross*rate, 10000+rate)
+			for j, share := range allocate(tax, weights) {
+				details[lines[j]].TaxAmount = share
+				net[lines[j]] -= share
+			}
+		}
+	}
+
+	totalNet := 0
+	for _, n := range net {
+		totalNet += n
+	}
+	service := divideHalfUp(totalNet*settings.ServiceChargeBP, 10000)
+	for i, share := range allocate(service, net) {
+		details[i].ServiceCharge = share
+	}
+
+	added := make([]int, len(details))
+	for _, rate := range order {
+		lines := groups[rate]
+		weights := make([]int, len(lines))
+		base := 0
+		for j, i := range lines {
+			weights[j] = details[i].ServiceCharge
+			if !settings.PricesIncludeTax {
+				weights[j] += net[i]
+			}
+			base += weights[j]
+		}
+		tax := divideHalfUp(base*rate, 10000)
+		for j, share := range allocate(tax, weights) {
+			details[lines[j]].TaxAmount += share
+			added[lines[j]] = share
+		}
+	}
+
+	trx.PricesIncludeTax = settings.PricesIncludeTax
+	trx.ServiceCharge = 0
+	trx.TaxAmount = 0
+	trx.TotalAmount = 0
+	for i := range details {
+		d := &details[i]
+		d.Total = d.Subtotal + d.ServiceCharge + added[i]
+		trx.ServiceCharge += d.ServiceCharge
+		trx.TaxAmount += d.TaxAmount
+		trx.TotalAmount += d.Total
+	}
+}
+
+// divideHalfUp divides non-negative a by b, rounding half up.
+func divideHalfUp(a, b int) int {
+	return (2*a + b) / (2 * b)
+}
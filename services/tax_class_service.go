@@ -0,0 +1,47 @@
+package services
+
+import (
+	"simple-cashier-api/models"
+)
+
+type TaxClassService struct {
+	repo TaxClassRepository
+}
+
+func NewTaxClassService(repo TaxClassRepository) *TaxClassService {
+	return &TaxClassService{repo: repo}
+}
+
+func (s *TaxClassService) GetAll(filter models.TaxClassFilter) (*models.Page[models.TaxClass], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	classes, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(classes, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *TaxClassService) Create(class *models.TaxClass) error {
+	if err := validateTaxClass(class); err != nil {
+		return err
+	}
+	return s.repo.Create(class)
+}
+
+func (s *TaxClassService) GetByID(id int) (*models.TaxClass, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *TaxClassService) Update(class *models.TaxClass) error {
+	if err := validateTaxClass(class); err != nil {
+		return err
+	}
+	return s.repo.Update(class)
+}
+
+func (s *TaxClassService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"simple-cashier-api/models"
+)
+
+func TestApplyTaxes(t *testing.T) {
+	exempt := 5
+	rates := map[int]int{exempt: 0}
+	withExempt := func(trx *models.Transaction) *models.Transaction {
+		trx.Details[1].TaxClassID = &exempt
+		return trx
+	}
+
+	tests := []struct {
+		name     string
+		trx      *models.Transaction
+		settings models.TaxSettings
+		services []int
+		taxes    []int
+		total    int
+	}{
+		{
+			name:     "tax on top",
+			trx:      cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			settings: models.TaxSettings{TaxRateBP: 1100},
+			services: []int{0, 0},
+			taxes:    []int{770, 220},
+			total:    9990,
+		},
+		{
+			name:     "exempt class",
+			trx:      withExempt(cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1})),
+			settings: models.TaxSettings{TaxRateBP: 1100},
+			services: []int{0, 0},
+			taxes:    []int{770, 0},
+			total:    9770,
+		},
+		{
+			name:     "tax rounds half up",
+			trx:      cart([3]int{3, 50, 1}),
+			settings: models.TaxSettings{TaxRateBP: 1100},
+			services: []int{0},
+			taxes:    []int{6},
+			total:    56,
+		},
+		{
+			name:     "tax included in prices",
+			trx:      cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			settings: models.TaxSettings{TaxRateBP: 1100, PricesIncludeTax: true},
+			services: []int{0, 0},
+			taxes:    []int{694, 198},
+			total:    9000,
+		},
+		{
+			name:     "service charge is taxed",
+			trx:      cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			settings: models.TaxSettings{TaxRateBP: 1100, ServiceChargeBP: 1000},
+			services: []int{700, 200},
+			taxes:    []int{847, 242},
+			total:    10989,
+		},
+		{
+			name:     "service charge on net of included tax",
+			trx:      cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			settings: models.TaxSettings{TaxRateBP: 1100, ServiceChargeBP: 500, PricesIncludeTax: true},
+			services: []int{315, 90},
+			taxes:    []int{729, 208},
+			total:    9450,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyTaxes(tt.trx, tt.settings, rates)
+
+			services := make([]int, len(tt.trx.Details))
+			taxes := make([]int, len(tt.trx.Details))
+			sum := 0
+			for i, d := range tt.trx.Details {
+				services[i] = d.ServiceCharge
+				taxes[i] = d.TaxAmount
+				sum += d.Total
+			}
+			if !slices.Equal(services, tt.services) || !slices.Equal(taxes, tt.taxes) {
+				t.Errorf("service %v tax %v, want %v %v", services, taxes, tt.services, tt.taxes)
+			}
+			if tt.trx.TotalAmount != tt.total || sum != tt.total {
+				t.Errorf("total = %d (lines %d), want %d", tt.trx.TotalAmount, sum, tt.total)
+			}
+		})
+	}
+}
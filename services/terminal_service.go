@@ -0,0 +1,68 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"simple-cashier-api/models"
+)
+
+// terminalKeyBytes of randomness make a key that can be stored as a plain
+// SHA-256 hash: unlike a PIN, it is far too long to guess.
+const terminalKeyBytes = 32
+
+type TerminalService struct {
+	repo TerminalRepository
+}
+
+func NewTerminalService(repo TerminalRepository) *TerminalService {
+	return &TerminalService{repo: repo}
+}
+
+func (s *TerminalService) GetAll(filter models.TerminalFilter) (*models.Page[models.Terminal], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	terminals, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(terminals, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+// Create registers a terminal with a new key, which is returned in
+// terminal.Key this once.
+func (s *TerminalService) Create(terminal *models.Terminal) error {
+	if err := validateTerminal(terminal); err != nil {
+		return err
+	}
+
+	b := make([]byte, terminalKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	terminal.Key = hex.EncodeToString(b)
+	terminal.KeyHash = hashTerminalKey(terminal.Key)
+	return s.repo.Create(terminal)
+}
+
+func (s *TerminalService) GetByID(id int) (*models.Terminal, error) {
+	return s.repo.GetByID(id)
+}
+
+// Update changes the code, name and active flag. Deactivating a terminal
+// ends the sessions of everyone signed in at it.
+func (s *TerminalService) Update(terminal *models.Terminal) error {
+	if err := validateTerminal(terminal); err != nil {
+		return err
+	}
+	terminal.Key = ""
+	return s.repo.Update(terminal)
+}
+
+func hashTerminalKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
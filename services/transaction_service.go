@@ -1,22 +1,154 @@
 package services
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"simple-cashier-api/models"
 	"simple-cashier-api/repositories"
 )
 
+// TransactionService rings up checkouts, voids and refunds them, and reports
+// on the sales.
 type TransactionService struct {
-	repo *repositories.TransactionRepository
+	repo       TransactionRepository
+	products   ProductRepository
+	promotions PromotionRepository
+	taxClasses TaxClassRepository
+	taxes      models.TaxSettings
+	// discountApprovalPercent is the largest manual discount a checkout may
+	// give without transaction:discount.
+	discountApprovalPercent int
 }
 
-func NewTransactionService(repo *repositories.TransactionRepository) *TransactionService {
-	return &TransactionService{repo: repo}
+var (
+	ErrInvalidQuantity = repositories.Errorf(repositories.ErrValidation, "quantity must be greater than zero")
+	// ErrRefundNotAtTerminal refuses voids and refunds after a password
+	// login: they are paid out of the drawer of a shift, which only a PIN
+	// login at a terminal has.
+	ErrRefundNotAtTerminal = repositories.Errorf(repositories.ErrConflict,
+		"voids and refunds are paid out of a shift's drawer: sign in at a terminal with a PIN first")
+)
+
+func NewTransactionService(repo TransactionRepository, products ProductRepository, promotions PromotionRepository,
+	taxClasses TaxClassRepository, taxes models.TaxSettings, discountApprovalPercent int) *TransactionService {
+	return &TransactionService{repo: repo, products: products, promotions: promotions, taxClasses: taxClasses, taxes: taxes,
+		discountApprovalPercent: discountApprovalPercent}
 }
 
-func (s *TransactionService) Checkout(items []models.CheckoutItem) (*models.Transaction, error) {
-	return s.repo.CreateTransaction(items)
+// CheckoutPermissions lists what req needs beyond transaction:create: price
+// overrides need transaction:price_override, and a manual discount above the
+// approval limit transaction:discount.
+func (s *TransactionService) CheckoutPermissions(req models.CheckoutRequest) []string {
+	permissions := make([]string, 0)
+	if slices.ContainsFunc(req.Items, func(item models.CheckoutItem) bool { return item.UnitPrice != nil }) {
+		permissions = append(permissions, models.PermTransactionPriceOverride)
+	}
+	if req.DiscountPercent > s.discountApprovalPercent {
+		permissions = append(permissions, models.PermTransactionDiscount)
+	}
+	return permissions
+}
+
+// Checkout prices the cart at its locked prices, or the overridden ones,
+// applies the promotions active now, then the voucher code if one was given
+// and the manual discount, adds the service charge and tax, and settles the
+// payments against the grand total. The code is locked until the checkout
+// commits, so its usage limits hold under concurrent checkouts. The
+// transaction is recorded as rung up by by, who must already hold what
+// CheckoutPermissions asks for or have it approved, in the open shift of
+// their terminal. A non-nil claim is the idempotency key the checkout is
+// recorded on.
+func (s *TransactionService) Checkout(req models.CheckoutRequest, by models.Actor, claim *models.IdempotencyClaim) (*models.Transaction, error) {
+	if by.TerminalID == nil {
+		return nil, ErrNotAtTerminal
+	}
+	items, err := normalizeCheckoutItems(req.Items, s.products)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePayments(req.Payments); err != nil {
+		return nil, err
+	}
+	if err := validateDiscountPercent(req.DiscountPercent); err != nil {
+		return nil, err
+	}
+	code, err := normalizeVoucherCode(req.VoucherCode)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	promotions, err := s.promotions.GetActive(now)
+	if err != nil {
+		return nil, err
+	}
+	rates, err := s.taxClasses.GetRates()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateTransaction(items, code, claim, func(trx *models.Transaction, voucher *models.RedeemableVoucher) error {
+		trx.UserID = by.UserID()
+		trx.Cashier = by.Username()
+		trx.TerminalID = by.TerminalID
+		trx.ApproverID = by.ApproverID()
+		trx.ApprovedBy = by.ApprovedBy()
+		applyPriceOverrides(trx, items)
+		applyPromotions(trx, promotions)
+		if voucher != nil {
+			if err := applyVoucher(trx, voucher, now); err != nil {
+				return err
+			}
+		}
+		applyManualDiscount(trx, req.DiscountPercent)
+		applyTaxes(trx, s.taxes, rates)
+		return settlePayments(trx, req.Payments)
+	})
+}
+
+func (s *TransactionService) GetTransactions(filter models.TransactionFilter) (*models.Page[models.Transaction], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	transactions, total, err := s.repo.GetTransactions(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(transactions, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *TransactionService) GetTransactionByID(id int) (*models.Transaction, error) {
+	return s.repo.GetTransactionByID(id)
+}
+
+// VoidTransaction voids transaction id, paying it back out of the open shift
+// at by's terminal.
+func (s *TransactionService) VoidTransaction(id int, reason string, by models.Actor) (*models.Refund, error) {
+	if by.TerminalID == nil {
+		return nil, ErrRefundNotAtTerminal
+	}
+	return s.repo.VoidTransaction(id, reason, by)
+}
+
+// RefundTransaction refunds the items of req on transaction id, paying them
+// back out of the open shift at by's terminal.
+func (s *TransactionService) RefundTransaction(id int, req models.RefundRequest, by models.Actor) (*models.Refund, error) {
+	if by.TerminalID == nil {
+		return nil, ErrRefundNotAtTerminal
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", repositories.ErrInvalidRefund)
+	}
+	for _, item := range req.Items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: detail id %d: %w", repositories.ErrInvalidRefund, item.DetailID, ErrInvalidQuantity)
+		}
+	}
+
+	return s.repo.RefundTransaction(id, req, by)
 }
 
 func (s *TransactionService) GetTransactionReport(startDate *time.Time, endDate *time.Time) (*models.TransactionReport, error) {
@@ -30,5 +162,12 @@ func (s *TransactionService) GetTransactionReport(startDate *time.Time, endDate
 		eDate = *endDate
 	}
 
-	return s.repo.GetTransactionReport(sDate, eDate)
+	report, err := s.repo.GetTransactionReport(sDate, eDate)
+	if err != nil {
+		return nil, err
+	}
+
+	summarizeProfit(report)
+	summarizePromotions(report)
+	return report, nil
 }
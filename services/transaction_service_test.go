@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+	"simple-cashier-api/repositories/memory"
+)
+
+// openTestTill registers a terminal with an open shift and returns its ID,
+// for the actor ringing up sales at it.
+func openTestTill(t *testing.T, store *memory.Store) *int {
+	t.Helper()
+
+	terminal := models.Terminal{Code: "KASIR-1", Name: "Kasir 1", Active: true, KeyHash: "test"}
+	if err := memory.NewTerminalRepository(store).Create(&terminal); err != nil {
+		t.Fatalf("create terminal: %v", err)
+	}
+	if err := memory.NewShiftRepository(store).Open(&models.Shift{TerminalID: terminal.ID}); err != nil {
+		t.Fatalf("open shift: %v", err)
+	}
+	return &terminal.ID
+}
+
+func TestCheckoutSettlesPaymentsAgainstLockedPrices(t *testing.T) {
+	store := memory.NewStore()
+	product := models.Product{Name: "Indomie Goreng", Price: 3500, Stock: 10}
+	if err := memory.NewProductRepository(store).Create(&product); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	service := NewTransactionService(memory.NewTransactionRepository(store), memory.NewProductRepository(store), memory.NewPromotionRepository(store),
+		memory.NewTaxClassRepository(store), models.TaxSettings{}, 0)
+	cashier := models.Actor{User: &models.User{ID: 1, Username: "kasir", Role: models.RoleCashier}, TerminalID: openTestTill(t, store)}
+
+	items := []models.CheckoutItem{{ProductID: product.ID, Quantity: 2}}
+
+	_, err := service.Checkout(models.CheckoutRequest{
+		Items:    items,
+		Payments: []models.CheckoutPayment{{Method: models.PaymentMethodCash, Amount: 5000}},
+	}, cashier, nil)
+	if !errors.Is(err, ErrInsufficientPayment) {
+		t.Fatalf("underpaid checkout: got %v, want ErrInsufficientPayment", err)
+	}
+
+	trx, err := service.Checkout(models.CheckoutRequest{
+		Items:    items,
+		Payments: []models.CheckoutPayment{{Method: models.PaymentMethodCash, Amount: 10000}},
+	}, cashier, nil)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if trx.TotalAmount != 7000 || trx.ChangeAmount != 3000 || len(trx.Payments) != 1 || trx.Cashier != "kasir" {
+		t.Errorf("unexpected transaction %+v", trx)
+	}
+
+	_, err = service.Checkout(models.CheckoutRequest{
+		Items:    []models.CheckoutItem{{ProductID: product.ID, Quantity: 0}},
+		Payments: []models.CheckoutPayment{{Method: models.PaymentMethodCash, Amount: 10000}},
+	}, cashier, nil)
+	var validationErr *repositories.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "items[0].quantity" {
+		t.Errorf("zero quantity: got %v, want a validation error on items[0].quantity", err)
+	}
+}
+
+func TestCheckoutMergesDuplicateLines(t *testing.T) {
+	store := memory.NewStore()
+	product := models.Product{Name: "Indomie Goreng", Price: 3500, Stock: 3}
+	if err := memory.NewProductRepository(store).Create(&product); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	service := NewTransactionService(memory.NewTransactionRepository(store), memory.NewProductRepository(store), memory.NewPromotionRepository(store),
+		memory.NewTaxClassRepository(store), models.TaxSettings{}, 0)
+	cashier := models.Actor{User: &models.User{ID: 1, Username: "kasir", Role: models.RoleCashier}, TerminalID: openTestTill(t, store)}
+
+	trx, err := service.Checkout(models.CheckoutRequest{
+		Items:    []models.CheckoutItem{{ProductID: product.ID, Quantity: 1}, {ProductID: product.ID, Quantity: 2}},
+		Payments: []models.CheckoutPayment{{Method: models.PaymentMethodCash, Amount: 10500}},
+	}, cashier, nil)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if len(trx.Details) != 1 || trx.Details[0].Quantity != 3 || trx.TotalAmount != 10500 {
+		t.Errorf("duplicate lines not merged: %+v", trx.Details)
+	}
+}
+
+func TestCheckoutPriceOverrideAndManualDiscount(t *testing.T) {
+	store := memory.NewStore()
+	product := models.Product{Name: "Kopi Susu", Price: 20000, Stock: 10}
+	if err := memory.NewProductRepository(store).Create(&product); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	service := NewTransactionService(memory.NewTransactionRepository(store), memory.NewProductRepository(store), memory.NewPromotionRepository(store),
+		memory.NewTaxClassRepository(store), models.TaxSettings{}, 10)
+	supervisor := &models.User{ID: 2, Username: "spv", Role: models.RoleSupervisor}
+	price := 15000
+	by := models.Actor{User: &models.User{ID: 1, Username: "kasir", Role: models.RoleCashier}, Approver: supervisor, TerminalID: openTestTill(t, store)}
+
+	req := models.CheckoutRequest{
+		Items:           []models.CheckoutItem{{ProductID: product.ID, Quantity: 2, UnitPrice: &price}},
+		DiscountPercent: 20,
+		Payments:        []models.CheckoutPayment{{Method: models.PaymentMethodCash, Amount: 24000}},
+	}
+	want := []string{models.PermTransactionPriceOverride, models.PermTransactionDiscount}
+	if got := service.CheckoutPermissions(req); !slices.Equal(got, want) {
+		t.Errorf("CheckoutPermissions = %v, want %v", got, want)
+	}
+
+	trx, err := service.Checkout(req, by, nil)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	d := trx.Details[0]
+	if d.UnitPrice != 15000 || d.OriginalPrice == nil || *d.OriginalPrice != 20000 || d.Discount != 6000 {
+		t.Errorf("unexpected detail %+v", d)
+	}
+	if trx.Subtotal != 30000 || trx.DiscountAmount != 6000 || trx.TotalAmount != 24000 {
+		t.Errorf("unexpected totals: subtotal %d, discount %d, total %d", trx.Subtotal, trx.DiscountAmount, trx.TotalAmount)
+	}
+	if len(trx.Promotions) != 1 || trx.Promotions[0].Type != models.DiscountTypeManual || trx.Promotions[0].PromotionID != nil {
+		t.Errorf("manual discount not recorded: %+v", trx.Promotions)
+	}
+	if trx.ApprovedBy != "spv" || trx.ApproverID == nil || *trx.ApproverID != 2 {
+		t.Errorf("approver not recorded: %+v", trx)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+var ErrLastAdmin = repositories.Errorf(repositories.ErrConflict, "the last active admin cannot be demoted or deactivated")
+
+type UserService struct {
+	repo UserRepository
+}
+
+func NewUserService(repo UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+func (s *UserService) GetAll(filter models.UserFilter) (*models.Page[models.User], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	users, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(users, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *UserService) Create(user *models.User) error {
+	if err := validateUser(user, true); err != nil {
+		return err
+	}
+	if err := setPassword(user); err != nil {
+		return err
+	}
+	if err := setPIN(user); err != nil {
+		return err
+	}
+	return s.repo.Create(user)
+}
+
+func (s *UserService) GetByID(id int) (*models.User, error) {
+	return s.repo.GetByID(id)
+}
+
+// Update replaces the user's details, and the password and PIN when they are
+// given. A new PIN also unlocks one locked by failed attempts. At least one
+// active admin must remain, so nobody is locked out of user management.
+func (s *UserService) Update(user *models.User) error {
+	if err := validateUser(user, false); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetByID(user.ID)
+	if err != nil {
+		return err
+	}
+	if existing.Role == models.RoleAdmin && existing.Active && (user.Role != models.RoleAdmin || !user.Active) {
+		active := true
+		_, admins, err := s.repo.GetAll(models.UserFilter{Role: models.RoleAdmin, Active: &active, Page: 1, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	if user.Password == "" {
+		user.PasswordHash = existing.PasswordHash
+	} else if err := setPassword(user); err != nil {
+		return err
+	}
+	if user.PIN == "" {
+		user.PINHash, user.PINFailures, user.PINLockedUntil = existing.PINHash, existing.PINFailures, existing.PINLockedUntil
+	} else if err := setPIN(user); err != nil {
+		return err
+	}
+	return s.repo.Update(user)
+}
+
+// EnsureAdmin creates an active admin with the given credentials when there
+// are no users at all, so a fresh install can be logged into. It reports
+// whether it created one.
+func (s *UserService) EnsureAdmin(username, password string) (bool, error) {
+	_, total, err := s.repo.GetAll(models.UserFilter{Page: 1, Limit: 1})
+	if err != nil || total > 0 {
+		return false, err
+	}
+
+	admin := models.User{Username: username, Name: "Administrator", Role: models.RoleAdmin, Active: true, Password: password}
+	if err := s.Create(&admin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// setPassword hashes user.Password into PasswordHash and clears it, so the
+// plain text is never stored or echoed back.
+func setPassword(user *models.User) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = string(hash)
+	user.Password = ""
+	return nil
+}
+
+// setPIN hashes user.PIN, when one is given, into PINHash and clears it.
+func setPIN(user *models.User) error {
+	if user.PIN == "" {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.PIN), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.PINHash = string(hash)
+	user.PINFailures = 0
+	user.PINLockedUntil = time.Time{}
+	user.PIN = ""
+	return nil
+}
@@ -0,0 +1,531 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+const (
+	MaxProductNameLength         = 100
+	MaxSKULength                 = 32
+	MaxBarcodesPerProduct        = 10
+	MaxCategoryNameLength        = 50
+	MaxCategoryDescriptionLength = 255
+	MaxCheckoutItems             = 100
+	MaxSupplierNameLength        = 100
+	MaxContactNameLength         = 100
+	MaxPhoneLength               = 30
+	MaxEmailLength               = 100
+	MaxAddressLength             = 255
+	MaxNotesLength               = 500
+	MaxPurchaseOrderItems        = 100
+	MaxPromotionNameLength       = 100
+	MaxPromotionScope            = 100
+	MaxVoucherNameLength         = 100
+	MaxVoucherPrefixLength       = 12
+	MaxVoucherCodeLength         = 32
+	MaxVoucherCodesPerRequest    = 1000
+	MaxTaxClassNameLength        = 50
+	MaxUsernameLength            = 50
+	MaxDisplayNameLength         = 100
+	MinPasswordLength            = 8
+	// MaxPasswordLength is the most bcrypt will hash.
+	MaxPasswordLength     = 72
+	MinPINLength          = 4
+	MaxPINLength          = 6
+	MaxTerminalCodeLength = 20
+	MaxTerminalNameLength = 100
+	// MaxRateBP is 100% in basis points.
+	MaxRateBP = 10000
+)
+
+// Validation rules reported in models.FieldError.
+const (
+	RuleRequired = "required"
+	RuleMin      = "min"
+	RuleMax      = "max"
+	RuleMaxLen   = "max_length"
+	RuleOneOf    = "one_of"
+	RuleUnique   = "unique"
+	RuleFormat   = "format"
+	RuleBarcode  = "barcode"
+	RuleExists   = "exists"
+	// RuleRedeemable reports a voucher code that exists but can't be used on
+	// this checkout.
+	RuleRedeemable = "redeemable"
+)
+
+// validator collects field errors so a request is rejected with all of its
+// problems at once instead of one per round trip.
+type validator struct {
+	fields []models.FieldError
+}
+
+func (v *validator) check(ok bool, field, rule, format string, args ...any) {
+	if !ok {
+		v.fields = append(v.fields, models.FieldError{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (v *validator) checkName(name, field string, maxLen int) {
+	v.check(name != "", field, RuleRequired, "must not be empty")
+	v.check(utf8.RuneCountInString(name) <= maxLen, field, RuleMaxLen, "must be at most %d characters", maxLen)
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return repositories.NewValidationError(v.fields...)
+}
+
+// validateProduct trims the name and normalises the SKU and barcodes before
+// checking them, so what is stored is what was validated.
+func validateProduct(p *models.Product) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.SKU = normalizeSKU(p.SKU)
+
+	var v validator
+	v.checkName(p.Name, "name", MaxProductNameLength)
+	v.check(utf8.RuneCountInString(p.SKU) <= MaxSKULength, "sku", RuleMaxLen, "must be at most %d characters", MaxSKULength)
+	v.check(isSKU(p.SKU), "sku", RuleFormat, "may only contain letters, digits, '.', '-' and '_'")
+	v.check(len(p.Barcodes) <= MaxBarcodesPerProduct, "barcodes", RuleMax, "at most %d barcodes are allowed", MaxBarcodesPerProduct)
+
+	seen := make(map[string]bool, len(p.Barcodes))
+	for i, code := range p.Barcodes {
+		field := fmt.Sprintf("barcodes[%d]", i)
+		normalized, err := NormalizeBarcode(code)
+		if err != nil {
+			v.check(false, field, RuleBarcode, "%v", err)
+			continue
+		}
+		v.check(!seen[normalized], field, RuleUnique, "is listed more than once")
+		seen[normalized] = true
+		p.Barcodes[i] = normalized
+	}
+
+	v.check(p.Price >= 0, "price", RuleMin, "must not be negative")
+	v.check(p.CostPrice >= 0, "cost_price", RuleMin, "must not be negative")
+	v.check(p.Stock >= 0, "stock", RuleMin, "must not be negative")
+	if p.CategoryID != nil {
+		v.check(*p.CategoryID > 0, "category_id", RuleMin, "must be a positive id")
+	}
+	if p.TaxClassID != nil {
+		v.check(*p.TaxClassID > 0, "tax_class_id", RuleMin, "must be a positive id")
+	}
+	return v.err()
+}
+
+// normalizeSKU makes SKUs case-insensitive by storing them upper-cased.
+func normalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+func isSKU(sku string) bool {
+	return !strings.ContainsFunc(sku, func(r rune) bool {
+		return !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789.-_", r)
+	})
+}
+
+func validateCategory(c *models.Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+
+	var v validator
+	v.checkName(c.Name, "name", MaxCategoryNameLength)
+	v.check(utf8.RuneCountInString(c.Description) <= MaxCategoryDescriptionLength,
+		"description", RuleMaxLen, "must be at most %d characters", MaxCategoryDescriptionLength)
+	return v.err()
+}
+
+func validateTaxClass(c *models.TaxClass) error {
+	c.Name = strings.TrimSpace(c.Name)
+
+	var v validator
+	v.checkName(c.Name, "name", MaxTaxClassNameLength)
+	v.check(c.RateBP >= 0, "rate_bp", RuleMin, "must not be negative")
+	v.check(c.RateBP <= MaxRateBP, "rate_bp", RuleMax, "must be at most %d (100%%)", MaxRateBP)
+	return v.err()
+}
+
+var (
+	usernamePattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+	pinPattern          = regexp.MustCompile(fmt.Sprintf(`^[0-9]{%d,%d}$`, MinPINLength, MaxPINLength))
+	terminalCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]*$`)
+)
+
+// validateUser checks a user before it is saved. The password is required
+// when creating and optional on update, where empty keeps the current one.
+// The PIN is always optional.
+func validateUser(u *models.User, creating bool) error {
+	u.Username = strings.ToLower(strings.TrimSpace(u.Username))
+	u.Name = strings.TrimSpace(u.Name)
+
+	var v validator
+	v.checkName(u.Username, "username", MaxUsernameLength)
+	if u.Username != "" {
+		v.check(usernamePattern.MatchString(u.Username), "username", RuleFormat, "may only contain letters, digits, '.', '_' and '-'")
+	}
+	v.checkName(u.Name, "name", MaxDisplayNameLength)
+	v.check(slices.Contains(models.Roles, u.Role), "role", RuleOneOf, "must be one of %s", strings.Join(models.Roles, ", "))
+	if creating || u.Password != "" {
+		v.check(len(u.Password) >= MinPasswordLength, "password", RuleMin, "must be at least %d characters", MinPasswordLength)
+		v.check(len(u.Password) <= MaxPasswordLength, "password", RuleMaxLen, "must be at most %d bytes", MaxPasswordLength)
+	}
+	if u.PIN != "" {
+		v.check(pinPattern.MatchString(u.PIN), "pin", RuleFormat, "must be %d to %d digits", MinPINLength, MaxPINLength)
+	}
+	return v.err()
+}
+
+// validateTerminal stores codes upper case, so "pos-01" and "POS-01" are the
+// same terminal.
+func validateTerminal(t *models.Terminal) error {
+	t.Code = strings.ToUpper(strings.TrimSpace(t.Code))
+	t.Name = strings.TrimSpace(t.Name)
+
+	var v validator
+	v.checkName(t.Code, "code", MaxTerminalCodeLength)
+	if t.Code != "" {
+		v.check(terminalCodePattern.MatchString(t.Code), "code", RuleFormat, "may only contain letters, digits and '-'")
+	}
+	v.checkName(t.Name, "name", MaxTerminalNameLength)
+	return v.err()
+}
+
+func validateSupplier(s *models.Supplier) error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.ContactName = strings.TrimSpace(s.ContactName)
+	s.Phone = strings.TrimSpace(s.Phone)
+	s.Email = strings.TrimSpace(s.Email)
+	s.Address = strings.TrimSpace(s.Address)
+
+	var v validator
+	v.checkName(s.Name, "name", MaxSupplierNameLength)
+	v.checkMaxLen(s.ContactName, "contact_name", MaxContactNameLength)
+	v.checkMaxLen(s.Phone, "phone", MaxPhoneLength)
+	v.checkMaxLen(s.Email, "email", MaxEmailLength)
+	v.check(s.Email == "" || isEmail(s.Email), "email", RuleFormat, "must be an email address")
+	v.checkMaxLen(s.Address, "address", MaxAddressLength)
+	return v.err()
+}
+
+func (v *validator) checkMaxLen(value, field string, maxLen int) {
+	v.check(utf8.RuneCountInString(value) <= maxLen, field, RuleMaxLen, "must be at most %d characters", maxLen)
+}
+
+// isEmail accepts a bare address such as "sales@example.com", without a
+// display name.
+func isEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// newPurchaseOrder validates req and turns it into a purchase order with its
+// subtotals and total cost worked out.
+func newPurchaseOrder(req models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	po := &models.PurchaseOrder{
+		SupplierID: req.SupplierID,
+		Notes:      strings.TrimSpace(req.Notes),
+		Items:      make([]models.PurchaseOrderItem, 0, len(req.Items)),
+	}
+
+	var v validator
+	v.check(po.SupplierID > 0, "supplier_id", RuleRequired, "must be a positive id")
+	v.checkMaxLen(po.Notes, "notes", MaxNotesLength)
+	v.check(len(req.Items) > 0, "items", RuleRequired, "at least one item is required")
+	v.check(len(req.Items) <= MaxPurchaseOrderItems, "items", RuleMax, "at most %d items are allowed", MaxPurchaseOrderItems)
+
+	seen := make(map[int]bool, len(req.Items))
+	for i, line := range req.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		v.check(line.ProductID > 0, field+".product_id", RuleMin, "must be a positive id")
+		v.check(!seen[line.ProductID], field+".product_id", RuleUnique, "is listed more than once")
+		v.check(line.Quantity > 0, field+".quantity", RuleMin, "must be greater than zero")
+		v.check(line.UnitCost >= 0, field+".unit_cost", RuleMin, "must not be negative")
+		seen[line.ProductID] = true
+
+		item := models.PurchaseOrderItem{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			UnitCost:  line.UnitCost,
+			Subtotal:  line.Quantity * line.UnitCost,
+		}
+		po.TotalCost += item.Subtotal
+		po.Items = append(po.Items, item)
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return po, nil
+}
+
+func validateReceipt(req *models.ReceiveRequest) error {
+	var v validator
+	v.check(len(req.Items) > 0, "items", RuleRequired, "at least one item is required")
+
+	seen := make(map[int]bool, len(req.Items))
+	for i, item := range req.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		v.check(item.ItemID > 0, field+".item_id", RuleMin, "must be a positive id")
+		v.check(!seen[item.ItemID], field+".item_id", RuleUnique, "is listed more than once")
+		v.check(item.Quantity > 0, field+".quantity", RuleMin, "must be greater than zero")
+		seen[item.ItemID] = true
+	}
+	return v.err()
+}
+
+// validatePromotion checks the fields p.Type uses and that the ones it does
+// not use are left empty, so a stored promotion always means what it says.
+func validatePromotion(p *models.Promotion) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.ProductIDs == nil {
+		p.ProductIDs = []int{}
+	}
+	if p.CategoryIDs == nil {
+		p.CategoryIDs = []int{}
+	}
+	if p.BundleItems == nil {
+		p.BundleItems = []models.BundleItem{}
+	}
+
+	var v validator
+	v.checkName(p.Name, "name", MaxPromotionNameLength)
+	v.check(p.MinSpend >= 0, "min_spend", RuleMin, "must not be negative")
+	if p.StartsAt != nil && p.EndsAt != nil {
+		v.check(p.EndsAt.After(*p.StartsAt), "ends_at", RuleMin, "must be after starts_at")
+	}
+	if !slices.Contains(models.PromotionTypes, p.Type) {
+		v.check(false, "type", RuleOneOf, "must be one of %s", strings.Join(models.PromotionTypes, ", "))
+		return v.err()
+	}
+
+	switch p.Type {
+	case models.PromotionTypePercentOff, models.PromotionTypeCartPercentOff:
+		v.check(p.Value > 0, "value", RuleMin, "must be greater than zero")
+		v.check(p.Value <= 100, "value", RuleMax, "must be at most 100 percent")
+	case models.PromotionTypeAmountOff, models.PromotionTypeCartAmountOff:
+		v.check(p.Value > 0, "value", RuleMin, "must be greater than zero")
+	default:
+		v.check(p.Value == 0, "value", RuleMax, "is not used by %s promotions", p.Type)
+	}
+
+	if p.Type == models.PromotionTypeBuyXGetY {
+		v.check(p.BuyQuantity > 0, "buy_quantity", RuleMin, "must be greater than zero")
+		v.check(p.GetQuantity > 0, "get_quantity", RuleMin, "must be greater than zero")
+	} else {
+		v.check(p.BuyQuantity == 0, "buy_quantity", RuleMax, "is only used by buy_x_get_y promotions")
+		v.check(p.GetQuantity == 0, "get_quantity", RuleMax, "is only used by buy_x_get_y promotions")
+	}
+
+	if p.Type == models.PromotionTypeBundle {
+		v.check(p.BundlePrice >= 0, "bundle_price", RuleMin, "must not be negative")
+		v.check(len(p.BundleItems) > 0, "bundle_items", RuleRequired, "at least one item is required")
+		v.check(len(p.BundleItems) <= MaxPromotionScope, "bundle_items", RuleMax, "at most %d items are allowed", MaxPromotionScope)
+
+		units := 0
+		seen := make(map[int]bool, len(p.BundleItems))
+		for i, item := range p.BundleItems {
+			field := fmt.Sprintf("bundle_items[%d]", i)
+			v.check(item.ProductID > 0, field+".product_id", RuleMin, "must be a positive id")
+			v.check(!seen[item.ProductID], field+".product_id", RuleUnique, "is listed more than once")
+			v.check(item.Quantity > 0, field+".quantity", RuleMin, "must be greater than zero")
+			seen[item.ProductID] = true
+			units += item.Quantity
+		}
+		v.check(units != 1, "bundle_items", RuleMin, "a bundle needs at least two units")
+	} else {
+		v.check(p.BundlePrice == 0, "bundle_price", RuleMax, "is only used by bundle promotions")
+		v.check(len(p.BundleItems) == 0, "bundle_items", RuleMax, "is only used by bundle promotions")
+	}
+
+	if p.Type != models.PromotionTypeBundle {
+		v.checkIDs(p.ProductIDs, "product_ids")
+		v.checkIDs(p.CategoryIDs, "category_ids")
+	} else {
+		v.check(len(p.ProductIDs) == 0, "product_ids", RuleMax, "is not used by bundle promotions")
+		v.check(len(p.CategoryIDs) == 0, "category_ids", RuleMax, "is not used by bundle promotions")
+	}
+	return v.err()
+}
+
+// validateVoucher trims the name and checks the discount, limits and scopes.
+func validateVoucher(voucher *models.Voucher) error {
+	voucher.Name = strings.TrimSpace(voucher.Name)
+	if voucher.ProductIDs == nil {
+		voucher.ProductIDs = []int{}
+	}
+	if voucher.CategoryIDs == nil {
+		voucher.CategoryIDs = []int{}
+	}
+
+	var v validator
+	v.checkName(voucher.Name, "name", MaxVoucherNameLength)
+	switch voucher.Type {
+	case models.VoucherTypePercentOff:
+		v.check(voucher.Value > 0, "value", RuleMin, "must be greater than zero")
+		v.check(voucher.Value <= 100, "value", RuleMax, "must be at most 100 percent")
+		v.check(voucher.MaxDiscount >= 0, "max_discount", RuleMin, "must not be negative")
+	case models.VoucherTypeAmountOff:
+		v.check(voucher.Value > 0, "value", RuleMin, "must be greater than zero")
+		v.check(voucher.MaxDiscount == 0, "max_discount", RuleMax, "is only used by percent_off vouchers")
+	default:
+		v.check(false, "type", RuleOneOf, "must be one of %s", strings.Join(models.VoucherTypes, ", "))
+	}
+	v.check(voucher.MinPurchase >= 0, "min_purchase", RuleMin, "must not be negative")
+	v.check(voucher.UsesPerCode > 0, "uses_per_code", RuleMin, "must be greater than zero")
+	v.check(voucher.MaxUses >= 0, "max_uses", RuleMin, "must not be negative")
+	if voucher.StartsAt != nil && voucher.EndsAt != nil {
+		v.check(voucher.EndsAt.After(*voucher.StartsAt), "ends_at", RuleMin, "must be after starts_at")
+	}
+	v.checkIDs(voucher.ProductIDs, "product_ids")
+	v.checkIDs(voucher.CategoryIDs, "category_ids")
+	return v.err()
+}
+
+// validateGenerateCodes upper-cases the prefix, which may only hold letters,
+// digits and dashes so printed codes stay easy to type.
+func validateGenerateCodes(req *models.GenerateCodesRequest) error {
+	req.Prefix = strings.ToUpper(strings.TrimSpace(req.Prefix))
+
+	var v validator
+	v.check(req.Count > 0, "count", RuleMin, "must be greater than zero")
+	v.check(req.Count <= MaxVoucherCodesPerRequest, "count", RuleMax, "at most %d codes can be generated at once", MaxVoucherCodesPerRequest)
+	v.checkMaxLen(req.Prefix, "prefix", MaxVoucherPrefixLength)
+	v.check(strings.Trim(req.Prefix, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-") == "", "prefix", RuleFormat,
+		"may only contain letters, digits and dashes")
+	return v.err()
+}
+
+// normalizeVoucherCode upper-cases code, since codes are stored that way and
+// are read back by people.
+func normalizeVoucherCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	var v validator
+	v.checkMaxLen(code, "voucher_code", MaxVoucherCodeLength)
+	return code, v.err()
+}
+
+// checkIDs checks a list of ids that scopes a promotion or voucher.
+func (v *validator) checkIDs(ids []int, field string) {
+	v.check(len(ids) <= MaxPromotionScope, field, RuleMax, "at most %d ids are allowed", MaxPromotionScope)
+
+	seen := make(map[int]bool, len(ids))
+	for i, id := range ids {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		v.check(id > 0, itemField, RuleMin, "must be a positive id")
+		v.check(!seen[id], itemField, RuleUnique, "is listed more than once")
+		seen[id] = true
+	}
+}
+
+// productFinder is the part of ProductRepository checkout needs to resolve
+// scanned codes.
+type productFinder interface {
+	GetByBarcode(barcode string) (*models.ProductDetail, error)
+	GetBySKU(sku string) (*models.ProductDetail, error)
+}
+
+// normalizeCheckoutItems validates the items, resolves barcodes and SKUs to
+// product ids, and merges lines for the same product into one, keeping the
+// position of the first occurrence.
+func normalizeCheckoutItems(items []models.CheckoutItem, products productFinder) ([]models.CheckoutItem, error) {
+	var v validator
+	v.check(len(items) > 0, "items", RuleRequired, "at least one item is required")
+	v.check(len(items) <= MaxCheckoutItems, "items", RuleMax, "at most %d items are allowed", MaxCheckoutItems)
+
+	resolved := make([]models.CheckoutItem, len(items))
+	for i, item := range items {
+		field := fmt.Sprintf("items[%d]", i)
+		v.check(item.Quantity > 0, field+".quantity", RuleMin, "must be greater than zero")
+
+		references := 0
+		for _, set := range []bool{item.ProductID != 0, item.Barcode != "", item.SKU != ""} {
+			if set {
+				references++
+			}
+		}
+		if references != 1 {
+			v.check(false, field, RuleOneOf, "exactly one of product_id, barcode or sku is required")
+			continue
+		}
+
+		if item.UnitPrice != nil {
+			v.check(*item.UnitPrice >= 0, field+".unit_price", RuleMin, "must not be negative")
+		}
+
+		resolved[i] = models.CheckoutItem{ProductID: item.ProductID, Quantity: item.Quantity, UnitPrice: item.UnitPrice}
+		switch {
+		case item.ProductID != 0:
+			v.check(item.ProductID > 0, field+".product_id", RuleMin, "must be a positive id")
+		case item.Barcode != "":
+			barcode, err := NormalizeBarcode(item.Barcode)
+			if err != nil {
+				v.check(false, field+".barcode", RuleBarcode, "%v", err)
+				continue
+			}
+			p, err := products.GetByBarcode(barcode)
+			if err := v.checkFound(err, field+".barcode", "no product has this barcode"); err != nil {
+				return nil, err
+			}
+			if p != nil {
+				resolved[i].ProductID = p.ID
+			}
+		default:
+			p, err := products.GetBySKU(normalizeSKU(item.SKU))
+			if err := v.checkFound(err, field+".sku", "no product has this SKU"); err != nil {
+				return nil, err
+			}
+			if p != nil {
+				resolved[i].ProductID = p.ID
+			}
+		}
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	merged := make([]models.CheckoutItem, 0, len(resolved))
+	index := make(map[int]int, len(resolved))
+	for n, item := range resolved {
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			v.check(samePrice(merged[i].UnitPrice, item.UnitPrice), fmt.Sprintf("items[%d].unit_price", n), RuleOneOf,
+				"must match the other items of the same product")
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged, v.err()
+}
+
+func samePrice(a, b *int) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func validateDiscountPercent(percent int) error {
+	var v validator
+	v.check(percent >= 0, "discount_percent", RuleMin, "must not be negative")
+	v.check(percent <= 100, "discount_percent", RuleMax, "must be at most 100")
+	return v.err()
+}
+
+// checkFound records a field error when a lookup found nothing. Any other
+// error is returned as is.
+func (v *validator) checkFound(err error, field, message string) error {
+	if errors.Is(err, repositories.ErrNotFound) {
+		v.check(false, field, RuleExists, message)
+		return nil
+	}
+	return err
+}
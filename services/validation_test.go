@@ -0,0 +1,305 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+	"simple-cashier-api/repositories/memory"
+)
+
+func fieldErrors(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+	var validationErr *repositories.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("got %v, want a *ValidationError", err)
+	}
+	fields := make([]string, len(validationErr.Fields))
+	for i, f := range validationErr.Fields {
+		fields[i] = f.Field + "/" + f.Rule
+	}
+	return fields
+}
+
+func TestValidateProduct(t *testing.T) {
+	categoryID := 0
+	tests := []struct {
+		name    string
+		product models.Product
+		want    []string
+	}{
+		{"valid", models.Product{Name: "Indomie", Price: 3500, Stock: 10}, nil},
+		{"free item", models.Product{Name: "Kantong Plastik"}, nil},
+		{"blank name", models.Product{Name: "   ", Price: 1}, []string{"name/required"}},
+		{"long name", models.Product{Name: strings.Repeat("a", MaxProductNameLength+1)}, []string{"name/max_length"}},
+		{"negative numbers", models.Product{Name: "x", Price: -1, Stock: -1}, []string{"price/min", "stock/min"}},
+		{"zero category", models.Product{Name: "x", CategoryID: &categoryID}, []string{"category_id/min"}},
+		{"zero tax class", models.Product{Name: "x", TaxClassID: &categoryID}, []string{"tax_class_id/min"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.product
+			got := fieldErrors(t, validateProduct(&p))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCategoryTrims(t *testing.T) {
+	c := models.Category{Name: "  Minuman ", Description: " Dingin "}
+	if err := validateCategory(&c); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if c.Name != "Minuman" || c.Description != "Dingin" {
+		t.Errorf("not trimmed: %+v", c)
+	}
+
+	c = models.Category{Description: strings.Repeat("a", MaxCategoryDescriptionLength+1)}
+	if got := fieldErrors(t, validateCategory(&c)); strings.Join(got, ",") != "name/required,description/max_length" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestNormalizeCheckoutItems(t *testing.T) {
+	store := memory.NewStore()
+	products := memory.NewProductRepository(store)
+	for _, p := range []models.Product{
+		{Name: "Indomie Goreng", SKU: "IDM-GRG", Barcodes: []string{"0036000291452"}},
+		{Name: "Aqua 600ml", SKU: "AQ-600"},
+	} {
+		if err := products.Create(&p); err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+
+	items, err := normalizeCheckoutItems([]models.CheckoutItem{
+		{ProductID: 2, Quantity: 1},
+		{Barcode: "036000291452", Quantity: 2},
+		{SKU: "aq-600", Quantity: 3},
+		{ProductID: 1, Quantity: 1},
+	}, products)
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	want := []models.CheckoutItem{{ProductID: 2, Quantity: 4}, {ProductID: 1, Quantity: 3}}
+	if len(items) != len(want) || items[0] != want[0] || items[1] != want[1] {
+		t.Errorf("got %+v, want %+v", items, want)
+	}
+
+	_, err = normalizeCheckoutItems(nil, products)
+	if got := fieldErrors(t, err); strings.Join(got, ",") != "items/required" {
+		t.Errorf("empty items: got %v", got)
+	}
+
+	_, err = normalizeCheckoutItems([]models.CheckoutItem{
+		{Quantity: 1},
+		{ProductID: 1, Quantity: -1},
+		{ProductID: 1, SKU: "AQ-600", Quantity: 1},
+		{Barcode: "4006381333932", Quantity: 1},
+		{Barcode: "4006381333931", Quantity: 1},
+		{SKU: "NOPE", Quantity: 1},
+	}, products)
+	want2 := "items[0]/one_of,items[1].quantity/min,items[2]/one_of,items[3].barcode/barcode,items[4].barcode/exists,items[5].sku/exists"
+	if got := fieldErrors(t, err); strings.Join(got, ",") != want2 {
+		t.Errorf("bad lines: got %v", got)
+	}
+}
+
+func TestNormalizeBarcode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"4006381333931", "4006381333931"},
+		{"8992761111113", "8992761111113"},
+		{"036000291452", "0036000291452"},
+		{" 036000291452 ", "0036000291452"},
+		{"4006381333932", ""},
+		{"400638133393", ""},
+		{"40063813339310", ""},
+		{"40063813339a1", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeBarcode(tt.code)
+		if got != tt.want || (err == nil) != (tt.want != "") {
+			t.Errorf("NormalizeBarcode(%q) = %q, %v, want %q", tt.code, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateProductCodes(t *testing.T) {
+	p := models.Product{Name: "Indomie", SKU: " idm-grg ", Barcodes: []string{"036000291452", "4006381333931"}}
+	if err := validateProduct(&p); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if p.SKU != "IDM-GRG" || p.Barcodes[0] != "0036000291452" {
+		t.Errorf("not normalised: %+v", p)
+	}
+
+	p = models.Product{Name: "Indomie", SKU: "IDM GRG", Barcodes: []string{"036000291452", "0036000291452", "123"}}
+	want := "sku/format,barcodes[1]/unique,barcodes[2]/barcode"
+	if got := fieldErrors(t, validateProduct(&p)); strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestNewPurchaseOrder(t *testing.T) {
+	po, err := newPurchaseOrder(models.PurchaseOrderRequest{
+		SupplierID: 1,
+		Notes:      " Kirim Senin ",
+		Items: []models.PurchaseOrderItemLine{
+			{ProductID: 1, Quantity: 40, UnitCost: 2800},
+			{ProductID: 2, Quantity: 24, UnitCost: 1500},
+		},
+	})
+	if err != nil {
+		t.Fatalf("newPurchaseOrder: %v", err)
+	}
+	if po.Notes != "Kirim Senin" || po.TotalCost != 148000 || po.Items[1].Subtotal != 36000 {
+		t.Errorf("unexpected purchase order %+v", po)
+	}
+
+	_, err = newPurchaseOrder(models.PurchaseOrderRequest{
+		Items: []models.PurchaseOrderItemLine{
+			{ProductID: 1, Quantity: 0, UnitCost: -1},
+			{ProductID: 1, Quantity: 1},
+		},
+	})
+	want := "supplier_id/required,items[0].quantity/min,items[0].unit_cost/min,items[1].product_id/unique"
+	if got := strings.Join(fieldErrors(t, err), ","); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestValidateSupplierEmail(t *testing.T) {
+	for email, valid := range map[string]bool{
+		"":                          true,
+		"sales@example.com":         true,
+		"sales":                     false,
+		"Sales <sales@example.com>": false,
+	} {
+		s := models.Supplier{Name: "PT Contoh", Email: email}
+		if err := validateSupplier(&s); (err == nil) != valid {
+			t.Errorf("email %q: got %v, want valid=%v", email, err, valid)
+		}
+	}
+}
+
+func TestValidatePromotion(t *testing.T) {
+	p := models.Promotion{Name: " Hemat ", Type: models.PromotionTypePercentOff, Value: 10}
+	if err := validatePromotion(&p); err != nil {
+		t.Fatalf("validatePromotion: %v", err)
+	}
+	if p.Name != "Hemat" || p.ProductIDs == nil || p.BundleItems == nil {
+		t.Errorf("promotion not normalised: %+v", p)
+	}
+
+	scoped := models.Promotion{Name: "Diskon minuman", Type: models.PromotionTypeCartAmountOff, Value: 5000, ProductIDs: []int{1}, CategoryIDs: []int{2}}
+	if err := validatePromotion(&scoped); err != nil {
+		t.Errorf("scoped cart promotion: %v", err)
+	}
+
+	err := validatePromotion(&models.Promotion{
+		Name:        "Diskon keranjang",
+		Type:        models.PromotionTypeCartPercentOff,
+		Value:       120,
+		BuyQuantity: 2,
+		ProductIDs:  []int{1, 1},
+	})
+	want := "value/max,buy_quantity/max,product_ids[1]/unique"
+	if got := strings.Join(fieldErrors(t, err), ","); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	err = validatePromotion(&models.Promotion{
+		Name:        "Paket",
+		Type:        models.PromotionTypeBundle,
+		BundleItems: []models.BundleItem{{ProductID: 1, Quantity: 1}},
+	})
+	if got := strings.Join(fieldErrors(t, err), ","); got != "bundle_items/min" {
+		t.Errorf("single-unit bundle: got %v, want bundle_items/min", got)
+	}
+}
+
+func TestValidateVoucher(t *testing.T) {
+	voucher := models.Voucher{Name: " Hemat ", Type: models.VoucherTypePercentOff, Value: 10, UsesPerCode: 1}
+	if err := validateVoucher(&voucher); err != nil {
+		t.Fatalf("validateVoucher: %v", err)
+	}
+	if voucher.Name != "Hemat" || voucher.ProductIDs == nil || voucher.CategoryIDs == nil {
+		t.Errorf("voucher not normalised: %+v", voucher)
+	}
+
+	err := validateVoucher(&models.Voucher{
+		Name:        "Potongan",
+		Type:        models.VoucherTypeAmountOff,
+		Value:       5000,
+		MaxDiscount: 1000,
+		MaxUses:     -1,
+		CategoryIDs: []int{3, 3},
+	})
+	want := "max_discount/max,uses_per_code/min,max_uses/min,category_ids[1]/unique"
+	if got := strings.Join(fieldErrors(t, err), ","); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	req := models.GenerateCodesRequest{Count: 10, Prefix: " ramadan-"}
+	if err := validateGenerateCodes(&req); err != nil || req.Prefix != "RAMADAN-" {
+		t.Errorf("validateGenerateCodes = %v, prefix %q", err, req.Prefix)
+	}
+	err = validateGenerateCodes(&models.GenerateCodesRequest{Prefix: "HEMAT_"})
+	if got := strings.Join(fieldErrors(t, err), ","); got != "count/min,prefix/format" {
+		t.Errorf("got %v, want count/min,prefix/format", got)
+	}
+}
+
+func TestValidateTaxClass(t *testing.T) {
+	c := models.TaxClass{Name: " Bebas PPN ", RateBP: 0}
+	if err := validateTaxClass(&c); err != nil || c.Name != "Bebas PPN" {
+		t.Errorf("validateTaxClass = %v, name %q", err, c.Name)
+	}
+
+	for rate, want := range map[int]string{-1: "name/required,rate_bp/min", MaxRateBP + 1: "name/required,rate_bp/max"} {
+		got := fieldErrors(t, validateTaxClass(&models.TaxClass{RateBP: rate}))
+		if strings.Join(got, ",") != want {
+			t.Errorf("rate %d: got %v, want %s", rate, got, want)
+		}
+	}
+}
+
+func TestValidateUser(t *testing.T) {
+	u := models.User{Username: " Kasir.01 ", Name: " Siti ", Role: models.RoleCashier, Password: "rahasia123"}
+	if err := validateUser(&u, true); err != nil || u.Username != "kasir.01" || u.Name != "Siti" {
+		t.Errorf("validateUser = %v, username %q, name %q", err, u.Username, u.Name)
+	}
+
+	cases := []struct {
+		name     string
+		user     models.User
+		creating bool
+		want     string
+	}{
+		{"empty", models.User{}, true, "username/required,name/required,role/one_of,password/min"},
+		{"bad username", models.User{Username: "-siti budi", Name: "Siti", Role: models.RoleAdmin, Password: "rahasia123"}, true, "username/format"},
+		{"long password", models.User{Username: "siti", Name: "Siti", Role: models.RoleAdmin, Password: strings.Repeat("x", MaxPasswordLength+1)}, true, "password/max_length"},
+		{"update keeps password", models.User{Username: "siti", Name: "Siti", Role: models.RoleSupervisor}, false, ""},
+		{"update with short password", models.User{Username: "siti", Name: "Siti", Role: models.RoleSupervisor, Password: "pendek"}, false, "password/min"},
+	}
+	for _, tc := range cases {
+		got := fieldErrors(t, validateUser(&tc.user, tc.creating))
+		if strings.Join(got, ",") != tc.want {
+			t.Errorf("%s: got %v, want %s", tc.name, got, tc.want)
+		}
+	}
+}
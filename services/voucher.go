@@ -0,0 +1,120 @@
+package services
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+// voucherCodeAlphabet leaves out 0, 1, I and O, which are easily misread on
+// a printed voucher. Its 32 letters divide 256, so every byte maps to a
+// letter without bias.
+const voucherCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// VoucherCodeLength random letters give 50 bits per code, far too many to
+// guess a valid one at the till.
+const VoucherCodeLength = 10
+
+// newVoucherCode returns prefix followed by VoucherCodeLength random letters.
+func newVoucherCode(prefix string) (string, error) {
+	b := make([]byte, VoucherCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = voucherCodeAlphabet[int(b[i])%len(voucherCodeAlphabet)]
+	}
+	return prefix + string(b), nil
+}
+
+// voucherError reports why a code that exists can't be redeemed.
+func voucherError(format string, args ...any) error {
+	return repositories.NewValidationError(models.FieldError{
+		Field:   "voucher_code",
+		Rule:    RuleRedeemable,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkVoucherCode checks that rv can be redeemed at the given time, whatever
+// the cart.
+func checkVoucherCode(rv *models.RedeemableVoucher, at time.Time) error {
+	v := rv.Voucher
+	switch {
+	case !v.Active:
+		return voucherError("voucher is not active")
+	case v.StartsAt != nil && at.Before(*v.StartsAt):
+		return voucherError("voucher is not valid until %s", v.StartsAt.Format(time.RFC3339))
+	case v.EndsAt != nil && !at.Before(*v.EndsAt):
+		return voucherError("voucher expired at %s", v.EndsAt.Format(time.RFC3339))
+	case rv.Code.Redemptions >= v.UsesPerCode:
+		return voucherError("code has already been used")
+	case v.MaxUses > 0 && v.Redemptions >= v.MaxUses:
+		return voucherError("voucher has been fully redeemed")
+	}
+	return nil
+}
+
+// remainingUses is how many more times rv's code can be redeemed.
+func remainingUses(rv *models.RedeemableVoucher) int {
+	left := max(rv.Voucher.UsesPerCode-rv.Code.Redemptions, 0)
+	if rv.Voucher.MaxUses > 0 {
+		left = min(left, max(rv.Voucher.MaxUses-rv.Voucher.Redemptions, 0))
+	}
+	return left
+}
+
+// applyVoucher redeems rv on the checkout draft trx, after its promotions.
+// The discount is taken off what the lines in scope cost after promotions
+// and spread over them like a cart promotion.
+func applyVoucher(trx *models.Transaction, rv *models.RedeemableVoucher, at time.Time) error {
+	if err := checkVoucherCode(rv, at); err != nil {
+		return err
+	}
+	v := rv.Voucher
+	if trx.TotalAmount < v.MinPurchase {
+		return voucherError("voucher needs a minimum purchase of %d", v.MinPurchase)
+	}
+
+	scope := models.Promotion{ProductIDs: v.ProductIDs, CategoryIDs: v.CategoryIDs}
+	weights := make([]int, len(trx.Details))
+	base := 0
+	for i, d := range trx.Details {
+		if inPromotionScope(scope, d) {
+			weights[i] = d.Subtotal
+			base += d.Subtotal
+		}
+	}
+	if base == 0 {
+		return voucherError("voucher does not apply to any item in the cart")
+	}
+
+	amount := min(v.Value, base)
+	if v.Type == models.VoucherTypePercentOff {
+		amount = percentOf(base, v.Value)
+		if v.MaxDiscount > 0 {
+			amount = min(amount, v.MaxDiscount)
+		}
+	}
+	if amount == 0 {
+		return voucherError("voucher gives no discount on this cart")
+	}
+
+	for i, discount := range allocate(amount, weights) {
+		trx.Details[i].Discount += discount
+		trx.Details[i].Subtotal -= discount
+	}
+	trx.Voucher = &models.AppliedVoucher{
+		VoucherID:     v.ID,
+		VoucherCodeID: rv.Code.ID,
+		Code:          rv.Code.Code,
+		Name:          v.Name,
+		Amount:        amount,
+	}
+	trx.DiscountAmount += amount
+	trx.TotalAmount -= amount
+	return nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"simple-cashier-api/models"
+)
+
+// maxCodeBatches bounds how often GenerateCodes asks for replacements of
+// codes that were already taken. With 50 random bits per code a second batch
+// is already unlikely.
+const maxCodeBatches = 5
+
+var errCodeSpaceExhausted = errors.New("could not generate enough unique voucher codes")
+
+type VoucherService struct {
+	repo       VoucherRepository
+	products   ProductRepository
+	promotions PromotionRepository
+}
+
+func NewVoucherService(repo VoucherRepository, products ProductRepository, promotions PromotionRepository) *VoucherService {
+	return &VoucherService{repo: repo, products: products, promotions: promotions}
+}
+
+func (s *VoucherService) GetAll(filter models.VoucherFilter) (*models.Page[models.Voucher], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	vouchers, total, err := s.repo.GetAll(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(vouchers, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+func (s *VoucherService) Create(voucher *models.Voucher) error {
+	if err := validateVoucher(voucher); err != nil {
+		return err
+	}
+	return s.repo.Create(voucher)
+}
+
+func (s *VoucherService) GetByID(id int) (*models.Voucher, error) {
+	return s.repo.GetByID(id)
+}
+
+func (s *VoucherService) Update(voucher *models.Voucher) error {
+	if err := validateVoucher(voucher); err != nil {
+		return err
+	}
+	return s.repo.Update(voucher)
+}
+
+func (s *VoucherService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+// GenerateCodes adds req.Count new random codes to the voucher. Codes that
+// collide with existing ones are replaced, so exactly Count are returned.
+func (s *VoucherService) GenerateCodes(voucherID int, req models.GenerateCodesRequest) ([]models.VoucherCode, error) {
+	if err := validateGenerateCodes(&req); err != nil {
+		return nil, err
+	}
+
+	codes := make([]models.VoucherCode, 0, req.Count)
+	for batch := 0; len(codes) < req.Count; batch++ {
+		if batch == maxCodeBatches {
+			return nil, errCodeSpaceExhausted
+		}
+
+		want := req.Count - len(codes)
+		seen := make(map[string]bool, want)
+		candidates := make([]string, 0, want)
+		for len(candidates) < want {
+			code, err := newVoucherCode(req.Prefix)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[code] {
+				seen[code] = true
+				candidates = append(candidates, code)
+			}
+		}
+
+		added, err := s.repo.AddCodes(voucherID, candidates)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, added...)
+	}
+	return codes, nil
+}
+
+func (s *VoucherService) GetCodes(filter models.VoucherCodeFilter) (*models.Page[models.VoucherCode], error) {
+	filter.Page, filter.Limit = normalizePage(filter.Page, filter.Limit)
+
+	codes, total, err := s.repo.GetCodes(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := models.NewPage(codes, filter.Page, filter.Limit, total)
+	return &page, nil
+}
+
+// Validate previews redeeming req.VoucherCode on a checkout of req.Items at
+// current prices and promotions, failing like the checkout would. Stock is
+// not checked and nothing is reserved: the checkout itself may still be
+// refused if the code is used up in the meantime.
+func (s *VoucherService) Validate(req models.VoucherValidationRequest) (*models.VoucherPreview, error) {
+	code, err := normalizeVoucherCode(req.VoucherCode)
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		var v validator
+		v.check(false, "voucher_code", RuleRequired, "must not be empty")
+		return nil, v.err()
+	}
+
+	rv, err := s.repo.GetByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preview := &models.VoucherPreview{
+		VoucherCode:   rv.Code.Code,
+		VoucherID:     rv.Voucher.ID,
+		Name:          rv.Voucher.Name,
+		RemainingUses: remainingUses(rv),
+	}
+	if len(req.Items) == 0 {
+		return preview, checkVoucherCode(rv, now)
+	}
+
+	trx, err := s.priceCart(req.Items, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyVoucher(trx, rv, now); err != nil {
+		return nil, err
+	}
+
+	preview.Subtotal = trx.Subtotal
+	preview.DiscountAmount = trx.DiscountAmount
+	preview.VoucherDiscount = trx.Voucher.Amount
+	preview.TotalAmount = trx.TotalAmount
+	return preview, nil
+}
+
+// priceCart builds the draft a checkout of items would start from, with the
+// promotions active at the given time applied.
+func (s *VoucherService) priceCart(items []models.CheckoutItem, at time.Time) (*models.Transaction, error) {
+	items, err := normalizeCheckoutItems(items, s.products)
+	if err != nil {
+		return nil, err
+	}
+
+	var v validator
+	trx := &models.Transaction{}
+	for i, item := range items {
+		p, err := s.products.GetByID(item.ProductID)
+		if err != nil {
+			if err := v.checkFound(err, fmt.Sprintf("items[%d].product_id", i), "product does not exist"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		subtotal := p.Price * item.Quantity
+		trx.Details = append(trx.Details, models.TransactionDetail{
+			ProductID:   p.ID,
+			ProductName: p.Name,
+			CategoryID:  p.CategoryID,
+			Quantity:    item.Quantity,
+			UnitPrice:   p.Price,
+			UnitCost:    p.CostPrice,
+			Subtotal:    subtotal,
+		})
+		trx.Subtotal += subtotal
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	trx.TotalAmount = trx.Subtotal
+
+	promotions, err := s.promotions.GetActive(at)
+	if err != nil {
+		return nil, err
+	}
+	applyPromotions(trx, promotions)
+	return trx, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"simple-cashier-api/models"
+	"simple-cashier-api/repositories"
+)
+
+func TestApplyVoucher(t *testing.T) {
+	now := time.Now()
+	yesterday := now.Add(-24 * time.Hour)
+
+	redeemable := func(v models.Voucher, used int) *models.RedeemableVoucher {
+		v.ID, v.Name, v.Active = 1, "Hemat", true
+		if v.UsesPerCode == 0 {
+			v.UsesPerCode = 1
+		}
+		return &models.RedeemableVoucher{Voucher: v, Code: models.VoucherCode{ID: 7, VoucherID: 1, Code: "HEMAT", Redemptions: used}}
+	}
+
+	tests := []struct {
+		name      string
+		trx       *models.Transaction
+		voucher   *models.RedeemableVoucher
+		discounts []int
+		problem   string
+	}{
+		{
+			name:      "percent off the whole cart",
+			trx:       cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			voucher:   redeemable(models.Voucher{Type: models.VoucherTypePercentOff, Value: 10}, 0),
+			discounts: []int{700, 200},
+		},
+		{
+			name:      "percent off is capped",
+			trx:       cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			voucher:   redeemable(models.Voucher{Type: models.VoucherTypePercentOff, Value: 50, MaxDiscount: 1000}, 0),
+			discounts: []int{778, 222},
+		},
+		{
+			name:      "amount off the category in scope",
+			trx:       cart([3]int{1, 3500, 2}, [3]int{3, 2000, 1}),
+			voucher:   redeemable(models.Voucher{Type: models.VoucherTypeAmountOff, Value: 10000, CategoryIDs: []int{10}}, 0),
+			discounts: []int{7000, 0},
+		},
+		{
+			name:    "nothing in scope",
+			trx:     cart([3]int{3, 2000, 1}),
+			voucher: redeemable(models.Voucher{Type: models.VoucherTypeAmountOff, Value: 500, ProductIDs: []int{1}}, 0),
+			problem: "does not apply",
+		},
+		{
+			name:    "below minimum purchase",
+			trx:     cart([3]int{3, 2000, 1}),
+			voucher: redeemable(models.Voucher{Type: models.VoucherTypeAmountOff, Value: 500, MinPurchase: 5000}, 0),
+			problem: "minimum purchase",
+		},
+		{
+			name:    "code used up",
+			trx:     cart([3]int{3, 2000, 1}),
+			voucher: redeemable(models.Voucher{Type: models.VoucherTypeAmountOff, Value: 500, UsesPerCode: 2}, 2),
+			problem: "already been used",
+		},
+		{
+			name:    "voucher fully redeemed",
+			trx:     cart([3]int{3, 2000, 1}),
+			voucher: redeemable(models.Voucher{Type: models.VoucherTypeAmountOff, Value: 500, MaxUses: 5, Redemptions: 5}, 0),
+			problem: "fully redeemed",
+		},
+		{
+			name:    "expired",
+			trx:     cart([3]int{3, 2000, 1}),
+			voucher: redeemable(models.Voucher{Type: models.VoucherTypeAmountOff, Value: 500, EndsAt: &yesterday}, 0),
+			problem: "expired",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := applyVoucher(tc.trx, tc.voucher, now)
+			if tc.problem != "" {
+				var verr *repositories.ValidationError
+				if !errors.As(err, &verr) || verr.Fields[0].Rule != RuleRedeemable || !strings.Contains(verr.Fields[0].Message, tc.problem) {
+					t.Fatalf("err = %v, want %q", err, tc.problem)
+				}
+				if tc.trx.Voucher != nil || tc.trx.DiscountAmount != 0 {
+					t.Errorf("rejected voucher changed the draft: %+v", tc.trx)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyVoucher: %v", err)
+			}
+
+			discounts := make([]int, len(tc.trx.Details))
+			for i, d := range tc.trx.Details {
+				discounts[i] = d.Discount
+			}
+			if !slices.Equal(discounts, tc.discounts) {
+				t.Errorf("discounts = %v, want %v", discounts, tc.discounts)
+			}
+			v := tc.trx.Voucher
+			if v == nil || v.VoucherCodeID != 7 || v.Amount != tc.trx.DiscountAmount || tc.trx.Subtotal-v.Amount != tc.trx.TotalAmount {
+				t.Errorf("voucher %+v does not add up: subtotal %d, total %d", v, tc.trx.Subtotal, tc.trx.TotalAmount)
+			}
+		})
+	}
+}
+
+func TestNewVoucherCode(t *testing.T) {
+	seen := map[string]bool{}
+	for range 1000 {
+		code, err := newVoucherCode("PROMO")
+		if err != nil {
+			t.Fatalf("newVoucherCode: %v", err)
+		}
+		random := strings.TrimPrefix(code, "PROMO")
+		if len(random) != VoucherCodeLength || strings.Trim(random, voucherCodeAlphabet) != "" || seen[code] {
+			t.Fatalf("bad or repeated code %q", code)
+		}
+		seen[code] = true
+	}
+}